@@ -0,0 +1,425 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Alert rules are declared in config.toml as [[alert]] tables and evaluated
+// against every scan tick (client stats, scan results, roams) and every
+// latency tick. Threshold rules track pending → firing → resolved state per
+// subject (interface or latency target) and only fire once the condition
+// has held for for_seconds; event rules (roam duration, new open SSID)
+// trigger once per occurrence. Every transition is published as an
+// AlertEvent.
+//
+//	[[alert]]
+//	name = "weak-signal"
+//	metric = "client.signal"
+//	op = "<"
+//	threshold = -72
+//	for_seconds = 30
+//
+//	[[alert]]
+//	name = "gateway-loss"
+//	metric = "latency.loss_percent"
+//	target = "gateway"
+//	op = ">"
+//	threshold = 5
+//	window_seconds = 60
+
+// AlertRule is one declarative rule from the config file.
+//
+//   - Metric: one of alertMetrics.
+//   - Op/Threshold: the firing condition, e.g. "<" -72. Ignored by
+//     network.new_open.
+//   - ForSeconds: how long a threshold condition must hold before firing.
+//     0 fires on the first matching tick.
+//   - Target: optional subject filter — an interface name for client.*, a
+//     latency target label for latency.*, an SSID for network.new_open.
+//   - WindowSeconds: latency window to read (1, 10 or 60; default 60).
+//   - Severity: free-form, "warning" when empty.
+type AlertRule struct {
+	Name          string  `toml:"name" json:"name"`
+	Metric        string  `toml:"metric" json:"metric"`
+	Op            string  `toml:"op" json:"op"`
+	Threshold     float64 `toml:"threshold" json:"threshold"`
+	ForSeconds    int     `toml:"for_seconds" json:"forSeconds"`
+	Target        string  `toml:"target" json:"target"`
+	WindowSeconds int     `toml:"window_seconds" json:"windowSeconds"`
+	Severity      string  `toml:"severity" json:"severity"`
+}
+
+// alertMetric describes how a metric is sampled. Threshold metrics yield a
+// value per subject per tick; event metrics fire per occurrence.
+type alertMetric struct {
+	event bool
+	unit  string
+}
+
+var alertMetrics = map[string]alertMetric{
+	"client.signal":        {unit: "dBm"},
+	"client.snr":           {unit: "dB"},
+	"client.retry_rate":    {unit: "%"},
+	"client.tx_bitrate":    {unit: "Mbps"},
+	"client.connected":     {},
+	"latency.loss_percent": {unit: "%"},
+	"latency.avg_ms":       {unit: "ms"},
+	"latency.max_ms":       {unit: "ms"},
+	"roam.duration_ms":     {event: true, unit: "ms"},
+	"network.new_open":     {event: true},
+}
+
+var alertOps = map[string]func(v, t float64) bool{
+	"<":  func(v, t float64) bool { return v < t },
+	"<=": func(v, t float64) bool { return v <= t },
+	">":  func(v, t float64) bool { return v > t },
+	">=": func(v, t float64) bool { return v >= t },
+	"==": func(v, t float64) bool { return v == t },
+	"!=": func(v, t float64) bool { return v != t },
+}
+
+// validateAlertRules drops rules that can't be evaluated and fills
+// defaults, returning a note per dropped rule for Config.validate.
+func validateAlertRules(rules []AlertRule) ([]AlertRule, []string) {
+	var out []AlertRule
+	var notes []string
+	seen := make(map[string]bool)
+	for i, r := range rules {
+		m, ok := alertMetrics[r.Metric]
+		switch {
+		case r.Name == "":
+			notes = append(notes, fmt.Sprintf("alert %d has no name, dropped", i))
+			continue
+		case seen[r.Name]:
+			notes = append(notes, fmt.Sprintf("alert %q defined twice, later one dropped", r.Name))
+			continue
+		case !ok:
+			notes = append(notes, fmt.Sprintf("alert %q: unknown metric %q, dropped", r.Name, r.Metric))
+			continue
+		case r.Metric != "network.new_open" && alertOps[r.Op] == nil:
+			notes = append(notes, fmt.Sprintf("alert %q: unknown op %q, dropped", r.Name, r.Op))
+			continue
+		case r.WindowSeconds != 0 && !slices.Contains(latencyWindows, r.WindowSeconds):
+			// Only these windows are computed; any other would never match.
+			notes = append(notes, fmt.Sprintf("alert %q: window_seconds=%d must be 1, 10 or 60, dropped", r.Name, r.WindowSeconds))
+			continue
+		}
+		seen[r.Name] = true
+		if m.event || r.ForSeconds < 0 {
+			r.ForSeconds = 0
+		}
+		if r.WindowSeconds == 0 {
+			r.WindowSeconds = 60
+		}
+		if r.Severity == "" {
+			r.Severity = "warning"
+		}
+		out = append(out, r)
+	}
+	return out, notes
+}
+
+// Alert states.
+const (
+	AlertFiring    = "firing"
+	AlertResolved  = "resolved"
+	AlertTriggered = "triggered" // event rules: fired once, nothing to resolve
+)
+
+// Alert is one rule transition for one subject.
+type Alert struct {
+	Rule      string    `json:"rule"`
+	Metric    string    `json:"metric"`
+	Severity  string    `json:"severity"`
+	Subject   string    `json:"subject"` // interface, latency target or SSID
+	State     string    `json:"state"`
+	Value     float64   `json:"value"`
+	Threshold float64   `json:"threshold"`
+	Message   string    `json:"message"`
+	Since     time.Time `json:"since"` // when the condition started holding
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// AlertsSnapshot is what GetAlerts returns: the currently firing alerts
+// plus the most recent transitions, newest last.
+type AlertsSnapshot struct {
+	Active  []Alert `json:"active"`
+	History []Alert `json:"history"`
+}
+
+// alertHistorySize bounds the retained transitions.
+const alertHistorySize = 200
+
+type alertKey struct{ rule, subject string }
+
+type alertState struct {
+	pendingSince time.Time // zero while the condition doesn't hold
+	firing       *Alert
+}
+
+// alertEngine is the bus subscriber that evaluates the configured rules.
+// Rules are re-read from the live config on every sample, so edits apply
+// on the next tick; state for rules that disappear is dropped silently.
+type alertEngine struct {
+	cfg *liveConfig
+	bus *EventBus
+
+	mu       sync.Mutex
+	states   map[alertKey]*alertState
+	history  []Alert
+	seenSSID map[string]bool // network.new_open baseline; nil until the first scan
+}
+
+func newAlertEngine(cfg *liveConfig, bus *EventBus) *alertEngine {
+	return &alertEngine{cfg: cfg, bus: bus, states: make(map[alertKey]*alertState)}
+}
+
+func (e *alertEngine) handleEvent(ev Event) {
+	var out []Alert
+	switch ev := ev.(type) {
+	case ClientUpdateEvent:
+		out = e.observeClient(ev)
+	case LatencyUpdateEvent:
+		out = e.observeLatency(ev)
+	case RoamEvent:
+		out = e.observeRoam(ev)
+	case ScanResultEvent:
+		out = e.observeScan(ev)
+	default:
+		return
+	}
+	// Published after e.mu is released: subscribers may call back in.
+	for _, a := range out {
+		e.bus.Publish(AlertEvent{Alert: a})
+	}
+}
+
+func (e *alertEngine) rules(prefix string) []AlertRule {
+	var out []AlertRule
+	for _, r := range e.cfg.Get().Alerts {
+		if strings.HasPrefix(r.Metric, prefix) {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+func (e *alertEngine) observeClient(ev ClientUpdateEvent) []Alert {
+	rules := e.rules("client.")
+	if len(rules) == 0 {
+		return nil
+	}
+	s := ev.Stats
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []Alert
+	for _, r := range rules {
+		if r.Target != "" && r.Target != ev.Interface {
+			continue
+		}
+		var v float64
+		ok := s.Connected
+		switch r.Metric {
+		case "client.signal":
+			v = float64(s.Signal)
+		case "client.snr":
+			v = float64(s.SNR)
+		case "client.retry_rate":
+			v = s.RetryRate
+		case "client.tx_bitrate":
+			v = s.TxBitrate
+		case "client.connected":
+			v, ok = boolFloat(s.Connected), true
+		}
+		// A disconnected radio has no signal to compare; that ends
+		// (resolves) a weak-signal condition rather than holding it.
+		out = e.sampleLocked(r, ev.Interface, v, ok, ev.Timestamp, out)
+	}
+	return out
+}
+
+func (e *alertEngine) observeLatency(ev LatencyUpdateEvent) []Alert {
+	rules := e.rules("latency.")
+	if len(rules) == 0 {
+		return nil
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []Alert
+	for _, sum := range ev.Summaries {
+		ts := time.Now()
+		if sum.LastProbe != nil {
+			ts = sum.LastProbe.Timestamp
+		}
+		for _, r := range rules {
+			if r.Target != "" && r.Target != sum.Label {
+				continue
+			}
+			var w *LatencyStats
+			for i := range sum.Windows {
+				if sum.Windows[i].WindowSeconds == r.WindowSeconds {
+					w = &sum.Windows[i]
+				}
+			}
+			ok := w != nil && w.Samples > 0
+			var v float64
+			if ok {
+				switch r.Metric {
+				case "latency.loss_percent":
+					v = w.LossPercent
+				case "latency.avg_ms":
+					v = w.AvgMs
+					ok = w.LossPercent < 100 // no RTT when every probe was lost
+				case "latency.max_ms":
+					v = w.MaxMs
+					ok = w.LossPercent < 100 // no RTT when every probe was lost
+				}
+			}
+			out = e.sampleLocked(r, sum.Label, v, ok, ts, out)
+		}
+	}
+	return out
+}
+
+func (e *alertEngine) observeRoam(ev RoamEvent) []Alert {
+	var out []Alert
+	for _, r := range e.rules("roam.") {
+		if r.Target != "" && r.Target != ev.Interface {
+			continue
+		}
+		v := float64(ev.Roam.DurationMs)
+		if alertOps[r.Op](v, r.Threshold) {
+			out = append(out, e.newAlert(r, ev.Interface, AlertTriggered, v, ev.Roam.Timestamp, ev.Roam.Timestamp))
+		}
+	}
+	e.mu.Lock()
+	e.recordLocked(out)
+	e.mu.Unlock()
+	return out
+}
+
+// observeScan fires network.new_open for open SSIDs not seen before. The
+// first scan only establishes the baseline, so a restart doesn't re-alert
+// on every guest network in range.
+func (e *alertEngine) observeScan(ev ScanResultEvent) []Alert {
+	rules := e.rules("network.")
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	baseline := e.seenSSID == nil
+	if baseline {
+		e.seenSSID = make(map[string]bool)
+	}
+	var out []Alert
+	for _, n := range ev.Networks {
+		if n.Security != "Open" || n.SSID == "" || e.seenSSID[n.SSID] {
+			continue
+		}
+		e.seenSSID[n.SSID] = true
+		if baseline {
+			continue
+		}
+		for _, r := range rules {
+			if r.Target == "" || r.Target == n.SSID {
+				out = append(out, e.newAlert(r, n.SSID, AlertTriggered, float64(n.BestSignal), ev.Timestamp, ev.Timestamp))
+			}
+		}
+	}
+	e.recordLocked(out)
+	return out
+}
+
+// sampleLocked advances one threshold rule's state machine for subject and
+// appends any transition to out. ok=false means no sample this tick.
+func (e *alertEngine) sampleLocked(r AlertRule, subject string, v float64, ok bool, ts time.Time, out []Alert) []Alert {
+	key := alertKey{r.Name, subject}
+	st := e.states[key]
+	if st == nil {
+		st = &alertState{}
+		e.states[key] = st
+	}
+	if !ok || !alertOps[r.Op](v, r.Threshold) {
+		st.pendingSince = time.Time{}
+		if st.firing != nil {
+			a := e.newAlert(r, subject, AlertResolved, v, st.firing.Since, ts)
+			st.firing = nil
+			e.recordLocked([]Alert{a})
+			out = append(out, a)
+		}
+		return out
+	}
+	if st.pendingSince.IsZero() {
+		st.pendingSince = ts
+	}
+	if st.firing == nil && ts.Sub(st.pendingSince) >= time.Duration(r.ForSeconds)*time.Second {
+		a := e.newAlert(r, subject, AlertFiring, v, st.pendingSince, ts)
+		st.firing = &a
+		e.recordLocked([]Alert{a})
+		out = append(out, a)
+	}
+	if st.firing != nil {
+		st.firing.Value = v
+	}
+	return out
+}
+
+func (e *alertEngine) newAlert(r AlertRule, subject, state string, v float64, since, ts time.Time) Alert {
+	a := Alert{
+		Rule: r.Name, Metric: r.Metric, Severity: r.Severity, Subject: subject,
+		State: state, Value: v, Threshold: r.Threshold, Since: since, Timestamp: ts,
+	}
+	unit := alertMetrics[r.Metric].unit
+	switch {
+	case r.Metric == "network.new_open":
+		a.Message = fmt.Sprintf("%s: new open network %q (%d dBm)", r.Name, subject, int(v))
+	case state == AlertResolved:
+		a.Message = fmt.Sprintf("%s resolved on %s", r.Name, subject)
+	default:
+		a.Message = fmt.Sprintf("%s on %s: %s = %g%s (%s %g)", r.Name, subject, r.Metric, v, unit, r.Op, r.Threshold)
+	}
+	return a
+}
+
+func (e *alertEngine) recordLocked(alerts []Alert) {
+	e.history = append(e.history, alerts...)
+	if n := len(e.history) - alertHistorySize; n > 0 {
+		e.history = append([]Alert(nil), e.history[n:]...)
+	}
+}
+
+// snapshot returns the firing alerts (sorted by rule, then subject) and
+// the transition history. State for rules no longer configured is pruned.
+func (e *alertEngine) snapshot() AlertsSnapshot {
+	configured := make(map[string]bool)
+	for _, r := range e.cfg.Get().Alerts {
+		configured[r.Name] = true
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	out := AlertsSnapshot{Active: []Alert{}, History: append([]Alert{}, e.history...)}
+	for key, st := range e.states {
+		if !configured[key.rule] {
+			delete(e.states, key)
+			continue
+		}
+		if st.firing != nil {
+			out.Active = append(out.Active, *st.firing)
+		}
+	}
+	sort.Slice(out.Active, func(i, j int) bool {
+		if out.Active[i].Rule != out.Active[j].Rule {
+			return out.Active[i].Rule < out.Active[j].Rule
+		}
+		return out.Active[i].Subject < out.Active[j].Subject
+	})
+	return out
+}
+
+// GetAlerts returns the currently firing alerts and recent transitions.
+func (ws *WiFiService) GetAlerts() AlertsSnapshot {
+	return ws.alerts.snapshot()
+}
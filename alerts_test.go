@@ -0,0 +1,143 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// newTestAlertEngine wires an engine to its own bus and returns the
+// transitions it publishes.
+func newTestAlertEngine(t *testing.T, rules ...AlertRule) (*alertEngine, *EventBus, *[]Alert) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Alerts = rules
+	live := newLiveConfig(DefaultConfig())
+	live.Set(cfg) // validates, as LoadConfig does
+	if got := len(live.Get().Alerts); got != len(rules) {
+		t.Fatalf("%d of %d rules survived validation", got, len(rules))
+	}
+	bus := NewEventBus()
+	e := newAlertEngine(live, bus)
+	bus.Subscribe(e.handleEvent)
+	var fired []Alert
+	SubscribeTo(bus, func(ev AlertEvent) { fired = append(fired, ev.Alert) })
+	return e, bus, &fired
+}
+
+func TestAlertThresholdHoldsForDuration(t *testing.T) {
+	e, bus, fired := newTestAlertEngine(t, AlertRule{
+		Name: "weak-signal", Metric: "client.signal", Op: "<", Threshold: -72, ForSeconds: 30,
+	})
+	t0 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	sample := func(sec int, connected bool, signal int) {
+		bus.Publish(ClientUpdateEvent{
+			Interface: "wlan0", Primary: true, Timestamp: t0.Add(time.Duration(sec) * time.Second),
+			Stats: ClientStats{Connected: connected, Signal: signal},
+		})
+	}
+
+	sample(0, true, -75)
+	sample(10, false, 0) // disconnect restarts the hold
+	sample(20, true, -76)
+	sample(40, true, -80)
+	if len(*fired) != 0 {
+		t.Fatalf("fired before holding 30s: %+v", *fired)
+	}
+	sample(50, true, -78)
+	if len(*fired) != 1 || (*fired)[0].State != AlertFiring || (*fired)[0].Subject != "wlan0" {
+		t.Fatalf("after 30s below threshold: %+v", *fired)
+	}
+	if !(*fired)[0].Since.Equal(t0.Add(20 * time.Second)) {
+		t.Errorf("Since = %v, want start of the hold", (*fired)[0].Since)
+	}
+	if snap := e.snapshot(); len(snap.Active) != 1 || snap.Active[0].Value != -78 {
+		t.Errorf("active = %+v", snap.Active)
+	}
+
+	sample(60, true, -60)
+	if len(*fired) != 2 || (*fired)[1].State != AlertResolved {
+		t.Fatalf("after recovery: %+v", *fired)
+	}
+	if snap := e.snapshot(); len(snap.Active) != 0 || len(snap.History) != 2 {
+		t.Errorf("snapshot after resolve = %+v", snap)
+	}
+}
+
+func TestAlertLatencyLossWindow(t *testing.T) {
+	_, bus, fired := newTestAlertEngine(t, AlertRule{
+		Name: "gateway-loss", Metric: "latency.loss_percent", Target: "gateway", Op: ">", Threshold: 5,
+	})
+	summary := func(label string, window int, loss float64) LatencyTargetSummary {
+		return LatencyTargetSummary{
+			Label:     label,
+			LastProbe: &LatencyProbe{Timestamp: time.Unix(100, 0)},
+			Windows: []LatencyStats{
+				{WindowSeconds: 10, Samples: 10, LossPercent: 0},
+				{WindowSeconds: window, Samples: 60, LossPercent: loss},
+			},
+		}
+	}
+
+	bus.Publish(LatencyUpdateEvent{Summaries: []LatencyTargetSummary{summary("1.1.1.1", 60, 50)}})
+	bus.Publish(LatencyUpdateEvent{Summaries: []LatencyTargetSummary{summary("gateway", 60, 3)}})
+	if len(*fired) != 0 {
+		t.Fatalf("fired on other target or below threshold: %+v", *fired)
+	}
+	bus.Publish(LatencyUpdateEvent{Summaries: []LatencyTargetSummary{summary("gateway", 60, 8.5)}})
+	if len(*fired) != 1 || (*fired)[0].Value != 8.5 || (*fired)[0].Subject != "gateway" {
+		t.Fatalf("fired = %+v", *fired)
+	}
+}
+
+func TestAlertEventRules(t *testing.T) {
+	e, bus, fired := newTestAlertEngine(t,
+		AlertRule{Name: "slow-roam", Metric: "roam.duration_ms", Op: ">", Threshold: 2000},
+		AlertRule{Name: "open-ssid", Metric: "network.new_open", Severity: "critical"},
+	)
+
+	bus.Publish(RoamEvent{Interface: "wlan0", Roam: RoamingEvent{DurationMs: 1500}})
+	bus.Publish(RoamEvent{Interface: "wlan0", Roam: RoamingEvent{DurationMs: 4000}})
+
+	scan := func(ssids ...string) {
+		var networks []Network
+		for _, s := range ssids {
+			networks = append(networks, Network{SSID: s, Security: "Open", BestSignal: -60})
+		}
+		networks = append(networks, Network{SSID: "Corp", Security: "WPA2"})
+		bus.Publish(ScanResultEvent{Networks: networks})
+	}
+	scan("Guest")         // baseline
+	scan("Guest", "Free") // Free is new
+	scan("Guest", "Free")
+
+	if len(*fired) != 2 {
+		t.Fatalf("fired = %+v, want one slow roam and one new open SSID", *fired)
+	}
+	if a := (*fired)[0]; a.Rule != "slow-roam" || a.State != AlertTriggered || a.Value != 4000 {
+		t.Errorf("roam alert = %+v", a)
+	}
+	if a := (*fired)[1]; a.Rule != "open-ssid" || a.Subject != "Free" || a.Severity != "critical" {
+		t.Errorf("open SSID alert = %+v", a)
+	}
+	if snap := e.snapshot(); len(snap.Active) != 0 {
+		t.Errorf("event alerts left active: %+v", snap.Active)
+	}
+}
+
+func TestValidateAlertRules(t *testing.T) {
+	rules, notes := validateAlertRules([]AlertRule{
+		{Name: "ok", Metric: "client.snr", Op: "<=", Threshold: 15},
+		{Name: "ok", Metric: "client.snr", Op: "<", Threshold: 10},
+		{Name: "bad-metric", Metric: "client.vibes", Op: "<"},
+		{Name: "bad-op", Metric: "client.signal", Op: "=<"},
+		{Metric: "client.signal", Op: "<"},
+		{Name: "bad-window", Metric: "latency.avg_ms", Op: ">", Threshold: 100, WindowSeconds: 30},
+		{Name: "short-window", Metric: "latency.avg_ms", Op: ">", Threshold: 100, WindowSeconds: 10},
+	})
+	if len(rules) != 2 || rules[0].Severity != "warning" || rules[0].WindowSeconds != 60 || rules[1].WindowSeconds != 10 {
+		t.Errorf("rules = %+v", rules)
+	}
+	if len(notes) != 5 {
+		t.Errorf("notes = %q, want one per dropped rule", notes)
+	}
+}
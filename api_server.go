@@ -0,0 +1,706 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Local HTTP API. Opt-in via config api_listen (or the CLI's -api flag); it
+// exposes the same data as the Wails bindings as JSON and streams service
+// events over Server-Sent Events and WebSocket so dashboards and scripts can
+// follow a running instance. The API is read-only: starting/stopping scans
+// and editing config stay with the UI and CLI.
+//
+// Endpoints (all GET):
+//
+//	/api/status      scanning state, scanned interfaces (primary first), scan options
+//	/api/interfaces  GetAvailableInterfaces
+//	/api/networks    GetNetworks
+//	/api/client      GetClientStats
+//	/api/clients     GetInterfaceClientStats
+//	/api/capabilities
+//	                 GetBackendCapabilities
+//	/api/alerts      GetAlerts
+//	/api/rogues      GetRogueFindings
+//	/api/security    GetSecurityAudit
+//	/api/channels    GetChannelAnalysis
+//	/api/roaming     GetRoamingAnalysis
+//	/api/latency     GetLatency
+//	/api/throughput  GetThroughputResults
+//	/api/history     QueryHistory / ExportHistory (?metric=&subject=&from=&to=
+//	                 &resolution=&format=json|csv; from/to are RFC 3339 or
+//	                 a duration ago such as 24h)
+//	/api/export      ExportNetworks (?format=json|csv)
+//	/api/report      GenerateHTMLReport (?format=html|pdf; pdf is the
+//	                 SavePDFReport document)
+//	/api/jobs        GetJobHistory
+//	/api/surveys     ListSurveys; /api/surveys/{id} is ExportSurvey
+//	                 (?format=json|csv) and /api/surveys/compare is
+//	                 CompareSurveys (?a=&b=)
+//	/api/surveys/{id}/heatmap
+//	                 GetSurveyHeatmap (?ssid=&bssid=&threshold=&cell=
+//	                 &format=json|png|svg; png is composited over the
+//	                 floor plan)
+//	/api/events      event stream, text/event-stream
+//	/api/ws          event stream, WebSocket (text frames of apiEvent JSON)
+
+// apiEvent is the wire form of one streamed event.
+type apiEvent struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// apiSubscriberBuffer bounds how far a stream client may fall behind before
+// events are dropped for it. A few seconds of scan + latency traffic.
+const apiSubscriberBuffer = 64
+
+// eventStream fans emitted events out to API stream clients. Events are
+// encoded once at publish time; subscribers receive the JSON bytes.
+type eventStream struct {
+	mu   sync.Mutex
+	subs map[chan apiStreamMessage]struct{}
+}
+
+type apiStreamMessage struct {
+	name    string
+	payload []byte
+}
+
+func newEventStream() *eventStream {
+	return &eventStream{subs: make(map[chan apiStreamMessage]struct{})}
+}
+
+func (s *eventStream) subscribe() chan apiStreamMessage {
+	ch := make(chan apiStreamMessage, apiSubscriberBuffer)
+	s.mu.Lock()
+	s.subs[ch] = struct{}{}
+	s.mu.Unlock()
+	return ch
+}
+
+func (s *eventStream) unsubscribe(ch chan apiStreamMessage) {
+	s.mu.Lock()
+	delete(s.subs, ch)
+	s.mu.Unlock()
+}
+
+// handleEvent is the stream's EventBus subscriber: it forwards each event's
+// wire form to connected clients.
+func (s *eventStream) handleEvent(e Event) {
+	for _, w := range e.wire() {
+		s.publish(w.name, w.data)
+	}
+}
+
+// publish never blocks: a subscriber whose buffer is full misses the event
+// rather than stalling the scan loop.
+func (s *eventStream) publish(name string, data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.subs) == 0 {
+		return
+	}
+	payload, err := json.Marshal(apiEvent{Type: name, Data: data})
+	if err != nil {
+		slog.Debug("api event encode failed", "name", name, "err", err)
+		return
+	}
+	msg := apiStreamMessage{name: name, payload: payload}
+	for ch := range s.subs {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
+
+// apiServer is the opt-in HTTP listener serving /api/*.
+type apiServer struct {
+	addr  string
+	token string
+	srv   *http.Server
+	// stop cancels the context every request derives from, which is how
+	// Close reaches event streams: hijacked WebSocket connections are
+	// beyond the http.Server's reach.
+	stop context.CancelFunc
+}
+
+func startAPIServer(addr, token string, ws *WiFiService) (*apiServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("api listen %s: %w", addr, err)
+	}
+	ctx, stop := context.WithCancel(context.Background())
+	srv := &http.Server{
+		Handler:           newAPIHandler(ws, token),
+		ReadHeaderTimeout: 5 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("api server stopped", "event", "api_error", "addr", addr, "err", err)
+		}
+	}()
+	slog.Info("api server listening", "event", "api_start", "addr", ln.Addr().String())
+	return &apiServer{addr: addr, token: token, srv: srv, stop: stop}, nil
+}
+
+func (a *apiServer) Close() {
+	if a == nil {
+		return
+	}
+	// Ending the streams first lets Shutdown drain SSE responses; the
+	// WebSocket handlers send a close frame and drop their connections,
+	// which Shutdown and Close never touch once hijacked.
+	a.stop()
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = a.srv.Shutdown(ctx)
+	_ = a.srv.Close()
+}
+
+// syncAPIServer starts, stops or restarts the API listener to match
+// config.APIListen / APIToken. Called on attach and after every config
+// update.
+func (ws *WiFiService) syncAPIServer() {
+	cfg := ws.config.Get()
+	ws.apiMu.Lock()
+	defer ws.apiMu.Unlock()
+	if ws.apiServer != nil && ws.apiServer.addr == cfg.APIListen && ws.apiServer.token == cfg.APIToken {
+		return
+	}
+	ws.apiServer.Close()
+	ws.apiServer = nil
+	if cfg.APIListen == "" {
+		return
+	}
+	srv, err := startAPIServer(cfg.APIListen, cfg.APIToken, ws)
+	if err != nil {
+		slog.Error("api server failed to start", "event", "api_error", "addr", cfg.APIListen, "err", err)
+		return
+	}
+	ws.apiServer = srv
+}
+
+// newAPIHandler builds the /api mux. The JSON endpoints go through an App
+// wrapping ws so the API can't drift from what the Wails bindings return.
+func newAPIHandler(ws *WiFiService, token string) http.Handler {
+	app := &App{wifiService: ws}
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("GET /api/status", func(w http.ResponseWriter, r *http.Request) {
+		ifaces := ws.ScanningInterfaces()
+		primary := ""
+		if len(ifaces) > 0 {
+			primary = ifaces[0]
+		}
+		writeAPIJSON(w, map[string]interface{}{"scanning": app.IsScanning(), "interface": primary, "interfaces": ifaces, "scanOptions": app.GetScanOptions()})
+	})
+	mux.HandleFunc("GET /api/interfaces", func(w http.ResponseWriter, r *http.Request) {
+		ifaces, err := app.GetAvailableInterfaces()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeAPIJSON(w, ifaces)
+	})
+	mux.HandleFunc("GET /api/networks", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetNetworks())
+	})
+	mux.HandleFunc("GET /api/client", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetClientStats())
+	})
+	mux.HandleFunc("GET /api/clients", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetInterfaceClientStats())
+	})
+	mux.HandleFunc("GET /api/capabilities", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetBackendCapabilities())
+	})
+	mux.HandleFunc("GET /api/alerts", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetAlerts())
+	})
+	mux.HandleFunc("GET /api/rogues", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetRogueFindings())
+	})
+	mux.HandleFunc("GET /api/security", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetSecurityAudit())
+	})
+	mux.HandleFunc("GET /api/channels", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetChannelAnalysis())
+	})
+	mux.HandleFunc("GET /api/roaming", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetRoamingAnalysis())
+	})
+	mux.HandleFunc("GET /api/latency", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetLatency())
+	})
+	mux.HandleFunc("GET /api/throughput", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetThroughputResults())
+	})
+	mux.HandleFunc("GET /api/history", func(w http.ResponseWriter, r *http.Request) {
+		v := r.URL.Query()
+		q := HistoryQuery{Metric: v.Get("metric"), Subject: v.Get("subject"), Resolution: v.Get("resolution")}
+		var err error
+		if q.From, err = parseAPITime(v.Get("from")); err == nil {
+			q.To, err = parseAPITime(v.Get("to"))
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		format := v.Get("format")
+		if format == "" {
+			format = "json"
+		}
+		out, err := app.ExportHistory(q, format)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		_, _ = io.WriteString(w, out)
+	})
+	mux.HandleFunc("GET /api/export", func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		out, err := app.ExportNetworks(format)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		_, _ = io.WriteString(w, out)
+	})
+	mux.HandleFunc("GET /api/report", func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Query().Get("format") {
+		case "pdf":
+			pdf, err := ws.GeneratePDFReport()
+			if err != nil {
+				writeAPIError(w, http.StatusInternalServerError, err)
+				return
+			}
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = w.Write(pdf)
+			return
+		case "", "html":
+		default:
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unsupported report format %q (use html or pdf)", r.URL.Query().Get("format")))
+			return
+		}
+		out, err := app.GenerateHTMLReport()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, out)
+	})
+	mux.HandleFunc("GET /api/jobs", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIJSON(w, app.GetJobHistory())
+	})
+	mux.HandleFunc("GET /api/surveys", func(w http.ResponseWriter, r *http.Request) {
+		surveys, err := app.ListSurveys()
+		if err != nil {
+			writeAPIError(w, http.StatusInternalServerError, err)
+			return
+		}
+		writeAPIJSON(w, surveys)
+	})
+	mux.HandleFunc("GET /api/surveys/compare", func(w http.ResponseWriter, r *http.Request) {
+		cmp, err := app.CompareSurveys(r.URL.Query().Get("a"), r.URL.Query().Get("b"))
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		writeAPIJSON(w, cmp)
+	})
+	mux.HandleFunc("GET /api/surveys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = "json"
+		}
+		out, err := app.ExportSurvey(r.PathValue("id"), format)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		if format == "csv" {
+			w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		} else {
+			w.Header().Set("Content-Type", "application/json")
+		}
+		_, _ = io.WriteString(w, out)
+	})
+	mux.HandleFunc("GET /api/surveys/{id}/heatmap", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		req := HeatmapRequest{SSID: q.Get("ssid"), BSSID: q.Get("bssid")}
+		var err error
+		if req.Threshold, err = parseAPIInt(q.Get("threshold")); err == nil {
+			req.CellSize, err = parseAPIInt(q.Get("cell"))
+		}
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, err)
+			return
+		}
+		switch format := q.Get("format"); format {
+		case "", "json":
+			h, err := app.GetSurveyHeatmap(r.PathValue("id"), req)
+			if err != nil {
+				writeAPIError(w, http.StatusBadRequest, err)
+				return
+			}
+			writeAPIJSON(w, h)
+		case "png", "svg":
+			out, err := ws.RenderSurveyHeatmap(r.PathValue("id"), req, format)
+			if err != nil {
+				writeAPIError(w, http.StatusBadRequest, err)
+				return
+			}
+			if format == "png" {
+				w.Header().Set("Content-Type", "image/png")
+			} else {
+				w.Header().Set("Content-Type", "image/svg+xml")
+			}
+			_, _ = w.Write(out)
+		default:
+			writeAPIError(w, http.StatusBadRequest, fmt.Errorf("unsupported heatmap format %q (use json, png or svg)", format))
+		}
+	})
+	mux.HandleFunc("GET /api/events", func(w http.ResponseWriter, r *http.Request) {
+		serveSSE(w, r, ws.stream)
+	})
+	mux.HandleFunc("GET /api/ws", func(w http.ResponseWriter, r *http.Request) {
+		serveWebSocket(w, r, ws.stream)
+	})
+
+	return apiGuard(token, mux)
+}
+
+// parseAPITime accepts an RFC 3339 timestamp or a duration before now
+// ("90m", "24h"). Empty yields the zero time.
+func parseAPITime(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid time %q: want RFC 3339 or a duration like 24h", s)
+	}
+	return t, nil
+}
+
+// parseAPIInt parses an optional integer parameter. Empty yields 0.
+func parseAPIInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return n, nil
+}
+
+// apiGuard enforces the optional bearer token and refuses cross-origin
+// browser requests when no token is set. Without the latter, any web page
+// open on the machine could read the loopback API over WebSocket, which
+// isn't subject to CORS. Tokenless requests must also name a loopback
+// Host, or a page could rebind its own DNS name to 127.0.0.1 and pass the
+// origin check. EventSource can't send headers, so the token is also
+// accepted as ?token=.
+func apiGuard(token string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if got == "" {
+				got = r.URL.Query().Get("token")
+			}
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				writeAPIError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+				return
+			}
+		} else {
+			if !isLoopbackHost(r.Host) {
+				writeAPIError(w, http.StatusForbidden, errors.New("non-loopback hosts require api_token"))
+				return
+			}
+			if origin := r.Header.Get("Origin"); origin != "" {
+				u, err := url.Parse(origin)
+				if err != nil || u.Host != r.Host {
+					writeAPIError(w, http.StatusForbidden, errors.New("cross-origin requests require api_token"))
+					return
+				}
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLoopbackHost reports whether a Host header, port optional, is
+// localhost or a loopback address.
+func isLoopbackHost(host string) bool {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func writeAPIJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Debug("api write failed", "err", err)
+	}
+}
+
+func writeAPIError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// apiKeepalive is how often idle streams get a comment / ping so proxies
+// and NAT don't drop them.
+const apiKeepalive = 30 * time.Second
+
+func serveSSE(w http.ResponseWriter, r *http.Request, stream *eventStream) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("streaming unsupported"))
+		return
+	}
+	sub := stream.subscribe()
+	defer stream.unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	keepalive := time.NewTicker(apiKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-keepalive.C:
+			if _, err := io.WriteString(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+		case msg := <-sub:
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", msg.name, msg.payload); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// WebSocket support is a minimal RFC 6455 server: the stream is push-only,
+// so it sends unfragmented text frames and only reads client frames to
+// answer pings and honour close. Not worth a dependency for that.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// wsMaxClientFrame caps what a client may send. Nothing it sends is used,
+// so anything beyond control-frame sized chatter is a protocol abuse.
+const wsMaxClientFrame = 4096
+
+func serveWebSocket(w http.ResponseWriter, r *http.Request, stream *eventStream) {
+	if !headerContainsToken(r.Header, "Connection", "upgrade") ||
+		!strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		writeAPIError(w, http.StatusBadRequest, errors.New("websocket upgrade required"))
+		return
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		writeAPIError(w, http.StatusUpgradeRequired, errors.New("unsupported websocket version"))
+		return
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		writeAPIError(w, http.StatusBadRequest, errors.New("missing Sec-WebSocket-Key"))
+		return
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		writeAPIError(w, http.StatusInternalServerError, errors.New("websocket unsupported"))
+		return
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	_, _ = fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n", websocketAccept(key))
+	if err := rw.Flush(); err != nil {
+		return
+	}
+
+	sub := stream.subscribe()
+	defer stream.unsubscribe(sub)
+
+	// The reader forwards pings for the writer to answer (only the writer
+	// touches the connection for output) and closes done on close/EOF.
+	done := make(chan struct{})
+	pings := make(chan []byte, 1)
+	go func() {
+		defer close(done)
+		readWebSocketFrames(rw.Reader, pings)
+	}()
+
+	keepalive := time.NewTicker(apiKeepalive)
+	defer keepalive.Stop()
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			// The server is closing (a hijacked connection's request
+			// context only ends with the server's base context).
+			_ = writeWebSocketFrame(conn, wsOpClose, nil)
+			return
+		case <-done:
+			_ = writeWebSocketFrame(conn, wsOpClose, nil)
+			return
+		case payload := <-pings:
+			err = writeWebSocketFrame(conn, wsOpPong, payload)
+		case <-keepalive.C:
+			err = writeWebSocketFrame(conn, wsOpPing, nil)
+		case msg := <-sub:
+			err = writeWebSocketFrame(conn, wsOpText, msg.payload)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func websocketAccept(key string) string {
+	h := sha1.Sum([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h[:])
+}
+
+func headerContainsToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeWebSocketFrame(conn net.Conn, opcode byte, payload []byte) error {
+	header := make([]byte, 2, 10)
+	header[0] = 0x80 | opcode // FIN, no fragmentation
+	switch n := len(payload); {
+	case n < 126:
+		header[1] = byte(n)
+	case n <= 0xFFFF:
+		header[1] = 126
+		header = binary.BigEndian.AppendUint16(header, uint16(n))
+	default:
+		header[1] = 127
+		header = binary.BigEndian.AppendUint64(header, uint64(n))
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+	if _, err := conn.Write(header); err != nil {
+		return err
+	}
+	_, err := conn.Write(payload)
+	return err
+}
+
+// readWebSocketFrames consumes client frames until close, error, or an
+// oversized/unmasked frame. Ping payloads are handed to the writer.
+func readWebSocketFrames(r *bufio.Reader, pings chan<- []byte) {
+	var head [2]byte
+	for {
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return
+		}
+		opcode := head[0] & 0x0F
+		masked := head[1]&0x80 != 0
+		n := uint64(head[1] & 0x7F)
+		switch n {
+		case 126:
+			var ext [2]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			n = uint64(binary.BigEndian.Uint16(ext[:]))
+		case 127:
+			var ext [8]byte
+			if _, err := io.ReadFull(r, ext[:]); err != nil {
+				return
+			}
+			n = binary.BigEndian.Uint64(ext[:])
+		}
+		// Clients must mask (RFC 6455 §5.1).
+		if !masked || n > wsMaxClientFrame {
+			return
+		}
+		var mask [4]byte
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return
+		}
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+		switch opcode {
+		case wsOpClose:
+			return
+		case wsOpPing:
+			select {
+			case pings <- payload:
+			default:
+			}
+		}
+	}
+}
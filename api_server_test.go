@@ -0,0 +1,248 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAPIEndpointsMirrorBindings(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 21)
+	srv := httptest.NewServer(newAPIHandler(ws, ""))
+	defer srv.Close()
+
+	var networks []Network
+	getAPIJSON(t, srv.URL+"/api/networks", &networks)
+	if len(networks) != len(ws.GetNetworks()) || len(networks) == 0 {
+		t.Errorf("/api/networks returned %d networks, binding has %d", len(networks), len(ws.GetNetworks()))
+	}
+	var client ClientStats
+	getAPIJSON(t, srv.URL+"/api/client", &client)
+	if client.BSSID != "02:00:00:00:01:02" {
+		t.Errorf("/api/client BSSID = %q", client.BSSID)
+	}
+	var roaming RoamingQualityReport
+	getAPIJSON(t, srv.URL+"/api/roaming", &roaming)
+	if roaming.TotalRoams != 1 {
+		t.Errorf("/api/roaming TotalRoams = %d, want 1", roaming.TotalRoams)
+	}
+
+	var caps []BackendCapabilities
+	getAPIJSON(t, srv.URL+"/api/capabilities", &caps)
+	if len(caps) != 1 || caps[0].Interface != "sim0" || !caps[0].Noise || caps[0].Survey || len(caps[0].Bands) == 0 {
+		t.Errorf("/api/capabilities = %+v", caps)
+	}
+	var export []Network
+	getAPIJSON(t, srv.URL+"/api/export?format=json", &export)
+	if len(export) != len(networks) {
+		t.Errorf("json export: %d networks, want %d", len(export), len(networks))
+	}
+
+	resp, err := http.Get(srv.URL + "/api/export?format=csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if !strings.HasPrefix(string(body), "SSID,BSSID") {
+		t.Errorf("csv export starts %q", string(body[:min(len(body), 40)]))
+	}
+	if resp, _ := http.Get(srv.URL + "/api/export?format=xml"); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad export format status = %d", resp.StatusCode)
+	}
+}
+
+func TestAPIGuard(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 1)
+
+	open := httptest.NewServer(newAPIHandler(ws, ""))
+	defer open.Close()
+	req, _ := http.NewRequest("GET", open.URL+"/api/status", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross-origin without token: %v, %v", resp.StatusCode, err)
+	}
+	req, _ = http.NewRequest("GET", open.URL+"/api/status", nil)
+	req.Host = "rebound.example" // DNS rebinding: the name resolves to loopback
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusForbidden {
+		t.Errorf("non-loopback host without token: %v, %v", resp.StatusCode, err)
+	}
+	for _, host := range []string{"localhost:8080", "127.0.0.1", "[::1]:80"} {
+		if !isLoopbackHost(host) {
+			t.Errorf("isLoopbackHost(%q) = false", host)
+		}
+	}
+
+	locked := httptest.NewServer(newAPIHandler(ws, "s3cret"))
+	defer locked.Close()
+	if resp, _ := http.Get(locked.URL + "/api/status"); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("no token status = %d", resp.StatusCode)
+	}
+	if resp, _ := http.Get(locked.URL + "/api/status?token=s3cret"); resp.StatusCode != http.StatusOK {
+		t.Errorf("query token status = %d", resp.StatusCode)
+	}
+}
+
+func TestAPIServerSentEvents(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 1)
+	srv := httptest.NewServer(newAPIHandler(ws, ""))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "/api/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	waitForSubscriber(t, ws.stream)
+	ws.bus.Publish(RoamEvent{Interface: "sim0", Roam: RoamingEvent{NewBSSID: "aa:bb:cc:00:00:02"}})
+
+	r := bufio.NewReader(resp.Body)
+	event, _ := r.ReadString('\n')
+	data, _ := r.ReadString('\n')
+	if event != "event: roaming:detected\n" {
+		t.Errorf("event line = %q", event)
+	}
+	var got apiEvent
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(data, "data: ")), &got); err != nil || got.Type != "roaming:detected" {
+		t.Errorf("data line = %q (%v)", data, err)
+	}
+}
+
+func TestAPIWebSocket(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 1)
+	srv := httptest.NewServer(newAPIHandler(ws, ""))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(srv.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	fmt.Fprintf(conn, "GET /api/ws HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\nSec-WebSocket-Key: %s\r\nSec-WebSocket-Version: 13\r\n\r\n",
+		strings.TrimPrefix(srv.URL, "http://"), key)
+
+	r := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(r, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status = %d", resp.StatusCode)
+	}
+	// RFC 6455 §1.3 worked example.
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("Sec-WebSocket-Accept = %q", got)
+	}
+
+	waitForSubscriber(t, ws.stream)
+	ws.bus.Publish(LatencyUpdateEvent{Summaries: []LatencyTargetSummary{}})
+
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[0] != 0x80|wsOpText || head[1]&0x80 != 0 {
+		t.Fatalf("frame header = %#x %#x, want unmasked FIN text", head[0], head[1])
+	}
+	n := int(head[1] & 0x7F)
+	if n == 126 {
+		var ext [2]byte
+		io.ReadFull(r, ext[:])
+		n = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		t.Fatal(err)
+	}
+	var got apiEvent
+	if err := json.Unmarshal(payload, &got); err != nil || got.Type != "latency:updated" {
+		t.Errorf("payload = %s (%v)", payload, err)
+	}
+
+	// A masked close frame from the client ends the stream with a close.
+	conn.Write([]byte{0x80 | wsOpClose, 0x80, 1, 2, 3, 4})
+	if _, err := io.ReadFull(r, head[:]); err != nil || head[0] != 0x80|wsOpClose {
+		t.Errorf("after client close got %#x, %v; want close frame", head[0], err)
+	}
+}
+
+func TestAPIServerCloseEndsWebSockets(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 1)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+	srv, err := startAPIServer(addr, "", ws)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		srv.Close()
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(5 * time.Second))
+	fmt.Fprintf(conn, "GET /api/ws HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", addr)
+	r := bufio.NewReader(conn)
+	if resp, err := http.ReadResponse(r, nil); err != nil || resp.StatusCode != http.StatusSwitchingProtocols {
+		srv.Close()
+		t.Fatalf("handshake: %v", err)
+	}
+	waitForSubscriber(t, ws.stream)
+
+	// Stopping the listener closes the hijacked connection too.
+	srv.Close()
+	var head [2]byte
+	if _, err := io.ReadFull(r, head[:]); err != nil || head[0] != 0x80|wsOpClose {
+		t.Fatalf("after server close got %#x, %v; want close frame", head[0], err)
+	}
+	if _, err := r.ReadByte(); err != io.EOF {
+		t.Errorf("connection still open after close frame: %v", err)
+	}
+}
+
+func getAPIJSON(t *testing.T, url string, v interface{}) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("GET %s: status %d", url, resp.StatusCode)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("GET %s: decode: %v", url, err)
+	}
+}
+
+// waitForSubscriber blocks until a stream handler has subscribed, so an
+// event emitted next is guaranteed to reach it.
+func waitForSubscriber(t *testing.T, s *eventStream) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		n := len(s.subs)
+		s.mu.Unlock()
+		if n > 0 {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("no stream subscriber within 5s")
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/csv"
 	"encoding/json"
 	"fmt"
@@ -32,6 +33,7 @@ func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	installWailsForwarding(ctx)
 	a.wifiService.SetContext(ctx)
+	a.wifiService.StartJobScheduler(ctx)
 }
 
 // shutdown is called when the app is exiting.
@@ -41,7 +43,7 @@ func (a *App) shutdown(ctx context.Context) {
 
 // GetAvailableInterfaces returns a list of available WiFi interfaces
 func (a *App) GetAvailableInterfaces() ([]string, error) {
-	return a.wifiService.scanner.GetInterfaces()
+	return a.wifiService.scanner.GetInterfaces(a.wifiService.appContext())
 }
 
 // StartScanning begins periodic WiFi scanning on the specified interface
@@ -49,6 +51,26 @@ func (a *App) StartScanning(iface string) error {
 	return a.wifiService.StartScanning(iface)
 }
 
+// StartScanningInterfaces scans several interfaces concurrently and merges
+// their results per BSSID. The first interface is the primary one whose
+// client stats GetClientStats reports.
+func (a *App) StartScanningInterfaces(ifaces []string) error {
+	return a.wifiService.StartScanningInterfaces(ifaces)
+}
+
+// StartScanningWithOptions is StartScanningInterfaces with a targeted scan
+// (SSIDs, channels, passive-only, dwell time) for this session instead of
+// the config's [scan] table.
+func (a *App) StartScanningWithOptions(ifaces []string, opts ScanOptions) error {
+	return a.wifiService.StartScanningWithOptions(ifaces, opts)
+}
+
+// GetScanOptions returns the scan options of the current (or last)
+// scanning session.
+func (a *App) GetScanOptions() ScanOptions {
+	return a.wifiService.ScanOptions()
+}
+
 // StopScanning stops the periodic WiFi scanning
 func (a *App) StopScanning() {
 	a.wifiService.StopScanning()
@@ -64,6 +86,48 @@ func (a *App) GetClientStats() ClientStats {
 	return a.wifiService.GetClientStats()
 }
 
+// GetInterfaceClientStats returns client stats for every scanned
+// interface, keyed by interface name.
+func (a *App) GetInterfaceClientStats() map[string]ClientStats {
+	return a.wifiService.GetInterfaceClientStats()
+}
+
+// GetBackendCapabilities reports which fields the backend really measures
+// on each scanned interface (every available one while idle), so the UI
+// can tell an unmeasured zero from a real one.
+func (a *App) GetBackendCapabilities() []BackendCapabilities {
+	return a.wifiService.GetBackendCapabilities()
+}
+
+// GetAlerts returns the alert rules currently firing plus recent alert
+// transitions.
+func (a *App) GetAlerts() AlertsSnapshot {
+	return a.wifiService.GetAlerts()
+}
+
+// GetRogueFindings returns APs that look like rogues or evil twins of
+// known networks, most severe first.
+func (a *App) GetRogueFindings() []RogueFinding {
+	return a.wifiService.GetRogueFindings()
+}
+
+// TrustAccessPoint accepts a flagged BSSID as part of its network.
+func (a *App) TrustAccessPoint(bssid string) error {
+	return a.wifiService.TrustAccessPoint(bssid)
+}
+
+// GetSecurityAudit grades each network's advertised security settings and
+// returns the findings with remediation advice, worst network first.
+func (a *App) GetSecurityAudit() []NetworkSecurityAudit {
+	return a.wifiService.GetSecurityAudit()
+}
+
+// GetJobHistory returns the runs of the scheduled [[job]] surveys from
+// config.toml, newest first, including one still in progress.
+func (a *App) GetJobHistory() []JobRun {
+	return a.wifiService.GetJobHistory()
+}
+
 // GetAPSignalHistory returns the per-BSSID signal histories the backend
 // has accumulated. The Signal tab uses this on mount so the "Other APs"
 // chart can hydrate from the backend store rather than starting fresh
@@ -72,6 +136,25 @@ func (a *App) GetAPSignalHistory() []APSignalHistory {
 	return a.wifiService.GetAPSignalHistories()
 }
 
+// QueryHistory returns long-term metric history (client signal and retry
+// rate, per-AP signal, latency) for a time range, at raw or 1m/15m/1h
+// resolution.
+func (a *App) QueryHistory(q HistoryQuery) ([]HistorySeries, error) {
+	return a.wifiService.QueryHistory(q)
+}
+
+// ExportHistory renders a history query as "json" or "csv".
+func (a *App) ExportHistory(q HistoryQuery, format string) (string, error) {
+	return a.wifiService.ExportHistory(q, format)
+}
+
+// GenerateHTMLReport renders the diagnostic report from the current scan,
+// client and roaming state using the embedded template, or the one at
+// report_template_path when set.
+func (a *App) GenerateHTMLReport() (string, error) {
+	return a.wifiService.GenerateHTMLReport()
+}
+
 // GetChannelAnalysis returns channel utilization information
 func (a *App) GetChannelAnalysis() []ChannelInfo {
 	return a.wifiService.GetChannelAnalysis()
@@ -109,6 +192,142 @@ func (a *App) GetLatency() []LatencyTargetSummary {
 	return a.wifiService.GetLatencySummaries()
 }
 
+// RunThroughputTest runs iperf3 throughput tests against host for each
+// mode ("tcp-down", "tcp-up", "udp-down", "udp-up"); empty arguments use
+// the [throughput] config. Each result also goes out as a
+// `throughput:result` event as it completes.
+func (a *App) RunThroughputTest(host string, modes []string) ([]ThroughputResult, error) {
+	return a.wifiService.RunThroughputTest(host, modes)
+}
+
+// GetThroughputResults returns recent throughput results, newest first.
+func (a *App) GetThroughputResults() []ThroughputResult {
+	return a.wifiService.GetThroughputResults()
+}
+
+// ListSessions returns recorded sessions, newest first. Recording is
+// enabled with record_sessions in Settings.
+func (a *App) ListSessions() ([]SessionInfo, error) {
+	return a.wifiService.ListSessions()
+}
+
+// OpenSession loads every record of a past session for review or export.
+func (a *App) OpenSession(id string) (Session, error) {
+	return a.wifiService.OpenSession(id)
+}
+
+// DeleteSession removes a recorded session from disk.
+func (a *App) DeleteSession(id string) error {
+	return a.wifiService.DeleteSession(id)
+}
+
+// StartSurvey begins a named site survey. Mark points with MarkSurveyPoint
+// while scanning.
+func (a *App) StartSurvey(name string) (Survey, error) {
+	return a.wifiService.StartSurvey(name)
+}
+
+// StopSurvey ends the active survey and returns it.
+func (a *App) StopSurvey() (Survey, error) {
+	return a.wifiService.StopSurvey()
+}
+
+// MarkSurveyPoint captures a named measurement point ("Room 204"). It
+// resolves once the point's burst of scans is complete.
+func (a *App) MarkSurveyPoint(name string) (SurveyPoint, error) {
+	return a.wifiService.MarkSurveyPoint(name)
+}
+
+// GetActiveSurvey returns the survey in progress, or nil.
+func (a *App) GetActiveSurvey() *Survey {
+	if survey, ok := a.wifiService.GetActiveSurvey(); ok {
+		return &survey
+	}
+	return nil
+}
+
+// ListSurveys returns saved surveys, newest first.
+func (a *App) ListSurveys() ([]SurveyInfo, error) {
+	return a.wifiService.ListSurveys()
+}
+
+// OpenSurvey loads a survey with all its points.
+func (a *App) OpenSurvey(id string) (Survey, error) {
+	return a.wifiService.OpenSurvey(id)
+}
+
+// DeleteSurvey removes a finished survey from disk.
+func (a *App) DeleteSurvey(id string) error {
+	return a.wifiService.DeleteSurvey(id)
+}
+
+// ExportSurvey renders a survey as "json" or "csv" (one row per point and
+// BSSID).
+func (a *App) ExportSurvey(id, format string) (string, error) {
+	return a.wifiService.ExportSurvey(id, format)
+}
+
+// ChooseSurveyFloorPlan asks for a floor-plan image and attaches it to the
+// survey. A cancelled dialog returns the survey unchanged.
+func (a *App) ChooseSurveyFloorPlan(id string) (Survey, error) {
+	if a.ctx == nil {
+		return Survey{}, fmt.Errorf("app context not initialized")
+	}
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:   "Floor plan",
+		Filters: []runtime.FileFilter{{DisplayName: "Images (*.png;*.jpg;*.gif)", Pattern: "*.png;*.jpg;*.jpeg;*.gif"}},
+	})
+	if err != nil {
+		return Survey{}, err
+	}
+	if path == "" {
+		return a.wifiService.OpenSurvey(id)
+	}
+	return a.wifiService.SetSurveyFloorPlan(id, path)
+}
+
+// PlaceSurveyPoint positions a survey's point (by index) on its floor plan,
+// in image pixels.
+func (a *App) PlaceSurveyPoint(id string, index int, x, y float64) (Survey, error) {
+	return a.wifiService.PlaceSurveyPoint(id, index, x, y)
+}
+
+// MarkSurveyPointAt is MarkSurveyPoint for a spot clicked on the floor plan.
+func (a *App) MarkSurveyPointAt(name string, x, y float64) (SurveyPoint, error) {
+	return a.wifiService.MarkSurveyPointAt(name, x, y)
+}
+
+// GetSurveyHeatmap interpolates a survey's placed points into a signal grid
+// for one SSID, one BSSID, or the best of any network.
+func (a *App) GetSurveyHeatmap(id string, req HeatmapRequest) (Heatmap, error) {
+	return a.wifiService.GetSurveyHeatmap(id, req)
+}
+
+// RenderSurveyHeatmap returns the heatmap as SVG markup to overlay on the
+// floor plan ("svg"), or as a PNG data URL composited over it ("png").
+func (a *App) RenderSurveyHeatmap(id string, req HeatmapRequest, format string) (string, error) {
+	out, err := a.wifiService.RenderSurveyHeatmap(id, req, format)
+	if err != nil {
+		return "", err
+	}
+	if format == "png" {
+		return "data:image/png;base64," + base64.StdEncoding.EncodeToString(out), nil
+	}
+	return string(out), nil
+}
+
+// GetSurveyCoverage returns each SSID's covered share of the floor plan at
+// threshold dBm (0 uses coverage_threshold_dbm from the config).
+func (a *App) GetSurveyCoverage(id string, threshold int) ([]SSIDCoverage, error) {
+	return a.wifiService.GetSurveyCoverage(id, threshold)
+}
+
+// CompareSurveys matches the points of two surveys by name and reports the
+// per-BSSID signal change, e.g. before and after moving an AP.
+func (a *App) CompareSurveys(before, after string) (SurveyComparison, error) {
+	return a.wifiService.CompareSurveys(before, after)
+}
+
 func (a *App) ExportNetworks(format string) (string, error) {
 	networks := a.wifiService.GetNetworks()
 
@@ -121,6 +340,8 @@ func (a *App) ExportNetworks(format string) (string, error) {
 	return "", fmt.Errorf("unsupported format: %s. Use 'json' or 'csv'", format)
 }
 
+// exportToJSON writes the networks as a bare array, the format existing
+// consumers parse; the backend's capabilities are GetBackendCapabilities'.
 func (a *App) exportToJSON(networks []Network) (string, error) {
 	data, err := json.MarshalIndent(networks, "", "  ")
 	if err != nil {
@@ -205,10 +426,24 @@ func optIntString(v *int) string {
 	return strconv.Itoa(*v)
 }
 
+// clientStatsExport is the ExportClientStats document, with the primary
+// interface's capabilities alongside the stats.
+type clientStatsExport struct {
+	Capabilities BackendCapabilities `json:"capabilities"`
+	ClientStats
+}
+
 func (a *App) ExportClientStats() (string, error) {
 	stats := a.wifiService.GetClientStats()
+	iface := stats.Interface
+	if iface == "" {
+		iface = a.wifiService.GetConfig().DefaultInterface
+	}
 
-	data, err := json.MarshalIndent(stats, "", "  ")
+	data, err := json.MarshalIndent(clientStatsExport{
+		Capabilities: a.wifiService.scanner.Capabilities(iface),
+		ClientStats:  stats,
+	}, "", "  ")
 	if err != nil {
 		return "", fmt.Errorf("failed to marshal JSON: %w", err)
 	}
@@ -219,6 +454,21 @@ func (a *App) ExportClientStats() (string, error) {
 // SaveReport opens a save dialog and writes the given content to disk.
 // Returns the chosen file path or empty string if the user cancels.
 func (a *App) SaveReport(filename string, content string) (string, error) {
+	return a.saveReport(filename, []byte(content))
+}
+
+// SavePDFReport renders the PDF diagnostic report and saves it through the
+// same dialog as SaveReport. PDF bytes don't survive the JS string bridge,
+// so the frontend asks for the report rather than passing content.
+func (a *App) SavePDFReport(filename string) (string, error) {
+	pdf, err := a.wifiService.GeneratePDFReport()
+	if err != nil {
+		return "", err
+	}
+	return a.saveReport(filename, pdf)
+}
+
+func (a *App) saveReport(filename string, content []byte) (string, error) {
 	if a.ctx == nil {
 		return "", fmt.Errorf("app context not initialized")
 	}
@@ -232,7 +482,7 @@ func (a *App) SaveReport(filename string, content string) (string, error) {
 	if path == "" {
 		return "", nil
 	}
-	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+	if err := os.WriteFile(path, content, 0644); err != nil {
 		return "", err
 	}
 	if err := chownToSudoUser(path); err != nil {
@@ -0,0 +1,1075 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// cliCommands are the subcommands that run WiFiService without the Wails
+// window — for headless boxes (Raspberry Pis, field laptops over SSH) and
+// scripts. Any other first argument, or none, starts the GUI as before.
+var cliCommands = map[string]func(args []string) int{
+	"scan":       runScanCommand,
+	"monitor":    runMonitorCommand,
+	"interfaces": runInterfacesCommand,
+	"report":     runReportCommand,
+	"daemon":     runDaemonCommand,
+	"survey":     runSurveyCommand,
+	"heatmap":    runHeatmapCommand,
+	"throughput": runThroughputCommand,
+	"help":       runHelpCommand,
+	// Not a scan command, but here so the GUI binary doubles as the
+	// server end of a throughput test.
+	"throughput-server": runThroughputServerCommand,
+}
+
+const cliUsage = `Usage: wifi-app [command] [flags]
+
+Without a command the desktop app starts. Headless commands:
+
+  scan        Run one scan and print networks + client stats, then exit
+  monitor     Scan continuously and stream results until interrupted
+  interfaces  List WiFi interfaces the backend can scan on
+  report      Collect data for a while, then write the HTML or PDF diagnostic report
+  daemon      Run the scheduled [[job]] surveys from config.toml until interrupted
+  survey      Walk-through site survey: type a point name to measure there
+  heatmap     Render a survey's floor-plan heatmap and print its coverage
+  throughput  Run iperf3 throughput tests against a server and print the results
+  throughput-server
+              Serve throughput tests (iperf3 -s compatible) until interrupted
+  help        Show this message
+
+Run "wifi-app <command> -h" for command flags.
+`
+
+// cliOutputFormats are the accepted -format values. "table" is meant for
+// humans; "json" prints one indented document per result; "ndjson" prints
+// one compact object per line so the stream can be piped into jq or a log
+// shipper.
+var cliOutputFormats = []string{"table", "json", "ndjson"}
+
+// cliEvent is one emitted service event, captured with the wall-clock time
+// it was observed so NDJSON consumers don't have to guess tick boundaries.
+type cliEvent struct {
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// cliScanResult is the single document `scan -format json` prints.
+type cliScanResult struct {
+	Timestamp time.Time     `json:"timestamp"`
+	Interface string        `json:"interface"`
+	Networks  []Network     `json:"networks"`
+	Channels  []ChannelInfo `json:"channels"`
+	Client    ClientStats   `json:"client"`
+	// Capabilities of the backend on the scanned interfaces.
+	Capabilities []BackendCapabilities `json:"capabilities"`
+}
+
+// cliOptions holds the flags shared by scan, monitor and report.
+type cliOptions struct {
+	iface   string
+	format  string
+	timeout time.Duration
+	replay  string
+	speed   float64
+	sim     string
+	metrics string
+	api     string
+
+	// Scan flags; any of them replaces the config's [scan] table.
+	scanSSIDs    string
+	scanChannels string
+	scanFreqs    string
+	scanPassive  bool
+	scanDwell    time.Duration
+}
+
+func (o *cliOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&o.format, "format", "table", "output format: "+strings.Join(cliOutputFormats, ", "))
+	o.registerSource(fs)
+}
+
+// registerSource registers the flags that choose what to scan, for
+// commands with their own output (report).
+func (o *cliOptions) registerSource(fs *flag.FlagSet) {
+	fs.StringVar(&o.iface, "iface", "", "WiFi interface to scan, or a comma-separated list to scan concurrently (default: config default_interface, then first available)")
+	fs.StringVar(&o.replay, "replay", "", "replay a capture file (see WIFI_APP_CAPTURE) instead of scanning")
+	fs.Float64Var(&o.speed, "speed", 1, "replay speed factor; 0 replays as fast as possible")
+	fs.StringVar(&o.sim, "sim", "", "run against a simulated environment from a scenario file")
+	fs.StringVar(&o.scanSSIDs, "ssids", "", "comma-separated SSIDs to probe for; only they are reported (default: config [scan])")
+	fs.StringVar(&o.scanChannels, "channels", "", "comma-separated 2.4/5 GHz channels to scan (default: config [scan], else all)")
+	fs.StringVar(&o.scanFreqs, "freqs", "", "comma-separated frequencies in MHz to scan, e.g. 6 GHz ones")
+	fs.BoolVar(&o.scanPassive, "passive", false, "passive scan: listen for beacons, never send probe requests")
+	fs.DurationVar(&o.scanDwell, "dwell", 0, "time spent on each channel (default: the driver's)")
+}
+
+// scanOptions parses the scan flags. ok is false when none was given.
+func (o *cliOptions) scanOptions() (opts ScanOptions, ok bool, err error) {
+	ints := func(flagName, list string) ([]int, error) {
+		var out []int
+		for _, s := range strings.Split(list, ",") {
+			if s = strings.TrimSpace(s); s == "" {
+				continue
+			}
+			n, err := strconv.Atoi(s)
+			if err != nil {
+				return nil, fmt.Errorf("-%s: %q is not a number", flagName, s)
+			}
+			out = append(out, n)
+		}
+		return out, nil
+	}
+	if o.scanSSIDs != "" {
+		opts.SSIDs = strings.Split(o.scanSSIDs, ",")
+	}
+	if opts.Channels, err = ints("channels", o.scanChannels); err != nil {
+		return opts, false, err
+	}
+	if opts.Frequencies, err = ints("freqs", o.scanFreqs); err != nil {
+		return opts, false, err
+	}
+	opts.Passive = o.scanPassive
+	opts.DwellMs = int(o.scanDwell / time.Millisecond)
+	// Checked here: the config would drop what's invalid without a word.
+	if _, notes := opts.validate(); len(notes) > 0 {
+		return opts, false, fmt.Errorf("scan options: %s", joinComma(notes))
+	}
+	return opts, opts.targeted(), nil
+}
+
+// newService builds the service for a headless run: the platform backend
+// normally, a replay of a capture file with -replay, or a simulated
+// environment with -sim.
+func (o *cliOptions) newService() (*WiFiService, error) {
+	switch {
+	case o.sim != "":
+		backend, err := openSimBackend(o.sim)
+		if err != nil {
+			return nil, err
+		}
+		return newWiFiServiceWithBackend(backend), nil
+	case o.replay != "":
+		backend, err := openReplayBackend(o.replay, o.speed)
+		if err != nil {
+			return nil, err
+		}
+		return newWiFiServiceWithBackend(backend), nil
+	}
+	return NewWiFiService(), nil
+}
+
+func (o *cliOptions) validate() error {
+	for _, f := range cliOutputFormats {
+		if o.format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported format %q (use %s)", o.format, strings.Join(cliOutputFormats, ", "))
+}
+
+// runHeadless builds a service, attaches it to a signal-aware context and an
+// event channel, and starts scanning the interface(s) from opts. The
+// returned cleanup closes the service; callers must invoke it exactly once.
+func runHeadless(parent context.Context, opts cliOptions) (*WiFiService, string, <-chan cliEvent, func(), error) {
+	ws, events, cleanup, err := attachHeadless(parent, opts)
+	if err != nil {
+		return nil, "", nil, nil, err
+	}
+	iface := opts.iface
+	if iface == "" {
+		iface = ws.GetConfig().DefaultInterface
+	}
+	if iface == "" {
+		ifaces, err := ws.scanner.GetInterfaces(parent)
+		if err != nil {
+			cleanup()
+			return nil, "", nil, nil, fmt.Errorf("list interfaces: %w", err)
+		}
+		if len(ifaces) == 0 {
+			cleanup()
+			return nil, "", nil, nil, fmt.Errorf("no WiFi interfaces found")
+		}
+		iface = ifaces[0]
+	}
+	if err := ws.StartScanningInterfaces(strings.Split(iface, ",")); err != nil {
+		cleanup()
+		return nil, "", nil, nil, err
+	}
+	return ws, iface, events, cleanup, nil
+}
+
+// attachHeadless is runHeadless without starting a scan, for commands that
+// scan on their own schedule.
+func attachHeadless(parent context.Context, opts cliOptions) (*WiFiService, <-chan cliEvent, func(), error) {
+	ws, err := opts.newService()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	scan, scanSet, err := opts.scanOptions()
+	if err != nil {
+		_ = ws.Close()
+		return nil, nil, nil, err
+	}
+	if opts.metrics != "" || opts.api != "" || scanSet {
+		// Applied in memory only; a one-off -metrics / -api / scan flag
+		// shouldn't rewrite the user's config file.
+		cfg := ws.GetConfig()
+		if opts.metrics != "" {
+			cfg.MetricsListen = opts.metrics
+		}
+		if opts.api != "" {
+			cfg.APIListen = opts.api
+		}
+		if scanSet {
+			cfg.Scan = scan
+		}
+		ws.config.Set(cfg)
+	}
+	events := make(chan cliEvent, 64)
+	ctx, cancel := context.WithCancel(parent)
+	ws.AttachHeadless(ctx, func(name string, data interface{}) {
+		select {
+		case events <- cliEvent{Type: name, Timestamp: time.Now(), Data: data}:
+		case <-ctx.Done():
+		}
+	})
+	cleanup := func() {
+		cancel()
+		_ = ws.Close()
+	}
+	return ws, events, cleanup, nil
+}
+
+// signalContext returns a context cancelled on SIGINT/SIGTERM so Ctrl-C and
+// systemd stop both shut the scan loop down cleanly.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+func runHelpCommand(args []string) int {
+	fmt.Fprint(os.Stdout, cliUsage)
+	return 0
+}
+
+func runInterfacesCommand(args []string) int {
+	fs := flag.NewFlagSet("interfaces", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	ws := NewWiFiService()
+	defer ws.Close()
+	ifaces, err := ws.scanner.GetInterfaces(ctx)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	for _, name := range ifaces {
+		fmt.Fprintln(os.Stdout, name)
+	}
+	return 0
+}
+
+// runScanCommand performs a single scan tick through the regular scan loop
+// and prints what the GUI would have received on its first
+// networks/channels/client update.
+func runScanCommand(args []string) int {
+	var opts cliOptions
+	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
+	opts.register(fs)
+	// The nl80211 active scan alone can take up to activeScanTimeout, plus
+	// the retry backoff on a busy radio.
+	fs.DurationVar(&opts.timeout, "timeout", 60*time.Second, "give up if no scan result arrives within this long")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 2
+	}
+
+	sigCtx, stop := signalContext()
+	defer stop()
+	ctx, cancel := context.WithTimeout(sigCtx, opts.timeout)
+	defer cancel()
+
+	ws, iface, events, cleanup, err := runHeadless(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	defer cleanup()
+
+	result := cliScanResult{Interface: iface}
+	var collected []cliEvent
+	for {
+		select {
+		case <-ctx.Done():
+			fmt.Fprintln(os.Stderr, "wifi-app: no scan result:", ctx.Err())
+			return 1
+		case ev := <-events:
+			switch ev.Type {
+			case "scan:error":
+				fmt.Fprintln(os.Stderr, "wifi-app: scan failed:", ev.Data)
+				return 1
+			case "replay:finished":
+				fmt.Fprintln(os.Stderr, "wifi-app: no scan result: replay finished")
+				return 1
+			case "networks:updated":
+				result.Timestamp = ev.Timestamp
+				result.Networks, _ = ev.Data.([]Network)
+			case "channels:updated":
+				result.Channels, _ = ev.Data.([]ChannelInfo)
+			case "client:updated":
+				result.Client, _ = ev.Data.(ClientStats)
+			default:
+				// latency:updated and friends aren't part of a one-shot scan.
+				continue
+			}
+			collected = append(collected, ev)
+			// client:updated is the last event of a scan tick.
+			if ev.Type != "client:updated" {
+				continue
+			}
+			result.Capabilities = ws.GetBackendCapabilities()
+			if err := writeScanResult(os.Stdout, opts.format, result, collected); err != nil {
+				fmt.Fprintln(os.Stderr, "wifi-app:", err)
+				return 1
+			}
+			return 0
+		}
+	}
+}
+
+func writeScanResult(w io.Writer, format string, result cliScanResult, events []cliEvent) error {
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		for _, ev := range events {
+			if err := enc.Encode(ev); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		if err := writeNetworkTable(w, result.Networks, 0); err != nil {
+			return err
+		}
+		fmt.Fprintln(w)
+		writeClientLine(w, result.Client)
+		return nil
+	}
+}
+
+// runMonitorCommand streams every event the service emits until the duration
+// elapses or the process is interrupted.
+func runMonitorCommand(args []string) int {
+	var opts cliOptions
+	var duration time.Duration
+	var top int
+	fs := flag.NewFlagSet("monitor", flag.ContinueOnError)
+	opts.register(fs)
+	fs.DurationVar(&duration, "duration", 0, "stop after this long (0 = run until interrupted)")
+	fs.IntVar(&top, "top", 10, "table format: networks to show per scan (0 = all)")
+	fs.StringVar(&opts.metrics, "metrics", "", "serve Prometheus metrics on this host:port (overrides config metrics_listen)")
+	fs.StringVar(&opts.api, "api", "", "serve the REST + event stream API on this host:port (overrides config api_listen)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if err := opts.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 2
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	if duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, duration)
+		defer cancel()
+	}
+
+	ws, iface, events, cleanup, err := runHeadless(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	defer cleanup()
+	ws.StartJobScheduler(ctx)
+
+	if opts.format == "table" {
+		fmt.Fprintf(os.Stdout, "Monitoring %s (Ctrl-C to stop)\n", iface)
+	}
+	enc := json.NewEncoder(os.Stdout)
+	if opts.format == "json" {
+		enc.SetIndent("", "  ")
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			// Interrupt and -duration expiry are both normal exits.
+			return 0
+		case ev := <-events:
+			if opts.format != "table" {
+				if err := enc.Encode(ev); err != nil {
+					fmt.Fprintln(os.Stderr, "wifi-app:", err)
+					return 1
+				}
+			} else {
+				writeMonitorTableEvent(os.Stdout, ev, top)
+			}
+			// Scanning stops once every interface's replay has finished.
+			if ev.Type == "replay:finished" && !ws.IsScanning() {
+				return 0
+			}
+		}
+	}
+}
+
+// runReportCommand scans for -duration so the report has signal, roaming
+// and latency history to draw on, then renders the HTML or PDF report. An
+// interrupt ends collection early and still writes the report.
+func runReportCommand(args []string) int {
+	var opts cliOptions
+	var duration time.Duration
+	var out, tmpl, format string
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	opts.registerSource(fs)
+	fs.DurationVar(&duration, "duration", time.Minute, "collect data for this long before writing the report")
+	fs.StringVar(&out, "o", "", "write the report to this file (default: stdout)")
+	fs.StringVar(&format, "format", "", "html or pdf (default: from the -o extension, else html)")
+	fs.StringVar(&tmpl, "template", "", "html/template file to render (overrides config report_template_path)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if format == "" {
+		format = "html"
+		if strings.EqualFold(filepath.Ext(out), ".pdf") {
+			format = "pdf"
+		}
+	}
+	if format != "html" && format != "pdf" {
+		fmt.Fprintf(os.Stderr, "wifi-app: unsupported report format %q (use html or pdf)\n", format)
+		return 2
+	}
+	if duration <= 0 {
+		fmt.Fprintln(os.Stderr, "wifi-app: -duration must be positive")
+		return 2
+	}
+
+	sigCtx, stop := signalContext()
+	defer stop()
+	ctx, cancel := context.WithTimeout(sigCtx, duration)
+	defer cancel()
+
+	ws, iface, events, cleanup, err := runHeadless(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	defer cleanup()
+	if tmpl == "" {
+		tmpl = ws.GetConfig().ReportTemplatePath
+	}
+
+	fmt.Fprintf(os.Stderr, "Collecting on %s for %s (Ctrl-C to stop early)\n", iface, duration)
+	scanned := false
+collect:
+	for {
+		select {
+		case <-ctx.Done():
+			break collect
+		case ev := <-events:
+			switch ev.Type {
+			case "client:updated":
+				scanned = true
+			case "scan:error":
+				fmt.Fprintln(os.Stderr, "wifi-app: scan failed:", ev.Data)
+			case "replay:finished":
+				if !ws.IsScanning() {
+					break collect
+				}
+			}
+		}
+	}
+	if !scanned {
+		fmt.Fprintln(os.Stderr, "wifi-app: no scan completed; report not written")
+		return 1
+	}
+
+	// Render fully before touching -o so a template error doesn't leave a
+	// truncated file behind.
+	var buf bytes.Buffer
+	render := func() error { return renderHTMLReport(&buf, ws.BuildReportData(), tmpl) }
+	if format == "pdf" {
+		render = func() error { return renderPDFReport(&buf, ws.BuildReportData()) }
+	}
+	if err := render(); err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	if out == "" {
+		_, err = buf.WriteTo(os.Stdout)
+	} else {
+		err = os.WriteFile(out, buf.Bytes(), 0o644)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	return 0
+}
+
+// runDaemonCommand runs the scheduled jobs without a window — e.g. as a
+// systemd service on a survey box. It only scans while a job runs; -metrics
+// and -api stay up in between.
+func runDaemonCommand(args []string) int {
+	var opts cliOptions
+	fs := flag.NewFlagSet("daemon", flag.ContinueOnError)
+	opts.registerSource(fs)
+	fs.StringVar(&opts.metrics, "metrics", "", "serve Prometheus metrics on this host:port (overrides config metrics_listen)")
+	fs.StringVar(&opts.api, "api", "", "serve the REST + event stream API on this host:port (overrides config api_listen)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	ws, events, cleanup, err := attachHeadless(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	defer cleanup()
+	jobs := ws.GetConfig().Jobs
+	if len(jobs) == 0 {
+		fmt.Fprintln(os.Stderr, "wifi-app: no [[job]] tables in config; nothing to schedule")
+		return 1
+	}
+	ws.StartJobScheduler(ctx)
+	for _, j := range jobs {
+		when := "every " + j.Every
+		if j.At != "" {
+			when = "at " + j.At
+			if len(j.Weekdays) > 0 {
+				when += " on " + strings.Join(j.Weekdays, ",")
+			}
+		}
+		fmt.Fprintf(os.Stderr, "Scheduled %s %s\n", j.Name, when)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-events:
+			// Jobs report through the log; drain so emitters don't block.
+		}
+	}
+}
+
+// runSurveyCommand runs a site survey from the terminal: each line read
+// from stdin names a measurement point, captured on the spot. With
+// -floorplan, "Name @ x,y" also places the point on the plan. EOF or an
+// interrupt ends the survey, which is saved like one from the GUI.
+func runSurveyCommand(args []string) int {
+	var opts cliOptions
+	var name, floorPlan string
+	var top int
+	fs := flag.NewFlagSet("survey", flag.ContinueOnError)
+	opts.registerSource(fs)
+	fs.StringVar(&name, "name", "", "survey name (default: the start time)")
+	fs.StringVar(&floorPlan, "floorplan", "", "floor-plan image (PNG, JPEG or GIF) to place points on")
+	fs.IntVar(&top, "top", 10, "APs to print per point (0 = all)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	ws, iface, events, cleanup, err := runHeadless(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	defer cleanup()
+	if name == "" {
+		name = "Survey " + time.Now().Format("2006-01-02 15:04")
+	}
+	survey, err := ws.StartSurvey(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	if floorPlan != "" {
+		if survey, err = ws.SetSurveyFloorPlan(survey.ID, floorPlan); err != nil {
+			fmt.Fprintln(os.Stderr, "wifi-app:", err)
+			_, _ = ws.StopSurvey()
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "Floor plan %s is %dx%d px; enter \"Name @ x,y\" to place a point.\n", survey.FloorPlan.Source, survey.FloorPlan.Width, survey.FloorPlan.Height)
+	}
+	fmt.Fprintf(os.Stderr, "Survey %q on %s. Type a point name and press Enter to measure there; Ctrl-D ends the survey.\n", survey.Name, iface)
+
+	lines := make(chan string)
+	go func() {
+		sc := bufio.NewScanner(os.Stdin)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+		close(lines)
+	}()
+	type marked struct {
+		point SurveyPoint
+		err   error
+	}
+	// Marking blocks for the whole burst, while the events it depends on
+	// must keep draining — so it runs on its own goroutine.
+	var results chan marked
+	var pending []string
+	mark := func(line string) {
+		point, at, err := parseSurveyLine(line)
+		results = make(chan marked, 1)
+		if err != nil {
+			results <- marked{err: err}
+			return
+		}
+		fmt.Fprintf(os.Stderr, "Measuring %q…\n", point)
+		go func() {
+			p, err := ws.markSurveyPoint(point, at)
+			results <- marked{p, err}
+		}()
+	}
+	status := 0
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-events:
+		case line, ok := <-lines:
+			if !ok {
+				lines = nil
+				if results == nil {
+					break loop
+				}
+				continue
+			}
+			if line = strings.TrimSpace(line); line == "" {
+				continue
+			}
+			if results != nil {
+				pending = append(pending, line)
+				continue
+			}
+			mark(line)
+		case r := <-results:
+			results = nil
+			if r.err != nil {
+				fmt.Fprintln(os.Stderr, "wifi-app:", r.err)
+				status = 1
+			} else {
+				writeSurveyPoint(os.Stdout, r.point, top)
+			}
+			switch {
+			case len(pending) > 0:
+				mark(pending[0])
+				pending = pending[1:]
+			case lines == nil:
+				break loop
+			}
+		}
+	}
+
+	survey, err = ws.StopSurvey()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	fmt.Fprintf(os.Stderr, "Survey %s saved with %d points\n", survey.ID, len(survey.Points))
+	return status
+}
+
+// parseSurveyLine splits a survey command line into the point name and
+// its optional floor-plan position: "Room 204 @ 310,125".
+func parseSurveyLine(line string) (string, *FloorPoint, error) {
+	name, pos, ok := strings.Cut(line, "@")
+	name = strings.TrimSpace(name)
+	if !ok {
+		return name, nil, nil
+	}
+	xs, ys, ok := strings.Cut(pos, ",")
+	x, errX := strconv.ParseFloat(strings.TrimSpace(xs), 64)
+	y, errY := strconv.ParseFloat(strings.TrimSpace(ys), 64)
+	if !ok || errX != nil || errY != nil {
+		return "", nil, fmt.Errorf("invalid position %q (want x,y in floor-plan pixels)", strings.TrimSpace(pos))
+	}
+	return name, &FloorPoint{X: x, Y: y}, nil
+}
+
+func writeSurveyPoint(w io.Writer, p SurveyPoint, limit int) {
+	fmt.Fprintf(w, "\n%s — %d scans, %d APs\n", p.Name, p.Scans, len(p.APs))
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SSID\tBSSID\tAVG\tMIN\tMAX\tCH\tBAND")
+	for i, ap := range p.APs {
+		if limit > 0 && i >= limit {
+			break
+		}
+		ssid := ap.SSID
+		if ssid == "" {
+			ssid = "(hidden)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f dBm\t%d\t%d\t%d\t%s\n", ssid, ap.BSSID, ap.Avg, ap.Min, ap.Max, ap.Channel, ap.Band)
+	}
+	_ = tw.Flush()
+	writeClientLine(w, p.Client)
+}
+
+// runHeatmapCommand renders a saved survey's heatmap — PNG composited over
+// the floor plan, an SVG overlay, or the JSON grid — and prints coverage per
+// SSID to stderr.
+func runHeatmapCommand(args []string) int {
+	var id, out, format string
+	var req HeatmapRequest
+	fs := flag.NewFlagSet("heatmap", flag.ContinueOnError)
+	fs.StringVar(&id, "survey", "", "survey ID (see /api/surveys or the surveys directory)")
+	fs.StringVar(&req.SSID, "ssid", "", "map this SSID (default: the strongest AP of any network)")
+	fs.StringVar(&req.BSSID, "bssid", "", "map this single AP")
+	fs.IntVar(&req.Threshold, "threshold", 0, "coverage threshold in dBm (default: coverage_threshold_dbm)")
+	fs.IntVar(&req.CellSize, "cell", 0, "grid cell size in floor-plan pixels (default: about 100 cells across)")
+	fs.StringVar(&out, "o", "", "write the heatmap to this file (default: stdout)")
+	fs.StringVar(&format, "format", "", "png, svg or json (default: from the -o extension, else png)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if id == "" {
+		fmt.Fprintln(os.Stderr, "wifi-app: -survey is required")
+		return 2
+	}
+	if format == "" {
+		format = "png"
+		if ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(out), ".")); ext == "svg" || ext == "json" {
+			format = ext
+		}
+	}
+	if format != "png" && format != "svg" && format != "json" {
+		fmt.Fprintf(os.Stderr, "wifi-app: unsupported heatmap format %q (use png, svg or json)\n", format)
+		return 2
+	}
+
+	ws := NewWiFiService()
+	defer ws.Close()
+	h, err := ws.GetSurveyHeatmap(id, req)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	var data []byte
+	if format == "json" {
+		data, err = json.MarshalIndent(h, "", "  ")
+	} else {
+		data, err = ws.RenderSurveyHeatmap(id, req, format)
+	}
+	if err == nil {
+		if out == "" {
+			_, err = os.Stdout.Write(data)
+		} else {
+			err = os.WriteFile(out, data, 0o644)
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+
+	fmt.Fprintf(os.Stderr, "%.1f%% of the floor plan at or above %d dBm\n", h.CoveragePct, h.Threshold)
+	coverage, _ := ws.GetSurveyCoverage(id, req.Threshold)
+	tw := tabwriter.NewWriter(os.Stderr, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SSID\tCOVERAGE\tPOINTS")
+	for _, c := range coverage {
+		ssid := c.SSID
+		if ssid == "" {
+			ssid = "(hidden)"
+		}
+		fmt.Fprintf(tw, "%s\t%.1f%%\t%d\n", ssid, c.CoveragePct, c.Points)
+	}
+	_ = tw.Flush()
+	return 0
+}
+
+// runThroughputCommand runs the throughput tests once over the scanned
+// link. Flags override the [throughput] config for this run only.
+func runThroughputCommand(args []string) int {
+	var opts cliOptions
+	var host, modes string
+	var duration time.Duration
+	var parallel, udpMbps int
+	fs := flag.NewFlagSet("throughput", flag.ContinueOnError)
+	opts.registerSource(fs)
+	fs.StringVar(&host, "host", "", "iperf3 server, host or host:port (default: config [throughput] host)")
+	fs.StringVar(&modes, "mode", "", "comma-separated tests: "+strings.Join(throughputModes, ", ")+" (default: config modes)")
+	fs.DurationVar(&duration, "duration", 0, "length of each test (default: config duration_seconds)")
+	fs.IntVar(&parallel, "parallel", 0, "parallel streams per test (default: config parallel)")
+	fs.IntVar(&udpMbps, "udp-mbps", 0, "total UDP send rate in Mbit/s (default: config udp_mbps)")
+	fs.StringVar(&opts.format, "format", "table", "output format: table or json")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if opts.format != "table" && opts.format != "json" {
+		fmt.Fprintf(os.Stderr, "wifi-app: unsupported format %q (use table or json)\n", opts.format)
+		return 2
+	}
+	var modeList []string
+	if modes != "" {
+		for _, m := range strings.Split(modes, ",") {
+			m = strings.TrimSpace(m)
+			if !slices.Contains(throughputModes, m) {
+				fmt.Fprintf(os.Stderr, "wifi-app: unknown mode %q (use %s)\n", m, strings.Join(throughputModes, ", "))
+				return 2
+			}
+			modeList = append(modeList, m)
+		}
+	}
+
+	ctx, stop := signalContext()
+	defer stop()
+	ws, iface, events, cleanup, err := runHeadless(ctx, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	defer cleanup()
+	// Applied in memory only, like -metrics / -api.
+	cfg := ws.GetConfig()
+	if duration > 0 {
+		cfg.Throughput.DurationSeconds = int(duration.Round(time.Second) / time.Second)
+	}
+	if parallel > 0 {
+		cfg.Throughput.Parallel = parallel
+	}
+	if udpMbps > 0 {
+		cfg.Throughput.UDPMbps = udpMbps
+	}
+	ws.config.Set(cfg)
+	if host == "" && ws.GetConfig().Throughput.Host == "" {
+		fmt.Fprintln(os.Stderr, "wifi-app: no server; pass -host or set host in [throughput]")
+		return 2
+	}
+
+	type run struct {
+		results []ThroughputResult
+		err     error
+	}
+	// The tests block for their whole duration while events must keep
+	// draining, so they run on their own goroutine once the first scan
+	// has filled in the link.
+	var done chan run
+	start := func() {
+		done = make(chan run, 1)
+		fmt.Fprintf(os.Stderr, "Testing over %s (Ctrl-C to stop)\n", iface)
+		go func() {
+			results, err := ws.RunThroughputTest(host, modeList)
+			done <- run{results, err}
+		}()
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return 1
+		case ev := <-events:
+			switch ev.Type {
+			case "client:updated", "scan:error", "replay:finished":
+				if done == nil {
+					start()
+				}
+			case "throughput:result":
+				if r, ok := ev.Data.(ThroughputResult); ok && opts.format == "table" {
+					writeThroughputLine(os.Stdout, r)
+				}
+			}
+		case r := <-done:
+			if r.err != nil {
+				fmt.Fprintln(os.Stderr, "wifi-app:", r.err)
+				return 1
+			}
+			if opts.format == "json" {
+				data, _ := json.MarshalIndent(r.results, "", "  ")
+				fmt.Fprintln(os.Stdout, string(data))
+			}
+			for _, t := range r.results {
+				if t.Error != "" {
+					return 1
+				}
+			}
+			return 0
+		}
+	}
+}
+
+// runThroughputServerCommand serves throughput tests for this and other
+// machines' `throughput` runs, or for stock iperf3 clients.
+func runThroughputServerCommand(args []string) int {
+	var listen string
+	fs := flag.NewFlagSet("throughput-server", flag.ContinueOnError)
+	fs.StringVar(&listen, "listen", ":"+iperfDefaultPort, "TCP and UDP address to listen on")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	srv, err := listenIperfServer(listen)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	ctx, stop := signalContext()
+	defer stop()
+	fmt.Fprintf(os.Stderr, "Serving throughput tests on %s (Ctrl-C to stop)\n", srv.Addr())
+	if err := srv.Serve(ctx); err != nil {
+		fmt.Fprintln(os.Stderr, "wifi-app:", err)
+		return 1
+	}
+	return 0
+}
+
+func writeThroughputLine(w io.Writer, r ThroughputResult) {
+	if r.Error != "" {
+		fmt.Fprintf(w, "%-8s %s: failed: %s\n", r.Mode, r.Host, r.Error)
+		return
+	}
+	fmt.Fprintf(w, "%-8s %s: %.1f Mbit/s", r.Mode, r.Host, r.Mbps)
+	if strings.HasPrefix(r.Mode, "udp") {
+		fmt.Fprintf(w, " jitter %.2f ms loss %.2f%%", r.JitterMs, r.LossPercent)
+	}
+	if r.BSSID != "" {
+		fmt.Fprintf(w, " via %s ch %d %d dBm (PHY tx %.0f / rx %.0f Mbps)", r.BSSID, r.Channel, r.Signal, r.TxBitrate, r.RxBitrate)
+	}
+	if r.Roamed {
+		fmt.Fprint(w, ", roamed during the test")
+	}
+	fmt.Fprintln(w)
+}
+
+// writeMonitorTableEvent renders one event in the human-readable monitor
+// view. channels:updated is folded into the network table and skipped.
+func writeMonitorTableEvent(w io.Writer, ev cliEvent, top int) {
+	stamp := ev.Timestamp.Format("15:04:05")
+	switch ev.Type {
+	case "networks:updated":
+		networks, _ := ev.Data.([]Network)
+		aps := 0
+		for _, n := range networks {
+			aps += n.APCount
+		}
+		fmt.Fprintf(w, "\n[%s] %d networks, %d APs\n", stamp, len(networks), aps)
+		_ = writeNetworkTable(w, networks, top)
+	case "client:updated":
+		stats, _ := ev.Data.(ClientStats)
+		fmt.Fprintf(w, "[%s] ", stamp)
+		writeClientLine(w, stats)
+	case "roaming:detected":
+		roam, _ := ev.Data.(RoamingEvent)
+		fmt.Fprintf(w, "[%s] ROAM %s (%d dBm) -> %s (%d dBm) in %d ms\n", stamp,
+			roam.PreviousBSSID, roam.PreviousSignal, roam.NewBSSID, roam.NewSignal, roam.DurationMs)
+	case "latency:updated":
+		summaries, _ := ev.Data.([]LatencyTargetSummary)
+		writeLatencyLine(w, stamp, summaries)
+	case "throughput:result":
+		r, _ := ev.Data.(ThroughputResult)
+		fmt.Fprintf(w, "[%s] throughput ", stamp)
+		writeThroughputLine(w, r)
+	case "ap:appeared", "ap:disappeared":
+		ap, _ := ev.Data.(AccessPoint)
+		verb := "appeared"
+		if ev.Type == "ap:disappeared" {
+			verb = "disappeared"
+		}
+		fmt.Fprintf(w, "[%s] AP %s %s (%s) ch %d %d dBm\n", stamp, verb, ap.BSSID, ap.SSID, ap.Channel, ap.Signal)
+	case "replay:finished":
+		fmt.Fprintf(w, "[%s] replay finished on %v\n", stamp, ev.Data)
+	case "scan:error":
+		fmt.Fprintf(w, "[%s] scan error: %v\n", stamp, ev.Data)
+	}
+}
+
+// writeNetworkTable prints one row per access point, grouped by network in
+// the service's signal-descending order. limit caps the number of networks
+// shown; 0 shows all.
+func writeNetworkTable(w io.Writer, networks []Network, limit int) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "SSID\tBSSID\tSIGNAL\tAGE\tCH\tBAND\tWIDTH\tSECURITY\tVENDOR\tISSUES")
+	for i, n := range networks {
+		if limit > 0 && i >= limit {
+			break
+		}
+		aps := make([]AccessPoint, len(n.AccessPoints))
+		copy(aps, n.AccessPoints)
+		sort.Slice(aps, func(a, b int) bool { return aps[a].Signal > aps[b].Signal })
+		ssid := n.SSID
+		if ssid == "" {
+			ssid = "(hidden)"
+		}
+		for _, ap := range aps {
+			age := fmt.Sprintf("%ds", ap.AgeMs/1000)
+			if ap.Stale {
+				age += " stale"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%d dBm\t%s\t%d\t%s\t%d\t%s\t%s\t%s\n",
+				ssid, ap.BSSID, ap.Signal, age, ap.Channel, ap.Band, ap.ChannelWidth,
+				ap.Security, ap.Vendor, strings.Join(n.IssueMessages, "; "))
+		}
+	}
+	return tw.Flush()
+}
+
+func writeClientLine(w io.Writer, stats ClientStats) {
+	if !stats.Connected {
+		fmt.Fprintln(w, "client: not connected")
+		return
+	}
+	fmt.Fprintf(w, "client: %s %s ch %d %d dBm SNR %d dB tx %.1f / rx %.1f Mbps retries %.1f%% %s %s\n",
+		stats.SSID, stats.BSSID, stats.Channel, stats.Signal, stats.SNR,
+		stats.TxBitrate, stats.RxBitrate, stats.RetryRate, stats.WiFiStandard, stats.MIMOConfig)
+}
+
+func writeLatencyLine(w io.Writer, stamp string, summaries []LatencyTargetSummary) {
+	if len(summaries) == 0 {
+		return
+	}
+	parts := make([]string, 0, len(summaries))
+	for _, s := range summaries {
+		if !s.Available {
+			parts = append(parts, fmt.Sprintf("%s unavailable", s.Label))
+			continue
+		}
+		// The 10 s window smooths out single lost probes without lagging
+		// as far behind as the 60 s one.
+		var win LatencyStats
+		for _, candidate := range s.Windows {
+			if candidate.WindowSeconds == 10 {
+				win = candidate
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s %.1f ms (loss %.0f%%)", s.Label, win.AvgMs, win.LossPercent))
+	}
+	fmt.Fprintf(w, "[%s] latency: %s\n", stamp, strings.Join(parts, ", "))
+}
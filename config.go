@@ -21,21 +21,73 @@ import (
 //   - ScanIntervalSeconds: how often the backend triggers a fresh scan.
 //     Lower = more current data + more battery drain + more chance of EBUSY
 //     contention with NetworkManager.
+//   - ScanMaxAgeSeconds: results last heard longer ago than this are
+//     dropped, and how long an AP a scan missed is kept, marked stale,
+//     before it disappears (see scan_freshness.go).
 //   - SignalHistoryMinutes: how much per-AP signal history the connected
 //     chart and report generator can draw on.
 //   - RoamingHistorySize: hard cap on retained roaming events.
 //   - DefaultInterface: pre-selects the dropdown on launch when present.
 //   - LatencyTargets: hosts the future latency sampler (P2.1) will probe.
 //     "gateway" is a magic value resolved at runtime to the default route.
-//   - ReportTemplatePath: future override for the MSP report template
-//     (P3.3); empty means use the embedded default.
+//   - ReportTemplatePath: html/template file that replaces the embedded
+//     report template (custom branding for MSP reports); it receives the
+//     same ReportData and functions. Empty means use the default.
+//   - RecordSessions: when true, every scan tick, client sample, roaming
+//     event and latency probe is appended to a session file under
+//     sessionsDir() while scanning is active. Off by default — a busy
+//     environment writes tens of MB per hour.
+//   - MetricsListen: host:port for the Prometheus /metrics exporter, e.g.
+//     "127.0.0.1:9101". Empty disables the listener.
+//   - APIListen: host:port for the read-only REST + event stream API.
+//     Empty disables it.
+//   - APIToken: when set, API requests must present it as a bearer token
+//     (or ?token=). Required for cross-origin browser clients.
+//   - Alerts: declarative alert rules, one [[alert]] table each (see
+//     alerts.go). Invalid rules are dropped with a note. None by default.
+//   - RogueLearningMinutes: how long after an SSID is first seen its APs
+//     are learned as legitimate before unfamiliar ones are flagged as
+//     possible rogues (see rogue_detector.go). At least 1.
+//   - Notify: notification sinks plus their de-duplication and rate
+//     limits, the [notify] table (see notify.go). No sinks by default.
+//   - HistoryRawMinutes: how long unaggregated samples are kept in the
+//     long-term history store (see timeseries.go) before only the
+//     1m/15m/1h rollups remain.
+//   - HistoryRetentionDays: how long the hourly rollups are kept.
+//   - SurveyBurstScans: scans captured per site survey point (see
+//     survey.go); the per-BSSID statistics are taken over the burst.
+//   - CoverageThresholdDBM: signal a floor-plan cell needs to count as
+//     covered in survey heatmaps and the report's coverage figures.
+//   - Throughput: active iperf3 throughput tests, the [throughput] table
+//     (see throughput.go). Manual only until a host and interval are set.
+//   - Scan: what each scanning session asks the radio for, the [scan]
+//     table (ScanOptions): SSIDs, frequencies or channels, passive-only and
+//     dwell time. Empty is a full active scan. The app's scan options and
+//     the CLI's scan flags replace it for one session.
+//   - Jobs: scheduled unattended surveys, one [[job]] table each (see
+//     scheduler.go). Invalid jobs are dropped with a note. None by default.
 type Config struct {
-	ScanIntervalSeconds  int      `toml:"scan_interval_seconds" json:"scanIntervalSeconds"`
-	SignalHistoryMinutes int      `toml:"signal_history_minutes" json:"signalHistoryMinutes"`
-	RoamingHistorySize   int      `toml:"roaming_history_size" json:"roamingHistorySize"`
-	DefaultInterface     string   `toml:"default_interface" json:"defaultInterface"`
-	LatencyTargets       []string `toml:"latency_targets" json:"latencyTargets"`
-	ReportTemplatePath   string   `toml:"report_template_path" json:"reportTemplatePath"`
+	ScanIntervalSeconds  int              `toml:"scan_interval_seconds" json:"scanIntervalSeconds"`
+	ScanMaxAgeSeconds    int              `toml:"scan_max_age_seconds" json:"scanMaxAgeSeconds"`
+	SignalHistoryMinutes int              `toml:"signal_history_minutes" json:"signalHistoryMinutes"`
+	RoamingHistorySize   int              `toml:"roaming_history_size" json:"roamingHistorySize"`
+	DefaultInterface     string           `toml:"default_interface" json:"defaultInterface"`
+	LatencyTargets       []string         `toml:"latency_targets" json:"latencyTargets"`
+	ReportTemplatePath   string           `toml:"report_template_path" json:"reportTemplatePath"`
+	RecordSessions       bool             `toml:"record_sessions" json:"recordSessions"`
+	MetricsListen        string           `toml:"metrics_listen" json:"metricsListen"`
+	APIListen            string           `toml:"api_listen" json:"apiListen"`
+	APIToken             string           `toml:"api_token" json:"apiToken"`
+	RogueLearningMinutes int              `toml:"rogue_learning_minutes" json:"rogueLearningMinutes"`
+	Alerts               []AlertRule      `toml:"alert" json:"alerts"`
+	Notify               NotifyConfig     `toml:"notify" json:"notify"`
+	HistoryRawMinutes    int              `toml:"history_raw_minutes" json:"historyRawMinutes"`
+	HistoryRetentionDays int              `toml:"history_retention_days" json:"historyRetentionDays"`
+	SurveyBurstScans     int              `toml:"survey_burst_scans" json:"surveyBurstScans"`
+	CoverageThresholdDBM int              `toml:"coverage_threshold_dbm" json:"coverageThresholdDbm"`
+	Throughput           ThroughputConfig `toml:"throughput" json:"throughput"`
+	Scan                 ScanOptions      `toml:"scan" json:"scan"`
+	Jobs                 []SurveyJob      `toml:"job" json:"jobs"`
 }
 
 // DefaultConfig returns the values used when no config file exists or fields
@@ -44,11 +96,25 @@ type Config struct {
 func DefaultConfig() Config {
 	return Config{
 		ScanIntervalSeconds:  4,
+		ScanMaxAgeSeconds:    30,
 		SignalHistoryMinutes: 10,
 		RoamingHistorySize:   100,
 		DefaultInterface:     "",
 		LatencyTargets:       []string{"gateway", "1.1.1.1"},
 		ReportTemplatePath:   "",
+		RecordSessions:       false,
+		MetricsListen:        "",
+		APIListen:            "",
+		APIToken:             "",
+		RogueLearningMinutes: 10,
+		Alerts:               nil,
+		Notify:               defaultNotifyConfig(),
+		HistoryRawMinutes:    15,
+		HistoryRetentionDays: 30,
+		SurveyBurstScans:     3,
+		CoverageThresholdDBM: -67,
+		Throughput:           defaultThroughputConfig(),
+		Jobs:                 nil,
 	}
 }
 
@@ -69,6 +135,13 @@ func (c Config) validate() (Config, error) {
 		notes = append(notes, fmt.Sprintf("scan_interval_seconds=%d clamped to 600", c.ScanIntervalSeconds))
 		c.ScanIntervalSeconds = 600
 	}
+	if c.ScanMaxAgeSeconds < 1 {
+		c.ScanMaxAgeSeconds = defaults.ScanMaxAgeSeconds
+	}
+	if c.ScanMaxAgeSeconds > 3600 {
+		notes = append(notes, fmt.Sprintf("scan_max_age_seconds=%d clamped to 3600", c.ScanMaxAgeSeconds))
+		c.ScanMaxAgeSeconds = 3600
+	}
 	if c.SignalHistoryMinutes < 1 {
 		c.SignalHistoryMinutes = defaults.SignalHistoryMinutes
 	}
@@ -86,6 +159,54 @@ func (c Config) validate() (Config, error) {
 	if c.LatencyTargets == nil {
 		c.LatencyTargets = defaults.LatencyTargets
 	}
+	// With no learning window every AP of a new SSID would be flagged.
+	if c.RogueLearningMinutes < 1 {
+		c.RogueLearningMinutes = defaults.RogueLearningMinutes
+	}
+	if c.HistoryRawMinutes < 1 {
+		c.HistoryRawMinutes = defaults.HistoryRawMinutes
+	}
+	if c.HistoryRawMinutes > 240 {
+		notes = append(notes, fmt.Sprintf("history_raw_minutes=%d clamped to 240", c.HistoryRawMinutes))
+		c.HistoryRawMinutes = 240
+	}
+	if c.HistoryRetentionDays < 1 {
+		c.HistoryRetentionDays = defaults.HistoryRetentionDays
+	}
+	if c.HistoryRetentionDays > 365 {
+		notes = append(notes, fmt.Sprintf("history_retention_days=%d clamped to 365", c.HistoryRetentionDays))
+		c.HistoryRetentionDays = 365
+	}
+	if c.SurveyBurstScans < 1 {
+		c.SurveyBurstScans = defaults.SurveyBurstScans
+	}
+	if c.SurveyBurstScans > 20 {
+		notes = append(notes, fmt.Sprintf("survey_burst_scans=%d clamped to 20", c.SurveyBurstScans))
+		c.SurveyBurstScans = 20
+	}
+	// 0 is a missing key; anything else outside -100…-30 dBm is a typo.
+	if c.CoverageThresholdDBM == 0 {
+		c.CoverageThresholdDBM = defaults.CoverageThresholdDBM
+	}
+	if c.CoverageThresholdDBM < -100 || c.CoverageThresholdDBM > -30 {
+		notes = append(notes, fmt.Sprintf("coverage_threshold_dbm=%d out of range -100…-30, using %d", c.CoverageThresholdDBM, defaults.CoverageThresholdDBM))
+		c.CoverageThresholdDBM = defaults.CoverageThresholdDBM
+	}
+	var alertNotes []string
+	c.Alerts, alertNotes = validateAlertRules(c.Alerts)
+	notes = append(notes, alertNotes...)
+	var notifyNotes []string
+	c.Notify, notifyNotes = c.Notify.validate()
+	notes = append(notes, notifyNotes...)
+	var throughputNotes []string
+	c.Throughput, throughputNotes = c.Throughput.validate()
+	notes = append(notes, throughputNotes...)
+	var scanNotes []string
+	c.Scan, scanNotes = c.Scan.validate()
+	notes = append(notes, scanNotes...)
+	var jobNotes []string
+	c.Jobs, jobNotes = validateSurveyJobs(c.Jobs)
+	notes = append(notes, jobNotes...)
 
 	if len(notes) == 0 {
 		return c, nil
@@ -115,6 +236,11 @@ func (c Config) ScanInterval() time.Duration {
 	return time.Duration(c.ScanIntervalSeconds) * time.Second
 }
 
+// ScanMaxAge returns the configured result max age as a time.Duration.
+func (c Config) ScanMaxAge() time.Duration {
+	return time.Duration(c.ScanMaxAgeSeconds) * time.Second
+}
+
 // SignalHistorySize returns the per-AP signal history capacity in points,
 // derived from the minutes-of-history setting + scan interval. The default
 // (10 minutes / 4 s) yields 150 points; the legacy hard-coded value was 600
@@ -197,10 +323,16 @@ func SaveConfig(cfg Config) error {
 		return fmt.Errorf("encode toml: %w", err)
 	}
 
+	// Owner-only: the file holds api_token and the SMTP password. Chmod
+	// as well, since WriteFile keeps the mode of a tmp left by a crash.
 	tmp := path + ".tmp"
-	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
 		return fmt.Errorf("write %s: %w", tmp, err)
 	}
+	if err := os.Chmod(tmp, 0o600); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("chmod %s: %w", tmp, err)
+	}
 	if err := os.Rename(tmp, path); err != nil {
 		_ = os.Remove(tmp)
 		return fmt.Errorf("rename %s -> %s: %w", tmp, path, err)
@@ -224,7 +356,7 @@ func (s *saveBuffer) Bytes() []byte                { return s.b }
 
 // chownToSudoUser re-chowns path back to the SUDO_UID/SUDO_GID user when the
 // process is running under sudo. Without this, files written by the app
-// (config, reports, session DB) would be owned by root and unreadable to the
+// (config, reports, session files) would be owned by root and unreadable to the
 // invoking user.
 //
 // Returns nil when no chown was needed (not running under sudo) OR when the
@@ -0,0 +1,277 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Event is one typed notification published on the service's EventBus.
+// Subscribers switch on the concrete type. Each event also knows its wire
+// form — the (name, payload) pairs the Svelte front end, the API streams
+// and the CLI have always consumed — so those string-keyed transports are
+// plain subscribers that forward wire().
+type Event interface {
+	wire() []wireEvent
+}
+
+type wireEvent struct {
+	name string
+	data interface{}
+}
+
+// ScanResultEvent is published after every successful scan tick, once the
+// aggregated result has been committed. APs is the normalized raw scan,
+// aged (see scan_freshness.go); entries the scan didn't hear are Stale.
+// Held-over APs appear only in Networks.
+type ScanResultEvent struct {
+	Interface string
+	Timestamp time.Time
+	APs       []AccessPoint
+	Networks  []Network
+	Channels  []ChannelInfo
+}
+
+func (e ScanResultEvent) wire() []wireEvent {
+	return []wireEvent{{"networks:updated", e.Networks}, {"channels:updated", e.Channels}}
+}
+
+// APPresenceEvent is published when a BSSID enters the merged scan results
+// or, once it has been stale for scan_max_age_seconds, leaves them. AP is
+// the latest view of it.
+type APPresenceEvent struct {
+	Timestamp time.Time
+	AP        AccessPoint
+	Appeared  bool
+}
+
+func (e APPresenceEvent) wire() []wireEvent {
+	if e.Appeared {
+		return []wireEvent{{"ap:appeared", e.AP}}
+	}
+	return []wireEvent{{"ap:disappeared", e.AP}}
+}
+
+// ClientUpdateEvent carries the client stats snapshot taken on each scan
+// tick, connected or not. With several interfaces scanning there is one per
+// radio; only the primary's goes out as client:updated, which the front
+// end treats as "the" client.
+type ClientUpdateEvent struct {
+	Interface string
+	Primary   bool
+	Timestamp time.Time
+	Stats     ClientStats
+}
+
+func (e ClientUpdateEvent) wire() []wireEvent {
+	if !e.Primary {
+		return nil
+	}
+	return []wireEvent{{"client:updated", e.Stats}}
+}
+
+// RoamEvent is published when the associated BSSID changes between ticks.
+type RoamEvent struct {
+	Interface string
+	Roam      RoamingEvent
+}
+
+func (e RoamEvent) wire() []wireEvent {
+	return []wireEvent{{"roaming:detected", e.Roam}}
+}
+
+// ScanErrorEvent is published when a scan tick fails after retries.
+type ScanErrorEvent struct {
+	Interface string
+	Err       error
+}
+
+func (e ScanErrorEvent) wire() []wireEvent {
+	return []wireEvent{{"scan:error", e.Err.Error()}}
+}
+
+// ReplayFinishedEvent is published once when a replayed capture has no
+// more scans for an interface. Scanning stops when every interface's
+// replay has finished.
+type ReplayFinishedEvent struct {
+	Interface string
+}
+
+func (e ReplayFinishedEvent) wire() []wireEvent {
+	return []wireEvent{{"replay:finished", e.Interface}}
+}
+
+// LatencyUpdateEvent carries the per-target summaries the sampler builds
+// once per tick.
+type LatencyUpdateEvent struct {
+	Summaries []LatencyTargetSummary
+}
+
+func (e LatencyUpdateEvent) wire() []wireEvent {
+	return []wireEvent{{"latency:updated", e.Summaries}}
+}
+
+// LatencyProbeEvent is one raw probe result. Internal only: the front end
+// gets probes through LatencyUpdateEvent's history.
+type LatencyProbeEvent struct {
+	Probe LatencyProbe
+}
+
+func (e LatencyProbeEvent) wire() []wireEvent { return nil }
+
+// AlertEvent is one alert rule transition (see alerts.go).
+type AlertEvent struct {
+	Alert Alert
+}
+
+func (e AlertEvent) wire() []wireEvent {
+	if e.Alert.State == AlertResolved {
+		return []wireEvent{{"alert:resolved", e.Alert}}
+	}
+	return []wireEvent{{"alert:fired", e.Alert}}
+}
+
+// RogueAPEvent is published when the rogue detector raises a new finding.
+type RogueAPEvent struct {
+	Finding RogueFinding
+}
+
+func (e RogueAPEvent) wire() []wireEvent {
+	return []wireEvent{{"security:rogue", e.Finding}}
+}
+
+// SurveyPointEvent is published when a site survey point's burst of scans
+// is complete.
+type SurveyPointEvent struct {
+	Point SurveyPoint
+}
+
+func (e SurveyPointEvent) wire() []wireEvent {
+	return []wireEvent{{"survey:point", e.Point}}
+}
+
+// ThroughputEvent is published after each throughput test, failed ones
+// included.
+type ThroughputEvent struct {
+	Result ThroughputResult
+}
+
+func (e ThroughputEvent) wire() []wireEvent {
+	return []wireEvent{{"throughput:result", e.Result}}
+}
+
+// EventBus is a synchronous publish/subscribe hub. Publish calls every
+// subscriber in subscription order on the publisher's goroutine, so
+// subscribers see events in the order they happened and must not block;
+// anything slow (network writes) belongs behind a buffered channel, as in
+// eventStream.
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   []*eventSubscription
+	nextID uint64
+}
+
+type eventSubscription struct {
+	id uint64
+	fn func(Event)
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe registers fn for every published event and returns a function
+// that removes it. Safe to call from within a subscriber.
+func (b *EventBus) Subscribe(fn func(Event)) (unsubscribe func()) {
+	b.mu.Lock()
+	b.nextID++
+	id := b.nextID
+	// Copy-on-write so Publish can iterate a snapshot without holding the
+	// lock while subscribers run.
+	subs := make([]*eventSubscription, len(b.subs), len(b.subs)+1)
+	copy(subs, b.subs)
+	b.subs = append(subs, &eventSubscription{id: id, fn: fn})
+	b.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			subs := make([]*eventSubscription, 0, len(b.subs))
+			for _, s := range b.subs {
+				if s.id != id {
+					subs = append(subs, s)
+				}
+			}
+			b.subs = subs
+		})
+	}
+}
+
+// SubscribeTo registers fn for events of type T only.
+func SubscribeTo[T Event](b *EventBus, fn func(T)) (unsubscribe func()) {
+	return b.Subscribe(func(e Event) {
+		if ev, ok := e.(T); ok {
+			fn(ev)
+		}
+	})
+}
+
+// Publish delivers e to every current subscriber. Nil-safe so components
+// built without a bus (tests) can publish unconditionally.
+func (b *EventBus) Publish(e Event) {
+	if b == nil {
+		return
+	}
+	b.mu.RLock()
+	subs := b.subs
+	b.mu.RUnlock()
+	for _, s := range subs {
+		s.fn(e)
+	}
+}
+
+// emitterSubscriber adapts an eventEmitter (Wails runtime, CLI) to the bus
+// by forwarding each event's wire form.
+func emitterSubscriber(emit eventEmitter) func(Event) {
+	return func(e Event) {
+		for _, w := range e.wire() {
+			emit(w.name, w.data)
+		}
+	}
+}
+
+// logEvent is the bus subscriber that turns notable events into structured
+// log lines.
+func logEvent(e Event) {
+	switch ev := e.(type) {
+	case ScanErrorEvent:
+		slog.Error("scan failed", "event", "scan_error", "interface", ev.Interface, "err", ev.Err)
+	case RoamEvent:
+		slog.Info("roam detected", "event", "roam", "interface", ev.Interface,
+			"from", ev.Roam.PreviousBSSID, "to", ev.Roam.NewBSSID, "duration_ms", ev.Roam.DurationMs)
+	case RogueAPEvent:
+		slog.Warn("possible rogue AP", "event", "rogue_ap", "kind", ev.Finding.Kind, "ssid", ev.Finding.SSID,
+			"bssid", ev.Finding.BSSID, "severity", ev.Finding.Severity)
+	case SurveyPointEvent:
+		slog.Info("survey point captured", "event", "survey_point", "point", ev.Point.Name,
+			"scans", ev.Point.Scans, "aps", len(ev.Point.APs))
+	case ThroughputEvent:
+		if r := ev.Result; r.Error != "" {
+			slog.Warn("throughput test failed", "event", "throughput_error", "host", r.Host, "mode", r.Mode, "err", r.Error)
+		} else {
+			slog.Info("throughput test", "event", "throughput", "host", r.Host, "mode", r.Mode,
+				"mbps", r.Mbps, "bssid", r.BSSID, "channel", r.Channel)
+		}
+	case AlertEvent:
+		slog.Warn("alert "+ev.Alert.State, "event", "alert", "rule", ev.Alert.Rule,
+			"subject", ev.Alert.Subject, "value", ev.Alert.Value, "severity", ev.Alert.Severity)
+	case APPresenceEvent:
+		msg, name := "AP disappeared", "ap_disappeared"
+		if ev.Appeared {
+			msg, name = "AP appeared", "ap_appeared"
+		}
+		slog.Debug(msg, "event", name, "bssid", ev.AP.BSSID, "ssid", ev.AP.SSID, "channel", ev.AP.Channel)
+	}
+}
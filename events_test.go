@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestEventBusSubscribeUnsubscribe(t *testing.T) {
+	bus := NewEventBus()
+	var all, roams int
+	unsubAll := bus.Subscribe(func(Event) { all++ })
+	SubscribeTo(bus, func(RoamEvent) { roams++ })
+
+	bus.Publish(RoamEvent{})
+	bus.Publish(ScanErrorEvent{Err: errors.New("busy")})
+	unsubAll()
+	unsubAll() // idempotent
+	bus.Publish(RoamEvent{})
+
+	if all != 2 || roams != 2 {
+		t.Errorf("all = %d, roams = %d; want 2, 2", all, roams)
+	}
+	var nilBus *EventBus
+	nilBus.Publish(RoamEvent{}) // must not panic
+}
+
+func TestServiceEventsTypedAndWire(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	sim, err := openSimBackend("testdata/sim/roaming.toml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := newWiFiServiceWithBackend(sim)
+	defer ws.Close()
+
+	var roams []RoamEvent
+	var scans int
+	SubscribeTo(ws.Events(), func(e RoamEvent) { roams = append(roams, e) })
+	SubscribeTo(ws.Events(), func(ScanResultEvent) { scans++ })
+
+	// The headless emitter sees the same events in their wire form.
+	var names []string
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ws.AttachHeadless(ctx, func(name string, data interface{}) {
+		if name != "latency:updated" {
+			names = append(names, name)
+		}
+	})
+
+	for range 21 {
+		ws.performScan(context.Background(), "sim0")
+	}
+	cancel()
+
+	if scans != 21 {
+		t.Errorf("ScanResultEvents = %d, want 21", scans)
+	}
+	if len(roams) != 1 || roams[0].Interface != "sim0" || roams[0].Roam.NewBSSID != "02:00:00:00:01:02" {
+		t.Fatalf("RoamEvents = %+v", roams)
+	}
+	if got := names[:3]; got[0] != "networks:updated" || got[1] != "channels:updated" || got[2] != "client:updated" {
+		t.Errorf("first tick wire events = %v", got)
+	}
+	count := 0
+	for _, n := range names {
+		if n == "roaming:detected" {
+			count++
+		}
+	}
+	if count != 1 {
+		t.Errorf("roaming:detected emitted %d times, want 1", count)
+	}
+}
@@ -2,34 +2,102 @@
 // This file is automatically generated. DO NOT EDIT
 import {main} from '../models';
 
+export function ChooseSurveyFloorPlan(arg1:string):Promise<main.Survey>;
+
+export function CompareSurveys(arg1:string,arg2:string):Promise<main.SurveyComparison>;
+
+export function DeleteSession(arg1:string):Promise<void>;
+
+export function DeleteSurvey(arg1:string):Promise<void>;
+
 export function ExportClientStats():Promise<string>;
 
+export function ExportHistory(arg1:main.HistoryQuery,arg2:string):Promise<string>;
+
 export function ExportNetworks(arg1:string):Promise<string>;
 
+export function ExportSurvey(arg1:string,arg2:string):Promise<string>;
+
+export function GenerateHTMLReport():Promise<string>;
+
 export function GetAPPlacementRecommendations():Promise<Array<string>>;
 
 export function GetAPSignalHistory():Promise<Array<main.APSignalHistory>>;
 
+export function GetActiveSurvey():Promise<main.Survey>;
+
+export function GetAlerts():Promise<main.AlertsSnapshot>;
+
 export function GetAvailableInterfaces():Promise<Array<string>>;
 
+export function GetBackendCapabilities():Promise<Array<main.BackendCapabilities>>;
+
 export function GetChannelAnalysis():Promise<Array<main.ChannelInfo>>;
 
 export function GetClientStats():Promise<main.ClientStats>;
 
 export function GetConfig():Promise<main.Config>;
 
+export function GetInterfaceClientStats():Promise<Record<string, main.ClientStats>>;
+
+export function GetJobHistory():Promise<Array<main.JobRun>>;
+
 export function GetLatency():Promise<Array<main.LatencyTargetSummary>>;
 
 export function GetNetworks():Promise<Array<main.Network>>;
 
 export function GetRoamingAnalysis():Promise<main.RoamingQualityReport>;
 
+export function GetRogueFindings():Promise<Array<main.RogueFinding>>;
+
+export function GetScanOptions():Promise<main.ScanOptions>;
+
+export function GetSecurityAudit():Promise<Array<main.NetworkSecurityAudit>>;
+
+export function GetSurveyCoverage(arg1:string,arg2:number):Promise<Array<main.SSIDCoverage>>;
+
+export function GetSurveyHeatmap(arg1:string,arg2:main.HeatmapRequest):Promise<main.Heatmap>;
+
+export function GetThroughputResults():Promise<Array<main.ThroughputResult>>;
+
 export function IsScanning():Promise<boolean>;
 
+export function ListSessions():Promise<Array<main.SessionInfo>>;
+
+export function ListSurveys():Promise<Array<main.SurveyInfo>>;
+
+export function MarkSurveyPoint(arg1:string):Promise<main.SurveyPoint>;
+
+export function MarkSurveyPointAt(arg1:string,arg2:number,arg3:number):Promise<main.SurveyPoint>;
+
+export function OpenSession(arg1:string):Promise<main.Session>;
+
+export function OpenSurvey(arg1:string):Promise<main.Survey>;
+
+export function PlaceSurveyPoint(arg1:string,arg2:number,arg3:number,arg4:number):Promise<main.Survey>;
+
+export function QueryHistory(arg1:main.HistoryQuery):Promise<Array<main.HistorySeries>>;
+
+export function RenderSurveyHeatmap(arg1:string,arg2:main.HeatmapRequest,arg3:string):Promise<string>;
+
+export function RunThroughputTest(arg1:string,arg2:Array<string>):Promise<Array<main.ThroughputResult>>;
+
 export function SaveConfig(arg1:main.Config):Promise<void>;
 
+export function SavePDFReport(arg1:string):Promise<string>;
+
 export function SaveReport(arg1:string,arg2:string):Promise<string>;
 
 export function StartScanning(arg1:string):Promise<void>;
 
+export function StartScanningInterfaces(arg1:Array<string>):Promise<void>;
+
+export function StartScanningWithOptions(arg1:Array<string>,arg2:main.ScanOptions):Promise<void>;
+
+export function StartSurvey(arg1:string):Promise<main.Survey>;
+
 export function StopScanning():Promise<void>;
+
+export function StopSurvey():Promise<main.Survey>;
+
+export function TrustAccessPoint(arg1:string):Promise<void>;
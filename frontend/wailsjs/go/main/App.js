@@ -2,14 +2,42 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ChooseSurveyFloorPlan(arg1) {
+  return window['go']['main']['App']['ChooseSurveyFloorPlan'](arg1);
+}
+
+export function CompareSurveys(arg1, arg2) {
+  return window['go']['main']['App']['CompareSurveys'](arg1, arg2);
+}
+
+export function DeleteSession(arg1) {
+  return window['go']['main']['App']['DeleteSession'](arg1);
+}
+
+export function DeleteSurvey(arg1) {
+  return window['go']['main']['App']['DeleteSurvey'](arg1);
+}
+
 export function ExportClientStats() {
   return window['go']['main']['App']['ExportClientStats']();
 }
 
+export function ExportHistory(arg1, arg2) {
+  return window['go']['main']['App']['ExportHistory'](arg1, arg2);
+}
+
 export function ExportNetworks(arg1) {
   return window['go']['main']['App']['ExportNetworks'](arg1);
 }
 
+export function ExportSurvey(arg1, arg2) {
+  return window['go']['main']['App']['ExportSurvey'](arg1, arg2);
+}
+
+export function GenerateHTMLReport() {
+  return window['go']['main']['App']['GenerateHTMLReport']();
+}
+
 export function GetAPPlacementRecommendations() {
   return window['go']['main']['App']['GetAPPlacementRecommendations']();
 }
@@ -18,10 +46,22 @@ export function GetAPSignalHistory() {
   return window['go']['main']['App']['GetAPSignalHistory']();
 }
 
+export function GetActiveSurvey() {
+  return window['go']['main']['App']['GetActiveSurvey']();
+}
+
+export function GetAlerts() {
+  return window['go']['main']['App']['GetAlerts']();
+}
+
 export function GetAvailableInterfaces() {
   return window['go']['main']['App']['GetAvailableInterfaces']();
 }
 
+export function GetBackendCapabilities() {
+  return window['go']['main']['App']['GetBackendCapabilities']();
+}
+
 export function GetChannelAnalysis() {
   return window['go']['main']['App']['GetChannelAnalysis']();
 }
@@ -34,6 +74,14 @@ export function GetConfig() {
   return window['go']['main']['App']['GetConfig']();
 }
 
+export function GetInterfaceClientStats() {
+  return window['go']['main']['App']['GetInterfaceClientStats']();
+}
+
+export function GetJobHistory() {
+  return window['go']['main']['App']['GetJobHistory']();
+}
+
 export function GetLatency() {
   return window['go']['main']['App']['GetLatency']();
 }
@@ -46,14 +94,82 @@ export function GetRoamingAnalysis() {
   return window['go']['main']['App']['GetRoamingAnalysis']();
 }
 
+export function GetRogueFindings() {
+  return window['go']['main']['App']['GetRogueFindings']();
+}
+
+export function GetScanOptions() {
+  return window['go']['main']['App']['GetScanOptions']();
+}
+
+export function GetSecurityAudit() {
+  return window['go']['main']['App']['GetSecurityAudit']();
+}
+
+export function GetSurveyCoverage(arg1, arg2) {
+  return window['go']['main']['App']['GetSurveyCoverage'](arg1, arg2);
+}
+
+export function GetSurveyHeatmap(arg1, arg2) {
+  return window['go']['main']['App']['GetSurveyHeatmap'](arg1, arg2);
+}
+
+export function GetThroughputResults() {
+  return window['go']['main']['App']['GetThroughputResults']();
+}
+
 export function IsScanning() {
   return window['go']['main']['App']['IsScanning']();
 }
 
+export function ListSessions() {
+  return window['go']['main']['App']['ListSessions']();
+}
+
+export function ListSurveys() {
+  return window['go']['main']['App']['ListSurveys']();
+}
+
+export function MarkSurveyPoint(arg1) {
+  return window['go']['main']['App']['MarkSurveyPoint'](arg1);
+}
+
+export function MarkSurveyPointAt(arg1, arg2, arg3) {
+  return window['go']['main']['App']['MarkSurveyPointAt'](arg1, arg2, arg3);
+}
+
+export function OpenSession(arg1) {
+  return window['go']['main']['App']['OpenSession'](arg1);
+}
+
+export function OpenSurvey(arg1) {
+  return window['go']['main']['App']['OpenSurvey'](arg1);
+}
+
+export function PlaceSurveyPoint(arg1, arg2, arg3, arg4) {
+  return window['go']['main']['App']['PlaceSurveyPoint'](arg1, arg2, arg3, arg4);
+}
+
+export function QueryHistory(arg1) {
+  return window['go']['main']['App']['QueryHistory'](arg1);
+}
+
+export function RenderSurveyHeatmap(arg1, arg2, arg3) {
+  return window['go']['main']['App']['RenderSurveyHeatmap'](arg1, arg2, arg3);
+}
+
+export function RunThroughputTest(arg1, arg2) {
+  return window['go']['main']['App']['RunThroughputTest'](arg1, arg2);
+}
+
 export function SaveConfig(arg1) {
   return window['go']['main']['App']['SaveConfig'](arg1);
 }
 
+export function SavePDFReport(arg1) {
+  return window['go']['main']['App']['SavePDFReport'](arg1);
+}
+
 export function SaveReport(arg1, arg2) {
   return window['go']['main']['App']['SaveReport'](arg1, arg2);
 }
@@ -62,6 +178,26 @@ export function StartScanning(arg1) {
   return window['go']['main']['App']['StartScanning'](arg1);
 }
 
+export function StartScanningInterfaces(arg1) {
+  return window['go']['main']['App']['StartScanningInterfaces'](arg1);
+}
+
+export function StartScanningWithOptions(arg1, arg2) {
+  return window['go']['main']['App']['StartScanningWithOptions'](arg1, arg2);
+}
+
+export function StartSurvey(arg1) {
+  return window['go']['main']['App']['StartSurvey'](arg1);
+}
+
 export function StopScanning() {
   return window['go']['main']['App']['StopScanning']();
 }
+
+export function StopSurvey() {
+  return window['go']['main']['App']['StopSurvey']();
+}
+
+export function TrustAccessPoint(arg1) {
+  return window['go']['main']['App']['TrustAccessPoint'](arg1);
+}
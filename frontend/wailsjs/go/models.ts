@@ -69,6 +69,47 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class RadioReading {
+	    interface: string;
+	    signal: number;
+	    noise: number;
+	    // Go type: time
+	    lastSeen: any;
+	    ageMs: number;
+	    stale: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RadioReading(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.signal = source["signal"];
+	        this.noise = source["noise"];
+	        this.lastSeen = this.convertValues(source["lastSeen"], null);
+	        this.ageMs = source["ageMs"];
+	        this.stale = source["stale"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
 	export class AccessPoint {
 	    bssid: string;
 	    ssid: string;
@@ -85,6 +126,8 @@ export namespace main {
 	    band: string;
 	    // Go type: time
 	    lastSeen: any;
+	    ageMs: number;
+	    stale: boolean;
 	    capabilities: string[];
 	    beaconInt: number;
 	    bsstransition: boolean;
@@ -120,6 +163,7 @@ export namespace main {
 	    qosSupport: boolean;
 	    countryCode: string;
 	    apName: string;
+	    radios?: RadioReading[];
 	
 	    static createFrom(source: any = {}) {
 	        return new AccessPoint(source);
@@ -141,6 +185,8 @@ export namespace main {
 	        this.security = source["security"];
 	        this.band = source["band"];
 	        this.lastSeen = this.convertValues(source["lastSeen"], null);
+	        this.ageMs = source["ageMs"];
+	        this.stale = source["stale"];
 	        this.capabilities = source["capabilities"];
 	        this.beaconInt = source["beaconInt"];
 	        this.bsstransition = source["bsstransition"];
@@ -176,6 +222,115 @@ export namespace main {
 	        this.qosSupport = source["qosSupport"];
 	        this.countryCode = source["countryCode"];
 	        this.apName = source["apName"];
+	        this.radios = this.convertValues(source["radios"], RadioReading);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Alert {
+	    rule: string;
+	    metric: string;
+	    severity: string;
+	    subject: string;
+	    state: string;
+	    value: number;
+	    threshold: number;
+	    message: string;
+	    // Go type: time
+	    since: any;
+	    // Go type: time
+	    timestamp: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new Alert(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.metric = source["metric"];
+	        this.severity = source["severity"];
+	        this.subject = source["subject"];
+	        this.state = source["state"];
+	        this.value = source["value"];
+	        this.threshold = source["threshold"];
+	        this.message = source["message"];
+	        this.since = this.convertValues(source["since"], null);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class AlertRule {
+	    name: string;
+	    metric: string;
+	    op: string;
+	    threshold: number;
+	    forSeconds: number;
+	    target: string;
+	    windowSeconds: number;
+	    severity: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new AlertRule(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.metric = source["metric"];
+	        this.op = source["op"];
+	        this.threshold = source["threshold"];
+	        this.forSeconds = source["forSeconds"];
+	        this.target = source["target"];
+	        this.windowSeconds = source["windowSeconds"];
+	        this.severity = source["severity"];
+	    }
+	}
+	export class AlertsSnapshot {
+	    active: Alert[];
+	    history: Alert[];
+	
+	    static createFrom(source: any = {}) {
+	        return new AlertsSnapshot(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.active = this.convertValues(source["active"], Alert);
+	        this.history = this.convertValues(source["history"], Alert);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -196,6 +351,34 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class BackendCapabilities {
+	    interface: string;
+	    backend: string;
+	    informationElements: boolean;
+	    survey: boolean;
+	    noise: boolean;
+	    stationRetries: boolean;
+	    activeScan: boolean;
+	    passiveScan: boolean;
+	    bands: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new BackendCapabilities(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.interface = source["interface"];
+	        this.backend = source["backend"];
+	        this.informationElements = source["informationElements"];
+	        this.survey = source["survey"];
+	        this.noise = source["noise"];
+	        this.stationRetries = source["stationRetries"];
+	        this.activeScan = source["activeScan"];
+	        this.passiveScan = source["passiveScan"];
+	        this.bands = source["bands"];
+	    }
+	}
 	export class ChannelInfo {
 	    channel: number;
 	    frequency: number;
@@ -351,117 +534,122 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class Config {
-	    scanIntervalSeconds: number;
-	    signalHistoryMinutes: number;
-	    roamingHistorySize: number;
-	    defaultInterface: string;
-	    latencyTargets: string[];
-	    reportTemplatePath: string;
-	    macosHelperPath: string;
+	export class SurveyJob {
+	    name: string;
+	    at: string;
+	    weekdays: string[];
+	    every: string;
+	    interface: string;
+	    durationSeconds: number;
+	    formats: string[];
+	    outputDir: string;
+	    keepRuns: number;
+	    keepDays: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Config(source);
+	        return new SurveyJob(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.scanIntervalSeconds = source["scanIntervalSeconds"];
-	        this.signalHistoryMinutes = source["signalHistoryMinutes"];
-	        this.roamingHistorySize = source["roamingHistorySize"];
-	        this.defaultInterface = source["defaultInterface"];
-	        this.latencyTargets = source["latencyTargets"];
-	        this.reportTemplatePath = source["reportTemplatePath"];
-	        this.macosHelperPath = source["macosHelperPath"];
+	        this.name = source["name"];
+	        this.at = source["at"];
+	        this.weekdays = source["weekdays"];
+	        this.every = source["every"];
+	        this.interface = source["interface"];
+	        this.durationSeconds = source["durationSeconds"];
+	        this.formats = source["formats"];
+	        this.outputDir = source["outputDir"];
+	        this.keepRuns = source["keepRuns"];
+	        this.keepDays = source["keepDays"];
 	    }
 	}
-	export class LatencyProbe {
-	    // Go type: time
-	    timestamp: any;
-	    target: string;
-	    label: string;
-	    rttMs: number;
-	    lost: boolean;
-	    transport: string;
+	export class ScanOptions {
+	    ssids: string[];
+	    frequencies: number[];
+	    channels: number[];
+	    passive: boolean;
+	    dwellMs: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new LatencyProbe(source);
+	        return new ScanOptions(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.timestamp = this.convertValues(source["timestamp"], null);
-	        this.target = source["target"];
-	        this.label = source["label"];
-	        this.rttMs = source["rttMs"];
-	        this.lost = source["lost"];
-	        this.transport = source["transport"];
+	        this.ssids = source["ssids"];
+	        this.frequencies = source["frequencies"];
+	        this.channels = source["channels"];
+	        this.passive = source["passive"];
+	        this.dwellMs = source["dwellMs"];
 	    }
+	}
+	export class ThroughputConfig {
+	    host: string;
+	    intervalMinutes: number;
+	    durationSeconds: number;
+	    modes: string[];
+	    parallel: number;
+	    udpMbps: number;
 	
-		convertValues(a: any, classs: any, asMap: boolean = false): any {
-		    if (!a) {
-		        return a;
-		    }
-		    if (a.slice && a.map) {
-		        return (a as any[]).map(elem => this.convertValues(elem, classs));
-		    } else if ("object" === typeof a) {
-		        if (asMap) {
-		            for (const key of Object.keys(a)) {
-		                a[key] = new classs(a[key]);
-		            }
-		            return a;
-		        }
-		        return new classs(a);
-		    }
-		    return a;
-		}
+	    static createFrom(source: any = {}) {
+	        return new ThroughputConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.host = source["host"];
+	        this.intervalMinutes = source["intervalMinutes"];
+	        this.durationSeconds = source["durationSeconds"];
+	        this.modes = source["modes"];
+	        this.parallel = source["parallel"];
+	        this.udpMbps = source["udpMbps"];
+	    }
 	}
-	export class LatencyStats {
-	    windowSeconds: number;
-	    samples: number;
-	    minMs: number;
-	    avgMs: number;
-	    maxMs: number;
-	    stddevMs: number;
-	    lossPercent: number;
+	export class NotifySink {
+	    type: string;
+	    url: string;
+	    events: string[];
+	    smtpHost: string;
+	    smtpUser: string;
+	    smtpPassword: string;
+	    from: string;
+	    to: string[];
 	
 	    static createFrom(source: any = {}) {
-	        return new LatencyStats(source);
+	        return new NotifySink(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.windowSeconds = source["windowSeconds"];
-	        this.samples = source["samples"];
-	        this.minMs = source["minMs"];
-	        this.avgMs = source["avgMs"];
-	        this.maxMs = source["maxMs"];
-	        this.stddevMs = source["stddevMs"];
-	        this.lossPercent = source["lossPercent"];
+	        this.type = source["type"];
+	        this.url = source["url"];
+	        this.events = source["events"];
+	        this.smtpHost = source["smtpHost"];
+	        this.smtpUser = source["smtpUser"];
+	        this.smtpPassword = source["smtpPassword"];
+	        this.from = source["from"];
+	        this.to = source["to"];
 	    }
 	}
-	export class LatencyTargetSummary {
-	    label: string;
-	    target: string;
-	    transport: string;
-	    available: boolean;
-	    lastProbe?: LatencyProbe;
-	    windows: LatencyStats[];
-	    history: LatencyProbe[];
+	export class NotifyConfig {
+	    sinks: NotifySink[];
+	    dedupSeconds: number;
+	    rateLimitPerMinute: number;
+	    slowRoamMs: number;
+	    latencyLossPercent: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new LatencyTargetSummary(source);
+	        return new NotifyConfig(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.label = source["label"];
-	        this.target = source["target"];
-	        this.transport = source["transport"];
-	        this.available = source["available"];
-	        this.lastProbe = this.convertValues(source["lastProbe"], LatencyProbe);
-	        this.windows = this.convertValues(source["windows"], LatencyStats);
-	        this.history = this.convertValues(source["history"], LatencyProbe);
+	        this.sinks = this.convertValues(source["sinks"], NotifySink);
+	        this.dedupSeconds = source["dedupSeconds"];
+	        this.rateLimitPerMinute = source["rateLimitPerMinute"];
+	        this.slowRoamMs = source["slowRoamMs"];
+	        this.latencyLossPercent = source["latencyLossPercent"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -482,32 +670,56 @@ export namespace main {
 		    return a;
 		}
 	}
-	export class Network {
-	    ssid: string;
-	    accessPoints: AccessPoint[];
-	    bestSignal: number;
-	    bestSignalAP: string;
-	    channel: number;
-	    security: string;
-	    apCount: number;
-	    hasIssues: boolean;
-	    issueMessages: string[];
+	export class Config {
+	    scanIntervalSeconds: number;
+	    scanMaxAgeSeconds: number;
+	    signalHistoryMinutes: number;
+	    roamingHistorySize: number;
+	    defaultInterface: string;
+	    latencyTargets: string[];
+	    reportTemplatePath: string;
+	    recordSessions: boolean;
+	    metricsListen: string;
+	    apiListen: string;
+	    apiToken: string;
+	    rogueLearningMinutes: number;
+	    alerts: AlertRule[];
+	    notify: NotifyConfig;
+	    historyRawMinutes: number;
+	    historyRetentionDays: number;
+	    surveyBurstScans: number;
+	    coverageThresholdDbm: number;
+	    throughput: ThroughputConfig;
+	    scan: ScanOptions;
+	    jobs: SurveyJob[];
 	
 	    static createFrom(source: any = {}) {
-	        return new Network(source);
+	        return new Config(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.ssid = source["ssid"];
-	        this.accessPoints = this.convertValues(source["accessPoints"], AccessPoint);
-	        this.bestSignal = source["bestSignal"];
-	        this.bestSignalAP = source["bestSignalAP"];
-	        this.channel = source["channel"];
-	        this.security = source["security"];
-	        this.apCount = source["apCount"];
-	        this.hasIssues = source["hasIssues"];
-	        this.issueMessages = source["issueMessages"];
+	        this.scanIntervalSeconds = source["scanIntervalSeconds"];
+	        this.scanMaxAgeSeconds = source["scanMaxAgeSeconds"];
+	        this.signalHistoryMinutes = source["signalHistoryMinutes"];
+	        this.roamingHistorySize = source["roamingHistorySize"];
+	        this.defaultInterface = source["defaultInterface"];
+	        this.latencyTargets = source["latencyTargets"];
+	        this.reportTemplatePath = source["reportTemplatePath"];
+	        this.recordSessions = source["recordSessions"];
+	        this.metricsListen = source["metricsListen"];
+	        this.apiListen = source["apiListen"];
+	        this.apiToken = source["apiToken"];
+	        this.rogueLearningMinutes = source["rogueLearningMinutes"];
+	        this.alerts = this.convertValues(source["alerts"], AlertRule);
+	        this.notify = this.convertValues(source["notify"], NotifyConfig);
+	        this.historyRawMinutes = source["historyRawMinutes"];
+	        this.historyRetentionDays = source["historyRetentionDays"];
+	        this.surveyBurstScans = source["surveyBurstScans"];
+	        this.coverageThresholdDbm = source["coverageThresholdDbm"];
+	        this.throughput = this.convertValues(source["throughput"], ThroughputConfig);
+	        this.scan = this.convertValues(source["scan"], ScanOptions);
+	        this.jobs = this.convertValues(source["jobs"], SurveyJob);
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -528,27 +740,533 @@ export namespace main {
 		    return a;
 		}
 	}
+	export class FloorPlan {
+	    file: string;
+	    source: string;
+	    width: number;
+	    height: number;
 	
-	export class RoamingQualityReport {
-	    totalRoams: number;
-	    goodRoams: number;
-	    badRoams: number;
-	    avgSignalChange: number;
-	    excessiveRoaming: boolean;
-	    stickyClient: boolean;
-	    timeSinceLastRoam?: string;
-	    roamingAdvice: string;
-	    avgRoamDurationMs: number;
-	    maxRoamDurationMs: number;
-	    slowRoamCount: number;
+	    static createFrom(source: any = {}) {
+	        return new FloorPlan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.source = source["source"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	    }
+	}
+	export class FloorPoint {
+	    x: number;
+	    y: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new RoamingQualityReport(source);
+	        return new FloorPoint(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.totalRoams = source["totalRoams"];
+	        this.x = source["x"];
+	        this.y = source["y"];
+	    }
+	}
+	export class HeatmapPoint {
+	    name: string;
+	    x: number;
+	    y: number;
+	    signal: number;
+	    heard: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new HeatmapPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.x = source["x"];
+	        this.y = source["y"];
+	        this.signal = source["signal"];
+	        this.heard = source["heard"];
+	    }
+	}
+	export class Heatmap {
+	    survey: string;
+	    ssid: string;
+	    bssid: string;
+	    width: number;
+	    height: number;
+	    cellSize: number;
+	    cols: number;
+	    rows: number;
+	    values: number[];
+	    threshold: number;
+	    coveragePct: number;
+	    points: HeatmapPoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Heatmap(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.survey = source["survey"];
+	        this.ssid = source["ssid"];
+	        this.bssid = source["bssid"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.cellSize = source["cellSize"];
+	        this.cols = source["cols"];
+	        this.rows = source["rows"];
+	        this.values = source["values"];
+	        this.threshold = source["threshold"];
+	        this.coveragePct = source["coveragePct"];
+	        this.points = this.convertValues(source["points"], HeatmapPoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class HeatmapRequest {
+	    ssid: string;
+	    bssid: string;
+	    threshold: number;
+	    cellSize: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HeatmapRequest(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ssid = source["ssid"];
+	        this.bssid = source["bssid"];
+	        this.threshold = source["threshold"];
+	        this.cellSize = source["cellSize"];
+	    }
+	}
+	export class HistoryPoint {
+	    // Go type: time
+	    timestamp: any;
+	    min: number;
+	    avg: number;
+	    max: number;
+	    count: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.min = source["min"];
+	        this.avg = source["avg"];
+	        this.max = source["max"];
+	        this.count = source["count"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HistoryQuery {
+	    metric: string;
+	    subject: string;
+	    // Go type: time
+	    from: any;
+	    // Go type: time
+	    to: any;
+	    resolution: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new HistoryQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.metric = source["metric"];
+	        this.subject = source["subject"];
+	        this.from = this.convertValues(source["from"], null);
+	        this.to = this.convertValues(source["to"], null);
+	        this.resolution = source["resolution"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class HistorySeries {
+	    metric: string;
+	    subject: string;
+	    label?: string;
+	    resolution: string;
+	    points: HistoryPoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new HistorySeries(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.metric = source["metric"];
+	        this.subject = source["subject"];
+	        this.label = source["label"];
+	        this.resolution = source["resolution"];
+	        this.points = this.convertValues(source["points"], HistoryPoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class JobRun {
+	    job: string;
+	    // Go type: time
+	    started: any;
+	    // Go type: time
+	    finished: any;
+	    status: string;
+	    interfaces: string[];
+	    files: string[];
+	    pruned: number;
+	    note?: string;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new JobRun(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.job = source["job"];
+	        this.started = this.convertValues(source["started"], null);
+	        this.finished = this.convertValues(source["finished"], null);
+	        this.status = source["status"];
+	        this.interfaces = source["interfaces"];
+	        this.files = source["files"];
+	        this.pruned = source["pruned"];
+	        this.note = source["note"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LatencyProbe {
+	    // Go type: time
+	    timestamp: any;
+	    target: string;
+	    label: string;
+	    rttMs: number;
+	    lost: boolean;
+	    transport: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LatencyProbe(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.target = source["target"];
+	        this.label = source["label"];
+	        this.rttMs = source["rttMs"];
+	        this.lost = source["lost"];
+	        this.transport = source["transport"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LatencyStats {
+	    windowSeconds: number;
+	    samples: number;
+	    minMs: number;
+	    avgMs: number;
+	    maxMs: number;
+	    stddevMs: number;
+	    lossPercent: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LatencyStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.windowSeconds = source["windowSeconds"];
+	        this.samples = source["samples"];
+	        this.minMs = source["minMs"];
+	        this.avgMs = source["avgMs"];
+	        this.maxMs = source["maxMs"];
+	        this.stddevMs = source["stddevMs"];
+	        this.lossPercent = source["lossPercent"];
+	    }
+	}
+	export class LatencyTargetSummary {
+	    label: string;
+	    target: string;
+	    transport: string;
+	    available: boolean;
+	    lastProbe?: LatencyProbe;
+	    windows: LatencyStats[];
+	    history: LatencyProbe[];
+	
+	    static createFrom(source: any = {}) {
+	        return new LatencyTargetSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.label = source["label"];
+	        this.target = source["target"];
+	        this.transport = source["transport"];
+	        this.available = source["available"];
+	        this.lastProbe = this.convertValues(source["lastProbe"], LatencyProbe);
+	        this.windows = this.convertValues(source["windows"], LatencyStats);
+	        this.history = this.convertValues(source["history"], LatencyProbe);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Network {
+	    ssid: string;
+	    accessPoints: AccessPoint[];
+	    bestSignal: number;
+	    bestSignalAP: string;
+	    channel: number;
+	    security: string;
+	    apCount: number;
+	    hasIssues: boolean;
+	    issueMessages: string[];
+	    stale: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Network(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ssid = source["ssid"];
+	        this.accessPoints = this.convertValues(source["accessPoints"], AccessPoint);
+	        this.bestSignal = source["bestSignal"];
+	        this.bestSignalAP = source["bestSignalAP"];
+	        this.channel = source["channel"];
+	        this.security = source["security"];
+	        this.apCount = source["apCount"];
+	        this.hasIssues = source["hasIssues"];
+	        this.issueMessages = source["issueMessages"];
+	        this.stale = source["stale"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SecurityFinding {
+	    rule: string;
+	    severity: string;
+	    title: string;
+	    remediation: string;
+	    bssids: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SecurityFinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rule = source["rule"];
+	        this.severity = source["severity"];
+	        this.title = source["title"];
+	        this.remediation = source["remediation"];
+	        this.bssids = source["bssids"];
+	    }
+	}
+	export class NetworkSecurityAudit {
+	    ssid: string;
+	    security: string;
+	    apCount: number;
+	    score: number;
+	    grade: string;
+	    findings: SecurityFinding[];
+	
+	    static createFrom(source: any = {}) {
+	        return new NetworkSecurityAudit(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ssid = source["ssid"];
+	        this.security = source["security"];
+	        this.apCount = source["apCount"];
+	        this.score = source["score"];
+	        this.grade = source["grade"];
+	        this.findings = this.convertValues(source["findings"], SecurityFinding);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	
+	export class RoamingQualityReport {
+	    totalRoams: number;
+	    goodRoams: number;
+	    badRoams: number;
+	    avgSignalChange: number;
+	    excessiveRoaming: boolean;
+	    stickyClient: boolean;
+	    timeSinceLastRoam?: string;
+	    roamingAdvice: string;
+	    avgRoamDurationMs: number;
+	    maxRoamDurationMs: number;
+	    slowRoamCount: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RoamingQualityReport(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.totalRoams = source["totalRoams"];
 	        this.goodRoams = source["goodRoams"];
 	        this.badRoams = source["badRoams"];
 	        this.avgSignalChange = source["avgSignalChange"];
@@ -561,6 +1279,533 @@ export namespace main {
 	        this.slowRoamCount = source["slowRoamCount"];
 	    }
 	}
+	export class RogueFinding {
+	    kind: string;
+	    severity: string;
+	    ssid: string;
+	    bssid: string;
+	    vendor: string;
+	    channel: number;
+	    signal: number;
+	    interface: string;
+	    message: string;
+	    // Go type: time
+	    firstSeen: any;
+	    // Go type: time
+	    lastSeen: any;
+	
+	    static createFrom(source: any = {}) {
+	        return new RogueFinding(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.kind = source["kind"];
+	        this.severity = source["severity"];
+	        this.ssid = source["ssid"];
+	        this.bssid = source["bssid"];
+	        this.vendor = source["vendor"];
+	        this.channel = source["channel"];
+	        this.signal = source["signal"];
+	        this.interface = source["interface"];
+	        this.message = source["message"];
+	        this.firstSeen = this.convertValues(source["firstSeen"], null);
+	        this.lastSeen = this.convertValues(source["lastSeen"], null);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SSIDCoverage {
+	    ssid: string;
+	    coveragePct: number;
+	    points: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SSIDCoverage(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.ssid = source["ssid"];
+	        this.coveragePct = source["coveragePct"];
+	        this.points = source["points"];
+	    }
+	}
+	
+	
+	export class ThroughputResult {
+	    // Go type: time
+	    timestamp: any;
+	    host: string;
+	    mode: string;
+	    streams: number;
+	    durationSec: number;
+	    bytes: number;
+	    mbps: number;
+	    jitterMs: number;
+	    packets: number;
+	    lostPackets: number;
+	    lossPercent: number;
+	    error?: string;
+	    interface: string;
+	    ssid: string;
+	    bssid: string;
+	    channel: number;
+	    band: string;
+	    signal: number;
+	    txBitrate: number;
+	    rxBitrate: number;
+	    roamed: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ThroughputResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.host = source["host"];
+	        this.mode = source["mode"];
+	        this.streams = source["streams"];
+	        this.durationSec = source["durationSec"];
+	        this.bytes = source["bytes"];
+	        this.mbps = source["mbps"];
+	        this.jitterMs = source["jitterMs"];
+	        this.packets = source["packets"];
+	        this.lostPackets = source["lostPackets"];
+	        this.lossPercent = source["lossPercent"];
+	        this.error = source["error"];
+	        this.interface = source["interface"];
+	        this.ssid = source["ssid"];
+	        this.bssid = source["bssid"];
+	        this.channel = source["channel"];
+	        this.band = source["band"];
+	        this.signal = source["signal"];
+	        this.txBitrate = source["txBitrate"];
+	        this.rxBitrate = source["rxBitrate"];
+	        this.roamed = source["roamed"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionScan {
+	    // Go type: time
+	    timestamp: any;
+	    interface: string;
+	    accessPoints: AccessPoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionScan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.timestamp = this.convertValues(source["timestamp"], null);
+	        this.interface = source["interface"];
+	        this.accessPoints = this.convertValues(source["accessPoints"], AccessPoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SessionInfo {
+	    id: string;
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    endedAt: any;
+	    interface: string;
+	    hostname: string;
+	    sizeBytes: number;
+	    active: boolean;
+	    capabilities?: BackendCapabilities[];
+	    scanOptions?: ScanOptions;
+	
+	    static createFrom(source: any = {}) {
+	        return new SessionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.endedAt = this.convertValues(source["endedAt"], null);
+	        this.interface = source["interface"];
+	        this.hostname = source["hostname"];
+	        this.sizeBytes = source["sizeBytes"];
+	        this.active = source["active"];
+	        this.capabilities = this.convertValues(source["capabilities"], BackendCapabilities);
+	        this.scanOptions = this.convertValues(source["scanOptions"], ScanOptions);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Session {
+	    info: SessionInfo;
+	    scans: SessionScan[];
+	    clientSamples: ClientStats[];
+	    roamingEvents: RoamingEvent[];
+	    latencyProbes: LatencyProbe[];
+	    throughputs: ThroughputResult[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Session(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.info = this.convertValues(source["info"], SessionInfo);
+	        this.scans = this.convertValues(source["scans"], SessionScan);
+	        this.clientSamples = this.convertValues(source["clientSamples"], ClientStats);
+	        this.roamingEvents = this.convertValues(source["roamingEvents"], RoamingEvent);
+	        this.latencyProbes = this.convertValues(source["latencyProbes"], LatencyProbe);
+	        this.throughputs = this.convertValues(source["throughputs"], ThroughputResult);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class SurveyAPStats {
+	    bssid: string;
+	    ssid: string;
+	    band: string;
+	    channel: number;
+	    security: string;
+	    samples: number;
+	    min: number;
+	    max: number;
+	    avg: number;
+	    stddev: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SurveyAPStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bssid = source["bssid"];
+	        this.ssid = source["ssid"];
+	        this.band = source["band"];
+	        this.channel = source["channel"];
+	        this.security = source["security"];
+	        this.samples = source["samples"];
+	        this.min = source["min"];
+	        this.max = source["max"];
+	        this.avg = source["avg"];
+	        this.stddev = source["stddev"];
+	    }
+	}
+	export class SurveyPoint {
+	    name: string;
+	    // Go type: time
+	    started: any;
+	    // Go type: time
+	    finished: any;
+	    interfaces: string[];
+	    scans: number;
+	    at?: FloorPoint;
+	    aps: SurveyAPStats[];
+	    client: ClientStats;
+	    latency: LatencyTargetSummary[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SurveyPoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.started = this.convertValues(source["started"], null);
+	        this.finished = this.convertValues(source["finished"], null);
+	        this.interfaces = source["interfaces"];
+	        this.scans = source["scans"];
+	        this.at = this.convertValues(source["at"], FloorPoint);
+	        this.aps = this.convertValues(source["aps"], SurveyAPStats);
+	        this.client = this.convertValues(source["client"], ClientStats);
+	        this.latency = this.convertValues(source["latency"], LatencyTargetSummary);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Survey {
+	    id: string;
+	    name: string;
+	    // Go type: time
+	    started: any;
+	    // Go type: time
+	    ended: any;
+	    floorPlan?: FloorPlan;
+	    points: SurveyPoint[];
+	    capabilities?: BackendCapabilities[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Survey(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.started = this.convertValues(source["started"], null);
+	        this.ended = this.convertValues(source["ended"], null);
+	        this.floorPlan = this.convertValues(source["floorPlan"], FloorPlan);
+	        this.points = this.convertValues(source["points"], SurveyPoint);
+	        this.capabilities = this.convertValues(source["capabilities"], BackendCapabilities);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SurveyAPDelta {
+	    bssid: string;
+	    ssid: string;
+	    a?: number;
+	    b?: number;
+	    delta?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SurveyAPDelta(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bssid = source["bssid"];
+	        this.ssid = source["ssid"];
+	        this.a = source["a"];
+	        this.b = source["b"];
+	        this.delta = source["delta"];
+	    }
+	}
+	
+	export class SurveyPointComparison {
+	    name: string;
+	    inA: boolean;
+	    inB: boolean;
+	    aps: SurveyAPDelta[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SurveyPointComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.inA = source["inA"];
+	        this.inB = source["inB"];
+	        this.aps = this.convertValues(source["aps"], SurveyAPDelta);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SurveyInfo {
+	    id: string;
+	    name: string;
+	    // Go type: time
+	    started: any;
+	    // Go type: time
+	    ended: any;
+	    points: number;
+	    active: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new SurveyInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.started = this.convertValues(source["started"], null);
+	        this.ended = this.convertValues(source["ended"], null);
+	        this.points = source["points"];
+	        this.active = source["active"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SurveyComparison {
+	    a: SurveyInfo;
+	    b: SurveyInfo;
+	    points: SurveyPointComparison[];
+	
+	    static createFrom(source: any = {}) {
+	        return new SurveyComparison(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.a = this.convertValues(source["a"], SurveyInfo);
+	        this.b = this.convertValues(source["b"], SurveyInfo);
+	        this.points = this.convertValues(source["points"], SurveyPointComparison);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	
+	
 
 }
 
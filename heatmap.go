@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html/template"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/gif" // floor plans may be GIF, JPEG or PNG
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Floor-plan heatmaps. A survey can carry a floor-plan image; points placed
+// on it (in image pixels) contribute their per-BSSID average signal, and
+// the signal between points is interpolated by inverse distance weighting
+// into a grid of cells. Where an AP wasn't heard at a point, that point
+// counts as heatmapFloorDBM for it, so coverage fades out towards places
+// the AP can't reach instead of spreading its nearest reading everywhere.
+
+// FloorPlan is the image a survey's points are placed on. The file is
+// copied next to the survey so the survey stays self-contained.
+type FloorPlan struct {
+	File   string `json:"file"`   // in the surveys directory, "<id>-floorplan.<ext>"
+	Source string `json:"source"` // original file name
+	Width  int    `json:"width"`  // pixels
+	Height int    `json:"height"`
+}
+
+// FloorPoint is a position on the floor plan, in image pixels from the
+// top-left corner.
+type FloorPoint struct {
+	X float64 `json:"x"`
+	Y float64 `json:"y"`
+}
+
+const (
+	// heatmapFloorDBM is the signal assumed at points where an AP wasn't
+	// heard.
+	heatmapFloorDBM = -100.0
+	// heatmapPower is the IDW distance exponent; 2 is the usual choice
+	// for RF surveys.
+	heatmapPower = 2.0
+	// heatmapCellsAcross sizes the default grid.
+	heatmapCellsAcross = 100
+	// heatmapMaxCellsAcross bounds the grid whatever cell size is asked
+	// for; the cell size is raised to fit.
+	heatmapMaxCellsAcross = 500
+	// floorPlanMaxSide is the largest floor plan accepted, in pixels per
+	// side. Rendering decodes the whole image.
+	floorPlanMaxSide = 8192
+)
+
+// HeatmapRequest selects what to interpolate. BSSID takes precedence over
+// SSID; with neither, each point contributes its strongest AP of any
+// network.
+type HeatmapRequest struct {
+	SSID      string `json:"ssid"`
+	BSSID     string `json:"bssid"`
+	Threshold int    `json:"threshold"` // dBm; 0 uses coverage_threshold_dbm
+	CellSize  int    `json:"cellSize"`  // pixels; 0 gives about 100 cells across
+}
+
+// Heatmap is an interpolated signal grid over the floor plan.
+type Heatmap struct {
+	Survey      string         `json:"survey"`
+	SSID        string         `json:"ssid"`
+	BSSID       string         `json:"bssid"`
+	Width       int            `json:"width"` // floor plan pixels
+	Height      int            `json:"height"`
+	CellSize    int            `json:"cellSize"`
+	Cols        int            `json:"cols"`
+	Rows        int            `json:"rows"`
+	Values      []float64      `json:"values"` // dBm at each cell centre, row-major
+	Threshold   int            `json:"threshold"`
+	CoveragePct float64        `json:"coveragePct"` // share of cells at or above Threshold
+	Points      []HeatmapPoint `json:"points"`
+}
+
+// HeatmapPoint is one placed survey point's contribution.
+type HeatmapPoint struct {
+	Name   string  `json:"name"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Signal float64 `json:"signal"` // heatmapFloorDBM when not heard
+	Heard  bool    `json:"heard"`
+}
+
+// SSIDCoverage is the share of the floor plan where a network is at or
+// above the coverage threshold.
+type SSIDCoverage struct {
+	SSID        string  `json:"ssid"`
+	CoveragePct float64 `json:"coveragePct"`
+	Points      int     `json:"points"` // placed points where it was heard
+}
+
+// check reports whether at lies on the plan. A nil at always passes.
+func (fp *FloorPlan) check(at *FloorPoint) error {
+	if at == nil {
+		return nil
+	}
+	if fp == nil {
+		return errors.New("load a floor plan before placing points")
+	}
+	if math.IsNaN(at.X) || math.IsNaN(at.Y) || at.X < 0 || at.Y < 0 || at.X > float64(fp.Width) || at.Y > float64(fp.Height) {
+		return fmt.Errorf("position %.0f,%.0f is outside the %dx%d floor plan", at.X, at.Y, fp.Width, fp.Height)
+	}
+	return nil
+}
+
+// path is where the survey id's floor plan image lives in dir. File is
+// read back from the survey's JSON, so only a name SetFloorPlan would have
+// given it is accepted; anything else could point outside dir.
+func (fp *FloorPlan) path(dir, id string) (string, error) {
+	switch fp.File {
+	case id + "-floorplan.png", id + "-floorplan.jpeg", id + "-floorplan.gif":
+		return filepath.Join(dir, fp.File), nil
+	}
+	return "", fmt.Errorf("survey %s: unexpected floor plan file %q", id, fp.File)
+}
+
+// SetFloorPlan copies the image at src into the surveys directory and
+// attaches it to the survey. Points already placed keep their pixel
+// positions.
+func (s *surveyStore) SetFloorPlan(id, src string) (Survey, error) {
+	if s.dir == "" {
+		return Survey{}, errors.New("surveys directory unavailable")
+	}
+	if !sessionIDPattern.MatchString(id) {
+		return Survey{}, fmt.Errorf("invalid survey id %q", id)
+	}
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return Survey{}, fmt.Errorf("floor plan: %w", err)
+	}
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return Survey{}, fmt.Errorf("floor plan %s: not a PNG, JPEG or GIF image", filepath.Base(src))
+	}
+	if cfg.Width == 0 || cfg.Height == 0 {
+		return Survey{}, fmt.Errorf("floor plan %s is empty", filepath.Base(src))
+	}
+	if cfg.Width > floorPlanMaxSide || cfg.Height > floorPlanMaxSide {
+		return Survey{}, fmt.Errorf("floor plan %s is %dx%d; scale it down to at most %d pixels a side",
+			filepath.Base(src), cfg.Width, cfg.Height, floorPlanMaxSide)
+	}
+	plan := &FloorPlan{File: id + "-floorplan." + format, Source: filepath.Base(src), Width: cfg.Width, Height: cfg.Height}
+	var previous string
+	survey, err := s.update(id, func(sv *Survey) error {
+		path := filepath.Join(s.dir, plan.File)
+		if err := os.MkdirAll(s.dir, 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path+".tmp", data, 0o644); err != nil {
+			return fmt.Errorf("save floor plan: %w", err)
+		}
+		if err := os.Rename(path+".tmp", path); err != nil {
+			_ = os.Remove(path + ".tmp")
+			return fmt.Errorf("save floor plan: %w", err)
+		}
+		_ = chownToSudoUser(path)
+		if sv.FloorPlan != nil && sv.FloorPlan.File != plan.File {
+			previous, _ = sv.FloorPlan.path(s.dir, id)
+		}
+		sv.FloorPlan = plan
+		return nil
+	})
+	if err == nil && previous != "" {
+		_ = os.Remove(previous)
+	}
+	return survey, err
+}
+
+// Place sets the floor-plan position of the survey's point at index.
+func (s *surveyStore) Place(id string, index int, at FloorPoint) (Survey, error) {
+	return s.update(id, func(sv *Survey) error {
+		if index < 0 || index >= len(sv.Points) {
+			return fmt.Errorf("survey %s has no point %d", id, index)
+		}
+		if err := sv.FloorPlan.check(&at); err != nil {
+			return err
+		}
+		sv.Points[index].At = &at
+		return nil
+	})
+}
+
+// floorPlanImage decodes a survey's floor plan.
+func (s *surveyStore) floorPlanImage(sv Survey) (image.Image, error) {
+	if sv.FloorPlan == nil {
+		return nil, fmt.Errorf("survey %q has no floor plan", sv.Name)
+	}
+	path, err := sv.FloorPlan.path(s.dir, sv.ID)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("floor plan: %w", err)
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("floor plan: %w", err)
+	}
+	return img, nil
+}
+
+// pointSignal is what a point contributes to the heatmap for req.
+func pointSignal(p SurveyPoint, req HeatmapRequest) (float64, bool) {
+	best, heard := heatmapFloorDBM, false
+	for _, ap := range p.APs {
+		switch {
+		case req.BSSID != "":
+			if !strings.EqualFold(ap.BSSID, req.BSSID) {
+				continue
+			}
+		case req.SSID != "":
+			if ap.SSID != req.SSID {
+				continue
+			}
+		}
+		if !heard || ap.Avg > best {
+			best, heard = ap.Avg, true
+		}
+	}
+	return best, heard
+}
+
+// buildHeatmap interpolates the survey's placed points over its floor plan.
+func buildHeatmap(sv Survey, req HeatmapRequest, threshold int) (Heatmap, error) {
+	if sv.FloorPlan == nil {
+		return Heatmap{}, fmt.Errorf("survey %q has no floor plan", sv.Name)
+	}
+	plan := sv.FloorPlan
+	h := Heatmap{Survey: sv.ID, SSID: req.SSID, BSSID: req.BSSID, Width: plan.Width, Height: plan.Height, Threshold: threshold, Points: []HeatmapPoint{}}
+	if req.BSSID != "" {
+		h.SSID = ""
+	}
+	for _, p := range sv.Points {
+		if p.At == nil {
+			continue
+		}
+		v, heard := pointSignal(p, req)
+		h.Points = append(h.Points, HeatmapPoint{Name: p.Name, X: p.At.X, Y: p.At.Y, Signal: v, Heard: heard})
+	}
+	if len(h.Points) == 0 {
+		return Heatmap{}, fmt.Errorf("survey %q has no points placed on the floor plan", sv.Name)
+	}
+
+	h.CellSize = req.CellSize
+	if h.CellSize <= 0 {
+		h.CellSize = int(math.Ceil(float64(max(plan.Width, plan.Height)) / heatmapCellsAcross))
+	}
+	h.CellSize = max(h.CellSize, 2, int(math.Ceil(float64(max(plan.Width, plan.Height))/heatmapMaxCellsAcross)))
+	h.Cols = (plan.Width + h.CellSize - 1) / h.CellSize
+	h.Rows = (plan.Height + h.CellSize - 1) / h.CellSize
+	h.Values = make([]float64, 0, h.Cols*h.Rows)
+	covered := 0
+	for row := 0; row < h.Rows; row++ {
+		y := math.Min((float64(row)+0.5)*float64(h.CellSize), float64(plan.Height))
+		for col := 0; col < h.Cols; col++ {
+			x := math.Min((float64(col)+0.5)*float64(h.CellSize), float64(plan.Width))
+			v := math.Round(idw(h.Points, x, y)*10) / 10
+			h.Values = append(h.Values, v)
+			if v >= float64(threshold) {
+				covered++
+			}
+		}
+	}
+	h.CoveragePct = math.Round(float64(covered)/float64(len(h.Values))*1000) / 10
+	return h, nil
+}
+
+// idw is the inverse-distance-weighted signal at x, y. A cell centre on a
+// point takes that point's value.
+func idw(points []HeatmapPoint, x, y float64) float64 {
+	var num, den float64
+	for _, p := range points {
+		d2 := (p.X-x)*(p.X-x) + (p.Y-y)*(p.Y-y)
+		if d2 < 1e-9 {
+			return p.Signal
+		}
+		w := 1 / math.Pow(d2, heatmapPower/2)
+		num += w * p.Signal
+		den += w
+	}
+	return num / den
+}
+
+// surveyCoverage computes the coverage of every SSID heard at a placed
+// point, best covered first.
+func surveyCoverage(sv Survey, threshold int) []SSIDCoverage {
+	heardAt := make(map[string]int)
+	for _, p := range sv.Points {
+		if p.At == nil {
+			continue
+		}
+		seen := make(map[string]bool)
+		for _, ap := range p.APs {
+			if ap.SSID != "" && !seen[ap.SSID] {
+				seen[ap.SSID] = true
+				heardAt[ap.SSID]++
+			}
+		}
+	}
+	out := make([]SSIDCoverage, 0, len(heardAt))
+	for ssid, n := range heardAt {
+		h, err := buildHeatmap(sv, HeatmapRequest{SSID: ssid}, threshold)
+		if err != nil {
+			continue
+		}
+		out = append(out, SSIDCoverage{SSID: ssid, CoveragePct: h.CoveragePct, Points: n})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CoveragePct != out[j].CoveragePct {
+			return out[i].CoveragePct > out[j].CoveragePct
+		}
+		return out[i].SSID < out[j].SSID
+	})
+	return out
+}
+
+// heatmapStops is the colour ramp, weakest first.
+var heatmapStops = []struct {
+	dbm     float64
+	r, g, b uint8
+}{
+	{-90, 0xb9, 0x1c, 0x1c},
+	{-80, 0xea, 0x58, 0x0c},
+	{-70, 0xea, 0xb3, 0x08},
+	{-60, 0x65, 0xa3, 0x0d},
+	{-50, 0x15, 0x80, 0x3d},
+}
+
+// heatmapColor maps a signal onto the ramp, clamping at both ends.
+func heatmapColor(dbm float64) color.NRGBA {
+	first, last := heatmapStops[0], heatmapStops[len(heatmapStops)-1]
+	if dbm <= first.dbm {
+		return color.NRGBA{first.r, first.g, first.b, 255}
+	}
+	if dbm >= last.dbm {
+		return color.NRGBA{last.r, last.g, last.b, 255}
+	}
+	for i := 1; i < len(heatmapStops); i++ {
+		lo, hi := heatmapStops[i-1], heatmapStops[i]
+		if dbm <= hi.dbm {
+			t := (dbm - lo.dbm) / (hi.dbm - lo.dbm)
+			mix := func(a, b uint8) uint8 { return uint8(math.Round(float64(a) + t*(float64(b)-float64(a)))) }
+			return color.NRGBA{mix(lo.r, hi.r), mix(lo.g, hi.g), mix(lo.b, hi.b), 255}
+		}
+	}
+	return color.NRGBA{last.r, last.g, last.b, 255}
+}
+
+// heatmapAlpha is the overlay opacity, so the floor plan shows through.
+const heatmapAlpha = 150
+
+// renderHeatmapPNG draws the heatmap at floor-plan size. With a plan image
+// it is composited over the plan; without, the PNG is a transparent
+// overlay. Placed points are drawn as dots.
+func renderHeatmapPNG(w io.Writer, h Heatmap, plan image.Image) error {
+	bounds := image.Rect(0, 0, h.Width, h.Height)
+	img := image.NewNRGBA(bounds)
+	if plan != nil {
+		draw.Draw(img, bounds, plan, plan.Bounds().Min, draw.Src)
+	}
+	overlay := image.NewNRGBA(bounds)
+	for row := 0; row < h.Rows; row++ {
+		for col := 0; col < h.Cols; col++ {
+			c := heatmapColor(h.Values[row*h.Cols+col])
+			c.A = heatmapAlpha
+			cell := image.Rect(col*h.CellSize, row*h.CellSize, (col+1)*h.CellSize, (row+1)*h.CellSize).Intersect(bounds)
+			draw.Draw(overlay, cell, image.NewUniform(c), image.Point{}, draw.Src)
+		}
+	}
+	draw.Draw(img, bounds, overlay, image.Point{}, draw.Over)
+	r := max(3, min(h.Width, h.Height)/120)
+	for _, p := range h.Points {
+		fillDisc(img, int(math.Round(p.X)), int(math.Round(p.Y)), r+1, color.NRGBA{255, 255, 255, 255})
+		fillDisc(img, int(math.Round(p.X)), int(math.Round(p.Y)), r, color.NRGBA{0x11, 0x18, 0x27, 255})
+	}
+	return png.Encode(w, img)
+}
+
+func fillDisc(img *image.NRGBA, cx, cy, r int, c color.NRGBA) {
+	for y := cy - r; y <= cy+r; y++ {
+		for x := cx - r; x <= cx+r; x++ {
+			if (x-cx)*(x-cx)+(y-cy)*(y-cy) <= r*r && image.Pt(x, y).In(img.Rect) {
+				img.SetNRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+// heatmapSVG renders the heatmap as an SVG overlay in floor-plan pixel
+// coordinates, so it can be stacked on the plan image at any display size.
+// Runs of same-coloured cells are merged to keep the markup small.
+func heatmapSVG(h Heatmap) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="%d" height="%d">`, h.Width, h.Height, h.Width, h.Height)
+	fmt.Fprintf(&b, `<g fill-opacity="%.2f" shape-rendering="crispEdges">`, heatmapAlpha/255.0)
+	hex := func(c color.NRGBA) string { return fmt.Sprintf("#%02x%02x%02x", c.R, c.G, c.B) }
+	for row := 0; row < h.Rows; row++ {
+		for col := 0; col < h.Cols; {
+			fill := hex(heatmapColor(h.Values[row*h.Cols+col]))
+			run := 1
+			for col+run < h.Cols && hex(heatmapColor(h.Values[row*h.Cols+col+run])) == fill {
+				run++
+			}
+			x, y := col*h.CellSize, row*h.CellSize
+			fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s"/>`,
+				x, y, min(run*h.CellSize, h.Width-x), min(h.CellSize, h.Height-y), fill)
+			col += run
+		}
+	}
+	b.WriteString(`</g>`)
+	r := math.Max(3, float64(min(h.Width, h.Height))/120)
+	for _, p := range h.Points {
+		label := "not heard"
+		if p.Heard {
+			label = formatDbm(p.Signal)
+		}
+		fmt.Fprintf(&b, `<circle cx="%.1f" cy="%.1f" r="%.1f" fill="#111827" stroke="#fff" stroke-width="1.5"><title>%s · %s</title></circle>`,
+			p.X, p.Y, r, template.HTMLEscapeString(p.Name), label)
+	}
+	b.WriteString(`</svg>`)
+	return b.String()
+}
+
+// coverageThreshold resolves a request threshold against the config.
+func (ws *WiFiService) coverageThreshold(threshold int) int {
+	if threshold == 0 {
+		return ws.config.Get().CoverageThresholdDBM
+	}
+	return threshold
+}
+
+// SetSurveyFloorPlan attaches a floor-plan image (PNG, JPEG or GIF) to a
+// survey.
+func (ws *WiFiService) SetSurveyFloorPlan(id, path string) (Survey, error) {
+	return ws.survey.SetFloorPlan(id, path)
+}
+
+// PlaceSurveyPoint positions the survey's point at index on its floor plan.
+func (ws *WiFiService) PlaceSurveyPoint(id string, index int, x, y float64) (Survey, error) {
+	return ws.survey.Place(id, index, FloorPoint{X: x, Y: y})
+}
+
+// MarkSurveyPointAt captures a point in the active survey at x, y on its
+// floor plan.
+func (ws *WiFiService) MarkSurveyPointAt(name string, x, y float64) (SurveyPoint, error) {
+	return ws.markSurveyPoint(name, &FloorPoint{X: x, Y: y})
+}
+
+// GetSurveyHeatmap interpolates a survey's placed points into a signal grid
+// with its coverage.
+func (ws *WiFiService) GetSurveyHeatmap(id string, req HeatmapRequest) (Heatmap, error) {
+	sv, err := ws.survey.Open(id)
+	if err != nil {
+		return Heatmap{}, err
+	}
+	return buildHeatmap(sv, req, ws.coverageThreshold(req.Threshold))
+}
+
+// RenderSurveyHeatmap renders a survey heatmap as "png" (composited over
+// the floor plan) or "svg" (a transparent overlay).
+func (ws *WiFiService) RenderSurveyHeatmap(id string, req HeatmapRequest, format string) ([]byte, error) {
+	sv, err := ws.survey.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	h, err := buildHeatmap(sv, req, ws.coverageThreshold(req.Threshold))
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "svg":
+		return []byte(heatmapSVG(h)), nil
+	case "png":
+		plan, err := ws.survey.floorPlanImage(sv)
+		if err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		if err := renderHeatmapPNG(&buf, h, plan); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unsupported heatmap format %q (use png or svg)", format)
+}
+
+// GetSurveyCoverage returns each SSID's share of the floor plan at or above
+// threshold dBm (0 uses coverage_threshold_dbm).
+func (ws *WiFiService) GetSurveyCoverage(id string, threshold int) ([]SSIDCoverage, error) {
+	sv, err := ws.survey.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	if sv.FloorPlan == nil {
+		return nil, fmt.Errorf("survey %q has no floor plan", sv.Name)
+	}
+	return surveyCoverage(sv, ws.coverageThreshold(threshold)), nil
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBuildHeatmap(t *testing.T) {
+	sv := Survey{
+		ID: "20240501-090000", Name: "Floor 2",
+		FloorPlan: &FloorPlan{File: "plan.png", Width: 200, Height: 100},
+		Points: []SurveyPoint{
+			{Name: "West", At: &FloorPoint{X: 0, Y: 50}, APs: []SurveyAPStats{
+				{BSSID: "02:00:00:00:01:01", SSID: "Office", Avg: -45},
+				{BSSID: "02:00:00:00:02:01", SSID: "Guest", Avg: -70},
+			}},
+			{Name: "East", At: &FloorPoint{X: 200, Y: 50}, APs: []SurveyAPStats{
+				{BSSID: "02:00:00:00:01:02", SSID: "Office", Avg: -85},
+			}},
+			{Name: "Unplaced", APs: []SurveyAPStats{{BSSID: "02:00:00:00:01:01", SSID: "Office", Avg: -30}}},
+		},
+	}
+
+	h, err := buildHeatmap(sv, HeatmapRequest{CellSize: 10}, -65)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.Cols != 20 || h.Rows != 10 || len(h.Values) != 200 || len(h.Points) != 2 {
+		t.Fatalf("grid %dx%d, %d values, %d points", h.Cols, h.Rows, len(h.Values), len(h.Points))
+	}
+	west, east := h.Values[5*h.Cols], h.Values[5*h.Cols+h.Cols-1]
+	if west <= -50 || west >= -45 || east >= -80 || east <= -85 {
+		t.Errorf("west %v, east %v", west, east)
+	}
+	// -65 dBm is halfway: the strong half covers, the weak half doesn't.
+	if h.CoveragePct != 50 {
+		t.Errorf("coverage = %v, want 50", h.CoveragePct)
+	}
+
+	// A network not heard at a point pulls the map down there.
+	guest, _ := buildHeatmap(sv, HeatmapRequest{SSID: "Guest", CellSize: 10}, -67)
+	if p := guest.Points[1]; p.Heard || p.Signal != heatmapFloorDBM || guest.CoveragePct != 0 {
+		t.Errorf("guest = %+v, coverage %v", p, guest.CoveragePct)
+	}
+	ap, _ := buildHeatmap(sv, HeatmapRequest{SSID: "Office", BSSID: "02:00:00:00:01:02", CellSize: 10}, -67)
+	if ap.SSID != "" || ap.Points[0].Heard || ap.Points[1].Signal != -85 {
+		t.Errorf("bssid heatmap = %+v", ap.Points)
+	}
+
+	coverage := surveyCoverage(sv, -65)
+	if len(coverage) != 2 || coverage[0].SSID != "Office" || coverage[0].CoveragePct != 50 || coverage[0].Points != 2 ||
+		coverage[1].SSID != "Guest" || coverage[1].Points != 1 {
+		t.Errorf("coverage = %+v", coverage)
+	}
+
+	// However small a cell is asked for, the grid stays bounded.
+	big := sv
+	big.FloorPlan = &FloorPlan{File: "plan.png", Width: 5000, Height: 1000}
+	if h, _ := buildHeatmap(big, HeatmapRequest{CellSize: 1}, -67); h.CellSize != 10 || h.Cols != 500 || h.Rows != 100 {
+		t.Errorf("oversized grid: cell %d, %dx%d", h.CellSize, h.Cols, h.Rows)
+	}
+
+	if _, err := buildHeatmap(Survey{Name: "No plan"}, HeatmapRequest{}, -67); err == nil {
+		t.Error("built a heatmap without a floor plan")
+	}
+	sv.Points = sv.Points[2:]
+	if _, err := buildHeatmap(sv, HeatmapRequest{}, -67); err == nil {
+		t.Error("built a heatmap without placed points")
+	}
+}
+
+func TestHeatmapColor(t *testing.T) {
+	weak, strong := heatmapStops[0], heatmapStops[len(heatmapStops)-1]
+	if c := heatmapColor(-100); c != (color.NRGBA{weak.r, weak.g, weak.b, 255}) {
+		t.Errorf("-100 dBm = %v", c)
+	}
+	if c := heatmapColor(-30); c != (color.NRGBA{strong.r, strong.g, strong.b, 255}) {
+		t.Errorf("-30 dBm = %v", c)
+	}
+	if c := heatmapColor(-70); c != (color.NRGBA{0xea, 0xb3, 0x08, 255}) {
+		t.Errorf("-70 dBm = %v", c)
+	}
+}
+
+func TestSurveyFloorPlan(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 0)
+	src := filepath.Join(t.TempDir(), "floor2.png")
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 320, 240))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(src, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	survey, _ := ws.StartSurvey("Floor 2")
+	if _, err := ws.survey.Mark(t.Context(), "Lobby", &FloorPoint{X: 10, Y: 10}, []string{"sim0"}); err == nil {
+		t.Error("placed a point before loading a floor plan")
+	}
+	if _, err := ws.SetSurveyFloorPlan(survey.ID, filepath.Join("testdata", "sim", "roaming.toml")); err == nil {
+		t.Error("accepted a non-image floor plan")
+	}
+	huge := filepath.Join(t.TempDir(), "huge.png")
+	buf.Reset()
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, floorPlanMaxSide+1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(huge, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.SetSurveyFloorPlan(survey.ID, huge); err == nil {
+		t.Error("accepted an oversized floor plan")
+	}
+	survey, err := ws.SetSurveyFloorPlan(survey.ID, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp := survey.FloorPlan; fp == nil || fp.Width != 320 || fp.Height != 240 || fp.Source != "floor2.png" {
+		t.Fatalf("floor plan = %+v", fp)
+	}
+	if _, err := ws.survey.Mark(t.Context(), "Lobby", &FloorPoint{X: 400, Y: 10}, []string{"sim0"}); err == nil {
+		t.Error("placed a point outside the floor plan")
+	}
+	if p := markPoint(t, ws, "Lobby", &FloorPoint{X: 40, Y: 40}); p.At == nil || p.At.X != 40 {
+		t.Errorf("lobby at %+v", p.At)
+	}
+	markPoint(t, ws, "Room 204", nil)
+	markPoint(t, ws, "Kitchen", &FloorPoint{X: 300, Y: 200})
+	ws.StopSurvey()
+
+	if _, err := ws.PlaceSurveyPoint(survey.ID, 5, 1, 1); err == nil {
+		t.Error("placed a point that doesn't exist")
+	}
+	survey, err = ws.PlaceSurveyPoint(survey.ID, 1, 280, 40)
+	if err != nil || survey.Points[1].At == nil {
+		t.Fatalf("place: %+v, %v", survey.Points[1], err)
+	}
+
+	h, err := ws.GetSurveyHeatmap(survey.ID, HeatmapRequest{SSID: "Office"})
+	if err != nil || len(h.Points) != 3 || h.Threshold != -67 || h.CellSize != 4 || h.Cols != 80 || h.Rows != 60 {
+		t.Fatalf("heatmap = %d points, threshold %d, cell %d, %dx%d, %v", len(h.Points), h.Threshold, h.CellSize, h.Cols, h.Rows, err)
+	}
+	if h.CoveragePct <= 0 || h.CoveragePct > 100 || math.IsNaN(h.CoveragePct) {
+		t.Errorf("coverage = %v", h.CoveragePct)
+	}
+
+	out, err := ws.RenderSurveyHeatmap(survey.ID, HeatmapRequest{}, "png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(out))
+	if err != nil || img.Bounds().Dx() != 320 || img.Bounds().Dy() != 240 {
+		t.Fatalf("png: %v, %v", img.Bounds(), err)
+	}
+	out, _ = ws.RenderSurveyHeatmap(survey.ID, HeatmapRequest{}, "svg")
+	if svg := string(out); !strings.HasPrefix(svg, "<svg") || strings.Count(svg, "<circle") != 3 || !strings.Contains(svg, "Room 204") {
+		t.Errorf("svg = %.200s", svg)
+	}
+
+	// The diagnostic report picks up the newest floor-plan survey.
+	html, err := ws.GenerateHTMLReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(html, `<div class="page">`); n != 6 || !strings.Contains(html, "Coverage survey") || !strings.Contains(html, "Floor 2") {
+		t.Errorf("html report: %d pages, coverage section present %v", n, strings.Contains(html, "Coverage survey"))
+	}
+	pdf, err := ws.GeneratePDFReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text := pdfContent(t, pdf); !strings.Contains(text, "(Coverage survey)") {
+		t.Error("PDF report missing the coverage section")
+	}
+
+	if err := ws.DeleteSurvey(survey.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(ws.survey.dir, survey.FloorPlan.File)); !os.IsNotExist(err) {
+		t.Errorf("floor plan left behind: %v", err)
+	}
+}
+
+func TestDeleteSurveyIgnoresForeignFloorPlanPath(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 0)
+	survey, _ := ws.StartSurvey("Floor 3")
+	ws.StopSurvey()
+	victim := filepath.Join(filepath.Dir(ws.survey.dir), "keep.txt")
+	if err := os.WriteFile(victim, []byte("x"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sv, err := ws.survey.read(survey.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sv.FloorPlan = &FloorPlan{File: "../keep.txt", Width: 10, Height: 10}
+	data, _ := json.Marshal(sv)
+	if err := os.WriteFile(filepath.Join(ws.survey.dir, survey.ID+surveyFileExt), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ws.DeleteSurvey(survey.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(victim); err != nil {
+		t.Errorf("file outside the surveys directory removed: %v", err)
+	}
+}
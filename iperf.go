@@ -0,0 +1,986 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// iperf3 wire protocol. The client speaks to a stock `iperf3 -s`; the
+// server answers a stock `iperf3 -c`, so a second machine can run either.
+// Only what the throughput tester needs is implemented: TCP or UDP, upload
+// or download (-R), parallel streams (-P) and a fixed duration (-t). There
+// is no omit interval, bidirectional mode, authentication or
+// --get-server-output.
+//
+// A test runs over a control connection: the client sends a random cookie,
+// then the server drives it through one-byte states (PARAM_EXCHANGE,
+// CREATE_STREAMS, TEST_START, TEST_RUNNING, …). Parameters and results are
+// JSON, each prefixed with its length as a 32-bit big-endian integer. Data
+// streams are separate TCP connections that start with the same cookie, or
+// UDP flows that start with a 4-byte hello, all on the same port.
+
+// iperfDefaultPort is iperf3's well-known port.
+const iperfDefaultPort = "5201"
+
+const (
+	iperfCookieSize    = 37 // 36 characters plus NUL
+	iperfTCPBlockSize  = 128 * 1024
+	iperfUDPBlockSize  = 1460
+	iperfUDPHeaderSize = 12 // sec, usec, 32-bit packet count
+	iperfMaxStreams    = 128
+	iperfMaxBlockSize  = 1 << 20
+	iperfMaxUDPPayload = 65507
+	iperfMaxJSON       = 1 << 20
+)
+
+// iperfSetupTimeout bounds every control-channel wait except the test
+// itself: a silent peer (wrong port, firewall) fails rather than hangs.
+const iperfSetupTimeout = 10 * time.Second
+
+// iperf3 control states, sent as one signed byte.
+const (
+	iperfTestStart       = 1
+	iperfTestRunning     = 2
+	iperfTestEnd         = 4
+	iperfParamExchange   = 9
+	iperfCreateStreams   = 10
+	iperfServerTerminate = 11
+	iperfClientTerminate = 12
+	iperfExchangeResults = 13
+	iperfDisplayResults  = 14
+	iperfDone            = 16
+	iperfAccessDenied    = -1
+	iperfServerError     = -2
+)
+
+// UDP streams open with a hello the server answers, so it learns the
+// client's address. iperf3 writes both as a native int — little-endian on
+// everything it ships for — and still accepts the values older releases
+// used.
+const (
+	iperfUDPConnectMsg         = 0x36373839
+	iperfUDPConnectReply       = 0x39383736
+	iperfLegacyUDPConnectMsg   = 123456789
+	iperfLegacyUDPConnectReply = 987654321
+)
+
+// errIperfBusy is a server refusing a test because it is running another.
+var errIperfBusy = errors.New("server is busy with another test")
+
+// iperfSpec describes one test.
+type iperfSpec struct {
+	UDP       bool
+	Reverse   bool // the server sends: a download from the client's side
+	Duration  time.Duration
+	Parallel  int
+	Bandwidth int64 // UDP target rate per stream, bit/s
+}
+
+// iperfParams is what the client sends at PARAM_EXCHANGE, in iperf3's
+// field names. The server ignores the fields iperf3 clients send that it
+// doesn't implement.
+type iperfParams struct {
+	TCP           bool  `json:"tcp,omitempty"`
+	UDP           bool  `json:"udp,omitempty"`
+	Omit          int   `json:"omit"`
+	Time          int   `json:"time"`
+	Parallel      int   `json:"parallel"`
+	Reverse       bool  `json:"reverse,omitempty"`
+	Len           int   `json:"len"`
+	Bandwidth     int64 `json:"bandwidth,omitempty"`
+	PacingTimer   int   `json:"pacing_timer,omitempty"`
+	UDPCounters64 bool  `json:"udp_counters_64bit,omitempty"`
+}
+
+// iperfResults is what each side sends at EXCHANGE_RESULTS. iperf3
+// rejects results without the CPU fields, so they're always present.
+type iperfResults struct {
+	CPUUtilTotal         float64             `json:"cpu_util_total"`
+	CPUUtilUser          float64             `json:"cpu_util_user"`
+	CPUUtilSystem        float64             `json:"cpu_util_system"`
+	SenderHasRetransmits int                 `json:"sender_has_retransmits"`
+	Streams              []iperfStreamResult `json:"streams"`
+}
+
+type iperfStreamResult struct {
+	ID          int     `json:"id"`
+	Bytes       int64   `json:"bytes"`
+	Retransmits int     `json:"retransmits"` // -1: not measured
+	Jitter      float64 `json:"jitter"`      // seconds
+	Errors      int64   `json:"errors"`      // UDP packets lost
+	Packets     int64   `json:"packets"`
+	StartTime   float64 `json:"start_time"`
+	EndTime     float64 `json:"end_time"`
+}
+
+// iperfOutcome is a finished test as measured by the receiving side: the
+// server for uploads, the client for downloads.
+type iperfOutcome struct {
+	Streams  int
+	Bytes    int64
+	Seconds  float64
+	Packets  int64 // UDP
+	Lost     int64
+	JitterMs float64
+}
+
+// Mbps is the receiver's goodput in Mbit/s.
+func (o iperfOutcome) Mbps() float64 {
+	if o.Seconds <= 0 {
+		return 0
+	}
+	return float64(o.Bytes) * 8 / o.Seconds / 1e6
+}
+
+// LossPercent is the share of UDP packets that never arrived.
+func (o iperfOutcome) LossPercent() float64 {
+	if o.Packets <= 0 {
+		return 0
+	}
+	return float64(o.Lost) / float64(o.Packets) * 100
+}
+
+// iperfAddr adds the default port to a bare host.
+func iperfAddr(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, iperfDefaultPort)
+}
+
+// iperfStreamIDs reproduces iperf3's stream numbering (1, 3, 4, 5, …),
+// which both ends derive independently and match results by.
+func iperfStreamIDs(n int) []int {
+	ids := make([]int, n)
+	for i := range ids {
+		ids[i] = i + 1
+		if i > 0 {
+			ids[i]++
+		}
+	}
+	return ids
+}
+
+func newIperfCookie() ([]byte, error) {
+	const alphabet = "abcdefghijklmnopqrstuvwxyz234567"
+	cookie := make([]byte, iperfCookieSize)
+	if _, err := rand.Read(cookie[:iperfCookieSize-1]); err != nil {
+		return nil, err
+	}
+	for i := range iperfCookieSize - 1 {
+		cookie[i] = alphabet[cookie[i]%byte(len(alphabet))]
+	}
+	cookie[iperfCookieSize-1] = 0
+	return cookie, nil
+}
+
+func readIperfState(r io.Reader) (int8, error) {
+	var b [1]byte
+	if _, err := io.ReadFull(r, b[:]); err != nil {
+		return 0, err
+	}
+	return int8(b[0]), nil
+}
+
+func writeIperfState(w io.Writer, state int8) error {
+	_, err := w.Write([]byte{byte(state)})
+	return err
+}
+
+func readIperfJSON(r io.Reader, v interface{}) error {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return err
+	}
+	n := binary.BigEndian.Uint32(size[:])
+	if n == 0 || n > iperfMaxJSON {
+		return fmt.Errorf("iperf message of %d bytes", n)
+	}
+	body := make([]byte, n)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return err
+	}
+	return json.Unmarshal(body, v)
+}
+
+func writeIperfJSON(w io.Writer, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	msg := binary.BigEndian.AppendUint32(make([]byte, 0, 4+len(body)), uint32(len(body)))
+	_, err = w.Write(append(msg, body...))
+	return err
+}
+
+// isIperfUDPHello reports whether a datagram is a stream hello (or, with
+// reply, a hello's answer), in either byte order and either generation.
+func isIperfUDPHello(b []byte, reply bool) bool {
+	if len(b) != 4 {
+		return false
+	}
+	want := []uint32{iperfUDPConnectMsg, iperfLegacyUDPConnectMsg}
+	if reply {
+		want = []uint32{iperfUDPConnectReply, iperfLegacyUDPConnectReply}
+	}
+	for _, v := range want {
+		if binary.LittleEndian.Uint32(b) == v || binary.BigEndian.Uint32(b) == v {
+			return true
+		}
+	}
+	return false
+}
+
+// iperfStream is one data connection and its counters. The transfer
+// goroutine updates them while the control side may take a snapshot, so
+// they're guarded by mu.
+type iperfStream struct {
+	id   int
+	conn net.Conn // nil for server-side UDP, which shares the listening socket
+	addr net.Addr // server-side UDP peer
+
+	mu          sync.Mutex
+	bytes       int64
+	packets     int64 // sent, or the highest packet count received
+	lost        int64
+	jitter      float64 // seconds, smoothed as in RFC 1889
+	prevTransit float64
+}
+
+func (s *iperfStream) add(n int) {
+	s.mu.Lock()
+	s.bytes += int64(n)
+	s.mu.Unlock()
+}
+
+// receiveUDP accounts one datagram the way iperf3 does: a gap in the
+// packet count is loss, and a late packet takes one back.
+func (s *iperfStream) receiveUDP(pkt []byte, at time.Time, counters64 bool) {
+	if len(pkt) < iperfUDPHeaderSize || (counters64 && len(pkt) < iperfUDPHeaderSize+4) {
+		return
+	}
+	sent := float64(binary.BigEndian.Uint32(pkt[0:4])) + float64(binary.BigEndian.Uint32(pkt[4:8]))/1e6
+	var pcount int64
+	if counters64 {
+		pcount = int64(binary.BigEndian.Uint64(pkt[8:16]))
+	} else {
+		pcount = int64(binary.BigEndian.Uint32(pkt[8:12]))
+	}
+	transit := float64(at.UnixMicro())/1e6 - sent
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bytes += int64(len(pkt))
+	first := s.packets == 0
+	switch {
+	case pcount > s.packets:
+		s.lost += pcount - 1 - s.packets
+		s.packets = pcount
+	case s.lost > 0:
+		s.lost--
+	}
+	// The clocks needn't agree: only the change in transit time counts,
+	// so the first packet just sets the baseline.
+	if !first {
+		d := transit - s.prevTransit
+		if d < 0 {
+			d = -d
+		}
+		s.jitter += (d - s.jitter) / 16
+	}
+	s.prevTransit = transit
+}
+
+// sendTCP writes blocks until ctx ends or the peer goes away.
+func (s *iperfStream) sendTCP(ctx context.Context, size int) error {
+	stop := context.AfterFunc(ctx, func() { _ = s.conn.SetWriteDeadline(time.Now()) })
+	defer stop()
+	buf := make([]byte, size)
+	for {
+		n, err := s.conn.Write(buf)
+		s.add(n)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// receiveTCP counts bytes until the connection is closed.
+func (s *iperfStream) receiveTCP() {
+	buf := make([]byte, iperfTCPBlockSize)
+	for {
+		n, err := s.conn.Read(buf)
+		s.add(n)
+		if err != nil {
+			return
+		}
+	}
+}
+
+// sendUDP writes numbered datagrams at bandwidth bit/s until ctx ends,
+// catching up to the target rate every millisecond like iperf3's pacing
+// timer.
+func (s *iperfStream) sendUDP(ctx context.Context, write func([]byte) (int, error), bandwidth int64, size int, counters64 bool) error {
+	buf := make([]byte, size)
+	tick := time.NewTicker(time.Millisecond)
+	defer tick.Stop()
+	start := time.Now()
+	var sent int64
+	for {
+		for bandwidth <= 0 || float64(sent*8) < float64(bandwidth)*time.Since(start).Seconds() {
+			if ctx.Err() != nil {
+				return nil
+			}
+			now := time.Now()
+			s.mu.Lock()
+			s.packets++
+			pcount := s.packets
+			s.mu.Unlock()
+			binary.BigEndian.PutUint32(buf[0:], uint32(now.Unix()))
+			binary.BigEndian.PutUint32(buf[4:], uint32(now.Nanosecond()/1000))
+			if counters64 {
+				binary.BigEndian.PutUint64(buf[8:], uint64(pcount))
+			} else {
+				binary.BigEndian.PutUint32(buf[8:], uint32(pcount))
+			}
+			n, err := write(buf)
+			if err != nil && !errors.Is(err, syscall.ENOBUFS) {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+			sent += int64(n)
+			s.add(n)
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-tick.C:
+		}
+	}
+}
+
+// result snapshots the stream's counters for EXCHANGE_RESULTS.
+func (s *iperfStream) result(elapsed time.Duration) iperfStreamResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return iperfStreamResult{
+		ID: s.id, Bytes: s.bytes, Retransmits: -1, Jitter: s.jitter, Errors: s.lost, Packets: s.packets,
+		EndTime: elapsed.Seconds(),
+	}
+}
+
+func iperfSnapshot(streams []*iperfStream, elapsed time.Duration) iperfResults {
+	r := iperfResults{Streams: make([]iperfStreamResult, 0, len(streams))}
+	for _, s := range streams {
+		r.Streams = append(r.Streams, s.result(elapsed))
+	}
+	return r
+}
+
+// iperfOutcomeOf sums the receiver's results. fallback is used when the
+// receiver didn't report how long it measured for.
+func iperfOutcomeOf(r iperfResults, fallback time.Duration) iperfOutcome {
+	out := iperfOutcome{Streams: len(r.Streams), Seconds: fallback.Seconds()}
+	var jitter, secs float64
+	for _, s := range r.Streams {
+		out.Bytes += s.Bytes
+		out.Packets += s.Packets
+		out.Lost += s.Errors
+		jitter += s.Jitter
+		secs = max(secs, s.EndTime-s.StartTime)
+	}
+	if secs > 0 {
+		out.Seconds = secs
+	}
+	if len(r.Streams) > 0 {
+		out.JitterMs = jitter / float64(len(r.Streams)) * 1000
+	}
+	return out
+}
+
+func iperfBlockSize(udp bool) int {
+	if udp {
+		return iperfUDPBlockSize
+	}
+	return iperfTCPBlockSize
+}
+
+// runIperfClient runs one test against an iperf3 server at addr.
+func runIperfClient(ctx context.Context, addr string, spec iperfSpec) (iperfOutcome, error) {
+	cookie, err := newIperfCookie()
+	if err != nil {
+		return iperfOutcome{}, err
+	}
+	d := net.Dialer{Timeout: iperfSetupTimeout}
+	ctrl, err := d.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return iperfOutcome{}, fmt.Errorf("connect to %s: %w", addr, err)
+	}
+	c := &iperfClient{spec: spec, addr: addr, cookie: cookie, ctrl: ctrl}
+	stop := context.AfterFunc(ctx, c.close)
+	defer stop()
+	defer c.close()
+	out, err := c.run(ctx)
+	if ctx.Err() != nil {
+		return iperfOutcome{}, ctx.Err()
+	}
+	return out, err
+}
+
+type iperfClient struct {
+	spec   iperfSpec
+	addr   string
+	cookie []byte
+	ctrl   net.Conn
+
+	mu      sync.Mutex
+	streams []*iperfStream
+	closed  bool
+	wg      sync.WaitGroup
+	cancel  context.CancelFunc
+	timer   *time.Timer
+	elapsed time.Duration
+	ours    iperfResults
+	endErr  error
+}
+
+func (c *iperfClient) close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	if c.timer != nil {
+		c.timer.Stop()
+	}
+	if c.cancel != nil {
+		c.cancel()
+	}
+	_ = c.ctrl.Close()
+	for _, s := range c.streams {
+		_ = s.conn.Close()
+	}
+	c.mu.Unlock()
+	c.wg.Wait()
+}
+
+func (c *iperfClient) run(ctx context.Context) (iperfOutcome, error) {
+	if _, err := c.ctrl.Write(c.cookie); err != nil {
+		return iperfOutcome{}, err
+	}
+	var peer iperfResults
+	for {
+		_ = c.ctrl.SetReadDeadline(time.Now().Add(c.spec.Duration + iperfSetupTimeout))
+		state, err := readIperfState(c.ctrl)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = errors.New("server closed the connection")
+			}
+			return iperfOutcome{}, err
+		}
+		switch state {
+		case iperfParamExchange:
+			err = writeIperfJSON(c.ctrl, c.params())
+		case iperfCreateStreams:
+			err = c.connectStreams(ctx)
+		case iperfTestStart:
+		case iperfTestRunning:
+			c.start(ctx)
+		case iperfExchangeResults:
+			c.mu.Lock()
+			ours, endErr := c.ours, c.endErr
+			c.mu.Unlock()
+			if endErr != nil {
+				return iperfOutcome{}, endErr
+			}
+			if err = writeIperfJSON(c.ctrl, ours); err == nil {
+				err = readIperfJSON(c.ctrl, &peer)
+			}
+		case iperfDisplayResults:
+			_ = writeIperfState(c.ctrl, iperfDone)
+			c.mu.Lock()
+			elapsed, ours := c.elapsed, c.ours
+			c.mu.Unlock()
+			if c.spec.Reverse {
+				return iperfOutcomeOf(ours, elapsed), nil
+			}
+			return iperfOutcomeOf(peer, elapsed), nil
+		case iperfAccessDenied:
+			return iperfOutcome{}, fmt.Errorf("%s: %w", c.addr, errIperfBusy)
+		case iperfServerError:
+			var codes [8]byte
+			_, _ = io.ReadFull(c.ctrl, codes[:])
+			return iperfOutcome{}, fmt.Errorf("server error %d (errno %d)", int32(binary.BigEndian.Uint32(codes[:4])), int32(binary.BigEndian.Uint32(codes[4:])))
+		case iperfServerTerminate:
+			return iperfOutcome{}, errors.New("server ended the test")
+		default:
+			return iperfOutcome{}, fmt.Errorf("unexpected iperf state %d", state)
+		}
+		if err != nil {
+			return iperfOutcome{}, err
+		}
+	}
+}
+
+func (c *iperfClient) params() iperfParams {
+	p := iperfParams{
+		Time:     max(int(c.spec.Duration.Round(time.Second)/time.Second), 1),
+		Parallel: c.spec.Parallel,
+		Reverse:  c.spec.Reverse,
+		Len:      iperfBlockSize(c.spec.UDP),
+	}
+	if c.spec.UDP {
+		p.UDP, p.Bandwidth, p.PacingTimer = true, c.spec.Bandwidth, 1000
+	} else {
+		p.TCP = true
+	}
+	return p
+}
+
+func (c *iperfClient) connectStreams(ctx context.Context) error {
+	d := net.Dialer{Timeout: iperfSetupTimeout}
+	for _, id := range iperfStreamIDs(c.spec.Parallel) {
+		network := "tcp"
+		if c.spec.UDP {
+			network = "udp"
+		}
+		conn, err := d.DialContext(ctx, network, c.addr)
+		if err != nil {
+			return fmt.Errorf("open data stream: %w", err)
+		}
+		c.mu.Lock()
+		c.streams = append(c.streams, &iperfStream{id: id, conn: conn})
+		c.mu.Unlock()
+		if c.spec.UDP {
+			err = iperfUDPHandshake(conn)
+		} else {
+			_, err = conn.Write(c.cookie)
+		}
+		if err != nil {
+			return fmt.Errorf("open data stream: %w", err)
+		}
+	}
+	return nil
+}
+
+// iperfUDPHandshake sends the stream hello until the server answers. A
+// hello or its answer can be lost like any datagram.
+func iperfUDPHandshake(conn net.Conn) error {
+	if u, ok := conn.(*net.UDPConn); ok {
+		_ = u.SetReadBuffer(4 << 20)
+	}
+	hello := binary.LittleEndian.AppendUint32(nil, iperfUDPConnectMsg)
+	buf := make([]byte, 64*1024)
+	for attempt := 0; attempt < 3; attempt++ {
+		if _, err := conn.Write(hello); err != nil {
+			return err
+		}
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+		for {
+			n, err := conn.Read(buf)
+			if err != nil {
+				break
+			}
+			if isIperfUDPHello(buf[:n], true) {
+				return conn.SetReadDeadline(time.Time{})
+			}
+		}
+	}
+	return errors.New("no answer to the UDP stream hello")
+}
+
+// start launches the transfers and the timer that ends the test.
+func (c *iperfClient) start(ctx context.Context) {
+	dataCtx, cancel := context.WithCancel(ctx)
+	began := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cancel = cancel
+	for _, s := range c.streams {
+		c.wg.Add(1)
+		go func() {
+			defer c.wg.Done()
+			var err error
+			switch {
+			case c.spec.UDP && c.spec.Reverse:
+				buf := make([]byte, 64*1024)
+				for {
+					n, rerr := s.conn.Read(buf)
+					if rerr != nil {
+						return
+					}
+					s.receiveUDP(buf[:n], time.Now(), false)
+				}
+			case c.spec.UDP:
+				err = s.sendUDP(dataCtx, s.conn.Write, c.spec.Bandwidth, iperfUDPBlockSize, false)
+			case c.spec.Reverse:
+				s.receiveTCP()
+			default:
+				err = s.sendTCP(dataCtx, iperfTCPBlockSize)
+			}
+			if err != nil {
+				slog.Debug("iperf stream failed", "stream", s.id, "err", err)
+			}
+		}()
+	}
+	c.timer = time.AfterFunc(c.spec.Duration, func() {
+		c.mu.Lock()
+		c.elapsed = time.Since(began)
+		c.ours = iperfSnapshot(c.streams, c.elapsed)
+		c.mu.Unlock()
+		cancel()
+		if err := writeIperfState(c.ctrl, iperfTestEnd); err != nil {
+			c.mu.Lock()
+			c.endErr = err
+			c.mu.Unlock()
+		}
+	})
+}
+
+// iperfServer answers iperf3 clients one test at a time, on a TCP
+// listener and a UDP socket sharing a port.
+type iperfServer struct {
+	tcp net.Listener
+	udp net.PacketConn
+
+	mu   sync.Mutex
+	test *iperfServerTest // nil while idle
+}
+
+type iperfServerTest struct {
+	cookie     []byte
+	params     iperfParams
+	tcpStreams chan net.Conn
+	udpStreams map[string]*iperfStream // by peer address
+	udpReady   chan *iperfStream
+}
+
+// listenIperfServer binds addr (":5201" for all interfaces) for TCP and
+// UDP. A zero port picks a free one for both.
+func listenIperfServer(addr string) (*iperfServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	host, _, _ := net.SplitHostPort(addr)
+	port := ln.Addr().(*net.TCPAddr).Port
+	pc, err := net.ListenPacket("udp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	if u, ok := pc.(*net.UDPConn); ok {
+		_ = u.SetReadBuffer(4 << 20)
+	}
+	return &iperfServer{tcp: ln, udp: pc}, nil
+}
+
+// Addr is the address the server listens on.
+func (s *iperfServer) Addr() net.Addr { return s.tcp.Addr() }
+
+// Serve answers clients until ctx is cancelled.
+func (s *iperfServer) Serve(ctx context.Context) error {
+	stop := context.AfterFunc(ctx, func() {
+		_ = s.tcp.Close()
+		_ = s.udp.Close()
+	})
+	defer stop()
+	go s.readUDP()
+	for {
+		conn, err := s.tcp.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			_ = s.udp.Close()
+			return err
+		}
+		go s.accept(ctx, conn)
+	}
+}
+
+// accept routes a new connection by the cookie it starts with: a data
+// stream of the running test, a new test, or a refusal while busy.
+func (s *iperfServer) accept(ctx context.Context, conn net.Conn) {
+	cookie := make([]byte, iperfCookieSize)
+	_ = conn.SetReadDeadline(time.Now().Add(iperfSetupTimeout))
+	if _, err := io.ReadFull(conn, cookie); err != nil {
+		_ = conn.Close()
+		return
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	s.mu.Lock()
+	t := s.test
+	switch {
+	case t != nil && bytes.Equal(t.cookie, cookie):
+		s.mu.Unlock()
+		select {
+		case t.tcpStreams <- conn:
+		default:
+			_ = conn.Close()
+		}
+		return
+	case t != nil:
+		s.mu.Unlock()
+		_ = writeIperfState(conn, iperfAccessDenied)
+		_ = conn.Close()
+		return
+	}
+	t = &iperfServerTest{
+		cookie:     cookie,
+		tcpStreams: make(chan net.Conn, iperfMaxStreams),
+		udpStreams: make(map[string]*iperfStream),
+		udpReady:   make(chan *iperfStream, iperfMaxStreams),
+	}
+	s.test = t
+	s.mu.Unlock()
+
+	out, p, err := s.serveTest(ctx, conn, t)
+	s.release(t)
+	client := conn.RemoteAddr().String()
+	if err != nil {
+		slog.Warn("throughput test failed", "event", "iperf_error", "client", client, "err", err)
+		return
+	}
+	protocol := "tcp"
+	if p.UDP {
+		protocol = "udp"
+	}
+	slog.Info("throughput test served", "event", "iperf_served", "client", client, "protocol", protocol,
+		"reverse", p.Reverse, "streams", out.Streams, "mbps", math.Round(out.Mbps()*10)/10)
+}
+
+// release frees the server for the next test.
+func (s *iperfServer) release(t *iperfServerTest) {
+	s.mu.Lock()
+	if s.test == t {
+		s.test = nil
+	}
+	s.mu.Unlock()
+}
+
+// serveTest runs one test on its control connection.
+func (s *iperfServer) serveTest(ctx context.Context, ctrl net.Conn, t *iperfServerTest) (iperfOutcome, iperfParams, error) {
+	defer ctrl.Close()
+	stop := context.AfterFunc(ctx, func() { _ = ctrl.Close() })
+	defer stop()
+
+	var p iperfParams
+	_ = ctrl.SetReadDeadline(time.Now().Add(iperfSetupTimeout))
+	if err := writeIperfState(ctrl, iperfParamExchange); err != nil {
+		return iperfOutcome{}, p, err
+	}
+	if err := readIperfJSON(ctrl, &p); err != nil {
+		return iperfOutcome{}, p, fmt.Errorf("read parameters: %w", err)
+	}
+	if p.Parallel < 1 {
+		p.Parallel = 1
+	}
+	if p.Parallel > iperfMaxStreams || p.TCP == p.UDP {
+		return iperfOutcome{}, p, fmt.Errorf("unsupported parameters %+v", p)
+	}
+	if p.Len <= 0 {
+		p.Len = iperfBlockSize(p.UDP)
+	}
+	p.Len = min(max(p.Len, iperfUDPHeaderSize+4), iperfMaxBlockSize)
+	if p.UDP {
+		p.Len = min(p.Len, iperfMaxUDPPayload)
+	}
+	s.mu.Lock()
+	t.params = p
+	s.mu.Unlock()
+
+	if err := writeIperfState(ctrl, iperfCreateStreams); err != nil {
+		return iperfOutcome{}, p, err
+	}
+	dataCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var wg sync.WaitGroup
+	streams, err := t.collectStreams(ctx, p)
+	defer func() {
+		cancel()
+		for _, st := range streams {
+			if st.conn != nil {
+				_ = st.conn.Close()
+			}
+		}
+		wg.Wait()
+	}()
+	if err != nil {
+		return iperfOutcome{}, p, err
+	}
+	if err := writeIperfState(ctrl, iperfTestStart); err != nil {
+		return iperfOutcome{}, p, err
+	}
+	if err := writeIperfState(ctrl, iperfTestRunning); err != nil {
+		return iperfOutcome{}, p, err
+	}
+
+	began := time.Now()
+	for _, st := range streams {
+		if p.UDP && !p.Reverse {
+			continue // readUDP feeds these
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			switch {
+			case p.UDP:
+				write := func(b []byte) (int, error) { return s.udp.WriteTo(b, st.addr) }
+				_ = st.sendUDP(dataCtx, write, p.Bandwidth, p.Len, p.UDPCounters64)
+			case p.Reverse:
+				_ = st.sendTCP(dataCtx, p.Len)
+			default:
+				st.receiveTCP()
+			}
+		}()
+	}
+
+	// The client's clock ends the test; time-less tests (-n, -k) get an
+	// hour.
+	limit := time.Hour
+	if p.Time > 0 {
+		limit = time.Duration(p.Time)*time.Second + iperfSetupTimeout
+	}
+	_ = ctrl.SetReadDeadline(time.Now().Add(limit))
+	for {
+		state, err := readIperfState(ctrl)
+		if err != nil {
+			return iperfOutcome{}, p, fmt.Errorf("waiting for test end: %w", err)
+		}
+		if state == iperfTestEnd {
+			break
+		}
+		if state == iperfClientTerminate || state == iperfDone {
+			return iperfOutcome{}, p, errors.New("client ended the test")
+		}
+	}
+	elapsed := time.Since(began)
+	cancel()
+	ours := iperfSnapshot(streams, elapsed)
+
+	var theirs iperfResults
+	_ = ctrl.SetReadDeadline(time.Now().Add(iperfSetupTimeout))
+	if err := writeIperfState(ctrl, iperfExchangeResults); err != nil {
+		return iperfOutcome{}, p, err
+	}
+	if err := readIperfJSON(ctrl, &theirs); err != nil {
+		return iperfOutcome{}, p, fmt.Errorf("read results: %w", err)
+	}
+	if err := writeIperfJSON(ctrl, ours); err != nil {
+		return iperfOutcome{}, p, err
+	}
+	// Free the server before the client can see the test is over, so its
+	// next test isn't turned away while this one is cleaned up.
+	s.release(t)
+	if err := writeIperfState(ctrl, iperfDisplayResults); err != nil {
+		return iperfOutcome{}, p, err
+	}
+	// The client answers IPERF_DONE and hangs up; either will do.
+	_, _ = readIperfState(ctrl)
+	if p.Reverse {
+		return iperfOutcomeOf(theirs, elapsed), p, nil
+	}
+	return iperfOutcomeOf(ours, elapsed), p, nil
+}
+
+// collectStreams waits for the client's data streams, numbering them in
+// arrival order as the client numbers them in connect order. On error the
+// streams collected so far are returned for closing.
+func (t *iperfServerTest) collectStreams(ctx context.Context, p iperfParams) ([]*iperfStream, error) {
+	ids := iperfStreamIDs(p.Parallel)
+	streams := make([]*iperfStream, 0, p.Parallel)
+	timeout := time.NewTimer(iperfSetupTimeout)
+	defer timeout.Stop()
+	for len(streams) < p.Parallel {
+		select {
+		case conn := <-t.tcpStreams:
+			if p.UDP {
+				_ = conn.Close()
+				continue
+			}
+			streams = append(streams, &iperfStream{id: ids[len(streams)], conn: conn})
+		case st := <-t.udpReady:
+			st.id = ids[len(streams)]
+			streams = append(streams, st)
+		case <-timeout.C:
+			return streams, fmt.Errorf("got %d of %d data streams", len(streams), p.Parallel)
+		case <-ctx.Done():
+			return streams, ctx.Err()
+		}
+	}
+	return streams, nil
+}
+
+// readUDP serves the shared UDP socket: hellos open streams of the running
+// test, and datagrams on a stream are counted when the client sends.
+func (s *iperfServer) readUDP() {
+	buf := make([]byte, 64*1024)
+	reply := binary.LittleEndian.AppendUint32(nil, iperfUDPConnectReply)
+	var delay time.Duration // backoff while reads keep failing
+	for {
+		n, addr, err := s.udp.ReadFrom(buf)
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			// Some errors are per-datagram (an ICMP unreachable surfacing
+			// as ECONNRESET on Windows) and the next read succeeds; a
+			// socket that fails every read mustn't spin a core.
+			delay = max(2*delay, 5*time.Millisecond)
+			if delay > time.Second {
+				delay = time.Second
+			}
+			slog.Warn("throughput server UDP read failed", "event", "iperf_udp_error", "err", err, "retry_in", delay)
+			time.Sleep(delay)
+			continue
+		}
+		delay = 0
+		at := time.Now()
+		hello := isIperfUDPHello(buf[:n], false)
+
+		s.mu.Lock()
+		t := s.test
+		var p iperfParams
+		var st *iperfStream
+		if t != nil {
+			p = t.params
+			st = t.udpStreams[addr.String()]
+			if st == nil && hello && p.UDP && len(t.udpStreams) < p.Parallel {
+				st = &iperfStream{addr: addr}
+				t.udpStreams[addr.String()] = st
+				t.udpReady <- st
+			}
+		}
+		s.mu.Unlock()
+
+		switch {
+		case st == nil:
+		case hello:
+			// Answer repeats too: the first answer may have been lost.
+			_, _ = s.udp.WriteTo(reply, addr)
+		case !p.Reverse:
+			st.receiveUDP(buf[:n], at, p.UDPCounters64)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startIperfServer runs the built-in server on a free loopback port.
+func startIperfServer(t *testing.T) string {
+	t.Helper()
+	srv, err := listenIperfServer("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- srv.Serve(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		if err := <-done; err != nil {
+			t.Errorf("serve: %v", err)
+		}
+	})
+	return srv.Addr().String()
+}
+
+func TestIperfClientServer(t *testing.T) {
+	addr := startIperfServer(t)
+	cases := []struct {
+		name string
+		spec iperfSpec
+	}{
+		{"tcp-up", iperfSpec{Parallel: 1}},
+		{"tcp-down", iperfSpec{Reverse: true, Parallel: 3}},
+		{"udp-up", iperfSpec{UDP: true, Parallel: 2, Bandwidth: 20e6}},
+		{"udp-down", iperfSpec{UDP: true, Reverse: true, Parallel: 1, Bandwidth: 20e6}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.spec.Duration = 500 * time.Millisecond
+			out, err := runIperfClient(context.Background(), addr, c.spec)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if out.Streams != c.spec.Parallel || out.Bytes == 0 || out.Seconds < 0.4 || out.Seconds > 2 {
+				t.Fatalf("outcome = %+v", out)
+			}
+			if !c.spec.UDP {
+				return
+			}
+			// Paced at 20 Mbit/s per stream, with slack for timer jitter.
+			want := 20 * float64(c.spec.Parallel)
+			if mbps := out.Mbps(); mbps < want*0.7 || mbps > want*1.3 {
+				t.Errorf("%.1f Mbit/s, want about %.0f", mbps, want)
+			}
+			if out.Packets == 0 || out.LossPercent() > 5 {
+				t.Errorf("packets %d, loss %.1f%%", out.Packets, out.LossPercent())
+			}
+		})
+	}
+}
+
+func TestIperfServerBusy(t *testing.T) {
+	addr := startIperfServer(t)
+	running := make(chan error, 1)
+	go func() {
+		_, err := runIperfClient(context.Background(), addr, iperfSpec{Parallel: 1, Duration: 700 * time.Millisecond})
+		running <- err
+	}()
+	time.Sleep(200 * time.Millisecond)
+	if _, err := runIperfClient(context.Background(), addr, iperfSpec{Parallel: 1, Duration: time.Second}); err == nil || !strings.Contains(err.Error(), "busy") {
+		t.Errorf("second client: %v", err)
+	}
+	if err := <-running; err != nil {
+		t.Errorf("first client: %v", err)
+	}
+
+	// Cancelling mid-test returns promptly and frees the server.
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := runIperfClient(ctx, addr, iperfSpec{Parallel: 1, Duration: 5 * time.Second}); err == nil || time.Since(start) > time.Second {
+		t.Errorf("cancelled client: %v after %s", err, time.Since(start))
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		_, err := runIperfClient(context.Background(), addr, iperfSpec{Parallel: 1, Duration: 200 * time.Millisecond})
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server still busy after a cancelled test: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestIperfWireHelpers(t *testing.T) {
+	if got := iperfStreamIDs(4); !slices.Equal(got, []int{1, 3, 4, 5}) {
+		t.Errorf("stream ids = %v", got)
+	}
+	cookie, _ := newIperfCookie()
+	if len(cookie) != iperfCookieSize || cookie[iperfCookieSize-1] != 0 || strings.Trim(string(cookie[:iperfCookieSize-1]), "abcdefghijklmnopqrstuvwxyz234567") != "" {
+		t.Errorf("cookie = %q", cookie)
+	}
+	for _, c := range []struct{ in, want string }{
+		{"iperf.lan", "iperf.lan:5201"},
+		{"10.0.0.2:5202", "10.0.0.2:5202"},
+		{"fd00::2", "[fd00::2]:5201"},
+	} {
+		if got := iperfAddr(c.in); got != c.want {
+			t.Errorf("iperfAddr(%q) = %q", c.in, got)
+		}
+	}
+	// Both hello generations, in both byte orders.
+	for _, b := range [][]byte{{0x39, 0x38, 0x37, 0x36}, {0x15, 0xcd, 0x5b, 0x07}, {0x07, 0x5b, 0xcd, 0x15}} {
+		if !isIperfUDPHello(b, false) {
+			t.Errorf("% x not a hello", b)
+		}
+	}
+
+	// Loss and reordering are counted like iperf3 does.
+	var s iperfStream
+	now := time.Now()
+	for _, n := range []uint32{1, 2, 5, 4, 6} {
+		pkt := make([]byte, iperfUDPBlockSize)
+		pkt[8], pkt[9], pkt[10], pkt[11] = byte(n>>24), byte(n>>16), byte(n>>8), byte(n)
+		s.receiveUDP(pkt, now, false)
+	}
+	if s.packets != 6 || s.lost != 1 {
+		t.Errorf("packets %d, lost %d; want 6 and 1", s.packets, s.lost)
+	}
+}
@@ -13,7 +13,6 @@ import (
 	"sync/atomic"
 	"time"
 
-	wailsruntime "github.com/wailsapp/wails/v2/pkg/runtime"
 	"golang.org/x/net/icmp"
 	"golang.org/x/net/ipv4"
 )
@@ -53,7 +52,7 @@ type probeTarget struct {
 
 // LatencySampler runs a goroutine per active sampler, fires one probe per
 // target per probeInterval tick, keeps a bounded raw history per target, and
-// emits a `latency:updated` Wails event every tick with a per-target summary
+// publishes a LatencyUpdateEvent every tick with a per-target summary
 // (rolling stats over 1 / 10 / 60 s windows plus the raw history for
 // charting). Multiple Start calls are coalesced — Start is idempotent.
 //
@@ -63,9 +62,10 @@ type probeTarget struct {
 type LatencySampler struct {
 	mu sync.RWMutex
 
-	cfg       *liveConfig
-	wailsCtx  context.Context
-	eventName string
+	cfg *liveConfig
+	// bus receives a LatencyProbeEvent per probe result and a
+	// LatencyUpdateEvent per tick. Nil drops both.
+	bus *EventBus
 
 	running    bool
 	cancelLoop context.CancelFunc
@@ -93,26 +93,18 @@ type LatencySampler struct {
 	readerWG  sync.WaitGroup
 }
 
-// NewLatencySampler constructs a sampler bound to a liveConfig source. The
-// Wails context is set later via SetWailsContext so App.startup can pass it
-// through the same path the rest of the service uses.
-func NewLatencySampler(cfg *liveConfig) *LatencySampler {
+// NewLatencySampler constructs a sampler bound to a liveConfig source that
+// publishes on bus — the same bus the rest of the service uses, so
+// latency results reach the UI, API and recorder like any other event.
+func NewLatencySampler(cfg *liveConfig, bus *EventBus) *LatencySampler {
 	return &LatencySampler{
-		cfg:       cfg,
-		eventName: "latency:updated",
-		history:   make(map[string][]LatencyProbe),
-		pending:   make(map[uint16]chan *icmp.Echo),
+		cfg:     cfg,
+		bus:     bus,
+		history: make(map[string][]LatencyProbe),
+		pending: make(map[uint16]chan *icmp.Echo),
 	}
 }
 
-// SetWailsContext attaches the Wails runtime context used for EventsEmit.
-// Called from App.startup after SetContext on the wifi service.
-func (s *LatencySampler) SetWailsContext(ctx context.Context) {
-	s.mu.Lock()
-	s.wailsCtx = ctx
-	s.mu.Unlock()
-}
-
 // Start boots the sampler goroutine. Safe to call multiple times — a second
 // Start is a no-op while the first is still running. The parent context
 // drives shutdown; cancelling it stops the goroutine cleanly.
@@ -239,6 +231,12 @@ func (s *LatencySampler) tick(ctx context.Context) {
 	}
 	s.mu.Unlock()
 
+	for _, p := range results {
+		if p.Label != "" {
+			s.bus.Publish(LatencyProbeEvent{Probe: p})
+		}
+	}
+
 	s.emitSummary(historyCap)
 }
 
@@ -564,8 +562,8 @@ func (s *LatencySampler) tryOpenICMP() {
 		"event", "latency_icmp_unavailable", "err", s.icmpErr)
 }
 
-// emitSummary builds one LatencyTargetSummary per active target and emits it
-// over the Wails event bus. Reads history under lock and copies out so
+// emitSummary builds one LatencyTargetSummary per active target and publishes
+// them as a LatencyUpdateEvent. Reads history under lock and copies out so
 // downstream Svelte listeners never alias the sampler's live slices.
 func (s *LatencySampler) emitSummary(historyCap int) {
 	s.mu.RLock()
@@ -592,16 +590,12 @@ func (s *LatencySampler) emitSummary(historyCap int) {
 		}
 		summaries = append(summaries, summary)
 	}
-	ctx := s.wailsCtx
 	s.mu.RUnlock()
 
-	if ctx == nil {
-		return
-	}
-	wailsruntime.EventsEmit(ctx, s.eventName, summaries)
+	s.bus.Publish(LatencyUpdateEvent{Summaries: summaries})
 }
 
-// SnapshotSummaries returns the same per-target view the Wails event emits,
+// SnapshotSummaries returns the same per-target view the latency event emits,
 // for synchronous API calls (GetLatency binding).
 func (s *LatencySampler) SnapshotSummaries() []LatencyTargetSummary {
 	s.mu.RLock()
@@ -665,14 +659,16 @@ func filteredLen(items []string) int {
 	return n
 }
 
+// latencyWindows are the stats window lengths, in seconds.
+var latencyWindows = []int{1, 10, 60}
+
 // computeWindows rolls up a probe slice into 1s / 10s / 60s stats windows
 // relative to now. Windows with zero samples still appear in the output so
 // the frontend can render "—" without branching on length.
 func computeWindows(history []LatencyProbe) []LatencyStats {
-	windows := []int{1, 10, 60}
-	out := make([]LatencyStats, 0, len(windows))
+	out := make([]LatencyStats, 0, len(latencyWindows))
 	now := time.Now()
-	for _, secs := range windows {
+	for _, secs := range latencyWindows {
 		cutoff := now.Add(-time.Duration(secs) * time.Second)
 		out = append(out, statsForWindow(history, cutoff, secs))
 	}
@@ -3,6 +3,7 @@ package main
 import (
 	"embed"
 	"log/slog"
+	"os"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -13,6 +14,14 @@ import (
 var assets embed.FS
 
 func main() {
+	// Headless subcommands (scan, monitor, ...) drive the same WiFiService
+	// without a webview. See cli.go.
+	if len(os.Args) > 1 {
+		if cmd, ok := cliCommands[os.Args[1]]; ok {
+			os.Exit(cmd(os.Args[2:]))
+		}
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prometheus exposition. The exporter is opt-in (config metrics_listen or
+// the CLI's -metrics flag) and writes the text format by hand: the data is
+// a handful of gauges read from WiFiService snapshots plus counters and
+// histograms accumulated here, which doesn't justify pulling in
+// client_golang and its dependency tree.
+//
+// Gauges reflect the most recent scan tick. Counters and histograms are
+// accumulated since process start, independent of the capped in-memory
+// histories, so they stay monotonic as Prometheus requires.
+
+// latencyBucketsMs are the RTT histogram upper bounds. Chosen to resolve
+// both a healthy LAN gateway (~1–5 ms) and a congested WAN path.
+var latencyBucketsMs = []float64{1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+// metricsCollector holds the cumulative series that can't be derived from
+// a point-in-time service snapshot.
+type metricsCollector struct {
+	mu         sync.Mutex
+	roams      map[string]uint64 // per interface
+	slowRoams  map[string]uint64
+	latency    map[latencyKey]*latencyHistogram
+	scanErrors map[string]uint64
+	throughput map[throughputKey]ThroughputResult // last successful test
+}
+
+type throughputKey struct {
+	host string
+	mode string
+}
+
+type latencyKey struct {
+	target    string
+	transport string
+}
+
+type latencyHistogram struct {
+	buckets []uint64 // cumulative counts per latencyBucketsMs bound
+	sum     float64
+	count   uint64 // successful probes
+	lost    uint64
+}
+
+func newMetricsCollector() *metricsCollector {
+	return &metricsCollector{
+		roams:      make(map[string]uint64),
+		slowRoams:  make(map[string]uint64),
+		latency:    make(map[latencyKey]*latencyHistogram),
+		scanErrors: make(map[string]uint64),
+		throughput: make(map[throughputKey]ThroughputResult),
+	}
+}
+
+// handleEvent is the collector's EventBus subscriber.
+func (m *metricsCollector) handleEvent(e Event) {
+	switch ev := e.(type) {
+	case ScanErrorEvent:
+		m.observeScanError(ev.Interface)
+	case RoamEvent:
+		m.observeRoam(ev.Interface, ev.Roam)
+	case LatencyProbeEvent:
+		m.observeProbe(ev.Probe)
+	case ThroughputEvent:
+		if ev.Result.Error == "" {
+			m.mu.Lock()
+			m.throughput[throughputKey{host: ev.Result.Host, mode: ev.Result.Mode}] = ev.Result
+			m.mu.Unlock()
+		}
+	}
+}
+
+func (m *metricsCollector) observeScanError(iface string) {
+	m.mu.Lock()
+	m.scanErrors[iface]++
+	m.mu.Unlock()
+}
+
+func (m *metricsCollector) observeRoam(iface string, ev RoamingEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.roams[iface]++
+	// Same 2 s threshold AnalyzeRoamingQuality uses for SlowRoamCount.
+	if ev.DurationMs >= 2000 {
+		m.slowRoams[iface]++
+	}
+}
+
+func (m *metricsCollector) observeProbe(p LatencyProbe) {
+	if p.Label == "" {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := latencyKey{target: p.Label, transport: p.Transport}
+	h, ok := m.latency[key]
+	if !ok {
+		h = &latencyHistogram{buckets: make([]uint64, len(latencyBucketsMs))}
+		m.latency[key] = h
+	}
+	if p.Lost {
+		h.lost++
+		return
+	}
+	h.count++
+	h.sum += p.RTTMs
+	for i, bound := range latencyBucketsMs {
+		if p.RTTMs <= bound {
+			h.buckets[i]++
+		}
+	}
+}
+
+// promWriter emits the Prometheus text format. Families must be written
+// contiguously: call family() once, then sample() for each series.
+type promWriter struct {
+	w *bytes.Buffer
+}
+
+type promLabel struct{ name, value string }
+
+func (p promWriter) family(name, typ, help string) {
+	fmt.Fprintf(p.w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func (p promWriter) sample(name string, labels []promLabel, value float64) {
+	p.w.WriteString(name)
+	if len(labels) > 0 {
+		p.w.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				p.w.WriteByte(',')
+			}
+			p.w.WriteString(l.name)
+			p.w.WriteString(`="`)
+			p.w.WriteString(promEscape(l.value))
+			p.w.WriteByte('"')
+		}
+		p.w.WriteByte('}')
+	}
+	p.w.WriteByte(' ')
+	p.w.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	p.w.WriteByte('\n')
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// promEscape escapes a label value. SSIDs are attacker-controlled bytes, so
+// this matters.
+func promEscape(v string) string {
+	return promEscaper.Replace(v)
+}
+
+func apLabels(r RadioReading, ap AccessPoint) []promLabel {
+	return []promLabel{
+		{"interface", r.Interface},
+		{"ssid", ap.SSID},
+		{"bssid", ap.BSSID},
+		{"band", ap.Band},
+		{"channel", strconv.Itoa(ap.Channel)},
+	}
+}
+
+// apReading pairs a merged AP with one radio's observation of it, so AP
+// series carry the interface that heard them.
+type apReading struct {
+	ap AccessPoint
+	r  RadioReading
+}
+
+// WriteMetrics renders every metric family in the Prometheus text format.
+// The exposition is rendered into memory first: the metrics lock is also
+// taken by the bus subscriber on the scan path, so it mustn't be held
+// while a slow scraper reads the response.
+func (ws *WiFiService) WriteMetrics(out io.Writer) error {
+	var buf bytes.Buffer
+	ws.renderMetrics(&buf)
+	_, err := buf.WriteTo(out)
+	return err
+}
+
+func (ws *WiFiService) renderMetrics(buf *bytes.Buffer) {
+	ws.mu.RLock()
+	networks := ws.networks
+	scanning := ws.scanning
+	ws.mu.RUnlock()
+	ifaces := ws.ScanningInterfaces()
+	clients := ws.GetInterfaceClientStats()
+
+	m := ws.metrics
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	p := promWriter{w: buf}
+
+	var readings []apReading
+	for _, n := range networks {
+		for _, ap := range n.AccessPoints {
+			for _, r := range ap.Radios {
+				readings = append(readings, apReading{ap: ap, r: r})
+			}
+		}
+	}
+	sort.Slice(readings, func(i, j int) bool {
+		if readings[i].ap.BSSID != readings[j].ap.BSSID {
+			return readings[i].ap.BSSID < readings[j].ap.BSSID
+		}
+		return readings[i].r.Interface < readings[j].r.Interface
+	})
+
+	p.family("wifi_scanning", "gauge", "1 while the scan loop is running.")
+	for _, iface := range ifaces {
+		p.sample("wifi_scanning", []promLabel{{"interface", iface}}, boolFloat(scanning))
+	}
+
+	p.family("wifi_ap_signal_dbm", "gauge", "Received signal strength of each visible BSS, per interface that heard it.")
+	for _, rd := range readings {
+		p.sample("wifi_ap_signal_dbm", apLabels(rd.r, rd.ap), float64(rd.r.Signal))
+	}
+	p.family("wifi_ap_age_seconds", "gauge", "Time since each BSS was last heard, as of the interface's latest scan.")
+	for _, rd := range readings {
+		p.sample("wifi_ap_age_seconds", apLabels(rd.r, rd.ap), float64(rd.r.AgeMs)/1000)
+	}
+	p.family("wifi_ap_snr_db", "gauge", "Signal-to-noise ratio of each visible BSS, where the noise floor is known.")
+	for _, rd := range readings {
+		if rd.r.Noise != 0 {
+			p.sample("wifi_ap_snr_db", apLabels(rd.r, rd.ap), float64(rd.r.Signal-rd.r.Noise))
+		}
+	}
+	p.family("wifi_ap_bss_load_utilization_percent", "gauge", "Channel utilization advertised in the BSS Load element.")
+	for _, rd := range readings {
+		if rd.ap.BSSLoadUtilization != nil {
+			p.sample("wifi_ap_bss_load_utilization_percent", apLabels(rd.r, rd.ap), float64(*rd.ap.BSSLoadUtilization))
+		}
+	}
+	p.family("wifi_ap_bss_load_stations", "gauge", "Associated station count advertised in the BSS Load element.")
+	for _, rd := range readings {
+		if rd.ap.BSSLoadStations != nil {
+			p.sample("wifi_ap_bss_load_stations", apLabels(rd.r, rd.ap), float64(*rd.ap.BSSLoadStations))
+		}
+	}
+	p.family("wifi_ap_survey_utilization_percent", "gauge", "Channel busy percentage from the local radio's channel survey.")
+	for _, rd := range readings {
+		if rd.ap.SurveyUtilization > 0 {
+			p.sample("wifi_ap_survey_utilization_percent", apLabels(rd.r, rd.ap), float64(rd.ap.SurveyUtilization))
+		}
+	}
+
+	clientIfaces := make([]string, 0, len(clients))
+	for iface := range clients {
+		clientIfaces = append(clientIfaces, iface)
+	}
+	sort.Strings(clientIfaces)
+	var connected []string
+	p.family("wifi_client_connected", "gauge", "1 when the interface is associated.")
+	for _, iface := range clientIfaces {
+		p.sample("wifi_client_connected", []promLabel{{"interface", iface}}, boolFloat(clients[iface].Connected))
+		if clients[iface].Connected {
+			connected = append(connected, iface)
+		}
+	}
+	clientLabels := func(iface string) []promLabel {
+		c := clients[iface]
+		return []promLabel{
+			{"interface", iface},
+			{"ssid", c.SSID},
+			{"bssid", c.BSSID},
+			{"band", frequencyToBand(int(c.Frequency))},
+			{"channel", strconv.Itoa(c.Channel)},
+		}
+	}
+	gauges := []struct {
+		name, help string
+		value      func(ClientStats) float64
+	}{
+		{"wifi_client_signal_dbm", "Signal strength of the associated BSS.", func(c ClientStats) float64 { return float64(c.Signal) }},
+		{"wifi_client_snr_db", "Signal-to-noise ratio of the association.", func(c ClientStats) float64 { return float64(c.SNR) }},
+		{"wifi_client_tx_bitrate_mbps", "Current transmit bitrate.", func(c ClientStats) float64 { return c.TxBitrate }},
+		{"wifi_client_rx_bitrate_mbps", "Current receive bitrate.", func(c ClientStats) float64 { return c.RxBitrate }},
+		{"wifi_client_retry_rate_percent", "Transmit retries as a percentage of transmitted packets.", func(c ClientStats) float64 { return c.RetryRate }},
+	}
+	for _, g := range gauges {
+		p.family(g.name, "gauge", g.help)
+		for _, iface := range connected {
+			p.sample(g.name, clientLabels(iface), g.value(clients[iface]))
+		}
+	}
+	counters := []struct {
+		name, help string
+		value      func(ClientStats) uint64
+	}{
+		{"wifi_client_tx_retries_total", "Transmit retries reported by the driver for this association.", func(c ClientStats) uint64 { return c.TxRetries }},
+		{"wifi_client_tx_failed_total", "Failed transmissions reported by the driver for this association.", func(c ClientStats) uint64 { return c.TxFailed }},
+		{"wifi_client_tx_packets_total", "Transmitted packets for this association.", func(c ClientStats) uint64 { return c.TxPackets }},
+		{"wifi_client_rx_packets_total", "Received packets for this association.", func(c ClientStats) uint64 { return c.RxPackets }},
+	}
+	for _, c := range counters {
+		p.family(c.name, "counter", c.help)
+		for _, iface := range connected {
+			p.sample(c.name, clientLabels(iface), float64(c.value(clients[iface])))
+		}
+	}
+
+	p.family("wifi_roams_total", "counter", "Roaming events detected since start.")
+	for _, k := range sortedKeys(m.roams) {
+		p.sample("wifi_roams_total", []promLabel{{"interface", k}}, float64(m.roams[k]))
+	}
+	p.family("wifi_slow_roams_total", "counter", "Roaming events that took 2 s or longer.")
+	for _, k := range sortedKeys(m.slowRoams) {
+		p.sample("wifi_slow_roams_total", []promLabel{{"interface", k}}, float64(m.slowRoams[k]))
+	}
+	p.family("wifi_scan_errors_total", "counter", "Scan ticks that failed after retries.")
+	for _, k := range sortedKeys(m.scanErrors) {
+		p.sample("wifi_scan_errors_total", []promLabel{{"interface", k}}, float64(m.scanErrors[k]))
+	}
+
+	keys := make([]latencyKey, 0, len(m.latency))
+	for k := range m.latency {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].target != keys[j].target {
+			return keys[i].target < keys[j].target
+		}
+		return keys[i].transport < keys[j].transport
+	})
+	p.family("wifi_latency_rtt_ms", "histogram", "Round-trip time of successful latency probes.")
+	for _, k := range keys {
+		h := m.latency[k]
+		base := []promLabel{{"target", k.target}, {"transport", k.transport}}
+		for i, bound := range latencyBucketsMs {
+			p.sample("wifi_latency_rtt_ms_bucket", append(base[:2:2], promLabel{"le", strconv.FormatFloat(bound, 'g', -1, 64)}), float64(h.buckets[i]))
+		}
+		p.sample("wifi_latency_rtt_ms_bucket", append(base[:2:2], promLabel{"le", "+Inf"}), float64(h.count))
+		p.sample("wifi_latency_rtt_ms_sum", base, h.sum)
+		p.sample("wifi_latency_rtt_ms_count", base, float64(h.count))
+	}
+	p.family("wifi_latency_probes_lost_total", "counter", "Latency probes that timed out or failed.")
+	for _, k := range keys {
+		p.sample("wifi_latency_probes_lost_total", []promLabel{{"target", k.target}, {"transport", k.transport}}, float64(m.latency[k].lost))
+	}
+
+	tkeys := make([]throughputKey, 0, len(m.throughput))
+	for k := range m.throughput {
+		tkeys = append(tkeys, k)
+	}
+	sort.Slice(tkeys, func(i, j int) bool {
+		if tkeys[i].host != tkeys[j].host {
+			return tkeys[i].host < tkeys[j].host
+		}
+		return tkeys[i].mode < tkeys[j].mode
+	})
+	throughputLabels := func(k throughputKey) []promLabel { return []promLabel{{"host", k.host}, {"mode", k.mode}} }
+	p.family("wifi_throughput_mbps", "gauge", "Result of the last successful throughput test, in Mbit/s.")
+	for _, k := range tkeys {
+		p.sample("wifi_throughput_mbps", throughputLabels(k), m.throughput[k].Mbps)
+	}
+	p.family("wifi_throughput_jitter_ms", "gauge", "Jitter measured by the last successful UDP throughput test.")
+	for _, k := range tkeys {
+		if strings.HasPrefix(k.mode, "udp") {
+			p.sample("wifi_throughput_jitter_ms", throughputLabels(k), m.throughput[k].JitterMs)
+		}
+	}
+	p.family("wifi_throughput_loss_percent", "gauge", "Packet loss measured by the last successful UDP throughput test.")
+	for _, k := range tkeys {
+		if strings.HasPrefix(k.mode, "udp") {
+			p.sample("wifi_throughput_loss_percent", throughputLabels(k), m.throughput[k].LossPercent)
+		}
+	}
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func sortedKeys(m map[string]uint64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// metricsServer is the opt-in HTTP listener serving /metrics.
+type metricsServer struct {
+	addr string
+	srv  *http.Server
+}
+
+func startMetricsServer(addr string, ws *WiFiService) (*metricsServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("metrics listen %s: %w", addr, err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		if err := ws.WriteMetrics(w); err != nil {
+			slog.Debug("metrics write failed", "err", err)
+		}
+	})
+	srv := &http.Server{Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server stopped", "event", "metrics_error", "addr", addr, "err", err)
+		}
+	}()
+	slog.Info("metrics exporter listening", "event", "metrics_start", "addr", ln.Addr().String())
+	return &metricsServer{addr: addr, srv: srv}, nil
+}
+
+func (m *metricsServer) Close() {
+	if m == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	_ = m.srv.Shutdown(ctx)
+}
+
+// syncMetricsServer starts, stops or moves the /metrics listener to match
+// config.MetricsListen. Called on attach and after every config update.
+func (ws *WiFiService) syncMetricsServer() {
+	addr := ws.config.Get().MetricsListen
+	ws.metricsMu.Lock()
+	defer ws.metricsMu.Unlock()
+	if ws.metricsServer != nil && ws.metricsServer.addr == addr {
+		return
+	}
+	ws.metricsServer.Close()
+	ws.metricsServer = nil
+	if addr == "" {
+		return
+	}
+	srv, err := startMetricsServer(addr, ws)
+	if err != nil {
+		slog.Error("metrics exporter failed to start", "event", "metrics_error", "addr", addr, "err", err)
+		return
+	}
+	ws.metricsServer = srv
+}
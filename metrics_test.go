@@ -0,0 +1,44 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestWriteMetricsFromSimulation(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 21)
+	ws.bus.Publish(LatencyProbeEvent{Probe: LatencyProbe{Label: "gateway", Transport: "icmp", RTTMs: 3.5}})
+	ws.bus.Publish(LatencyProbeEvent{Probe: LatencyProbe{Label: "gateway", Transport: "icmp", RTTMs: 42}})
+	ws.bus.Publish(LatencyProbeEvent{Probe: LatencyProbe{Label: "gateway", Transport: "icmp", Lost: true}})
+
+	var b strings.Builder
+	if err := ws.WriteMetrics(&b); err != nil {
+		t.Fatalf("WriteMetrics: %v", err)
+	}
+	out := b.String()
+
+	for _, want := range []string{
+		`wifi_ap_signal_dbm{interface="sim0",ssid="Office",bssid="02:00:00:00:01:02"`,
+		`wifi_ap_age_seconds{interface="sim0",ssid="Office",bssid="02:00:00:00:01:02",band="5GHz",channel="149"} 0`,
+		`wifi_client_connected{interface="sim0"} 1`,
+		`wifi_client_signal_dbm{interface="sim0",ssid="Office",bssid="02:00:00:00:01:02"`,
+		`wifi_roams_total{interface="sim0"} 1`,
+		`wifi_latency_rtt_ms_bucket{target="gateway",transport="icmp",le="5"} 1`,
+		`wifi_latency_rtt_ms_bucket{target="gateway",transport="icmp",le="50"} 2`,
+		`wifi_latency_rtt_ms_bucket{target="gateway",transport="icmp",le="+Inf"} 2`,
+		`wifi_latency_rtt_ms_sum{target="gateway",transport="icmp"} 45.5`,
+		`wifi_latency_probes_lost_total{target="gateway",transport="icmp"} 1`,
+		"# TYPE wifi_latency_rtt_ms histogram",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("metrics output missing %q\n%s", want, out)
+		}
+	}
+}
+
+func TestPromEscape(t *testing.T) {
+	got := promEscape("a\"b\\c\nd")
+	if want := `a\"b\\c\nd`; got != want {
+		t.Errorf("promEscape = %q, want %q", got, want)
+	}
+}
@@ -18,6 +18,8 @@ type AccessPoint struct {
 	Security      string    `json:"security"`      // Security type (WPA2, WPA3, etc.)
 	Band          string    `json:"band"`          // 2.4GHz or 5GHz
 	LastSeen      time.Time `json:"lastSeen"`      // Last time this AP was seen
+	AgeMs         int64     `json:"ageMs"`         // How long before the scan it was last seen
+	Stale         bool      `json:"stale"`         // Not heard since the scan started (cached or held over)
 	Capabilities  []string  `json:"capabilities"`  // AP capabilities (HT, VHT, HE, etc.)
 	BeaconInt     int       `json:"beaconInt"`     // Beacon interval in TU
 	// Advanced capabilities
@@ -60,6 +62,22 @@ type AccessPoint struct {
 	QoSSupport  bool   `json:"qosSupport"`  // WMM/QoS support
 	CountryCode string `json:"countryCode"` // Regulatory country code (US, EU, etc.)
 	APName      string `json:"apName"`      // AP name/description if advertised
+
+	// Radios lists every scanning interface that heard this BSSID on its
+	// latest scan, strongest first. The AP's own Signal/Noise are taken
+	// from the strongest fresh reading.
+	Radios []RadioReading `json:"radios,omitempty"`
+}
+
+// RadioReading is one interface's observation of a BSSID when several
+// interfaces scan concurrently.
+type RadioReading struct {
+	Interface string    `json:"interface"`
+	Signal    int       `json:"signal"` // dBm as seen by this interface
+	Noise     int       `json:"noise"`  // dBm; 0 when the driver doesn't report it
+	LastSeen  time.Time `json:"lastSeen"`
+	AgeMs     int64     `json:"ageMs"`
+	Stale     bool      `json:"stale"` // this interface didn't hear it on its latest scan
 }
 
 // Network represents a WiFi network (SSID) that may have multiple access points
@@ -73,6 +91,7 @@ type Network struct {
 	APCount       int           `json:"apCount"`       // Number of APs for this SSID
 	HasIssues     bool          `json:"hasIssues"`     // True if misconfigurations detected
 	IssueMessages []string      `json:"issueMessages"` // List of detected issues
+	Stale         bool          `json:"stale"`         // True when every AP is stale
 }
 
 // RoamingEvent represents a client roaming from one AP to another.
@@ -0,0 +1,491 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Notification sinks page someone when something goes wrong: slow roams,
+// scan errors, latency loss starting/stopping on a target, and alert rule
+// transitions. The notifier is a bus subscriber that turns those events
+// into Notifications, drops repeats within the de-duplication window and
+// anything over each sink's rate limit, and hands the rest to a single
+// delivery goroutine so a slow webhook or SMTP server never stalls
+// scanning.
+//
+//	[notify]
+//	dedup_seconds = 300
+//	rate_limit_per_minute = 6
+//
+//	[[notify.sink]]
+//	type = "slack"
+//	url = "https://hooks.slack.com/services/..."
+//	events = ["roam", "latency_loss"]
+
+// NotifyConfig is the [notify] section of the config file.
+//
+//   - Sinks: where to deliver; none by default.
+//   - DedupSeconds: an identical notification (same kind, subject and
+//     detail) is sent at most once per window.
+//   - RateLimitPerMinute: per-sink cap; excess notifications are dropped.
+//   - SlowRoamMs: roams at least this slow notify. 0 notifies every roam.
+//   - LatencyLossPercent: 10 s loss at or above this marks a latency target
+//     lossy; dropping back below notifies recovery.
+type NotifyConfig struct {
+	Sinks              []NotifySink `toml:"sink" json:"sinks"`
+	DedupSeconds       int          `toml:"dedup_seconds" json:"dedupSeconds"`
+	RateLimitPerMinute int          `toml:"rate_limit_per_minute" json:"rateLimitPerMinute"`
+	SlowRoamMs         int          `toml:"slow_roam_ms" json:"slowRoamMs"`
+	LatencyLossPercent float64      `toml:"latency_loss_percent" json:"latencyLossPercent"`
+}
+
+// NotifySink is one delivery target.
+//
+//   - Type: "webhook" (POSTs the Notification as JSON), "slack" (a
+//     Slack-compatible incoming webhook), "email" or "desktop".
+//   - Events: notification kinds to deliver ("roam", "scan_error",
+//     "latency_loss", "alert", "rogue"); empty delivers all.
+//   - SMTPHost ("host:port"), SMTPUser, SMTPPassword, From, To: email only.
+//     Auth is skipped when SMTPUser is empty; STARTTLS is used when the
+//     server offers it.
+type NotifySink struct {
+	Type         string   `toml:"type" json:"type"`
+	URL          string   `toml:"url" json:"url"`
+	Events       []string `toml:"events" json:"events"`
+	SMTPHost     string   `toml:"smtp_host" json:"smtpHost"`
+	SMTPUser     string   `toml:"smtp_user" json:"smtpUser"`
+	SMTPPassword string   `toml:"smtp_password" json:"smtpPassword"`
+	From         string   `toml:"from" json:"from"`
+	To           []string `toml:"to" json:"to"`
+}
+
+func defaultNotifyConfig() NotifyConfig {
+	return NotifyConfig{
+		DedupSeconds:       300,
+		RateLimitPerMinute: 6,
+		SlowRoamMs:         2000,
+		LatencyLossPercent: 5,
+	}
+}
+
+// validate fills defaults and drops sinks that can't deliver, returning a
+// note per dropped sink.
+func (c NotifyConfig) validate() (NotifyConfig, []string) {
+	defaults := defaultNotifyConfig()
+	if c.DedupSeconds < 0 {
+		c.DedupSeconds = defaults.DedupSeconds
+	}
+	if c.RateLimitPerMinute < 1 {
+		c.RateLimitPerMinute = defaults.RateLimitPerMinute
+	}
+	if c.SlowRoamMs < 0 {
+		c.SlowRoamMs = defaults.SlowRoamMs
+	}
+	if c.LatencyLossPercent <= 0 {
+		c.LatencyLossPercent = defaults.LatencyLossPercent
+	}
+	var notes []string
+	var sinks []NotifySink
+	for i, s := range c.Sinks {
+		var missing string
+		switch s.Type {
+		case "webhook", "slack":
+			if s.URL == "" {
+				missing = "url"
+			}
+		case "email":
+			if s.SMTPHost == "" || s.From == "" || len(s.To) == 0 {
+				missing = "smtp_host, from and to"
+			}
+		case "desktop":
+		default:
+			notes = append(notes, fmt.Sprintf("notify sink %d: unknown type %q, dropped", i, s.Type))
+			continue
+		}
+		if missing != "" {
+			notes = append(notes, fmt.Sprintf("notify sink %d (%s): needs %s, dropped", i, s.Type, missing))
+			continue
+		}
+		sinks = append(sinks, s)
+	}
+	c.Sinks = sinks
+	return c, notes
+}
+
+// id identifies a sink for rate limiting across config reloads.
+func (s NotifySink) id() string {
+	switch s.Type {
+	case "email":
+		return "email:" + s.SMTPHost + ":" + strings.Join(s.To, ",")
+	case "desktop":
+		return "desktop"
+	}
+	return s.Type + ":" + s.URL
+}
+
+func (s NotifySink) wants(kind string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, k := range s.Events {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification kinds.
+const (
+	NotifyRoam        = "roam"
+	NotifyScanError   = "scan_error"
+	NotifyLatencyLoss = "latency_loss"
+	NotifyAlert       = "alert"
+	NotifyRogue       = "rogue"
+)
+
+// Notification is what a sink delivers. Webhook sinks receive it as JSON.
+type Notification struct {
+	Kind      string      `json:"kind"`
+	Severity  string      `json:"severity"`
+	Subject   string      `json:"subject"` // interface or latency target
+	Title     string      `json:"title"`
+	Message   string      `json:"message"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+
+	dedupKey string
+}
+
+// notifyQueueSize bounds deliveries waiting on slow sinks.
+const notifyQueueSize = 64
+
+// notifyTimeout bounds one delivery.
+const notifyTimeout = 10 * time.Second
+
+type notifyJob struct {
+	sink NotifySink
+	n    Notification
+}
+
+type notifier struct {
+	cfg *liveConfig
+	now func() time.Time
+	// deliver sends one notification; replaced in tests.
+	deliver func(ctx context.Context, s NotifySink, n Notification) error
+
+	mu       sync.Mutex
+	lastSent map[string]time.Time   // dedup key → last enqueue
+	sent     map[string][]time.Time // sink id → enqueues in the last minute
+	lossy    map[string]bool        // latency target → currently lossy
+
+	queue     chan notifyJob
+	startOnce sync.Once
+	ctx       context.Context
+	cancel    context.CancelFunc
+}
+
+func newNotifier(cfg *liveConfig) *notifier {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &notifier{
+		cfg:      cfg,
+		now:      time.Now,
+		deliver:  deliverNotification,
+		lastSent: make(map[string]time.Time),
+		sent:     make(map[string][]time.Time),
+		lossy:    make(map[string]bool),
+		queue:    make(chan notifyJob, notifyQueueSize),
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Close stops the delivery goroutine; queued notifications are dropped.
+func (n *notifier) Close() {
+	n.cancel()
+}
+
+func (n *notifier) handleEvent(e Event) {
+	cfg := n.cfg.Get().Notify
+	if len(cfg.Sinks) == 0 {
+		return
+	}
+	var out []Notification
+	switch ev := e.(type) {
+	case RoamEvent:
+		if ev.Roam.DurationMs >= int64(cfg.SlowRoamMs) {
+			out = append(out, Notification{
+				Kind: NotifyRoam, Severity: "warning", Subject: ev.Interface,
+				Title: fmt.Sprintf("Slow roam on %s", ev.Interface),
+				Message: fmt.Sprintf("%s → %s took %d ms (%d → %d dBm)", ev.Roam.PreviousBSSID, ev.Roam.NewBSSID,
+					ev.Roam.DurationMs, ev.Roam.PreviousSignal, ev.Roam.NewSignal),
+				Timestamp: ev.Roam.Timestamp, Data: ev.Roam,
+				dedupKey: "roam|" + ev.Interface + "|" + ev.Roam.PreviousBSSID + "|" + ev.Roam.NewBSSID,
+			})
+		}
+	case ScanErrorEvent:
+		out = append(out, Notification{
+			Kind: NotifyScanError, Severity: "critical", Subject: ev.Interface,
+			Title:     fmt.Sprintf("Scan failing on %s", ev.Interface),
+			Message:   ev.Err.Error(),
+			Timestamp: n.now(),
+			dedupKey:  "scan_error|" + ev.Interface + "|" + ev.Err.Error(),
+		})
+	case LatencyUpdateEvent:
+		out = n.latencyTransitions(ev, cfg.LatencyLossPercent)
+	case RogueAPEvent:
+		f := ev.Finding
+		out = append(out, Notification{
+			Kind: NotifyRogue, Severity: f.Severity, Subject: f.SSID,
+			Title: fmt.Sprintf("Possible rogue AP for %s", f.SSID), Message: f.BSSID + ": " + f.Message,
+			Timestamp: f.FirstSeen, Data: f,
+			dedupKey: "rogue|" + f.Kind + "|" + f.BSSID,
+		})
+	case AlertEvent:
+		a := ev.Alert
+		title := fmt.Sprintf("Alert %s: %s", a.State, a.Rule)
+		out = append(out, Notification{
+			Kind: NotifyAlert, Severity: a.Severity, Subject: a.Subject,
+			Title: title, Message: a.Message, Timestamp: a.Timestamp, Data: a,
+			dedupKey: "alert|" + a.Rule + "|" + a.Subject + "|" + a.State,
+		})
+	default:
+		return
+	}
+	for _, note := range out {
+		n.dispatch(cfg, note)
+	}
+}
+
+// latencyTransitions reports targets whose 10 s loss crossed threshold
+// since the previous tick, in either direction.
+func (n *notifier) latencyTransitions(ev LatencyUpdateEvent, threshold float64) []Notification {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var out []Notification
+	for _, sum := range ev.Summaries {
+		var w *LatencyStats
+		for i := range sum.Windows {
+			if sum.Windows[i].WindowSeconds == 10 {
+				w = &sum.Windows[i]
+			}
+		}
+		if w == nil || w.Samples == 0 {
+			continue
+		}
+		lossy := w.LossPercent >= threshold
+		if lossy == n.lossy[sum.Label] {
+			continue
+		}
+		n.lossy[sum.Label] = lossy
+		note := Notification{
+			Kind: NotifyLatencyLoss, Subject: sum.Label, Timestamp: n.now(), Data: sum.Windows,
+		}
+		if lossy {
+			note.Severity = "critical"
+			note.Title = fmt.Sprintf("Packet loss to %s", sum.Label)
+			note.Message = fmt.Sprintf("%.0f%% of probes to %s lost over 10 s", w.LossPercent, sum.Label)
+			note.dedupKey = "latency_loss|" + sum.Label + "|lossy"
+		} else {
+			note.Severity = "info"
+			note.Title = fmt.Sprintf("Packet loss to %s recovered", sum.Label)
+			note.Message = fmt.Sprintf("loss to %s back to %.0f%% over 10 s", sum.Label, w.LossPercent)
+			note.dedupKey = "latency_loss|" + sum.Label + "|recovered"
+		}
+		out = append(out, note)
+	}
+	return out
+}
+
+// dispatch applies de-duplication and per-sink rate limits, then queues the
+// notification for every sink that wants it.
+func (n *notifier) dispatch(cfg NotifyConfig, note Notification) {
+	now := n.now()
+	window := time.Duration(cfg.DedupSeconds) * time.Second
+	n.mu.Lock()
+	// Keys outside the window can't suppress anything; dropping them keeps
+	// the map to what was sent recently rather than every SSID, target and
+	// rule ever seen.
+	for key, last := range n.lastSent {
+		if now.Sub(last) >= window {
+			delete(n.lastSent, key)
+		}
+	}
+	if last, ok := n.lastSent[note.dedupKey]; ok && now.Sub(last) < window {
+		n.mu.Unlock()
+		slog.Debug("notification suppressed as duplicate", "event", "notify_dedup", "key", note.dedupKey)
+		return
+	}
+	var jobs []notifyJob
+	for _, s := range cfg.Sinks {
+		if !s.wants(note.Kind) {
+			continue
+		}
+		id := s.id()
+		recent := n.sent[id][:0]
+		for _, t := range n.sent[id] {
+			if now.Sub(t) < time.Minute {
+				recent = append(recent, t)
+			}
+		}
+		if len(recent) >= cfg.RateLimitPerMinute {
+			n.sent[id] = recent
+			slog.Warn("notification dropped by rate limit", "event", "notify_rate_limited", "sink", s.Type, "title", note.Title)
+			continue
+		}
+		n.sent[id] = append(recent, now)
+		jobs = append(jobs, notifyJob{sink: s, n: note})
+	}
+	// Only a notification that went somewhere suppresses its repeats; one
+	// the rate limit ate can still get out next time.
+	if len(jobs) > 0 {
+		n.lastSent[note.dedupKey] = now
+	}
+	n.mu.Unlock()
+
+	if len(jobs) == 0 {
+		return
+	}
+	n.startOnce.Do(func() { go n.run() })
+	for _, j := range jobs {
+		select {
+		case n.queue <- j:
+		default:
+			slog.Warn("notification queue full, dropping", "event", "notify_dropped", "sink", j.sink.Type, "title", j.n.Title)
+		}
+	}
+}
+
+func (n *notifier) run() {
+	for {
+		select {
+		case <-n.ctx.Done():
+			return
+		case j := <-n.queue:
+			ctx, cancel := context.WithTimeout(n.ctx, notifyTimeout)
+			if err := n.deliver(ctx, j.sink, j.n); err != nil {
+				slog.Warn("notification delivery failed", "event", "notify_error", "sink", j.sink.Type, "err", err)
+			}
+			cancel()
+		}
+	}
+}
+
+func deliverNotification(ctx context.Context, s NotifySink, n Notification) error {
+	switch s.Type {
+	case "webhook":
+		return postJSON(ctx, s.URL, n)
+	case "slack":
+		return postJSON(ctx, s.URL, map[string]string{"text": "*" + n.Title + "*\n" + n.Message})
+	case "email":
+		return sendEmail(ctx, s, n)
+	case "desktop":
+		return desktopNotify(ctx, n.Title, n.Message)
+	}
+	return fmt.Errorf("unknown sink type %q", s.Type)
+}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("POST %s: %s", url, resp.Status)
+	}
+	return nil
+}
+
+// sendEmail is smtp.SendMail bounded by ctx. net/smtp has no context
+// support, so the connection is dialed here, given ctx's deadline, and
+// cut short if ctx is cancelled; a server that accepts and then stalls
+// can't hold the delivery goroutine.
+func sendEmail(ctx context.Context, s NotifySink, n Notification) error {
+	host, _, err := net.SplitHostPort(s.SMTPHost)
+	if err != nil {
+		return fmt.Errorf("smtp_host %q: %w", s.SMTPHost, err)
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", s.SMTPHost)
+	if err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if s.SMTPUser != "" {
+		if err := c.Auth(smtp.PlainAuth("", s.SMTPUser, s.SMTPPassword, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(s.From); err != nil {
+		return err
+	}
+	for _, to := range s.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(emailMessage(s, n)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+func emailMessage(s NotifySink, n Notification) []byte {
+	var b bytes.Buffer
+	// Header values are single-line; a CR/LF in a title would otherwise
+	// inject headers.
+	clean := strings.NewReplacer("\r", " ", "\n", " ")
+	fmt.Fprintf(&b, "From: %s\r\n", clean.Replace(s.From))
+	fmt.Fprintf(&b, "To: %s\r\n", clean.Replace(strings.Join(s.To, ", ")))
+	// Titles carry SSIDs, which needn't be ASCII; headers must be (RFC 2047).
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", "[wifi-app] "+clean.Replace(n.Title)))
+	fmt.Fprintf(&b, "Date: %s\r\n", n.Timestamp.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(strings.ReplaceAll(n.Message, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
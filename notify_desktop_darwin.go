@@ -0,0 +1,24 @@
+//go:build darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// desktopNotify posts a Notification Center banner via osascript. Title and
+// message are passed as script arguments rather than spliced into the
+// AppleScript source, so quotes in an SSID can't break out of the string.
+func desktopNotify(ctx context.Context, title, message string) error {
+	out, err := exec.CommandContext(ctx, "osascript",
+		"-e", "on run argv",
+		"-e", "display notification (item 2 of argv) with title (item 1 of argv)",
+		"-e", "end run",
+		title, message).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("osascript: %w: %s", err, out)
+	}
+	return nil
+}
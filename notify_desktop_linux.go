@@ -0,0 +1,20 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+)
+
+// desktopNotify shows a desktop notification through notify-send
+// (libnotify), present on every mainstream desktop. Running under sudo
+// without the user's DBus session it fails; the caller logs and moves on.
+func desktopNotify(ctx context.Context, title, message string) error {
+	out, err := exec.CommandContext(ctx, "notify-send", "--app-name=wifi-app", "--", title, message).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("notify-send: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !windows && !darwin
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// desktopNotify is unsupported on this platform; desktop sinks log the
+// error and other sinks still deliver.
+func desktopNotify(ctx context.Context, title, message string) error {
+	return fmt.Errorf("desktop notifications not implemented on this platform")
+}
@@ -0,0 +1,36 @@
+//go:build windows
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// toastScript raises a toast through the WinRT notification API. The text
+// comes from environment variables so nothing user-controlled is parsed as
+// PowerShell. Toasts need a registered AppUserModelID; PowerShell's own is
+// borrowed since an unpackaged Wails app doesn't have one.
+const toastScript = `$ErrorActionPreference = 'Stop'
+[void][Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime]
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+[void]$text.Item(0).AppendChild($xml.CreateTextNode($env:WIFI_APP_NOTIFY_TITLE))
+[void]$text.Item(1).AppendChild($xml.CreateTextNode($env:WIFI_APP_NOTIFY_MESSAGE))
+$app = '{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe'
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($app).Show([Windows.UI.Notifications.ToastNotification]::new($xml))`
+
+func desktopNotify(ctx context.Context, title, message string) error {
+	cmd := exec.CommandContext(ctx, "powershell", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	cmd.Env = append(os.Environ(), "WIFI_APP_NOTIFY_TITLE="+title, "WIFI_APP_NOTIFY_MESSAGE="+message)
+	cmd.SysProcAttr = &windows.SysProcAttr{HideWindow: true}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("powershell toast: %w: %s", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestNotifier returns a notifier whose deliveries are recorded instead
+// of sent, and whose clock the test controls.
+func newTestNotifier(t *testing.T, nc NotifyConfig) (*notifier, *time.Time, func() []Notification) {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.Notify = nc
+	live := newLiveConfig(DefaultConfig())
+	live.Set(cfg)
+	n := newNotifier(live)
+	t.Cleanup(n.Close)
+
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	n.now = func() time.Time { return now }
+	var mu sync.Mutex
+	var got []Notification
+	n.deliver = func(_ context.Context, s NotifySink, note Notification) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, note)
+		return nil
+	}
+	delivered := func() []Notification {
+		// Delivery is asynchronous; give the worker a moment to drain.
+		deadline := time.Now().Add(time.Second)
+		for time.Now().Before(deadline) && len(n.queue) > 0 {
+			time.Sleep(5 * time.Millisecond)
+		}
+		time.Sleep(20 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Notification(nil), got...)
+	}
+	return n, &now, delivered
+}
+
+func TestNotifierSlowRoamDedupAndRateLimit(t *testing.T) {
+	nc := defaultNotifyConfig()
+	nc.RateLimitPerMinute = 2
+	nc.Sinks = []NotifySink{{Type: "desktop"}}
+	n, now, delivered := newTestNotifier(t, nc)
+
+	roam := func(from, to string, ms int64) {
+		n.handleEvent(RoamEvent{Interface: "wlan0", Roam: RoamingEvent{PreviousBSSID: from, NewBSSID: to, DurationMs: ms}})
+	}
+	roam("a", "b", 800)  // fast: ignored
+	roam("a", "b", 3000) // sent
+	roam("a", "b", 3100) // same pair within dedup window
+	roam("b", "c", 2500) // sent
+	roam("c", "d", 2500) // over 2/minute
+	if got := delivered(); len(got) != 2 || got[0].Kind != NotifyRoam || !strings.Contains(got[1].Message, "b → c") {
+		t.Fatalf("delivered = %+v", got)
+	}
+
+	// The rate-limited roam wasn't sent, so it isn't a duplicate once the
+	// rate window has passed.
+	*now = now.Add(time.Minute)
+	roam("c", "d", 2500)
+	if got := delivered(); len(got) != 3 || !strings.Contains(got[2].Message, "c → d") {
+		t.Fatalf("rate-limited roam not retried: %+v", got)
+	}
+
+	*now = now.Add(6 * time.Minute) // past both the rate window and dedup
+	roam("a", "b", 3000)
+	if got := delivered(); len(got) != 4 {
+		t.Errorf("after windows expire delivered %d, want 4", len(got))
+	}
+	n.mu.Lock()
+	keys := len(n.lastSent)
+	n.mu.Unlock()
+	if keys != 1 {
+		t.Errorf("%d dedup keys kept, want only the one inside the window", keys)
+	}
+}
+
+func TestNotifierLatencyLossTransitions(t *testing.T) {
+	nc := defaultNotifyConfig()
+	nc.Sinks = []NotifySink{{Type: "desktop", Events: []string{NotifyLatencyLoss}}}
+	n, _, delivered := newTestNotifier(t, nc)
+
+	tick := func(loss float64) {
+		n.handleEvent(LatencyUpdateEvent{Summaries: []LatencyTargetSummary{{
+			Label:   "gateway",
+			Windows: []LatencyStats{{WindowSeconds: 10, Samples: 10, LossPercent: loss}},
+		}}})
+	}
+	tick(0)
+	tick(20)
+	tick(30) // still lossy: no new transition
+	tick(0)
+	n.handleEvent(ScanErrorEvent{Interface: "wlan0", Err: errors.New("busy")}) // filtered out
+
+	got := delivered()
+	if len(got) != 2 {
+		t.Fatalf("delivered = %+v, want loss + recovery", got)
+	}
+	if got[0].Severity != "critical" || got[1].Severity != "info" || got[1].Subject != "gateway" {
+		t.Errorf("transitions = %+v", got)
+	}
+}
+
+func TestNotifierWebhookAndSlackPayloads(t *testing.T) {
+	var mu sync.Mutex
+	bodies := make(map[string]string)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		bodies[r.URL.Path] = string(b)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	note := Notification{Kind: NotifyScanError, Subject: "wlan0", Title: "Scan failing on wlan0", Message: "busy"}
+	ctx := context.Background()
+	if err := deliverNotification(ctx, NotifySink{Type: "webhook", URL: srv.URL + "/hook"}, note); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverNotification(ctx, NotifySink{Type: "slack", URL: srv.URL + "/slack"}, note); err != nil {
+		t.Fatal(err)
+	}
+
+	var hook Notification
+	if err := json.Unmarshal([]byte(bodies["/hook"]), &hook); err != nil || hook.Kind != NotifyScanError || hook.Subject != "wlan0" {
+		t.Errorf("webhook body = %s (%v)", bodies["/hook"], err)
+	}
+	var slack map[string]string
+	if err := json.Unmarshal([]byte(bodies["/slack"]), &slack); err != nil || slack["text"] != "*Scan failing on wlan0*\nbusy" {
+		t.Errorf("slack body = %s (%v)", bodies["/slack"], err)
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "nope", http.StatusInternalServerError)
+	}))
+	defer failing.Close()
+	if err := deliverNotification(ctx, NotifySink{Type: "webhook", URL: failing.URL}, note); err == nil {
+		t.Error("500 from webhook not reported")
+	}
+}
+
+func TestEmailMessageHeaders(t *testing.T) {
+	msg := string(emailMessage(
+		NotifySink{From: "wifi@example.com", To: []string{"ops@example.com", "oncall@example.com"}},
+		Notification{Title: "Slow roam\r\nBcc: evil@example.com", Message: "line1\nline2", Timestamp: time.Unix(0, 0).UTC()},
+	))
+	if !strings.Contains(msg, "To: ops@example.com, oncall@example.com\r\n") {
+		t.Errorf("missing To header:\n%s", msg)
+	}
+	if strings.Contains(msg, "\r\nBcc:") {
+		t.Errorf("title injected a header:\n%s", msg)
+	}
+	if !strings.HasSuffix(msg, "\r\n\r\nline1\r\nline2\r\n") {
+		t.Errorf("body not CRLF-normalized:\n%q", msg)
+	}
+
+	msg = string(emailMessage(NotifySink{From: "wifi@example.com"}, Notification{Title: "Roam to Café"}))
+	if !strings.Contains(msg, "Subject: =?utf-8?q?[wifi-app]_Roam_to_Caf=C3=A9?=\r\n") {
+		t.Errorf("non-ASCII subject not encoded:\n%s", msg)
+	}
+}
+
+func TestSendEmailStalledServer(t *testing.T) {
+	// Accepts and never sends a greeting.
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err = sendEmail(ctx, NotifySink{SMTPHost: ln.Addr().String(), From: "wifi@example.com", To: []string{"ops@example.com"}}, Notification{Title: "x"})
+	if err == nil {
+		t.Fatal("stalled server reported success")
+	}
+	if d := time.Since(start); d > 2*time.Second {
+		t.Errorf("sendEmail took %v against a stalled server", d)
+	}
+}
+
+func TestNotifyConfigValidate(t *testing.T) {
+	c, notes := NotifyConfig{Sinks: []NotifySink{
+		{Type: "slack", URL: "https://hooks.example/x"},
+		{Type: "webhook"},
+		{Type: "email", SMTPHost: "smtp:25"},
+		{Type: "pager"},
+	}}.validate()
+	if len(c.Sinks) != 1 || len(notes) != 3 {
+		t.Errorf("sinks = %+v, notes = %q", c.Sinks, notes)
+	}
+	if c.RateLimitPerMinute != 6 || c.LatencyLossPercent != 5 {
+		t.Errorf("defaults not filled: %+v", c)
+	}
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Minimal PDF 1.4 writer for the PDF report: A4 pages, the standard
+// Helvetica / Helvetica-Bold / Courier fonts (not embedded, so every
+// viewer has them) and filled or stroked paths. That covers tables, text
+// and line/bar charts without pulling a PDF library into the module.
+// Coordinates are points from the top-left corner of the page; text y is
+// the baseline. The writer flips to PDF's bottom-left origin.
+
+const (
+	pdfPageWidth  = 595.28
+	pdfPageHeight = 841.89
+)
+
+type pdfFont int
+
+const (
+	pdfHelvetica pdfFont = iota
+	pdfHelveticaBold
+	pdfCourier
+)
+
+var pdfFontNames = [...]string{"Helvetica", "Helvetica-Bold", "Courier"}
+
+// Glyph widths (1/1000 em) for ASCII 32–126, from the standard AFM files.
+var pdfHelveticaWidths = [95]uint16{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+var pdfHelveticaBoldWidths = [95]uint16{
+	278, 333, 474, 556, 556, 889, 722, 238, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 333, 333, 584, 584, 584, 611,
+	975, 722, 722, 722, 722, 667, 611, 778, 722, 278, 556, 722, 611, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 333, 278, 333, 584, 556,
+	333, 556, 611, 556, 611, 556, 333, 611, 611, 278, 278, 556, 278, 889, 611, 611,
+	611, 611, 389, 556, 333, 611, 556, 778, 556, 556, 500, 389, 280, 389, 584,
+}
+
+// pdfWinAnsi maps the non-Latin-1 characters the report uses to their
+// WinAnsiEncoding byte, with an approximate Helvetica width.
+var pdfWinAnsi = map[rune]struct {
+	code  byte
+	width uint16
+}{
+	'€': {0x80, 556}, '…': {0x85, 1000}, '‘': {0x91, 222}, '’': {0x92, 222},
+	'“': {0x93, 333}, '”': {0x94, 333}, '•': {0x95, 350}, '–': {0x96, 556},
+	'—': {0x97, 1000}, '™': {0x99, 1000},
+}
+
+// pdfFallback spells out characters WinAnsi lacks.
+var pdfFallback = map[rune]string{
+	'≥': ">=", '≤': "<=", '→': "->", '←': "<-", '−': "-", 'σ': "sd", 'Δ': "d", '×': "x",
+}
+
+type pdfColor struct{ r, g, b float64 }
+
+// pdfRGB converts a 0xRRGGBB literal.
+func pdfRGB(hex uint32) pdfColor {
+	return pdfColor{float64(hex>>16&0xff) / 255, float64(hex>>8&0xff) / 255, float64(hex&0xff) / 255}
+}
+
+type pdfDocument struct {
+	Title   string
+	Created time.Time
+	pages   []*pdfPage
+}
+
+type pdfPage struct {
+	content bytes.Buffer
+}
+
+func (d *pdfDocument) addPage() *pdfPage {
+	p := &pdfPage{}
+	d.pages = append(d.pages, p)
+	return p
+}
+
+// pdfNum formats a coordinate or colour component compactly.
+func pdfNum(f float64) string {
+	s := strconv.FormatFloat(f, 'f', 2, 64)
+	s = strings.TrimRight(strings.TrimRight(s, "0"), ".")
+	if s == "-0" || s == "" {
+		return "0"
+	}
+	return s
+}
+
+func (p *pdfPage) op(args ...interface{}) {
+	for i, a := range args {
+		if i > 0 {
+			p.content.WriteByte(' ')
+		}
+		switch v := a.(type) {
+		case float64:
+			p.content.WriteString(pdfNum(v))
+		default:
+			fmt.Fprint(&p.content, v)
+		}
+	}
+	p.content.WriteByte('\n')
+}
+
+func (p *pdfPage) fillColor(c pdfColor)   { p.op(c.r, c.g, c.b, "rg") }
+func (p *pdfPage) strokeColor(c pdfColor) { p.op(c.r, c.g, c.b, "RG") }
+
+// text draws s with its baseline at y.
+func (p *pdfPage) text(x, y float64, font pdfFont, size float64, c pdfColor, s string) {
+	p.content.WriteString("BT\n")
+	p.fillColor(c)
+	p.op(fmt.Sprintf("/F%d", font+1), size, "Tf")
+	p.op(x, pdfPageHeight-y, "Td")
+	p.content.WriteByte('(')
+	p.content.Write(pdfEscape(pdfEncodeText(s)))
+	p.content.WriteString(") Tj\nET\n")
+}
+
+// textRight draws s ending at x.
+func (p *pdfPage) textRight(x, y float64, font pdfFont, size float64, c pdfColor, s string) {
+	p.text(x-pdfTextWidth(font, size, s), y, font, size, c, s)
+}
+
+func (p *pdfPage) rect(x, y, w, h float64, fill pdfColor) {
+	p.fillColor(fill)
+	p.op(x, pdfPageHeight-y-h, w, h, "re f")
+}
+
+func (p *pdfPage) strokeRect(x, y, w, h, width float64, c pdfColor) {
+	p.strokeColor(c)
+	p.op(width, "w")
+	p.op(x, pdfPageHeight-y-h, w, h, "re S")
+}
+
+func (p *pdfPage) line(x1, y1, x2, y2, width float64, c pdfColor) {
+	p.polyline([][2]float64{{x1, y1}, {x2, y2}}, width, c)
+}
+
+func (p *pdfPage) polyline(pts [][2]float64, width float64, c pdfColor) {
+	if len(pts) < 2 {
+		return
+	}
+	p.strokeColor(c)
+	p.op(width, "w 1 J 1 j")
+	p.op(pts[0][0], pdfPageHeight-pts[0][1], "m")
+	for _, pt := range pts[1:] {
+		p.op(pt[0], pdfPageHeight-pt[1], "l")
+	}
+	p.op("S")
+}
+
+func (p *pdfPage) circle(x, y, r float64, fill pdfColor) {
+	// Four Bézier arcs; k is the standard control-point distance.
+	const k = 0.5523
+	y = pdfPageHeight - y
+	p.fillColor(fill)
+	p.op(x+r, y, "m")
+	p.op(x+r, y+k*r, x+k*r, y+r, x, y+r, "c")
+	p.op(x-k*r, y+r, x-r, y+k*r, x-r, y, "c")
+	p.op(x-r, y-k*r, x-k*r, y-r, x, y-r, "c")
+	p.op(x+k*r, y-r, x+r, y-k*r, x+r, y, "c")
+	p.op("f")
+}
+
+// pdfEncodeText converts s to WinAnsiEncoding bytes.
+func pdfEncodeText(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r >= 32 && r < 127, r >= 0xa0 && r <= 0xff:
+			out = append(out, byte(r))
+		case r == '\t' || r == '\n':
+			out = append(out, ' ')
+		default:
+			if m, ok := pdfWinAnsi[r]; ok {
+				out = append(out, m.code)
+			} else if f, ok := pdfFallback[r]; ok {
+				out = append(out, f...)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+func pdfEscape(b []byte) []byte {
+	out := make([]byte, 0, len(b))
+	for _, c := range b {
+		if c == '(' || c == ')' || c == '\\' {
+			out = append(out, '\\')
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// pdfTextWidth is the rendered width of s in points.
+func pdfTextWidth(font pdfFont, size float64, s string) float64 {
+	if font == pdfCourier {
+		return float64(len(pdfEncodeText(s))) * 600 * size / 1000
+	}
+	widths := &pdfHelveticaWidths
+	if font == pdfHelveticaBold {
+		widths = &pdfHelveticaBoldWidths
+	}
+	total := 0
+	for _, r := range s {
+		switch {
+		case r >= 32 && r < 127:
+			total += int(widths[r-32])
+		case pdfWinAnsi[r].width > 0:
+			total += int(pdfWinAnsi[r].width)
+		case pdfFallback[r] != "":
+			for _, c := range pdfFallback[r] {
+				total += int(widths[c-32])
+			}
+		default:
+			total += 556
+		}
+	}
+	return float64(total) * size / 1000
+}
+
+// pdfTruncate shortens s with an ellipsis to fit maxWidth.
+func pdfTruncate(font pdfFont, size float64, s string, maxWidth float64) string {
+	if pdfTextWidth(font, size, s) <= maxWidth {
+		return s
+	}
+	r := []rune(s)
+	for len(r) > 0 && pdfTextWidth(font, size, string(r)+"…") > maxWidth {
+		r = r[:len(r)-1]
+	}
+	return string(r) + "…"
+}
+
+// pdfWrap breaks s into lines no wider than maxWidth, at spaces.
+func pdfWrap(font pdfFont, size float64, s string, maxWidth float64) []string {
+	var lines []string
+	line := ""
+	for _, word := range strings.Fields(s) {
+		candidate := word
+		if line != "" {
+			candidate = line + " " + word
+		}
+		if line != "" && pdfTextWidth(font, size, candidate) > maxWidth {
+			lines = append(lines, line)
+			candidate = word
+		}
+		line = candidate
+	}
+	if line != "" {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// WriteTo serialises the document. Object layout: 1 catalog, 2 page
+// tree, 3 info, then one object per font, then a page + content stream
+// pair per page.
+func (d *pdfDocument) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+	buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+
+	fontBase := 4
+	pageBase := fontBase + len(pdfFontNames)
+	kids := make([]string, len(d.pages))
+	for i := range d.pages {
+		kids[i] = fmt.Sprintf("%d 0 R", pageBase+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages)))
+	created := d.Created.UTC().Format("20060102150405")
+	obj(fmt.Sprintf("<< /Title (%s) /Producer (wifi-app) /CreationDate (D:%sZ) >>",
+		pdfEscape(pdfEncodeText(d.Title)), created))
+	var fonts []string
+	for i, name := range pdfFontNames {
+		obj(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name))
+		fonts = append(fonts, fmt.Sprintf("/F%d %d 0 R", i+1, fontBase+i))
+	}
+	for i, p := range d.pages {
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << %s >> >> /Contents %d 0 R >>",
+			pdfNum(pdfPageWidth), pdfNum(pdfPageHeight), strings.Join(fonts, " "), pageBase+2*i+1))
+		var z bytes.Buffer
+		zw := zlib.NewWriter(&z)
+		if _, err := zw.Write(p.content.Bytes()); err != nil {
+			return 0, err
+		}
+		if err := zw.Close(); err != nil {
+			return 0, err
+		}
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", len(offsets), z.Len())
+		buf.Write(z.Bytes())
+		buf.WriteString("\nendstream\nendobj\n")
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 3 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	n, err := w.Write(buf.Bytes())
+	return int64(n), err
+}
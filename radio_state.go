@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Multi-interface scanning. Survey rigs carry one USB adapter per band, so
+// the service can scan several interfaces at once: each gets its own scan
+// loop and radioState, and every tick re-merges the radios' latest scans
+// into one AP per BSSID (with a RadioReading per interface that heard it)
+// before aggregation. Client stats, signal history and roaming are tracked
+// per radio since each adapter may be associated independently. The first
+// interface is the primary: the single-interface bindings (GetClientStats,
+// AnalyzeRoamingQuality, the client:updated event) report on it.
+
+// radioState is the per-interface half of WiFiService. Guarded by ws.mu
+// except inFlight and replayDone.
+type radioState struct {
+	inFlight atomic.Bool
+	// replayDone is set once a replay has no more scans for the interface.
+	replayDone atomic.Bool
+
+	aps       []AccessPoint // normalized APs from this radio's latest scan
+	scannedAt time.Time
+
+	clientStats    ClientStats
+	signalHistory  []SignalDataPoint
+	roamingHistory []RoamingEvent
+	lastBSSID      string
+	// lastBSSIDSeenAt is the wall-clock time of the last scan tick that
+	// observed lastBSSID as the active (connected) BSSID. Used to estimate
+	// RoamingEvent.DurationMs on the next BSSID transition. Stays frozen
+	// across disconnects so a reconnect to a different BSSID naturally
+	// reports duration-including-the-gap rather than duration-since-reconnect.
+	lastBSSIDSeenAt time.Time
+}
+
+// cloneClientStats returns a deep copy of r.clientStats with fresh
+// SignalHistory/RoamingHistory slices. Caller must hold ws.mu.
+func (r *radioState) cloneClientStats() ClientStats {
+	out := r.clientStats
+	if n := len(r.signalHistory); n > 0 {
+		out.SignalHistory = make([]SignalDataPoint, n)
+		copy(out.SignalHistory, r.signalHistory)
+	} else {
+		out.SignalHistory = nil
+	}
+	if n := len(r.roamingHistory); n > 0 {
+		out.RoamingHistory = make([]RoamingEvent, n)
+		copy(out.RoamingHistory, r.roamingHistory)
+	} else {
+		out.RoamingHistory = nil
+	}
+	return out
+}
+
+// radio returns the state for iface, creating it on first use.
+func (ws *WiFiService) radio(iface string) *radioState {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.radioLocked(iface)
+}
+
+func (ws *WiFiService) radioLocked(iface string) *radioState {
+	if ws.radios == nil {
+		ws.radios = make(map[string]*radioState)
+	}
+	r, ok := ws.radios[iface]
+	if !ok {
+		r = &radioState{}
+		ws.radios[iface] = r
+	}
+	if ws.currentInterface == "" {
+		// performScan driven directly (tests, one-shot scans) without
+		// StartScanning: the first interface seen is the primary.
+		ws.currentInterface = iface
+	}
+	return r
+}
+
+// emptyRadio stands in for the primary before anything has been scanned.
+var emptyRadio = &radioState{}
+
+// primaryLocked returns the primary interface's state. Never nil. Caller
+// must hold ws.mu.
+func (ws *WiFiService) primaryLocked() *radioState {
+	if r, ok := ws.radios[ws.currentInterface]; ok {
+		return r
+	}
+	return emptyRadio
+}
+
+// StartScanningInterfaces begins scanning every interface in ifaces
+// concurrently, with the config's [scan] options. The first is the
+// primary. State for interfaces not in the new set is dropped so their
+// stale scans stop contributing to the merge.
+func (ws *WiFiService) StartScanningInterfaces(ifaces []string) error {
+	return ws.StartScanningWithOptions(ifaces, ws.config.Get().Scan)
+}
+
+// StartScanningWithOptions is StartScanningInterfaces with opts in place
+// of the [scan] config until scanning stops. Options that validate would
+// adjust are an error here, as is a passive scan on a backend that can't
+// do one.
+func (ws *WiFiService) StartScanningWithOptions(ifaces []string, opts ScanOptions) error {
+	opts, notes := opts.validate()
+	if len(notes) > 0 {
+		return fmt.Errorf("scan options: %s", joinComma(notes))
+	}
+	seen := make(map[string]bool, len(ifaces))
+	var set []string
+	for _, iface := range ifaces {
+		iface = strings.TrimSpace(iface)
+		if iface != "" && !seen[iface] {
+			seen[iface] = true
+			set = append(set, iface)
+		}
+	}
+	if len(set) == 0 {
+		return fmt.Errorf("no interface to scan")
+	}
+	if opts.Passive {
+		for _, iface := range set {
+			if !ws.scanner.Capabilities(iface).PassiveScan {
+				return fmt.Errorf("%s: %w", iface, errPassiveScanUnsupported)
+			}
+		}
+	}
+
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.scanning {
+		return fmt.Errorf("scanning already in progress")
+	}
+
+	// Inherit from the Wails app context so app shutdown cancels scanning.
+	scanCtx, cancel := context.WithCancel(ws.appContext())
+
+	for iface, r := range ws.radios {
+		if !seen[iface] {
+			delete(ws.radios, iface)
+			continue
+		}
+		// Restarting a finished replay reports it finished again.
+		r.replayDone.Store(false)
+	}
+	ws.currentInterface = set[0]
+	ws.interfaces = set
+	ws.scanOptions = opts
+	ws.scanning = true
+	ws.cancelFunc = cancel
+
+	for _, iface := range set {
+		go ws.scanLoop(scanCtx, iface)
+	}
+
+	return nil
+}
+
+// ScanOptions returns the options of the current scanning session, or of
+// the last one once scanning stops.
+func (ws *WiFiService) ScanOptions() ScanOptions {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return ws.scanOptions
+}
+
+// ScanningInterfaces returns the interfaces being scanned, primary first.
+func (ws *WiFiService) ScanningInterfaces() []string {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	if len(ws.interfaces) == 0 && ws.currentInterface != "" {
+		return []string{ws.currentInterface}
+	}
+	return append([]string(nil), ws.interfaces...)
+}
+
+// GetInterfaceClientStats returns a client stats snapshot for every radio
+// that has been scanned, keyed by interface.
+func (ws *WiFiService) GetInterfaceClientStats() map[string]ClientStats {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	out := make(map[string]ClientStats, len(ws.radios))
+	for iface, r := range ws.radios {
+		out[iface] = r.cloneClientStats()
+	}
+	return out
+}
+
+// GetBackendCapabilities describes what the backend reports on each
+// interface being scanned, or on every available one while idle.
+func (ws *WiFiService) GetBackendCapabilities() []BackendCapabilities {
+	ifaces := ws.ScanningInterfaces()
+	if len(ifaces) == 0 {
+		ifaces, _ = ws.scanner.GetInterfaces(ws.appContext())
+	}
+	return ws.backendCapabilities(ifaces)
+}
+
+func (ws *WiFiService) backendCapabilities(ifaces []string) []BackendCapabilities {
+	out := make([]BackendCapabilities, 0, len(ifaces))
+	for _, iface := range ifaces {
+		out = append(out, ws.scanner.Capabilities(iface))
+	}
+	return out
+}
+
+// mergeRadiosLocked combines every radio's latest scan into one AP per
+// BSSID. The merged AP is the strongest reading, so Signal/Noise and the
+// IE-derived fields come from the radio that heard it best; a fresh
+// reading beats any stale one, so the AP is stale only when every radio's
+// reading is. Radios lists each interface's own reading. APs without a
+// BSSID pass through unmerged. Caller must hold ws.mu.
+func (ws *WiFiService) mergeRadiosLocked() []AccessPoint {
+	ifaces := make([]string, 0, len(ws.radios))
+	for iface := range ws.radios {
+		ifaces = append(ifaces, iface)
+	}
+	sort.Strings(ifaces)
+
+	var merged []AccessPoint
+	index := make(map[string]int)
+	for _, iface := range ifaces {
+		r := ws.radios[iface]
+		for _, ap := range r.aps {
+			reading := RadioReading{Interface: iface, Signal: ap.Signal, Noise: ap.Noise, LastSeen: ap.LastSeen, AgeMs: ap.AgeMs, Stale: ap.Stale}
+			if ap.BSSID == "" {
+				ap.Radios = []RadioReading{reading}
+				merged = append(merged, ap)
+				continue
+			}
+			i, ok := index[ap.BSSID]
+			if !ok {
+				ap.Radios = []RadioReading{reading}
+				index[ap.BSSID] = len(merged)
+				merged = append(merged, ap)
+				continue
+			}
+			radios := append(merged[i].Radios, reading)
+			better := ap.Signal > merged[i].Signal
+			if ap.Stale != merged[i].Stale {
+				better = !ap.Stale
+			}
+			if better {
+				merged[i] = ap
+			}
+			merged[i].Radios = radios
+		}
+	}
+	for i := range merged {
+		sort.SliceStable(merged[i].Radios, func(a, b int) bool {
+			return merged[i].Radios[a].Signal > merged[i].Radios[b].Signal
+		})
+	}
+	return merged
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMultiRadioMergePerBSSID(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	sim, err := openSimBackend(filepath.Join("testdata", "sim", "multi-radio.toml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ws := newWiFiServiceWithBackend(sim)
+	defer ws.Close()
+
+	for range 3 {
+		ws.performScan(context.Background(), "sim0")
+		ws.performScan(context.Background(), "sim1")
+	}
+
+	aps := make(map[string]AccessPoint)
+	for _, n := range ws.GetNetworks() {
+		for _, ap := range n.AccessPoints {
+			aps[ap.BSSID] = ap
+		}
+	}
+	if len(aps) != 2 {
+		t.Fatalf("merged %d APs, want one per BSSID (2): %+v", len(aps), aps)
+	}
+
+	ap5 := aps["02:00:00:00:02:02"]
+	if len(ap5.Radios) != 2 || ap5.Radios[0].Interface != "sim1" || ap5.Radios[1].Interface != "sim0" {
+		t.Fatalf("5 GHz AP radios = %+v, want sim1 then sim0", ap5.Radios)
+	}
+	if d := ap5.Radios[0].Signal - ap5.Radios[1].Signal; d != 4 {
+		t.Errorf("sim1 - sim0 signal = %d dB, want the 4 dB antenna gain", d)
+	}
+	if ap5.Signal != ap5.Radios[0].Signal {
+		t.Errorf("merged signal %d, want strongest reading %d", ap5.Signal, ap5.Radios[0].Signal)
+	}
+	if ap24 := aps["02:00:00:00:02:01"]; len(ap24.Radios) != 1 || ap24.Radios[0].Interface != "sim0" {
+		t.Errorf("2.4 GHz AP radios = %+v, want sim0 only", ap24.Radios)
+	}
+
+	// The primary (first scanned) radio is the one associated; the
+	// scan-only adapter still gets its own, disconnected, stats.
+	if !ws.GetClientStats().Connected {
+		t.Error("primary client stats not connected")
+	}
+	per := ws.GetInterfaceClientStats()
+	if len(per) != 2 || !per["sim0"].Connected || per["sim1"].Connected {
+		t.Errorf("per-interface client stats = %+v", per)
+	}
+}
+
+func TestStartScanningInterfacesValidation(t *testing.T) {
+	ws := runSimScenario(t, "multi-radio.toml", 0)
+	if err := ws.StartScanningInterfaces([]string{"", " "}); err == nil {
+		t.Error("empty interface list accepted")
+	}
+	if err := ws.StartScanningInterfaces([]string{"sim1", " sim0", "sim1"}); err != nil {
+		t.Fatal(err)
+	}
+	defer ws.StopScanning()
+	got := ws.ScanningInterfaces()
+	if len(got) != 2 || got[0] != "sim1" || got[1] != "sim0" {
+		t.Errorf("ScanningInterfaces = %v, want [sim1 sim0]", got)
+	}
+}
+
+func TestScanOptions(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	got, notes := ScanOptions{
+		SSIDs:       []string{" Office ", "Office", "", "a-name-that-is-longer-than-32-bytes"},
+		Frequencies: []int{5955, 100, 5955},
+		Channels:    []int{6, 20, 36},
+		DwellMs:     5000,
+	}.validate()
+	if len(got.SSIDs) != 1 || got.SSIDs[0] != "Office" || len(got.Frequencies) != 1 || len(got.Channels) != 2 ||
+		got.DwellMs != 1000 || len(notes) != 4 {
+		t.Errorf("validate = %+v, notes %q", got, notes)
+	}
+	if freqs := got.frequencies(); len(freqs) != 3 || freqs[0] != 2437 || freqs[1] != 5180 || freqs[2] != 5955 {
+		t.Errorf("frequencies = %v", freqs)
+	}
+	// Filtering copies: the input may be a published event's APs.
+	aps := []AccessPoint{{SSID: "Guest", Frequency: 2437}, {SSID: "Office", Frequency: 2437}}
+	if kept := got.filter(aps); len(kept) != 1 || kept[0].SSID != "Office" || aps[0].SSID != "Guest" || aps[1].SSID != "Office" {
+		t.Errorf("filter = %+v, input now %+v", kept, aps)
+	}
+
+	ws := runSimScenario(t, "congested-2g.toml", 0)
+	if err := ws.StartScanningWithOptions([]string{"sim0"}, ScanOptions{Channels: []int{20}}); err == nil {
+		t.Error("started with an invalid channel")
+	}
+	// Only the channel asked for is reported, whatever the backend returns.
+	ws.mu.Lock()
+	ws.scanOptions = ScanOptions{Channels: []int{3}}
+	ws.mu.Unlock()
+	ws.performScan(context.Background(), "sim0")
+	nets := ws.GetNetworks()
+	if len(nets) != 1 || nets[0].Channel != 3 {
+		t.Errorf("networks = %+v, want only the channel 3 one", nets)
+	}
+
+	// A backend that can't scan passively refuses rather than probing.
+	scripted := newWiFiServiceWithBackend(&scriptedBackend{})
+	defer scripted.Close()
+	if err := scripted.StartScanningWithOptions([]string{"wlan0"}, ScanOptions{Passive: true}); !errors.Is(err, errPassiveScanUnsupported) {
+		t.Errorf("passive scan on scripted backend: %v", err)
+	}
+}
+
+// slowLinkBackend blocks GetLinkInfo until released.
+type slowLinkBackend struct {
+	*scriptedBackend
+	entered, release chan struct{}
+}
+
+func (b slowLinkBackend) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	close(b.entered)
+	<-b.release
+	return b.scriptedBackend.GetLinkInfo(ctx, iface)
+}
+
+func TestSlowLinkQueryDoesNotBlockReaders(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	backend := slowLinkBackend{
+		scriptedBackend: &scriptedBackend{ticks: []scriptedTick{{aps: []AccessPoint{{BSSID: "aa:bb:cc:00:00:01", SSID: "Office", Signal: -55}}}}},
+		entered:         make(chan struct{}),
+		release:         make(chan struct{}),
+	}
+	ws := newWiFiServiceWithBackend(backend)
+	defer ws.Close()
+	done := make(chan struct{})
+	go func() {
+		ws.performScan(context.Background(), "wlan0")
+		close(done)
+	}()
+	<-backend.entered
+
+	read := make(chan struct{})
+	go func() {
+		ws.GetNetworks()
+		ws.GetClientStats()
+		close(read)
+	}()
+	select {
+	case <-read:
+	case <-time.After(2 * time.Second):
+		t.Error("readers blocked behind the link query")
+	}
+	close(backend.release)
+	<-done
+	if stats := ws.GetClientStats(); !stats.Connected || stats.SSID != "Office" {
+		t.Errorf("client stats = %+v", stats)
+	}
+}
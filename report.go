@@ -0,0 +1,1014 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html"
+	"html/template"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HTML diagnostic report. Built from the service state with html/template
+// so the desktop export, the CLI `report` command and scheduled runs all
+// produce the same document. The embedded templates/report.html is the
+// default; config.ReportTemplatePath points at a replacement (custom
+// branding for MSP deliverables) that receives the same ReportData and
+// template functions.
+
+//go:embed templates/report.html
+var reportTemplates embed.FS
+
+// ReportData is everything a report template can render. Field names are
+// part of the custom template contract: rename with care.
+type ReportData struct {
+	ID          string // "RPT-20240501-0930"
+	GeneratedAt time.Time
+	Connected   bool
+	Client      ClientStats
+	Network     *Network     // network of the connected AP; nil when not connected or not in the scan
+	AP          *AccessPoint // connected AP from the latest scan
+	Band        string       // "2.4 GHz", "5 GHz", "6 GHz", or "—"
+
+	Grade    ReportGrade
+	Findings []ReportFinding
+	Counts   ReportSeverityCounts
+	Signal   ReportSignalSummary
+
+	Survey   ReportSurvey
+	Networks []ReportNetworkRow // connected first, then strongest; at most reportMaxNetworks
+	Channels []ChannelInfo
+
+	Channel24  []ReportChannelCell // channels 1–14
+	Selected   ReportSelectedChannel
+	Roaming    RoamingQualityReport
+	Roams      []RoamingEvent // newest first, at most reportMaxRoams
+	RoamTone   string         // KPI tones: "ok", "warn" or "bad"
+	RetryTone  string
+	AvgRoam    string // average roam transition, formatted
+	Latency    []LatencyTargetSummary
+	Security   []NetworkSecurityAudit
+	Charts     ReportCharts    `json:"-"` // SVG markup, HTML only
+	Capability []ReportKV      // connected AP capabilities for the appendix
+	Sources    []ReportKV      // what the backend measures on each interface, for the appendix
+	Coverage   *ReportCoverage // latest floor-plan survey; nil when there is none
+}
+
+// ReportGrade is the overall verdict shown on the first page.
+type ReportGrade struct {
+	Grade string // "A+" … "F"
+	Score int    // 0–100
+	Tone  string // "ok", "warn", "bad"
+	Label string // "Healthy", "Needs attention", "Action required"
+}
+
+// ReportFinding is one recommendation. Severity is "bad", "warn", "info"
+// or "ok" (a passed check); Code numbers findings per severity (W-01…).
+type ReportFinding struct {
+	Severity    string
+	Code        string
+	Title       string
+	Description string
+	Action      string
+}
+
+// SeverityName is the label printed in the finding's severity column.
+func (f ReportFinding) SeverityName() string {
+	switch f.Severity {
+	case "bad":
+		return "Critical"
+	case "warn":
+		return "Warning"
+	case "info":
+		return "Info"
+	}
+	return "Pass"
+}
+
+type ReportSeverityCounts struct {
+	Critical, Warning, Info, Pass int
+}
+
+// ReportSignalSummary describes the connected AP's signal history. All
+// values are zero when Count is 0.
+type ReportSignalSummary struct {
+	Count                                            int
+	Min, Max, Avg, Stddev                            float64
+	PctExcellent, PctGood, PctFair, PctWeak, PctPoor float64
+}
+
+type ReportSurvey struct {
+	SSIDs, BSSIDs, Bands int
+	APs24, APs5, APs6    int
+}
+
+type ReportNetworkRow struct {
+	SSID      string // "(hidden)" for hidden networks
+	Hidden    bool
+	Connected bool
+	AP        AccessPoint // the network's strongest AP
+}
+
+type ReportChannelCell struct {
+	Channel int
+	APs     int
+	Level   string // "empty", "low", "medium", "high"
+}
+
+// ReportSelectedChannel summarises the connected channel.
+type ReportSelectedChannel struct {
+	Frequency string // "5.180 GHz"
+	APs       int
+	Strongest string
+	Average   string
+	Busiest24 int // 0 when no 2.4 GHz APs
+	Cleanest  int // least used of 1/6/11
+	Total24   int
+}
+
+// ReportCoverage summarizes the most recent survey with points placed on a
+// floor plan.
+type ReportCoverage struct {
+	Survey    string // survey name
+	SurveyID  string
+	Taken     time.Time
+	Threshold int            // dBm
+	Points    int            // placed points
+	Overall   float64        // best-signal coverage, %
+	SSIDs     []SSIDCoverage // best covered first
+	Heatmap   Heatmap        `json:"-"` // best signal of any network
+	SVG       template.HTML  `json:"-"`
+}
+
+// ReportCharts are pre-rendered inline SVG.
+type ReportCharts struct {
+	Signal       template.HTML
+	Distribution template.HTML
+	Spectrum5GHz template.HTML
+}
+
+type ReportKV struct{ Key, Value string }
+
+const (
+	reportMaxNetworks = 24
+	reportMaxRoams    = 8
+)
+
+// BuildReportData snapshots the service state for a report.
+func (ws *WiFiService) BuildReportData() ReportData {
+	d := buildReportData(time.Now(), ws.GetNetworks(), ws.GetClientStats(), ws.GetChannelAnalysis(),
+		ws.AnalyzeRoamingQuality(), ws.GetLatencySummaries(), ws.GetSecurityAudit())
+	d.Coverage = ws.reportCoverage()
+	d.Sources = reportSources(ws.GetBackendCapabilities())
+	return d
+}
+
+// reportCoverage picks the newest survey that has points on a floor plan.
+func (ws *WiFiService) reportCoverage() *ReportCoverage {
+	surveys, err := ws.ListSurveys()
+	if err != nil {
+		return nil
+	}
+	threshold := ws.coverageThreshold(0)
+	for _, info := range surveys {
+		sv, err := ws.OpenSurvey(info.ID)
+		if err != nil || sv.FloorPlan == nil {
+			continue
+		}
+		h, err := buildHeatmap(sv, HeatmapRequest{}, threshold)
+		if err != nil {
+			continue
+		}
+		return &ReportCoverage{
+			Survey: sv.Name, SurveyID: sv.ID, Taken: sv.Started, Threshold: threshold, Points: len(h.Points),
+			Overall: h.CoveragePct, SSIDs: surveyCoverage(sv, threshold), Heatmap: h,
+			SVG: template.HTML(heatmapSVG(h)),
+		}
+	}
+	return nil
+}
+
+func buildReportData(now time.Time, networks []Network, stats ClientStats, channels []ChannelInfo,
+	roaming RoamingQualityReport, latency []LatencyTargetSummary, security []NetworkSecurityAudit) ReportData {
+	d := ReportData{
+		ID:          "RPT-" + now.Format("20060102-1504"),
+		GeneratedAt: now,
+		Connected:   stats.Connected,
+		Client:      stats,
+		Band:        frequencyBand(stats.Frequency),
+		Channels:    channels,
+		Roaming:     roaming,
+		Latency:     latency,
+		Security:    security,
+	}
+	for i := range networks {
+		for j := range networks[i].AccessPoints {
+			if stats.BSSID != "" && strings.EqualFold(networks[i].AccessPoints[j].BSSID, stats.BSSID) {
+				d.Network, d.AP = &networks[i], &networks[i].AccessPoints[j]
+			}
+		}
+	}
+	d.Signal = summarizeSignal(stats.SignalHistory)
+	d.Survey = summarizeSurvey(networks)
+	d.Networks = reportNetworkRows(networks, stats.BSSID)
+	d.Channel24, d.Selected = reportChannels(networks, stats, d.AP)
+	d.Findings = reportFindings(d.Network, d.AP, networks, roaming)
+	for _, f := range d.Findings {
+		switch f.Severity {
+		case "bad":
+			d.Counts.Critical++
+		case "warn":
+			d.Counts.Warning++
+		case "info":
+			d.Counts.Info++
+		case "ok":
+			d.Counts.Pass++
+		}
+	}
+	avg := float64(stats.SignalAvg)
+	if d.Signal.Count > 0 {
+		avg = d.Signal.Avg
+	}
+	d.Grade = reportGrade(d.Counts, avg, d.Signal.Count > 0 || stats.SignalAvg != 0)
+
+	for i := len(stats.RoamingHistory) - 1; i >= 0 && len(d.Roams) < reportMaxRoams; i-- {
+		d.Roams = append(d.Roams, stats.RoamingHistory[i])
+	}
+	switch {
+	case roaming.BadRoams == 0 && roaming.TotalRoams > 0:
+		d.RoamTone = "ok"
+	case roaming.BadRoams > roaming.GoodRoams:
+		d.RoamTone = "bad"
+	default:
+		d.RoamTone = "warn"
+	}
+	switch {
+	case stats.RetryRate < 5:
+		d.RetryTone = "ok"
+	case stats.RetryRate < 10:
+		d.RetryTone = "warn"
+	default:
+		d.RetryTone = "bad"
+	}
+	d.AvgRoam = formatMs(float64(roaming.AvgRoamDurationMs))
+	d.Capability = reportCapabilities(d.AP)
+	d.Charts = ReportCharts{
+		Signal:       signalChartSVG(stats.SignalHistory),
+		Distribution: signalDistributionSVG(d.Signal),
+		Spectrum5GHz: spectrum5GHzSVG(networks, stats.BSSID, stats.Channel),
+	}
+	return d
+}
+
+func frequencyBand(mhz float64) string {
+	switch {
+	case mhz <= 0:
+		return "—"
+	case mhz >= 5925:
+		return "6 GHz"
+	case mhz >= 4900:
+		return "5 GHz"
+	}
+	return "2.4 GHz"
+}
+
+// signalQualityBucket maps dBm to the report's five quality bands.
+func signalQualityBucket(dbm float64) int {
+	switch {
+	case dbm >= -50:
+		return 0 // excellent
+	case dbm >= -60:
+		return 1 // good
+	case dbm >= -67:
+		return 2 // fair
+	case dbm >= -75:
+		return 3 // weak
+	}
+	return 4 // poor
+}
+
+func summarizeSignal(history []SignalDataPoint) ReportSignalSummary {
+	var s ReportSignalSummary
+	if len(history) == 0 {
+		return s
+	}
+	s.Count = len(history)
+	s.Min, s.Max = math.Inf(1), math.Inf(-1)
+	var sum float64
+	var buckets [5]int
+	for _, p := range history {
+		v := float64(p.Signal)
+		s.Min, s.Max = math.Min(s.Min, v), math.Max(s.Max, v)
+		sum += v
+		buckets[signalQualityBucket(v)]++
+	}
+	s.Avg = sum / float64(s.Count)
+	var variance float64
+	for _, p := range history {
+		variance += (float64(p.Signal) - s.Avg) * (float64(p.Signal) - s.Avg)
+	}
+	s.Stddev = math.Sqrt(variance / float64(s.Count))
+	pct := func(i int) float64 { return float64(buckets[i]) / float64(s.Count) * 100 }
+	s.PctExcellent, s.PctGood, s.PctFair, s.PctWeak, s.PctPoor = pct(0), pct(1), pct(2), pct(3), pct(4)
+	return s
+}
+
+func summarizeSurvey(networks []Network) ReportSurvey {
+	s := ReportSurvey{SSIDs: len(networks)}
+	bssids := make(map[string]bool)
+	for _, n := range networks {
+		for _, ap := range n.AccessPoints {
+			bssids[strings.ToLower(ap.BSSID)] = true
+			switch ap.Band {
+			case "2.4GHz":
+				s.APs24++
+			case "5GHz":
+				s.APs5++
+			case "6GHz":
+				s.APs6++
+			}
+		}
+	}
+	s.BSSIDs = len(bssids)
+	for _, c := range []int{s.APs24, s.APs5, s.APs6} {
+		if c > 0 {
+			s.Bands++
+		}
+	}
+	return s
+}
+
+func reportNetworkRows(networks []Network, bssid string) []ReportNetworkRow {
+	var rows []ReportNetworkRow
+	for _, n := range networks {
+		if len(n.AccessPoints) == 0 {
+			continue
+		}
+		best := n.AccessPoints[0]
+		for _, ap := range n.AccessPoints {
+			if ap.BSSID == n.BestSignalAP {
+				best = ap
+			}
+		}
+		row := ReportNetworkRow{SSID: n.SSID, AP: best, Hidden: n.SSID == ""}
+		row.Connected = bssid != "" && strings.EqualFold(best.BSSID, bssid)
+		if row.Hidden {
+			row.SSID = "(hidden)"
+		}
+		if best.Security == "" {
+			row.AP.Security = n.Security
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Connected != rows[j].Connected {
+			return rows[i].Connected
+		}
+		return rows[i].AP.Signal > rows[j].AP.Signal
+	})
+	if len(rows) > reportMaxNetworks {
+		rows = rows[:reportMaxNetworks]
+	}
+	return rows
+}
+
+func reportChannels(networks []Network, stats ClientStats, connected *AccessPoint) ([]ReportChannelCell, ReportSelectedChannel) {
+	counts := make(map[int]int)
+	var sel ReportSelectedChannel
+	var onSel []int
+	for _, n := range networks {
+		for _, ap := range n.AccessPoints {
+			if ap.Band == "2.4GHz" {
+				counts[ap.Channel]++
+				sel.Total24++
+			}
+			if connected != nil && ap.Channel == stats.Channel && ap.Band == connected.Band {
+				onSel = append(onSel, ap.Signal)
+			}
+		}
+	}
+	cells := make([]ReportChannelCell, 0, 14)
+	for ch := 1; ch <= 14; ch++ {
+		c := ReportChannelCell{Channel: ch, APs: counts[ch], Level: "empty"}
+		switch {
+		case c.APs > 4:
+			c.Level = "high"
+		case c.APs > 2:
+			c.Level = "medium"
+		case c.APs > 0:
+			c.Level = "low"
+		}
+		if c.APs > counts[sel.Busiest24] {
+			sel.Busiest24 = ch
+		}
+		cells = append(cells, c)
+	}
+	sel.Cleanest = 1
+	for _, ch := range []int{6, 11} {
+		if counts[ch] < counts[sel.Cleanest] {
+			sel.Cleanest = ch
+		}
+	}
+	sel.Frequency, sel.Strongest, sel.Average = "—", "—", "—"
+	if stats.Frequency > 0 {
+		sel.Frequency = fmt.Sprintf("%.3f GHz", stats.Frequency/1000)
+	}
+	sel.APs = len(onSel)
+	if len(onSel) > 0 {
+		strongest, sum := onSel[0], 0
+		for _, s := range onSel {
+			strongest = max(strongest, s)
+			sum += s
+		}
+		sel.Strongest = formatDbm(float64(strongest))
+		sel.Average = formatDbm(float64(sum) / float64(len(onSel)))
+	}
+	return cells, sel
+}
+
+// reportFindings derives the prioritized recommendations from the
+// connected AP's capabilities, the surrounding survey and roaming
+// behaviour.
+func reportFindings(network *Network, ap *AccessPoint, networks []Network, roaming RoamingQualityReport) []ReportFinding {
+	var out []ReportFinding
+	seq := map[string]int{}
+	add := func(sev, prefix, title, desc, action string) {
+		seq[prefix]++
+		out = append(out, ReportFinding{Severity: sev, Code: fmt.Sprintf("%s-%02d", prefix, seq[prefix]), Title: title, Description: desc, Action: action})
+	}
+
+	if ap != nil {
+		if ap.QAMSupport >= 1024 && !ap.OBSSPD {
+			add("warn", "W", "OBSS PD (Spatial Reuse) not enabled on primary AP",
+				fmt.Sprintf("The connected AP (%s) advertises WiFi 6 with BSS Color %d but does not enable OBSS PD. In dense environments this limits spatial reuse and can reduce effective throughput when co-channel APs transmit.", ap.BSSID, ap.BSSColor),
+				"Enable Spatial Reuse (OBSS PD) for 5 GHz on this AP.")
+		}
+		if network != nil && network.SSID != "" && len(network.AccessPoints) > 1 {
+			withK := 0
+			for _, a := range network.AccessPoints {
+				if a.NeighborReport {
+					withK++
+				}
+			}
+			if withK < len(network.AccessPoints) {
+				add("warn", "W", "802.11k neighbor reports incomplete",
+					fmt.Sprintf("%d of %d access points serving %s advertise 802.11k neighbor lists. Without them, clients fall back to full active scans before roaming, adding 80–150 ms per transition.", withK, len(network.AccessPoints), network.SSID),
+					"Enable 802.11k neighbor reports on every AP in the SSID group.")
+			}
+		}
+		counts := make(map[int]int)
+		total := 0
+		for _, n := range networks {
+			for _, a := range n.AccessPoints {
+				if a.Band == "2.4GHz" {
+					counts[a.Channel]++
+					total++
+				}
+			}
+		}
+		busiest := 0
+		for ch, c := range counts {
+			if c > counts[busiest] || c == counts[busiest] && ch < busiest {
+				busiest = ch
+			}
+		}
+		if total >= 4 && counts[busiest] >= 3 {
+			add("info", "I", "2.4 GHz band is congested",
+				fmt.Sprintf("%d APs are visible on 2.4 GHz; channel %d is the busiest with %d APs.", total, busiest, counts[busiest]),
+				"If IoT or guest devices on 2.4 GHz report dropouts, prefer ch 6 or 11 (cleanest non-overlapping).")
+		}
+		switch {
+		case strings.Contains(ap.Security, "WPA3") && ap.PMF == "Required" && !ap.WPS:
+			ciphers := strings.Join(ap.SecurityCiphers, ", ")
+			if ciphers == "" {
+				ciphers = "CCMP"
+			}
+			add("ok", "P", "Security posture is modern",
+				fmt.Sprintf("%s with PMF Required and %s cipher. WPS disabled. No action required.", ap.Security, ciphers), "")
+		case strings.EqualFold(ap.Security, "Open"):
+			add("bad", "C", "Connected AP is open / unencrypted",
+				"The connected SSID is open. All traffic is observable on the air; this is not safe for production use.",
+				"Disconnect immediately; configure WPA2 (minimum) or WPA3 on the AP.")
+		}
+	}
+
+	if roaming.SlowRoamCount > 0 {
+		add("warn", "W", fmt.Sprintf("%d slow roam%s (≥ 2 s) detected", roaming.SlowRoamCount, plural(roaming.SlowRoamCount)),
+			"Slow roams typically indicate authentication delay (full re-auth without 802.11r FT) or 802.1X RADIUS latency.",
+			"Verify FT is enabled across SSID; check RADIUS server latency if 802.1X is in use.")
+	}
+	if roaming.ExcessiveRoaming {
+		add("warn", "W", "Excessive roaming (more than 10/hr)",
+			"The client is changing APs more often than expected. Often caused by overlapping cell coverage or aggressive roaming thresholds.",
+			"Reduce AP transmit power or raise the client min-RSSI threshold.")
+	}
+	if roaming.StickyClient {
+		add("warn", "W", "Sticky-client behavior detected",
+			"The client is lingering on weak APs instead of roaming. Performance will suffer near the cell edge.",
+			"Lower the min-RSSI threshold on the AP, or enable 802.11k/v BSS Transition Management.")
+	}
+	return out
+}
+
+// reportGrade scores the findings and average signal.
+func reportGrade(c ReportSeverityCounts, signalAvg float64, haveSignal bool) ReportGrade {
+	score := 100 - c.Critical*25 - c.Warning*8 - c.Info*2
+	if haveSignal {
+		switch {
+		case signalAvg < -75:
+			score -= 20
+		case signalAvg < -67:
+			score -= 10
+		case signalAvg < -60:
+			score -= 4
+		}
+	}
+	score = max(0, score)
+	g := ReportGrade{Score: score, Tone: "ok"}
+	switch {
+	case score >= 95:
+		g.Grade = "A+"
+	case score >= 90:
+		g.Grade = "A"
+	case score >= 85:
+		g.Grade = "A−"
+	case score >= 80:
+		g.Grade = "B+"
+	case score >= 75:
+		g.Grade, g.Tone = "B", "warn"
+	case score >= 70:
+		g.Grade, g.Tone = "B−", "warn"
+	case score >= 60:
+		g.Grade, g.Tone = "C", "warn"
+	case score >= 50:
+		g.Grade, g.Tone = "D", "bad"
+	default:
+		g.Grade, g.Tone = "F", "bad"
+	}
+	g.Label = map[string]string{"ok": "Healthy", "warn": "Needs attention", "bad": "Action required"}[g.Tone]
+	return g
+}
+
+func reportCapabilities(ap *AccessPoint) []ReportKV {
+	if ap == nil {
+		return nil
+	}
+	opt := func(n int, format string) string {
+		if n <= 0 {
+			return "—"
+		}
+		return fmt.Sprintf(format, n)
+	}
+	optPtr := func(p *int, format string) string {
+		if p == nil {
+			return "—"
+		}
+		return fmt.Sprintf(format, *p)
+	}
+	mimo := "—"
+	if ap.MIMOStreams > 0 {
+		mimo = fmt.Sprintf("%d × %d", ap.MIMOStreams, ap.MIMOStreams)
+	}
+	return []ReportKV{
+		{"WiFi Standard", orDash(ap.WiFiStandard)},
+		{"WiFi Generation", orDash(ap.WiFiGeneration)},
+		{"BSS Transition (802.11v)", supported(ap.BSSTransition)},
+		{"Fast BSS Transition (.11r)", supported(ap.FastRoaming)},
+		{"Neighbor Report (802.11k)", supported(ap.NeighborReport)},
+		{"UAPSD (U-APSD)", supported(ap.UAPSD)},
+		{"PMF (802.11w)", orDash(ap.PMF)},
+		{"Auth methods", orDash(strings.Join(ap.AuthMethods, ", "))},
+		{"Encryption cipher", orDash(strings.Join(ap.SecurityCiphers, ", "))},
+		{"WPS", map[bool]string{true: "Enabled", false: "Disabled"}[ap.WPS]},
+		{"Max QAM", opt(ap.QAMSupport, "%d-QAM")},
+		{"MU-MIMO", supported(ap.MUMIMO)},
+		{"Beamforming", supported(ap.Beamforming)},
+		{"BSS Color", fmt.Sprint(ap.BSSColor)},
+		{"OBSS PD (Spatial Reuse)", supported(ap.OBSSPD)},
+		{"QoS (WMM)", supported(ap.QoSSupport)},
+		{"DTIM interval", opt(ap.DTIM, "%d")},
+		{"MIMO streams", mimo},
+		{"Max PHY rate", opt(ap.MaxPhyRate, "%d Mbps")},
+		{"BSS load utilization", optPtr(ap.BSSLoadUtilization, "%d %%")},
+		{"Connected stations", optPtr(ap.BSSLoadStations, "%d")},
+		{"Country code", orDash(ap.CountryCode)},
+	}
+}
+
+// ── Formatting helpers, also exposed to templates ───────────────────────
+
+func formatDbm(v float64) string { return fmt.Sprintf("%d dBm", int(math.Round(v))) }
+
+func formatMbps(v float64) string {
+	if v <= 0 {
+		return "—"
+	}
+	return fmt.Sprintf("%.1f Mbps", v)
+}
+
+func formatMs(v float64) string {
+	switch {
+	case v <= 0:
+		return "—"
+	case v < 1000:
+		return fmt.Sprintf("%d ms", int(math.Round(v)))
+	}
+	return fmt.Sprintf("%.1f s", v/1000)
+}
+
+func formatSeconds(s int) string {
+	h, m, sec := s/3600, s%3600/60, s%60
+	switch {
+	case h > 0:
+		return fmt.Sprintf("%dh %dm %ds", h, m, sec)
+	case m > 0:
+		return fmt.Sprintf("%dm %ds", m, sec)
+	}
+	return fmt.Sprintf("%ds", sec)
+}
+
+// formatCount renders n with thousands separators.
+func formatCount(n uint64) string {
+	s := fmt.Sprint(n)
+	for i := len(s) - 3; i > 0; i -= 3 {
+		s = s[:i] + "," + s[i:]
+	}
+	return s
+}
+
+// reportSources lists, per interface, the optional measurements its
+// backend provides. Anything missing from the list shows up in the report
+// as a zero or a dash, not as a finding.
+func reportSources(caps []BackendCapabilities) []ReportKV {
+	var kvs []ReportKV
+	for _, c := range caps {
+		var have []string
+		for _, m := range []struct {
+			ok   bool
+			name string
+		}{
+			{c.InformationElements, "beacon IEs"},
+			{c.Survey, "channel survey"},
+			{c.Noise, "noise floor"},
+			{c.StationRetries, "retry counters"},
+			{c.ActiveScan, "active scan"},
+			{c.PassiveScan, "passive scan"},
+		} {
+			if m.ok {
+				have = append(have, m.name)
+			}
+		}
+		value := orDash(strings.Join(have, ", "))
+		if len(c.Bands) > 0 {
+			value += " · " + strings.Join(c.Bands, ", ")
+		}
+		kvs = append(kvs, ReportKV{fmt.Sprintf("%s (%s)", c.Interface, c.Backend), value})
+	}
+	return kvs
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "—"
+	}
+	return s
+}
+
+func supported(b bool) string {
+	if b {
+		return "Supported"
+	}
+	return "Not supported"
+}
+
+func plural(n int) string {
+	if n == 1 {
+		return ""
+	}
+	return "s"
+}
+
+// signalTone matches the frontend's signalToneClass thresholds.
+func signalTone(dbm float64) string {
+	switch {
+	case dbm >= -60:
+		return "ok"
+	case dbm >= -72:
+		return "warn"
+	}
+	return "bad"
+}
+
+// signalBars renders the four-bar signal glyph used in the networks table.
+func signalBars(dbm int) template.HTML {
+	bars := 0
+	switch {
+	case dbm >= -55:
+		bars = 4
+	case dbm >= -65:
+		bars = 3
+	case dbm >= -75:
+		bars = 2
+	case dbm >= -85:
+		bars = 1
+	}
+	var b strings.Builder
+	b.WriteString(`<span class="sig-bar">`)
+	for i, h := range []int{3, 5, 7, 9} {
+		if i < bars {
+			fmt.Fprintf(&b, `<span class="on %s" style="height:%dpx"></span>`, signalTone(float64(dbm)), h)
+		} else {
+			fmt.Fprintf(&b, `<span style="height:%dpx"></span>`, h)
+		}
+	}
+	b.WriteString(`</span>`)
+	return template.HTML(b.String())
+}
+
+// reportFuncs are available to the default and custom templates.
+var reportFuncs = template.FuncMap{
+	"dbm":          func(v interface{}) string { return formatDbm(toFloat(v)) },
+	"mbps":         formatMbps,
+	"ms":           func(v interface{}) string { return formatMs(toFloat(v)) },
+	"pct":          func(v float64) string { return fmt.Sprintf("%.1f %%", v) },
+	"seconds":      formatSeconds,
+	"count":        formatCount,
+	"pad2":         func(n int) string { return fmt.Sprintf("%02d", n) },
+	"tone":         func(v interface{}) string { return signalTone(toFloat(v)) },
+	"bars":         signalBars,
+	"dash":         orDash,
+	"plural":       plural,
+	"join":         strings.Join,
+	"clock":        func(t time.Time) string { return t.Local().Format("15:04:05") },
+	"datetime":     func(t time.Time) string { return t.Local().Format("2006-01-02 15:04:05") },
+	"date":         func(t time.Time) string { return t.Local().Format("2006-01-02") },
+	"add":          func(a, b int) int { return a + b },
+	"sub":          func(a, b int) int { return a - b },
+	"contains":     strings.Contains,
+	"deref":        func(p *int) int { return *p },
+	"roamTone":     roamDurationTone,
+	"coverageTone": coverageTone,
+	"gradeTone": func(grade string) string {
+		switch grade {
+		case "A", "B":
+			return "ok"
+		case "C":
+			return "warn"
+		}
+		return "bad"
+	},
+	// dict builds the argument map for sub-templates: {{template "x" (dict "k" v)}}.
+	"dict": func(kv ...interface{}) (map[string]interface{}, error) {
+		if len(kv)%2 != 0 {
+			return nil, fmt.Errorf("dict: odd number of arguments")
+		}
+		m := make(map[string]interface{}, len(kv)/2)
+		for i := 0; i < len(kv); i += 2 {
+			k, ok := kv[i].(string)
+			if !ok {
+				return nil, fmt.Errorf("dict: key %v is not a string", kv[i])
+			}
+			m[k] = kv[i+1]
+		}
+		return m, nil
+	},
+}
+
+// roamDurationTone grades a roam transition time; 2 s and over counts as
+// a slow roam, as in AnalyzeRoamingQuality.
+func roamDurationTone(ms int64) string {
+	switch {
+	case ms <= 0:
+		return ""
+	case ms < 500:
+		return "ok"
+	case ms < 2000:
+		return "warn"
+	}
+	return "bad"
+}
+
+// coverageTone grades the covered share of a floor plan.
+func coverageTone(pct float64) string {
+	switch {
+	case pct >= 90:
+		return "ok"
+	case pct >= 70:
+		return "warn"
+	}
+	return "bad"
+}
+
+func toFloat(v interface{}) float64 {
+	switch v := v.(type) {
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case float64:
+		return v
+	}
+	return 0
+}
+
+// ── Charts ─────────────────────────────────────────────────────────────
+
+// signalChartSVG plots the connected AP's RSSI over the quality zones.
+func signalChartSVG(history []SignalDataPoint) template.HTML {
+	const w, h = 700.0, 180.0
+	const left, right, top, bottom = 40.0, 10.0, 10.0, 22.0
+	innerW, innerH := w-left-right, h-top-bottom
+	y := func(v float64) float64 { return top + (-30-v)/60*innerH }
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="100%%" height="%g" viewBox="0 0 %g %g" preserveAspectRatio="none" style="display:block">`, h, w, h)
+	for _, z := range []struct {
+		from, to float64
+		color    string
+		opacity  float64
+	}{
+		{-30, -50, "#15803d", 0.07}, {-50, -60, "#15803d", 0.025}, {-60, -67, "#a16207", 0.05},
+		{-67, -75, "#a16207", 0.10}, {-75, -90, "#b91c1c", 0.11},
+	} {
+		fmt.Fprintf(&b, `<rect x="%g" y="%.1f" width="%g" height="%.1f" fill="%s" opacity="%g"/>`, left, y(z.from), innerW, y(z.to)-y(z.from), z.color, z.opacity)
+	}
+	fmt.Fprintf(&b, `<line x1="%g" y1="%.1f" x2="%g" y2="%.1f" stroke="#a16207" stroke-width="0.8" stroke-dasharray="4,4" opacity="0.5"/>`, left, y(-67), w-right, y(-67))
+	fmt.Fprintf(&b, `<line x1="%g" y1="%.1f" x2="%g" y2="%.1f" stroke="#b91c1c" stroke-width="0.8" stroke-dasharray="4,4" opacity="0.5"/>`, left, y(-75), w-right, y(-75))
+	b.WriteString(`<g font-family="JetBrains Mono" font-size="9" fill="#6b7383" text-anchor="end">`)
+	for t := -30; t >= -90; t -= 10 {
+		fmt.Fprintf(&b, `<text x="%g" y="%.1f">%d</text>`, left-4, y(float64(t))+3, t)
+	}
+	b.WriteString(`</g><g font-family="JetBrains Mono" font-size="8" font-weight="600" text-anchor="end" opacity="0.75">`)
+	for _, l := range []struct {
+		at    float64
+		color string
+		text  string
+	}{{-40, "#15803d", "EXCELLENT"}, {-63.5, "#a16207", "FAIR"}, {-71, "#a16207", "WEAK"}, {-82, "#b91c1c", "POOR"}} {
+		fmt.Fprintf(&b, `<text x="%g" y="%.1f" fill="%s">%s</text>`, w-right-4, y(l.at)+3, l.color, l.text)
+	}
+	b.WriteString(`</g>`)
+	if n := len(history); n >= 2 {
+		x := func(i int) float64 { return left + float64(i)/float64(n-1)*innerW }
+		b.WriteString(`<path d="`)
+		for i, p := range history {
+			cmd := "L"
+			if i == 0 {
+				cmd = "M"
+			}
+			fmt.Fprintf(&b, "%s%.1f,%.1f ", cmd, x(i), y(float64(p.Signal)))
+		}
+		fmt.Fprintf(&b, `" fill="none" stroke="#0b6e74" stroke-width="1.8" stroke-linejoin="round"/><circle cx="%.1f" cy="%.1f" r="2.5" fill="#0b6e74"/>`,
+			x(n-1), y(float64(history[n-1].Signal)))
+	} else {
+		fmt.Fprintf(&b, `<text x="%g" y="%g" text-anchor="middle" fill="#9ca3af" font-family="Inter" font-size="11">Not enough samples to render — start a scan</text>`, w/2, h/2)
+	}
+	b.WriteString(`</svg>`)
+	return template.HTML(b.String())
+}
+
+// signalDistributionSVG is the stacked quality-band bar.
+func signalDistributionSVG(s ReportSignalSummary) template.HTML {
+	if s.Count == 0 {
+		return `<div class="mut" style="font-size:10.5px">No samples in current capture window.</div>`
+	}
+	const w = 700.0
+	pcts := []float64{s.PctExcellent, s.PctGood, s.PctFair, s.PctWeak, s.PctPoor}
+	colors := []struct {
+		color   string
+		opacity float64
+	}{{"#15803d", 0.8}, {"#15803d", 0.4}, {"#a16207", 0.7}, {"#a16207", 0.95}, {"#b91c1c", 0.7}}
+	labels := []string{"Excellent", "Good", "Fair", "Weak", "Poor"}
+	var rects, texts strings.Builder
+	x := 0.0
+	for i, p := range pcts {
+		bw := p / 100 * w
+		if bw > 0.5 {
+			fmt.Fprintf(&rects, `<rect x="%.1f" y="0" width="%.1f" height="24" fill="%s" opacity="%g"/>`, x, bw, colors[i].color, colors[i].opacity)
+			if bw >= 56 {
+				fmt.Fprintf(&texts, `<text x="%.1f" y="33">%s %.0f%%</text>`, x, labels[i], p)
+			}
+		}
+		x += bw
+	}
+	return template.HTML(fmt.Sprintf(`<svg width="100%%" height="34" viewBox="0 0 %g 34" preserveAspectRatio="none" style="display:block">%s<g font-family="JetBrains Mono" font-size="9" fill="#6b7383">%s</g></svg>`,
+		w, rects.String(), texts.String()))
+}
+
+var spectrum5GHzChannels = []int{36, 40, 44, 48, 52, 56, 60, 64, 100, 104, 108, 112, 116, 120, 124, 128, 132, 136, 140, 144, 149, 153, 157, 161, 165}
+
+// spectrum5GHzSVG draws each 5 GHz AP as a bell over its channel, height
+// by signal and width by channel width.
+func spectrum5GHzSVG(networks []Network, bssid string, connectedChannel int) template.HTML {
+	const w, h = 700.0, 80.0
+	const top, bottom = 8.0, 18.0
+	innerH := h - top - bottom
+	step := w / float64(len(spectrum5GHzChannels)-1)
+	base := top + innerH
+	xCh := func(ch int) float64 {
+		for i, c := range spectrum5GHzChannels {
+			if c == ch {
+				return float64(i) * step
+			}
+		}
+		return -1
+	}
+	var aps []AccessPoint
+	for _, n := range networks {
+		for _, ap := range n.AccessPoints {
+			if ap.Band == "5GHz" {
+				if ap.SSID == "" {
+					ap.SSID = n.SSID
+				}
+				aps = append(aps, ap)
+			}
+		}
+	}
+	sort.SliceStable(aps, func(i, j int) bool { return aps[i].Signal < aps[j].Signal }) // strongest drawn last
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg width="100%%" height="%g" viewBox="0 0 %g %g" preserveAspectRatio="none" style="display:block; margin-bottom:6px"><line x1="0" y1="%g" x2="%g" y2="%g" stroke="#cfd4dc"/>`, h, w, h, base, w, base)
+	drawn := 0
+	for _, ap := range aps {
+		cx := xCh(ap.Channel)
+		if cx < 0 {
+			continue
+		}
+		drawn++
+		cy := top + (-30-float64(ap.Signal))/60*innerH
+		width := float64(ap.ChannelWidth)
+		if width <= 0 {
+			width = 20
+		}
+		half := step * width / 20 / 2
+		conn := bssid != "" && strings.EqualFold(ap.BSSID, bssid)
+		color := map[string]string{"ok": "#15803d", "warn": "#a16207", "bad": "#b91c1c"}[signalTone(float64(ap.Signal))]
+		opacity, stroke, weight := 0.22, 1.2, 600
+		if conn {
+			color, opacity, stroke, weight = "#0b6e74", 0.3, 1.6, 700
+		}
+		fmt.Fprintf(&b, `<path d="M%.1f,%g C%.1f,%g %.1f,%.1f %.1f,%.1f C%.1f,%.1f %.1f,%g %.1f,%g Z" fill="%s" opacity="%g" stroke="%s" stroke-width="%g"/>`,
+			cx-half, base, cx-half*0.35, base, cx-half*0.45, cy, cx, cy, cx+half*0.45, cy, cx+half*0.35, base, cx+half, base, color, opacity, color, stroke)
+		label := ap.SSID
+		if r := []rune(label); len(r) > 14 {
+			label = string(r[:13]) + "…"
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%.1f" font-family="Inter" font-size="9" font-weight="%d" fill="%s" text-anchor="middle">%s · %d</text>`,
+			cx, cy-4, weight, color, html.EscapeString(label), ap.Signal)
+	}
+	if drawn == 0 {
+		fmt.Fprintf(&b, `<text x="%g" y="%g" text-anchor="middle" fill="#9ca3af" font-family="Inter" font-size="11">No 5 GHz APs detected.</text>`, w/2, h/2)
+	}
+	b.WriteString(`<g font-family="JetBrains Mono" font-size="8.5" fill="#6b7383" text-anchor="middle">`)
+	for _, ch := range []int{36, 44, 52, 60, 100, 108, 116, 132, 149, 157, 165} {
+		attrs := ""
+		if ch == connectedChannel {
+			attrs = ` fill="#0b6e74" font-weight="600"`
+		}
+		fmt.Fprintf(&b, `<text x="%.1f" y="%g"%s>%d</text>`, xCh(ch), base+14, attrs, ch)
+	}
+	b.WriteString(`</g></svg>`)
+	return template.HTML(b.String())
+}
+
+// ── Rendering ──────────────────────────────────────────────────────────
+
+// loadReportTemplate parses the custom template at path, or the embedded
+// default when path is empty.
+func loadReportTemplate(path string) (*template.Template, error) {
+	if path == "" {
+		return template.New("report.html").Funcs(reportFuncs).ParseFS(reportTemplates, "templates/report.html")
+	}
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("report template: %w", err)
+	}
+	t, err := template.New(filepath.Base(path)).Funcs(reportFuncs).Parse(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("report template %s: %w", filepath.Base(path), err)
+	}
+	return t, nil
+}
+
+// renderHTMLReport executes the report template (custom or default) into w.
+func renderHTMLReport(w io.Writer, data ReportData, templatePath string) error {
+	t, err := loadReportTemplate(templatePath)
+	if err != nil {
+		return err
+	}
+	return t.Execute(w, data)
+}
+
+// GenerateHTMLReport renders the current state with the configured report
+// template.
+func (ws *WiFiService) GenerateHTMLReport() (string, error) {
+	var buf bytes.Buffer
+	if err := renderHTMLReport(&buf, ws.BuildReportData(), ws.GetConfig().ReportTemplatePath); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,657 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+)
+
+// PDF diagnostic report. Lays out the same ReportData as the HTML report
+// (networks, channel analysis, roaming quality, latency, findings) on A4
+// pages with pdf.go, with vector charts for signal over time and channel
+// occupancy. The layout is fixed; custom branding goes through the HTML
+// template instead.
+
+var (
+	pdfInk    = pdfRGB(0x111827)
+	pdfInk2   = pdfRGB(0x374151)
+	pdfInk3   = pdfRGB(0x6b7383)
+	pdfRule   = pdfRGB(0xe4e7ec)
+	pdfZebra  = pdfRGB(0xf7f8fa)
+	pdfAccent = pdfRGB(0x0b6e74)
+	pdfWhite  = pdfRGB(0xffffff)
+)
+
+// pdfTones are the ok/warn/bad colours: text, and a light fill.
+var pdfTones = map[string][2]pdfColor{
+	"ok":   {pdfRGB(0x15803d), pdfRGB(0xe8f4ec)},
+	"warn": {pdfRGB(0xa16207), pdfRGB(0xfaf3e2)},
+	"bad":  {pdfRGB(0xb91c1c), pdfRGB(0xfbe9e9)},
+	"info": {pdfRGB(0x1d4ed8), pdfRGB(0xe8eefb)},
+	"":     {pdfInk, pdfZebra},
+}
+
+const (
+	pdfMarginX  = 40.0
+	pdfTop      = 78.0  // first content baseline below the page header
+	pdfBottom   = 790.0 // content must end above the footer
+	pdfContentW = pdfPageWidth - 2*pdfMarginX
+)
+
+// pdfReport is the layout cursor: the current page and how far down it
+// content has reached.
+type pdfReport struct {
+	doc  *pdfDocument
+	page *pdfPage
+	y    float64
+	d    ReportData
+}
+
+// pdfColumn is one table column; widths are fractions of the content width.
+type pdfColumn struct {
+	title string
+	width float64
+	right bool
+	mono  bool
+}
+
+// renderPDFReport writes the PDF report for d to w.
+func renderPDFReport(w io.Writer, d ReportData) error {
+	r := &pdfReport{doc: &pdfDocument{Title: "WiFi Diagnostic Report " + d.ID, Created: d.GeneratedAt}, d: d}
+	r.newPage()
+	r.summary()
+	r.findings()
+	r.newPage()
+	r.signalChart()
+	r.channelCharts()
+	r.networks()
+	r.roaming()
+	r.latency()
+	r.security()
+	r.coverage()
+	r.footers()
+	_, err := r.doc.WriteTo(w)
+	return err
+}
+
+// GeneratePDFReport renders the current state as a PDF report.
+func (ws *WiFiService) GeneratePDFReport() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := renderPDFReport(&buf, ws.BuildReportData()); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (r *pdfReport) newPage() {
+	r.page = r.doc.addPage()
+	p := r.page
+	p.rect(pdfMarginX, 36, 18, 18, pdfAccent)
+	p.text(pdfMarginX+3.5, 49, pdfHelveticaBold, 10, pdfWhite, "W")
+	p.text(pdfMarginX+26, 44, pdfHelveticaBold, 10, pdfInk, "WiFi Diagnostic")
+	p.text(pdfMarginX+26, 54, pdfCourier, 7, pdfInk3, r.d.ID)
+	p.textRight(pdfPageWidth-pdfMarginX, 44, pdfCourier, 7.5, pdfInk3, "Captured "+r.d.GeneratedAt.Local().Format("2006-01-02 15:04:05"))
+	p.textRight(pdfPageWidth-pdfMarginX, 54, pdfCourier, 7.5, pdfInk3, "Interface "+orDash(r.d.Client.Interface))
+	p.line(pdfMarginX, 62, pdfPageWidth-pdfMarginX, 62, 0.75, pdfInk)
+	r.y = pdfTop
+}
+
+// need starts a new page unless h more points fit on this one.
+func (r *pdfReport) need(h float64) {
+	if r.y+h > pdfBottom {
+		r.newPage()
+	}
+}
+
+func (r *pdfReport) footers() {
+	n := len(r.doc.pages)
+	for i, p := range r.doc.pages {
+		p.line(pdfMarginX, 806, pdfPageWidth-pdfMarginX, 806, 0.5, pdfRule)
+		p.text(pdfMarginX, 818, pdfCourier, 7, pdfInk3, "WiFi Diagnostic · "+r.d.ID)
+		p.textRight(pdfPageWidth-pdfMarginX, 818, pdfCourier, 7, pdfInk3, fmt.Sprintf("Confidential · Page %d of %d", i+1, n))
+	}
+}
+
+func (r *pdfReport) heading(num, title, aside string) {
+	r.need(60)
+	r.y += 14
+	r.page.text(pdfMarginX, r.y, pdfCourier, 8, pdfAccent, num)
+	r.page.text(pdfMarginX+22, r.y, pdfHelveticaBold, 13, pdfInk, title)
+	if aside != "" {
+		r.page.textRight(pdfPageWidth-pdfMarginX, r.y, pdfCourier, 7.5, pdfInk3, aside)
+	}
+	r.y += 6
+	r.page.line(pdfMarginX, r.y, pdfPageWidth-pdfMarginX, r.y, 0.5, pdfRule)
+	r.y += 14
+}
+
+func (r *pdfReport) subHeading(title string) {
+	r.need(30)
+	r.page.text(pdfMarginX, r.y, pdfHelveticaBold, 8.5, pdfInk3, title)
+	r.y += 12
+}
+
+// paragraph writes wrapped body text.
+func (r *pdfReport) paragraph(s string, c pdfColor) {
+	for _, line := range pdfWrap(pdfHelvetica, 9, s, pdfContentW) {
+		r.need(12)
+		r.page.text(pdfMarginX, r.y, pdfHelvetica, 9, c, line)
+		r.y += 12
+	}
+	r.y += 4
+}
+
+// keyValues lays kvs out in two columns.
+func (r *pdfReport) keyValues(kvs []ReportKV) {
+	colW := pdfContentW / 2
+	for i := 0; i < len(kvs); i += 2 {
+		r.need(15)
+		for j := 0; j < 2 && i+j < len(kvs); j++ {
+			x := pdfMarginX + float64(j)*colW
+			kv := kvs[i+j]
+			r.page.text(x, r.y, pdfHelvetica, 8.5, pdfInk3, kv.Key)
+			r.page.textRight(x+colW-12, r.y, pdfCourier, 8, pdfInk, pdfTruncate(pdfCourier, 8, kv.Value, colW*0.55))
+			r.page.line(x, r.y+4.5, x+colW-12, r.y+4.5, 0.4, pdfRule)
+		}
+		r.y += 15
+	}
+	r.y += 6
+}
+
+// table draws rows under a header, repeating the header after a page
+// break. tones, when non-nil, colours a row's cells ("" for default).
+func (r *pdfReport) table(cols []pdfColumn, rows [][]string, tones []string) {
+	const rowH, size = 15.0, 8.0
+	header := func() {
+		r.page.rect(pdfMarginX, r.y-10, pdfContentW, rowH, pdfZebra)
+		x := pdfMarginX
+		for _, c := range cols {
+			w := c.width * pdfContentW
+			if c.right {
+				r.page.textRight(x+w-4, r.y, pdfHelveticaBold, 7, pdfInk3, strings.ToUpper(c.title))
+			} else {
+				r.page.text(x+4, r.y, pdfHelveticaBold, 7, pdfInk3, strings.ToUpper(c.title))
+			}
+			x += w
+		}
+		r.y += rowH
+	}
+	r.need(2 * rowH)
+	header()
+	for i, row := range rows {
+		if r.y+rowH > pdfBottom {
+			r.newPage()
+			header()
+		}
+		color := pdfInk
+		if tones != nil && tones[i] != "" {
+			color = pdfTones[tones[i]][0]
+		}
+		x := pdfMarginX
+		for j, c := range cols {
+			w := c.width * pdfContentW
+			font := pdfHelvetica
+			if c.mono {
+				font = pdfCourier
+			}
+			cell := pdfTruncate(font, size, row[j], w-8)
+			if c.right {
+				r.page.textRight(x+w-4, r.y, font, size, color, cell)
+			} else {
+				r.page.text(x+4, r.y, font, size, color, cell)
+			}
+			x += w
+		}
+		r.page.line(pdfMarginX, r.y+4.5, pdfPageWidth-pdfMarginX, r.y+4.5, 0.4, pdfRule)
+		r.y += rowH
+	}
+	r.y += 10
+}
+
+// ── Sections ───────────────────────────────────────────────────────────
+
+func (r *pdfReport) summary() {
+	d, p := r.d, r.page
+	p.text(pdfMarginX, r.y+8, pdfCourier, 8, pdfAccent, "DIAGNOSTIC REPORT")
+	p.text(pdfMarginX, r.y+32, pdfHelveticaBold, 22, pdfInk, "Wireless network assessment")
+	sub := "No active connection"
+	if d.Connected {
+		sub = fmt.Sprintf("%s · %s / ch %d", d.Client.SSID, d.Band, d.Client.Channel)
+	}
+	p.text(pdfMarginX, r.y+54, pdfHelvetica, 13, pdfInk2, pdfTruncate(pdfHelvetica, 13, sub, pdfContentW))
+	r.y += 80
+
+	// Verdict: grade badge, then the headline.
+	grade := pdfTones[d.Grade.Tone]
+	p.rect(pdfMarginX, r.y, 96, 84, grade[1])
+	p.text(pdfMarginX+12, r.y+40, pdfHelveticaBold, 32, grade[0], d.Grade.Grade)
+	p.text(pdfMarginX+12, r.y+60, pdfHelveticaBold, 8, grade[0], strings.ToUpper(d.Grade.Label))
+	p.text(pdfMarginX+12, r.y+74, pdfCourier, 7.5, grade[0], fmt.Sprintf("Score %d / 100", d.Grade.Score))
+	headline := "Connection has issues that warrant attention."
+	switch {
+	case !d.Connected:
+		headline = "No active connection; only the environment survey applies."
+	case d.Grade.Tone == "ok":
+		headline = "Connection is strong and stable; review any non-critical findings."
+	}
+	p.text(pdfMarginX+112, r.y+18, pdfHelveticaBold, 11, pdfInk, headline)
+	recs := d.Counts.Critical + d.Counts.Warning
+	lines := pdfWrap(pdfHelvetica, 9, fmt.Sprintf("%d recommendation%s (%d critical). %d SSIDs and %d BSSIDs across %d band%s were surveyed.",
+		recs, plural(recs), d.Counts.Critical, d.Survey.SSIDs, d.Survey.BSSIDs, d.Survey.Bands, plural(d.Survey.Bands)), pdfContentW-112)
+	for i, line := range lines {
+		p.text(pdfMarginX+112, r.y+36+float64(i)*12, pdfHelvetica, 9, pdfInk2, line)
+	}
+	r.y += 100
+
+	// KPI row.
+	signal, signalSub, tone := "—", "no samples", ""
+	if d.Signal.Count > 0 {
+		signal = formatDbm(d.Signal.Avg)
+		signalSub = fmt.Sprintf("worst %.0f · best %.0f dBm", d.Signal.Min, d.Signal.Max)
+		tone = signalTone(d.Signal.Avg)
+	}
+	kpis := []struct{ label, value, sub, tone string }{
+		{"Signal (avg)", signal, signalSub, tone},
+		{"Link rate", formatMbps(d.Client.TxBitrate), "TX " + d.Client.WiFiStandard, ""},
+		{"Retries", fmt.Sprintf("%.1f %%", d.Client.RetryRate), "last 60 s", d.RetryTone},
+		{"Roaming", fmt.Sprintf("%d / %d", d.Roaming.GoodRoams, d.Roaming.TotalRoams), "good / total", d.RoamTone},
+	}
+	w := (pdfContentW - 3*8) / 4
+	for i, k := range kpis {
+		x := pdfMarginX + float64(i)*(w+8)
+		p.strokeRect(x, r.y, w, 58, 0.6, pdfRule)
+		p.text(x+10, r.y+16, pdfHelveticaBold, 7, pdfInk3, strings.ToUpper(k.label))
+		p.text(x+10, r.y+36, pdfHelveticaBold, 15, pdfTones[k.tone][0], k.value)
+		p.text(x+10, r.y+50, pdfCourier, 7, pdfInk3, pdfTruncate(pdfCourier, 7, k.sub, w-16))
+	}
+	r.y += 78
+}
+
+func (r *pdfReport) findings() {
+	c := r.d.Counts
+	r.heading("01", "Findings & recommendations",
+		fmt.Sprintf("%d critical · %d warning · %d info · %d pass", c.Critical, c.Warning, c.Info, c.Pass))
+	if len(r.d.Findings) == 0 {
+		r.paragraph("No findings.", pdfInk3)
+		return
+	}
+	textW := pdfContentW - 80
+	for _, f := range r.d.Findings {
+		desc := pdfWrap(pdfHelvetica, 8.5, f.Description, textW)
+		action := pdfWrap(pdfHelvetica, 8.5, f.Action, textW-40)
+		h := 18 + 11*float64(len(desc)+len(action)) + 8
+		r.need(h)
+		tone := pdfTones[f.Severity]
+		p, top := r.page, r.y
+		p.rect(pdfMarginX, top, 3, h-6, tone[0])
+		p.text(pdfMarginX+10, top+11, pdfHelveticaBold, 7.5, tone[0], strings.ToUpper(f.SeverityName()))
+		p.text(pdfMarginX+10, top+22, pdfCourier, 7, pdfInk3, f.Code)
+		p.text(pdfMarginX+80, top+11, pdfHelveticaBold, 9.5, pdfInk, pdfTruncate(pdfHelveticaBold, 9.5, f.Title, textW))
+		y := top + 24
+		for _, line := range desc {
+			p.text(pdfMarginX+80, y, pdfHelvetica, 8.5, pdfInk2, line)
+			y += 11
+		}
+		for i, line := range action {
+			if i == 0 {
+				p.text(pdfMarginX+80, y, pdfHelveticaBold, 7, pdfAccent, "ACTION")
+			}
+			p.text(pdfMarginX+120, y, pdfHelvetica, 8.5, pdfInk, line)
+			y += 11
+		}
+		r.y = top + h
+	}
+	r.y += 6
+}
+
+// signalChart plots the connected AP's RSSI over the quality zones.
+func (r *pdfReport) signalChart() {
+	s := r.d.Signal
+	r.heading("02", "Signal over time", fmt.Sprintf("%d sample%s", s.Count, plural(s.Count)))
+	const h = 170.0
+	r.need(h + 30)
+	p := r.page
+	left, top := pdfMarginX+30, r.y
+	w := pdfContentW - 30
+	y := func(dbm float64) float64 { return top + (-30-dbm)/60*h }
+
+	zones := []struct {
+		hi, lo float64
+		tone   string
+	}{{-30, -60, "ok"}, {-60, -67, ""}, {-67, -75, "warn"}, {-75, -90, "bad"}}
+	for _, z := range zones {
+		p.rect(left, y(z.hi), w, y(z.lo)-y(z.hi), pdfTones[z.tone][1])
+	}
+	for dbm := -30.0; dbm >= -90; dbm -= 10 {
+		p.line(left, y(dbm), left+w, y(dbm), 0.3, pdfRule)
+		p.textRight(left-4, y(dbm)+2.5, pdfCourier, 6.5, pdfInk3, fmt.Sprint(dbm))
+	}
+
+	history := r.d.Client.SignalHistory
+	if len(history) < 2 {
+		p.text(left+w/2-50, top+h/2, pdfHelvetica, 9, pdfInk3, "Not enough signal samples yet.")
+	} else {
+		t0, t1 := history[0].Timestamp, history[len(history)-1].Timestamp
+		span := t1.Sub(t0).Seconds()
+		pts := make([][2]float64, 0, len(history))
+		for i, pt := range history {
+			x := left + w*float64(i)/float64(len(history)-1)
+			if span > 0 {
+				x = left + w*pt.Timestamp.Sub(t0).Seconds()/span
+			}
+			v := math.Max(-90, math.Min(-30, float64(pt.Signal)))
+			pts = append(pts, [2]float64{x, y(v)})
+		}
+		p.polyline(pts, 1.2, pdfAccent)
+		for _, roam := range r.d.Roams {
+			if roam.Timestamp.Before(t0) || roam.Timestamp.After(t1) || span <= 0 {
+				continue
+			}
+			x := left + w*roam.Timestamp.Sub(t0).Seconds()/span
+			p.line(x, top, x, top+h, 0.6, pdfTones["warn"][0])
+		}
+		p.text(left, top+h+11, pdfCourier, 6.5, pdfInk3, t0.Local().Format("15:04:05"))
+		p.textRight(left+w, top+h+11, pdfCourier, 6.5, pdfInk3, t1.Local().Format("15:04:05"))
+	}
+	p.strokeRect(left, top, w, h, 0.5, pdfRule)
+	r.y += h + 26
+	if s.Count > 0 {
+		r.page.text(pdfMarginX, r.y, pdfCourier, 7.5, pdfInk2, fmt.Sprintf(
+			"MIN %s   MAX %s   AVG %s   SD %.1f dB   EXCELLENT %.0f%%  GOOD %.0f%%  FAIR %.0f%%  WEAK %.0f%%  POOR %.0f%%",
+			formatDbm(s.Min), formatDbm(s.Max), formatDbm(s.Avg), s.Stddev,
+			s.PctExcellent, s.PctGood, s.PctFair, s.PctWeak, s.PctPoor))
+		r.y += 18
+	}
+}
+
+// channelCharts draws APs per channel for each band, coloured by the
+// channel analysis congestion level, with the connected channel outlined.
+func (r *pdfReport) channelCharts() {
+	r.heading("03", "Channel occupancy", fmt.Sprintf("%d channel%s in use", len(r.d.Channels), plural(len(r.d.Channels))))
+	byBand := map[string][]ChannelInfo{}
+	for _, c := range r.d.Channels {
+		band := frequencyBand(float64(c.Frequency))
+		if c.Frequency == 0 {
+			band = strings.Replace(c.Band, "GHz", " GHz", 1)
+		}
+		byBand[band] = append(byBand[band], c)
+	}
+	// 2.4 GHz always shows channels 1–13 so gaps are visible.
+	ch24 := map[int]ChannelInfo{}
+	for _, c := range byBand["2.4 GHz"] {
+		ch24[c.Channel] = c
+	}
+	full24 := make([]ChannelInfo, 0, 13)
+	for ch := 1; ch <= 13; ch++ {
+		c, ok := ch24[ch]
+		if !ok {
+			c = ChannelInfo{Channel: ch}
+		}
+		full24 = append(full24, c)
+	}
+	if c, ok := ch24[14]; ok {
+		full24 = append(full24, c)
+	}
+	r.channelBars("2.4 GHz", full24)
+	// 5 and 6 GHz are drawn only when APs were seen there.
+	for _, band := range []string{"5 GHz", "6 GHz"} {
+		if cs := byBand[band]; len(cs) > 0 {
+			sort.Slice(cs, func(i, j int) bool { return cs[i].Channel < cs[j].Channel })
+			r.channelBars(band, cs)
+		}
+	}
+
+	var rows [][]string
+	var tones []string
+	for _, c := range r.d.Channels {
+		rows = append(rows, []string{
+			fmt.Sprint(c.Channel), orDash(c.Band), fmt.Sprint(c.NetworkCount), fmt.Sprint(c.OverlappingCount),
+			fmt.Sprintf("%d %%", c.Utilization), orDash(c.CongestionLevel), strings.Join(c.Networks, ", "),
+		})
+		tones = append(tones, map[string]string{"high": "bad", "medium": "warn"}[c.CongestionLevel])
+	}
+	if len(rows) > 0 {
+		r.subHeading("Channel analysis")
+		r.table([]pdfColumn{
+			{title: "Ch", width: 0.07, right: true, mono: true}, {title: "Band", width: 0.1, mono: true},
+			{title: "Networks", width: 0.1, right: true}, {title: "Overlap", width: 0.1, right: true},
+			{title: "Util", width: 0.09, right: true}, {title: "Congestion", width: 0.13}, {title: "SSIDs", width: 0.41},
+		}, rows, tones)
+	}
+}
+
+func (r *pdfReport) channelBars(band string, channels []ChannelInfo) {
+	const h = 70.0
+	r.need(h + 44)
+	r.subHeading(band)
+	p, top := r.page, r.y
+	peak := 4
+	for _, c := range channels {
+		peak = max(peak, c.NetworkCount)
+	}
+	slot := pdfContentW / float64(len(channels))
+	barW := math.Min(22, slot*0.7)
+	for i, c := range channels {
+		cx := pdfMarginX + slot*(float64(i)+0.5)
+		if c.NetworkCount > 0 {
+			bh := h * float64(c.NetworkCount) / float64(peak)
+			tone := map[string]string{"high": "bad", "medium": "warn", "low": "ok"}[c.CongestionLevel]
+			fill := pdfTones[tone][1]
+			if tone == "" {
+				fill = pdfRule
+			}
+			p.rect(cx-barW/2, top+h-bh, barW, bh, fill)
+			p.textRight(cx+pdfTextWidth(pdfCourier, 7, fmt.Sprint(c.NetworkCount))/2, top+h-bh-3, pdfCourier, 7, pdfInk2, fmt.Sprint(c.NetworkCount))
+		}
+		label := pdfInk3
+		if r.d.Connected && c.Channel == r.d.Client.Channel && frequencyBand(r.d.Client.Frequency) == band {
+			p.strokeRect(cx-barW/2-1.5, top-2, barW+3, h+4, 1, pdfAccent)
+			label = pdfAccent
+		}
+		p.textRight(cx+pdfTextWidth(pdfCourier, 6.5, fmt.Sprint(c.Channel))/2, top+h+10, pdfCourier, 6.5, label, fmt.Sprint(c.Channel))
+	}
+	p.line(pdfMarginX, top+h, pdfPageWidth-pdfMarginX, top+h, 0.5, pdfInk3)
+	r.y += h + 24
+}
+
+func (r *pdfReport) networks() {
+	s := r.d.Survey
+	r.heading("04", "Detected networks", fmt.Sprintf("%d SSIDs · %d BSSIDs · %d band%s", s.SSIDs, s.BSSIDs, s.Bands, plural(s.Bands)))
+	if len(r.d.Networks) == 0 {
+		r.paragraph("No networks detected — start a scan first.", pdfInk3)
+		return
+	}
+	var rows [][]string
+	var tones []string
+	for _, n := range r.d.Networks {
+		ssid := n.SSID
+		if n.Connected {
+			ssid = "• " + ssid
+		}
+		rows = append(rows, []string{
+			ssid, n.AP.BSSID, orDash(n.AP.Band), fmt.Sprint(n.AP.Channel), formatDbm(float64(n.AP.Signal)),
+			fmt.Sprint(n.AP.ChannelWidth), orDash(n.AP.Security), orDash(n.AP.Vendor),
+		})
+		tone := ""
+		switch {
+		case n.Connected:
+			tone = "info"
+		case n.AP.Security == "Open":
+			tone = "bad"
+		}
+		tones = append(tones, tone)
+	}
+	r.table([]pdfColumn{
+		{title: "SSID", width: 0.2}, {title: "BSSID", width: 0.19, mono: true}, {title: "Band", width: 0.08, mono: true},
+		{title: "Ch", width: 0.05, right: true, mono: true}, {title: "Signal", width: 0.1, right: true, mono: true},
+		{title: "Width", width: 0.07, right: true, mono: true}, {title: "Security", width: 0.15}, {title: "Vendor", width: 0.16},
+	}, rows, tones)
+}
+
+func (r *pdfReport) roaming() {
+	q := r.d.Roaming
+	r.heading("05", "Roaming & mobility", fmt.Sprintf("%d event%s", q.TotalRoams, plural(q.TotalRoams)))
+	yesNo := map[bool]string{true: "Yes", false: "No"}
+	r.keyValues([]ReportKV{
+		{"Total roams", fmt.Sprint(q.TotalRoams)},
+		{"Good / marginal", fmt.Sprintf("%d / %d", q.GoodRoams, q.BadRoams)},
+		{"Avg transition", r.d.AvgRoam},
+		{"Max transition", formatMs(float64(q.MaxRoamDurationMs))},
+		{"Slow roams (≥ 2 s)", fmt.Sprint(q.SlowRoamCount)},
+		{"Avg signal change", fmt.Sprintf("%+d dB", q.AvgSignalChange)},
+		{"Excessive roaming", yesNo[q.ExcessiveRoaming]},
+		{"Sticky client", yesNo[q.StickyClient]},
+	})
+	if q.RoamingAdvice != "" {
+		r.paragraph(q.RoamingAdvice, pdfInk2)
+	}
+	if len(r.d.Roams) == 0 {
+		return
+	}
+	r.subHeading("Roam timeline")
+	var rows [][]string
+	var tones []string
+	for _, e := range r.d.Roams {
+		delta := e.NewSignal - e.PreviousSignal
+		verdict, tone := "Regression", "bad"
+		switch {
+		case delta >= 6:
+			verdict, tone = "Good", "ok"
+		case delta >= 0:
+			verdict, tone = "Marginal", "warn"
+		}
+		rows = append(rows, []string{
+			e.Timestamp.Local().Format("15:04:05"), orDash(e.PreviousBSSID), orDash(e.NewBSSID),
+			fmt.Sprint(e.PreviousSignal), fmt.Sprint(e.NewSignal), fmt.Sprintf("%+d", delta), formatMs(float64(e.DurationMs)), verdict,
+		})
+		tones = append(tones, tone)
+	}
+	r.table([]pdfColumn{
+		{title: "Time", width: 0.1, mono: true}, {title: "From", width: 0.2, mono: true}, {title: "To", width: 0.2, mono: true},
+		{title: "Before", width: 0.09, right: true, mono: true}, {title: "After", width: 0.09, right: true, mono: true},
+		{title: "Delta", width: 0.07, right: true, mono: true}, {title: "Duration", width: 0.11, right: true, mono: true},
+		{title: "Verdict", width: 0.14},
+	}, rows, tones)
+}
+
+func (r *pdfReport) latency() {
+	r.heading("06", "Latency", fmt.Sprintf("%d target%s", len(r.d.Latency), plural(len(r.d.Latency))))
+	var rows [][]string
+	var tones []string
+	for _, t := range r.d.Latency {
+		for _, w := range t.Windows {
+			rows = append(rows, []string{
+				t.Label, orDash(t.Target), fmt.Sprintf("%d s", w.WindowSeconds), fmt.Sprint(w.Samples),
+				fmt.Sprintf("%.1f", w.MinMs), fmt.Sprintf("%.1f", w.AvgMs), fmt.Sprintf("%.1f", w.MaxMs),
+				fmt.Sprintf("%.1f", w.StddevMs), fmt.Sprintf("%.1f %%", w.LossPercent),
+			})
+			tone := ""
+			switch {
+			case w.LossPercent >= 5:
+				tone = "bad"
+			case w.LossPercent > 0:
+				tone = "warn"
+			}
+			tones = append(tones, tone)
+		}
+	}
+	if len(rows) == 0 {
+		r.paragraph("No latency samples; add targets under latency_targets in Settings.", pdfInk3)
+		return
+	}
+	r.table([]pdfColumn{
+		{title: "Target", width: 0.14}, {title: "Address", width: 0.18, mono: true}, {title: "Window", width: 0.08, right: true, mono: true},
+		{title: "Samples", width: 0.09, right: true, mono: true}, {title: "Min ms", width: 0.09, right: true, mono: true},
+		{title: "Avg ms", width: 0.09, right: true, mono: true}, {title: "Max ms", width: 0.09, right: true, mono: true},
+		{title: "Jitter", width: 0.11, right: true, mono: true}, {title: "Loss", width: 0.13, right: true, mono: true},
+	}, rows, tones)
+}
+
+func (r *pdfReport) security() {
+	r.heading("07", "Security posture", fmt.Sprintf("%d network%s audited", len(r.d.Security), plural(len(r.d.Security))))
+	if len(r.d.Security) == 0 {
+		r.paragraph("No networks scanned.", pdfInk3)
+		return
+	}
+	var rows [][]string
+	var tones []string
+	for _, a := range r.d.Security {
+		ssid := a.SSID
+		if ssid == "" {
+			ssid = "(hidden)"
+		}
+		titles := make([]string, 0, len(a.Findings))
+		for _, f := range a.Findings {
+			titles = append(titles, f.Title)
+		}
+		rows = append(rows, []string{ssid, orDash(a.Security), fmt.Sprint(a.APCount),
+			fmt.Sprintf("%s · %d", a.Grade, a.Score), orDash(strings.Join(titles, "; "))})
+		tones = append(tones, map[string]string{"A": "", "B": "", "C": "warn"}[a.Grade])
+		if a.Grade == "D" || a.Grade == "F" {
+			tones[len(tones)-1] = "bad"
+		}
+	}
+	r.table([]pdfColumn{
+		{title: "Network", width: 0.2}, {title: "Security", width: 0.14}, {title: "APs", width: 0.06, right: true, mono: true},
+		{title: "Grade", width: 0.1, mono: true}, {title: "Findings", width: 0.5},
+	}, rows, tones)
+}
+
+// coverage draws the latest floor-plan survey's best-signal heatmap (on
+// white, the plan image isn't embedded) and per-SSID coverage.
+func (r *pdfReport) coverage() {
+	c := r.d.Coverage
+	if c == nil {
+		return
+	}
+	r.heading("08", "Coverage survey", fmt.Sprintf("%s · %d point%s", c.Survey, c.Points, plural(c.Points)))
+	h := c.Heatmap
+	scale := math.Min(pdfContentW/float64(h.Width), 320/float64(h.Height))
+	w, ht := float64(h.Width)*scale, float64(h.Height)*scale
+	r.need(ht + 40)
+	p, left, top := r.page, pdfMarginX, r.y
+	// The overlay's alpha, pre-blended onto white.
+	blend := func(v float64) pdfColor {
+		col, a := heatmapColor(v), heatmapAlpha/255.0
+		mix := func(x uint8) float64 { return 1 - a + a*float64(x)/255 }
+		return pdfColor{mix(col.R), mix(col.G), mix(col.B)}
+	}
+	cell := float64(h.CellSize) * scale
+	for row := 0; row < h.Rows; row++ {
+		for col := 0; col < h.Cols; {
+			fill := heatmapColor(h.Values[row*h.Cols+col])
+			run := 1
+			for col+run < h.Cols && heatmapColor(h.Values[row*h.Cols+col+run]) == fill {
+				run++
+			}
+			x, y := left+float64(col)*cell, top+float64(row)*cell
+			p.rect(x, y, math.Min(float64(run)*cell, left+w-x), math.Min(cell, top+ht-y), blend(h.Values[row*h.Cols+col]))
+			col += run
+		}
+	}
+	for _, pt := range h.Points {
+		p.circle(left+pt.X*scale, top+pt.Y*scale, 3, pdfWhite)
+		p.circle(left+pt.X*scale, top+pt.Y*scale, 2.2, pdfInk)
+	}
+	p.strokeRect(left, top, w, ht, 0.5, pdfRule)
+	r.y += ht + 16
+	r.page.text(pdfMarginX, r.y, pdfCourier, 7.5, pdfTones[coverageTone(c.Overall)][0], fmt.Sprintf(
+		"COVERED AT OR ABOVE %d dBm: %.1f%% (BEST SIGNAL, ANY NETWORK)", c.Threshold, c.Overall))
+	r.y += 18
+
+	var rows [][]string
+	var tones []string
+	for _, s := range c.SSIDs {
+		rows = append(rows, []string{s.SSID, fmt.Sprint(s.Points), fmt.Sprintf("%.1f %%", s.CoveragePct)})
+		tones = append(tones, coverageTone(s.CoveragePct))
+	}
+	if len(rows) > 0 {
+		r.table([]pdfColumn{
+			{title: "Network", width: 0.6}, {title: "Points heard", width: 0.2, right: true, mono: true},
+			{title: "Covered", width: 0.2, right: true, mono: true},
+		}, rows, tones)
+	}
+}
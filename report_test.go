@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReportFindingsAndGrade(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	cafe := Network{SSID: "Cafe", Security: "Open", AccessPoints: []AccessPoint{
+		{SSID: "Cafe", BSSID: "aa:bb:cc:00:00:01", Security: "Open", Signal: -70, Channel: 36, Band: "5GHz"},
+		{SSID: "Cafe", BSSID: "aa:bb:cc:00:00:02", Security: "Open", Signal: -80, Channel: 6, Band: "2.4GHz", NeighborReport: true},
+	}}
+	stats := ClientStats{
+		Connected: true, Interface: "wlan0", SSID: "Cafe", BSSID: "AA:BB:CC:00:00:01", Channel: 36, Frequency: 5180,
+		SignalHistory: []SignalDataPoint{{Timestamp: now.Add(-time.Minute), Signal: -68}, {Timestamp: now, Signal: -72}},
+	}
+	d := buildReportData(now, []Network{cafe}, stats, nil, RoamingQualityReport{TotalRoams: 1, BadRoams: 1, SlowRoamCount: 1}, nil, nil)
+
+	if d.ID != "RPT-20240501-0930" || d.AP == nil || d.AP.BSSID != "aa:bb:cc:00:00:01" || d.Band != "5 GHz" {
+		t.Fatalf("ID %q, AP %+v, band %q", d.ID, d.AP, d.Band)
+	}
+	var codes []string
+	for _, f := range d.Findings {
+		codes = append(codes, f.Code)
+	}
+	if got := strings.Join(codes, ","); got != "W-01,C-01,W-02" {
+		t.Errorf("finding codes = %s, want W-01 (802.11k), C-01 (open), W-02 (slow roam)", got)
+	}
+	if d.Counts != (ReportSeverityCounts{Critical: 1, Warning: 2}) {
+		t.Errorf("counts = %+v", d.Counts)
+	}
+	// 100 − 25 − 2×8 − 10 for a −70 dBm average.
+	if d.Grade.Score != 49 || d.Grade.Grade != "F" || d.Grade.Tone != "bad" {
+		t.Errorf("grade = %+v", d.Grade)
+	}
+	if d.Signal.Min != -72 || d.Signal.Max != -68 || d.Signal.Avg != -70 {
+		t.Errorf("signal summary = %+v", d.Signal)
+	}
+}
+
+func TestReportDefaultTemplate(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 21)
+	out, err := ws.GenerateHTMLReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := strings.Count(out, `<div class="page">`); n != 6 {
+		t.Errorf("%d pages, want 6", n)
+	}
+	for _, want := range []string{"02:00:00:00:01:01", "02:00:00:00:01:02", "Roam timeline", "<svg", "sim0 (sim)"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("report missing %q", want)
+		}
+	}
+	if strings.Contains(out, "ZgotmplZ") {
+		t.Error("html/template rejected a value (ZgotmplZ in output)")
+	}
+}
+
+func TestReportCustomTemplate(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 3)
+	dir := t.TempDir()
+	custom := filepath.Join(dir, "acme.html")
+	src := `<h1>Acme MSP</h1>{{range .Networks}}<p>{{.SSID}} {{dbm .AP.Signal}}</p>{{end}}<p>{{.Grade.Grade}}</p>`
+	if err := os.WriteFile(custom, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := ws.GetConfig()
+	cfg.ReportTemplatePath = custom
+	ws.config.Set(cfg)
+
+	out, err := ws.GenerateHTMLReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "<h1>Acme MSP</h1><p>") || !strings.Contains(out, " dBm</p>") {
+		t.Errorf("custom report = %q", out)
+	}
+
+	broken := filepath.Join(dir, "broken.html")
+	_ = os.WriteFile(broken, []byte(`{{.NoSuchField}}`), 0o644)
+	if err := renderHTMLReport(&bytes.Buffer{}, ws.BuildReportData(), broken); err == nil {
+		t.Error("unknown field rendered without error")
+	}
+	if err := renderHTMLReport(&bytes.Buffer{}, ReportData{}, filepath.Join(dir, "missing.html")); err == nil {
+		t.Error("missing template rendered without error")
+	}
+}
+
+func TestPDFReport(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 21)
+	pdf, err := ws.GeneratePDFReport()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(pdf, []byte("%%EOF\n")) {
+		t.Fatalf("not a PDF: %q…%q", pdf[:16], pdf[len(pdf)-16:])
+	}
+
+	// Every xref entry must point at its object.
+	m := regexp.MustCompile(`startxref\n(\d+)`).FindSubmatch(pdf)
+	xref, _ := strconv.Atoi(string(m[1]))
+	entries := regexp.MustCompile(`(\d{10}) 00000 n`).FindAllSubmatch(pdf[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		if !bytes.HasPrefix(pdf[off:], []byte(fmt.Sprintf("%d 0 obj", i+1))) {
+			t.Fatalf("xref entry %d points at %q", i+1, pdf[off:off+12])
+		}
+	}
+
+	// Look for the section text in the page contents.
+	text := pdfContent(t, pdf)
+	pages := regexp.MustCompile(`/Count (\d+)`).FindSubmatch(pdf)
+	for _, want := range []string{"(Signal over time)", "(Channel occupancy)", "(Roam timeline)", "(Latency)", "(02:00:00:00:01:02)",
+		fmt.Sprintf("(Confidential \xb7 Page %s of %s)", pages[1], pages[1])} {
+		if !strings.Contains(text, want) {
+			t.Errorf("PDF text missing %q", want)
+		}
+	}
+}
+
+// pdfContent inflates and concatenates the document's content streams.
+func pdfContent(t *testing.T, pdf []byte) string {
+	t.Helper()
+	var text strings.Builder
+	streams := regexp.MustCompile(`/Length (\d+) /Filter /FlateDecode >>\nstream\n`)
+	for _, loc := range streams.FindAllSubmatchIndex(pdf, -1) {
+		n, _ := strconv.Atoi(string(pdf[loc[2]:loc[3]]))
+		zr, err := zlib.NewReader(bytes.NewReader(pdf[loc[1] : loc[1]+n]))
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := io.ReadAll(zr)
+		text.Write(b)
+	}
+	return text.String()
+}
+
+func TestPDFText(t *testing.T) {
+	if got := string(pdfEscape(pdfEncodeText("ch (36) — ≥ 2 s\\ σ"))); got != "ch \\(36\\) \x97 >= 2 s\\\\ sd" {
+		t.Errorf("encoded = %q", got)
+	}
+	if w := pdfTextWidth(pdfHelvetica, 10, "Wi"); w != (944+222)*10/1000.0 {
+		t.Errorf("width = %v", w)
+	}
+	if got := pdfTruncate(pdfCourier, 10, "abcdefghij", 36); got != "abcde…" {
+		t.Errorf("truncate = %q", got)
+	}
+	if got := pdfWrap(pdfCourier, 10, "one two three four", 60); strings.Join(got, "|") != "one two|three four" {
+		t.Errorf("wrap = %q", got)
+	}
+}
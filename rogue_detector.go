@@ -0,0 +1,427 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Rogue / evil-twin detection. For every SSID the detector learns a
+// profile of what the legitimate network looks like — BSSIDs, OUIs,
+// channels, AKMs and whether PMF is required — during a learning window
+// after the SSID is first seen (config.RogueLearningMinutes). After that,
+// an AP advertising the SSID that doesn't fit the profile raises a
+// RogueFinding: unknown BSSID, a different vendor OUI, a downgraded AKM,
+// PMF no longer required, or an open twin of a secured network. Profiles
+// persist in trusted_networks.json next to config.toml so a restart
+// doesn't re-learn an environment that may already contain the rogue;
+// TrustAccessPoint folds a flagged AP into its profile.
+
+// Rogue finding kinds.
+const (
+	RogueUnknownBSSID   = "unknown_bssid"
+	RogueVendorMismatch = "vendor_mismatch"
+	RogueAKMDowngrade   = "akm_downgrade"
+	RoguePMFDowngrade   = "pmf_downgrade"
+	RogueOpenTwin       = "open_twin"
+)
+
+// RogueFinding is one suspicious AP. Findings are keyed by kind + BSSID;
+// repeat sightings update LastSeen/Signal rather than adding rows.
+type RogueFinding struct {
+	Kind      string    `json:"kind"`
+	Severity  string    `json:"severity"` // "low", "medium", "high", "critical"
+	SSID      string    `json:"ssid"`
+	BSSID     string    `json:"bssid"`
+	Vendor    string    `json:"vendor"`
+	Channel   int       `json:"channel"`
+	Signal    int       `json:"signal"`
+	Interface string    `json:"interface"`
+	Message   string    `json:"message"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// ssidProfile is the learned baseline for one SSID.
+type ssidProfile struct {
+	SSID        string    `json:"ssid"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	BSSIDs      []string  `json:"bssids"`
+	OUIs        []string  `json:"ouis"`
+	Vendors     []string  `json:"vendors"`
+	Channels    []int     `json:"channels"`
+	AKMs        []string  `json:"akms"`
+	Securities  []string  `json:"securities"`
+	PMFRequired bool      `json:"pmfRequired"`
+	// Trusted BSSIDs were accepted by the user and are never flagged.
+	Trusted []string `json:"trusted,omitempty"`
+}
+
+func (p *ssidProfile) learn(ap AccessPoint) bool {
+	changed := false
+	add := func(list *[]string, v string) {
+		if v != "" && !slices.Contains(*list, v) {
+			*list = append(*list, v)
+			changed = true
+		}
+	}
+	add(&p.BSSIDs, strings.ToLower(ap.BSSID))
+	if oui := bssidOUI(ap.BSSID); oui != "" {
+		add(&p.OUIs, oui)
+	}
+	add(&p.Vendors, ap.Vendor)
+	add(&p.Securities, ap.Security)
+	for _, akm := range ap.AuthMethods {
+		add(&p.AKMs, akm)
+	}
+	if ap.Channel > 0 && !slices.Contains(p.Channels, ap.Channel) {
+		p.Channels = append(p.Channels, ap.Channel)
+		sort.Ints(p.Channels)
+		changed = true
+	}
+	if ap.PMF == "Required" && !p.PMFRequired {
+		p.PMFRequired = true
+		changed = true
+	}
+	return changed
+}
+
+func (p *ssidProfile) secured() bool {
+	for _, s := range p.Securities {
+		if s != "Open" {
+			return true
+		}
+	}
+	return false
+}
+
+// bssidOUI returns the vendor OUI of a BSSID ("aa:bb:cc"), or "" for a
+// locally administered address — controllers derive per-SSID virtual
+// BSSIDs that way, so their prefix says nothing about the vendor.
+func bssidOUI(bssid string) string {
+	b := strings.ToLower(bssid)
+	if len(b) < 8 {
+		return ""
+	}
+	first, err := strconv.ParseUint(b[:2], 16, 8)
+	if err != nil || first&0x02 != 0 {
+		return ""
+	}
+	return b[:8]
+}
+
+func isSAE(akm string) bool   { return strings.Contains(akm, "SAE") }
+func isPSK(akm string) bool   { return strings.Contains(akm, "PSK") }
+func is8021X(akm string) bool { return strings.Contains(akm, "802.1X") }
+
+// akmDowngrade describes how ap's AKMs are weaker than the profile's, or
+// returns "" when they aren't.
+func akmDowngrade(p *ssidProfile, ap AccessPoint) string {
+	if len(ap.AuthMethods) == 0 {
+		return ""
+	}
+	switch {
+	case slices.ContainsFunc(p.AKMs, isSAE) && !slices.ContainsFunc(ap.AuthMethods, isSAE):
+		return fmt.Sprintf("offers %s where SAE (WPA3) was seen", strings.Join(ap.AuthMethods, "/"))
+	case slices.ContainsFunc(p.AKMs, is8021X) && !slices.ContainsFunc(p.AKMs, isPSK) &&
+		slices.ContainsFunc(ap.AuthMethods, isPSK):
+		return fmt.Sprintf("offers %s on an 802.1X (Enterprise) network", strings.Join(ap.AuthMethods, "/"))
+	}
+	return ""
+}
+
+type rogueKey struct{ kind, bssid string }
+
+// rogueDetector is the bus subscriber that learns profiles from scan
+// results and raises findings.
+type rogueDetector struct {
+	cfg  *liveConfig
+	bus  *EventBus
+	path string // trusted_networks.json; "" disables persistence
+
+	mu       sync.Mutex
+	loaded   bool
+	profiles map[string]*ssidProfile
+	findings map[rogueKey]*RogueFinding
+	lastAP   map[string]AccessPoint // by lower-case BSSID, for TrustAccessPoint
+	gen      uint64                 // bumped per snapshot taken for saving
+
+	writeMu sync.Mutex // serializes file writes, taken without mu
+	written uint64     // gen of the snapshot on disk
+}
+
+// trustedSnapshot is the encoded profiles, taken under mu so the file
+// write can happen off the bus goroutine.
+type trustedSnapshot struct {
+	gen  uint64
+	data []byte
+}
+
+func newRogueDetector(cfg *liveConfig, bus *EventBus, path string) *rogueDetector {
+	return &rogueDetector{
+		cfg:      cfg,
+		bus:      bus,
+		path:     path,
+		profiles: make(map[string]*ssidProfile),
+		findings: make(map[rogueKey]*RogueFinding),
+		lastAP:   make(map[string]AccessPoint),
+	}
+}
+
+// trustedNetworksPath returns where learned SSID profiles are persisted.
+func trustedNetworksPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "trusted_networks.json"), nil
+}
+
+func (d *rogueDetector) handleEvent(e Event) {
+	ev, ok := e.(ScanResultEvent)
+	if !ok {
+		return
+	}
+	for _, f := range d.observe(ev.Interface, ev.Timestamp, ev.APs) {
+		d.bus.Publish(RogueAPEvent{Finding: f})
+	}
+}
+
+// observe learns from and checks one radio's scan, returning findings
+// not raised before.
+func (d *rogueDetector) observe(iface string, now time.Time, aps []AccessPoint) []RogueFinding {
+	learning := time.Duration(d.cfg.Get().RogueLearningMinutes) * time.Minute
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.loadLocked()
+
+	var raised []RogueFinding
+	dirty := false
+	for _, ap := range aps {
+		if ap.SSID == "" || ap.BSSID == "" {
+			continue // hidden networks have no identity to impersonate
+		}
+		bssid := strings.ToLower(ap.BSSID)
+		d.lastAP[bssid] = ap
+		p, ok := d.profiles[ap.SSID]
+		if !ok {
+			p = &ssidProfile{SSID: ap.SSID, FirstSeen: now}
+			d.profiles[ap.SSID] = p
+			dirty = true
+		}
+		if slices.Contains(p.Trusted, bssid) {
+			continue
+		}
+
+		// An open twin is suspicious even while learning: a real network
+		// doesn't mix open and secured BSSs under one name.
+		if ap.Security == "Open" && p.secured() {
+			raised = d.raiseLocked(raised, RogueOpenTwin, "critical", ap, iface, now,
+				"open AP advertising secured network %q", ap.SSID)
+			continue
+		}
+		if now.Sub(p.FirstSeen) < learning {
+			if p.learn(ap) {
+				dirty = true
+			}
+			continue
+		}
+
+		if p.PMFRequired && ap.PMF != "Required" {
+			raised = d.raiseLocked(raised, RoguePMFDowngrade, "high", ap, iface, now,
+				"PMF %s on %q where it was required", strings.ToLower(ap.PMF), ap.SSID)
+		}
+		if why := akmDowngrade(p, ap); why != "" {
+			raised = d.raiseLocked(raised, RogueAKMDowngrade, "high", ap, iface, now, "%q %s", ap.SSID, why)
+		}
+		if slices.Contains(p.BSSIDs, bssid) {
+			continue
+		}
+		if oui := bssidOUI(bssid); oui != "" && len(p.OUIs) > 0 && !slices.Contains(p.OUIs, oui) {
+			raised = d.raiseLocked(raised, RogueVendorMismatch, "high", ap, iface, now,
+				"%q from vendor %s, network uses %s", ap.SSID, vendorOrOUI(ap.Vendor, oui), strings.Join(p.vendorsOrOUIs(), ", "))
+			continue
+		}
+		raised = d.raiseLocked(raised, RogueUnknownBSSID, "medium", ap, iface, now,
+			"unknown BSSID for %q on channel %d (known channels %s)", ap.SSID, ap.Channel, joinInts(p.Channels))
+	}
+	if dirty {
+		if snap := d.snapshotLocked(); snap != nil {
+			go d.write(snap)
+		}
+	}
+	return raised
+}
+
+func (d *rogueDetector) raiseLocked(raised []RogueFinding, kind, severity string, ap AccessPoint, iface string, now time.Time, format string, args ...interface{}) []RogueFinding {
+	key := rogueKey{kind, strings.ToLower(ap.BSSID)}
+	if f, ok := d.findings[key]; ok {
+		f.LastSeen, f.Signal, f.Channel = now, ap.Signal, ap.Channel
+		return raised
+	}
+	f := &RogueFinding{
+		Kind: kind, Severity: severity, SSID: ap.SSID, BSSID: ap.BSSID, Vendor: ap.Vendor,
+		Channel: ap.Channel, Signal: ap.Signal, Interface: iface,
+		Message: fmt.Sprintf(format, args...), FirstSeen: now, LastSeen: now,
+	}
+	d.findings[key] = f
+	return append(raised, *f)
+}
+
+func (p *ssidProfile) vendorsOrOUIs() []string {
+	if len(p.Vendors) > 0 {
+		return p.Vendors
+	}
+	return p.OUIs
+}
+
+func vendorOrOUI(vendor, oui string) string {
+	if vendor != "" {
+		return vendor
+	}
+	return oui
+}
+
+func joinInts(v []int) string {
+	s := make([]string, len(v))
+	for i, n := range v {
+		s[i] = strconv.Itoa(n)
+	}
+	return strings.Join(s, ", ")
+}
+
+// Findings returns every finding, most severe and most recent first.
+func (d *rogueDetector) Findings() []RogueFinding {
+	rank := map[string]int{"critical": 0, "high": 1, "medium": 2, "low": 3}
+	d.mu.Lock()
+	out := make([]RogueFinding, 0, len(d.findings))
+	for _, f := range d.findings {
+		out = append(out, *f)
+	}
+	d.mu.Unlock()
+	sort.Slice(out, func(i, j int) bool {
+		if rank[out[i].Severity] != rank[out[j].Severity] {
+			return rank[out[i].Severity] < rank[out[j].Severity]
+		}
+		return out[i].LastSeen.After(out[j].LastSeen)
+	})
+	return out
+}
+
+// Trust folds the last sighting of bssid into its SSID's profile and
+// clears its findings.
+func (d *rogueDetector) Trust(bssid string) error {
+	bssid = strings.ToLower(bssid)
+	d.mu.Lock()
+	ap, ok := d.lastAP[bssid]
+	if !ok {
+		d.mu.Unlock()
+		return fmt.Errorf("BSSID %s has not been seen", bssid)
+	}
+	p := d.profiles[ap.SSID]
+	if p == nil {
+		p = &ssidProfile{SSID: ap.SSID}
+		d.profiles[ap.SSID] = p
+	}
+	p.learn(ap)
+	if !slices.Contains(p.Trusted, bssid) {
+		p.Trusted = append(p.Trusted, bssid)
+	}
+	for key := range d.findings {
+		if key.bssid == bssid {
+			delete(d.findings, key)
+		}
+	}
+	snap := d.snapshotLocked()
+	d.mu.Unlock()
+	if snap != nil {
+		d.write(snap)
+	}
+	return nil
+}
+
+func (d *rogueDetector) loadLocked() {
+	if d.loaded || d.path == "" {
+		d.loaded = true
+		return
+	}
+	d.loaded = true
+	data, err := os.ReadFile(d.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("trusted networks unreadable, relearning", "event", "rogue_load_error", "path", d.path, "err", err)
+		}
+		return
+	}
+	var profiles []*ssidProfile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		slog.Warn("trusted networks corrupt, relearning", "event", "rogue_load_error", "path", d.path, "err", err)
+		return
+	}
+	for _, p := range profiles {
+		d.profiles[p.SSID] = p
+	}
+}
+
+// snapshotLocked encodes the profiles for saving, or returns nil when
+// persistence is off. Caller must hold d.mu.
+func (d *rogueDetector) snapshotLocked() *trustedSnapshot {
+	if d.path == "" {
+		return nil
+	}
+	profiles := make([]*ssidProfile, 0, len(d.profiles))
+	for _, p := range d.profiles {
+		profiles = append(profiles, p)
+	}
+	sort.Slice(profiles, func(i, j int) bool { return profiles[i].SSID < profiles[j].SSID })
+	data, err := json.MarshalIndent(profiles, "", "  ")
+	if err != nil {
+		slog.Warn("saving trusted networks failed", "event", "rogue_save_error", "path", d.path, "err", err)
+		return nil
+	}
+	d.gen++
+	return &trustedSnapshot{gen: d.gen, data: data}
+}
+
+// write stores snap atomically unless a newer one is already on disk.
+// Failures are logged: detection keeps working in memory.
+func (d *rogueDetector) write(snap *trustedSnapshot) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+	if snap.gen <= d.written {
+		return
+	}
+	err := os.MkdirAll(filepath.Dir(d.path), 0o755)
+	tmp := d.path + ".tmp"
+	if err == nil {
+		err = os.WriteFile(tmp, snap.data, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, d.path)
+	}
+	if err != nil {
+		slog.Warn("saving trusted networks failed", "event", "rogue_save_error", "path", d.path, "err", err)
+		return
+	}
+	d.written = snap.gen
+	_ = chownToSudoUser(d.path)
+}
+
+// GetRogueFindings returns the rogue / evil-twin findings so far.
+func (ws *WiFiService) GetRogueFindings() []RogueFinding {
+	return ws.rogue.Findings()
+}
+
+// TrustAccessPoint marks a flagged BSSID as legitimate.
+func (ws *WiFiService) TrustAccessPoint(bssid string) error {
+	return ws.rogue.Trust(bssid)
+}
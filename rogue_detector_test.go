@@ -0,0 +1,90 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func corpAP(bssid string, channel int) AccessPoint {
+	return AccessPoint{
+		SSID: "Corp", BSSID: bssid, Channel: channel, Signal: -55, Vendor: "Aruba",
+		Security: "WPA3", AuthMethods: []string{"SAE"}, PMF: "Required",
+	}
+}
+
+func TestRogueDetectorFindings(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.RogueLearningMinutes = 1
+	path := filepath.Join(t.TempDir(), "trusted_networks.json")
+	d := newRogueDetector(newLiveConfig(cfg), nil, path)
+	t0 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	// Learning: both legitimate APs are absorbed silently.
+	if got := d.observe("wlan0", t0, []AccessPoint{corpAP("00:0b:86:00:00:01", 1), corpAP("00:0b:86:00:00:02", 36)}); len(got) != 0 {
+		t.Fatalf("findings while learning: %+v", got)
+	}
+
+	later := t0.Add(2 * time.Minute)
+	sameVendor := corpAP("00:0b:86:00:00:03", 6)
+	otherVendor := corpAP("3c:84:6a:00:00:01", 6)
+	otherVendor.Vendor = "TP-Link"
+	downgraded := corpAP("00:0b:86:00:00:01", 1)
+	downgraded.AuthMethods, downgraded.PMF = []string{"PSK"}, "Disabled"
+	openTwin := corpAP("02:11:22:33:44:55", 11)
+	openTwin.Security, openTwin.AuthMethods, openTwin.PMF = "Open", nil, "Disabled"
+
+	got := d.observe("wlan0", later, []AccessPoint{sameVendor, otherVendor, downgraded, openTwin})
+	kinds := make(map[string]string)
+	for _, f := range got {
+		kinds[f.Kind] = f.BSSID
+	}
+	want := map[string]string{
+		RogueUnknownBSSID:   "00:0b:86:00:00:03",
+		RogueVendorMismatch: "3c:84:6a:00:00:01",
+		RogueAKMDowngrade:   "00:0b:86:00:00:01",
+		RoguePMFDowngrade:   "00:0b:86:00:00:01",
+		RogueOpenTwin:       "02:11:22:33:44:55",
+	}
+	for kind, bssid := range want {
+		if kinds[kind] != bssid {
+			t.Errorf("%s finding for %q, want %q (all: %+v)", kind, kinds[kind], bssid, got)
+		}
+	}
+	if len(got) != len(want) {
+		t.Errorf("%d findings, want %d: %+v", len(got), len(want), got)
+	}
+	if f := d.Findings(); len(f) != len(want) || f[0].Kind != RogueOpenTwin {
+		t.Errorf("Findings() not severity-ordered: %+v", f)
+	}
+
+	// Repeat sightings don't re-raise.
+	if again := d.observe("wlan0", later.Add(time.Minute), []AccessPoint{openTwin}); len(again) != 0 {
+		t.Errorf("re-raised: %+v", again)
+	}
+
+	// Trusting clears the findings and survives a restart.
+	if err := d.Trust("3C:84:6A:00:00:01"); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := newRogueDetector(newLiveConfig(cfg), nil, path)
+	got = reloaded.observe("wlan0", later.Add(2*time.Minute), []AccessPoint{otherVendor, corpAP("00:0b:86:00:00:02", 36)})
+	if len(got) != 0 {
+		t.Errorf("trusted or learned APs flagged after reload: %+v", got)
+	}
+	if err := reloaded.Trust("aa:aa:aa:aa:aa:aa"); err == nil {
+		t.Error("trusting an unseen BSSID succeeded")
+	}
+}
+
+func TestBSSIDOUI(t *testing.T) {
+	for bssid, want := range map[string]string{
+		"00:0B:86:12:34:56": "00:0b:86",
+		"02:0b:86:12:34:56": "", // locally administered
+		"bogus":             "",
+	} {
+		if got := bssidOUI(bssid); got != want {
+			t.Errorf("bssidOUI(%q) = %q, want %q", bssid, got, want)
+		}
+	}
+}
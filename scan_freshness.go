@@ -0,0 +1,93 @@
+package main
+
+import (
+	"sort"
+	"time"
+)
+
+// Scan-result freshness. Not everything a scan returns was heard by it: the
+// nl80211 and iw dumps include whatever the kernel still has cached (all of
+// it when another scan held the radio and the cached results were used),
+// with the age of each entry. Every AP is stamped with its age as of the
+// scan and marked Stale when it wasn't heard since the scan started.
+// Results older than scan_max_age_seconds are dropped. An AP one of the
+// radio's scans misses is held over, stale, until it reaches that age, so
+// a BSS that drops out of a scan or two fades in the UI rather than
+// flickering out of the list and back. The merged view's BSSIDs are
+// compared tick to tick and published as APPresenceEvents.
+
+// ageScan stamps aps, a scan begun at start, with their age as of now and
+// drops those older than maxAge. held is prev's APs the scan missed, stale,
+// that haven't aged out yet. A zero LastSeen (a backend that doesn't report
+// one) counts as heard now.
+func ageScan(aps, prev []AccessPoint, start, now time.Time, maxAge time.Duration) (scan, held []AccessPoint) {
+	scan = make([]AccessPoint, 0, len(aps))
+	heard := make(map[string]bool, len(aps))
+	for _, ap := range aps {
+		if ap.BSSID != "" {
+			heard[ap.BSSID] = true
+		}
+		if ap.LastSeen.IsZero() {
+			ap.LastSeen = now
+		}
+		if !stampAge(&ap, now, maxAge) {
+			continue
+		}
+		ap.Stale = ap.LastSeen.Before(start)
+		scan = append(scan, ap)
+	}
+	for _, ap := range prev {
+		if ap.BSSID == "" || heard[ap.BSSID] {
+			continue
+		}
+		if !stampAge(&ap, now, maxAge) {
+			continue
+		}
+		ap.Stale = true
+		held = append(held, ap)
+	}
+	return scan, held
+}
+
+// stampAge sets ap.AgeMs and reports whether ap is within maxAge.
+func stampAge(ap *AccessPoint, now time.Time, maxAge time.Duration) bool {
+	age := max(now.Sub(ap.LastSeen), 0)
+	ap.AgeMs = age.Milliseconds()
+	return age <= maxAge
+}
+
+// presenceChangesLocked diffs merged against the BSSIDs present on the
+// previous tick and records merged as the new baseline. The first call
+// only sets the baseline, as the initial scan's networks:updated already
+// lists everything in range. Caller must hold ws.mergeMu.
+func (ws *WiFiService) presenceChangesLocked(merged []AccessPoint, ts time.Time) []APPresenceEvent {
+	present := make(map[string]AccessPoint, len(merged))
+	for _, ap := range merged {
+		if ap.BSSID != "" {
+			present[ap.BSSID] = ap
+		}
+	}
+	prev := ws.presentAPs
+	ws.presentAPs = present
+	if prev == nil {
+		return nil
+	}
+	var out []APPresenceEvent
+	for bssid, ap := range present {
+		if _, ok := prev[bssid]; !ok {
+			out = append(out, APPresenceEvent{Timestamp: ts, AP: ap, Appeared: true})
+		}
+	}
+	for bssid, ap := range prev {
+		if _, ok := present[bssid]; !ok {
+			out = append(out, APPresenceEvent{Timestamp: ts, AP: ap})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Appeared != out[j].Appeared {
+			return out[i].Appeared
+		}
+		return out[i].AP.BSSID < out[j].AP.BSSID
+	})
+	return out
+}
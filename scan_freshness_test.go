@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAgeScan(t *testing.T) {
+	start := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := start.Add(2 * time.Second)
+	aps := []AccessPoint{
+		{BSSID: "02:00:00:00:00:01", LastSeen: start.Add(time.Second)},       // heard by this scan
+		{BSSID: "02:00:00:00:00:02", LastSeen: start.Add(-10 * time.Second)}, // cached
+		{BSSID: "02:00:00:00:00:03", LastSeen: start.Add(-time.Minute)},      // cached too long ago
+		{BSSID: "02:00:00:00:00:04"},                                         // no LastSeen reported
+	}
+	prev := []AccessPoint{
+		{BSSID: "02:00:00:00:00:01", LastSeen: start.Add(-5 * time.Second)},
+		{BSSID: "02:00:00:00:00:05", LastSeen: start.Add(-20 * time.Second)}, // missed, held over
+		{BSSID: "02:00:00:00:00:06", LastSeen: start.Add(-40 * time.Second)}, // missed, aged out
+	}
+	scan, held := ageScan(aps, prev, start, now, 30*time.Second)
+	if len(scan) != 3 {
+		t.Fatalf("scan = %+v", scan)
+	}
+	if ap := scan[0]; ap.Stale || ap.AgeMs != 1000 {
+		t.Errorf("fresh AP = stale %v age %d", ap.Stale, ap.AgeMs)
+	}
+	if ap := scan[1]; !ap.Stale || ap.AgeMs != 12000 {
+		t.Errorf("cached AP = stale %v age %d", ap.Stale, ap.AgeMs)
+	}
+	if ap := scan[2]; ap.Stale || ap.AgeMs != 0 || !ap.LastSeen.Equal(now) {
+		t.Errorf("AP without LastSeen = %+v", ap)
+	}
+	if len(held) != 1 || held[0].BSSID != "02:00:00:00:00:05" || !held[0].Stale || held[0].AgeMs != 22000 {
+		t.Errorf("held = %+v", held)
+	}
+}
+
+func TestScanFreshness(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	now := time.Now()
+	office := AccessPoint{BSSID: "aa:bb:cc:00:00:01", SSID: "Office", Signal: -55, Frequency: 5180}
+	lab := AccessPoint{BSSID: "aa:bb:cc:00:00:02", SSID: "Lab", Signal: -70, Frequency: 5200}
+	guest := AccessPoint{BSSID: "aa:bb:cc:00:00:03", SSID: "Guest", Signal: -65, Frequency: 5240}
+	cached := lab
+	cached.LastSeen = now.Add(-20 * time.Second)
+	backend := &scriptedBackend{ticks: []scriptedTick{
+		{aps: []AccessPoint{office, cached}},
+		{aps: []AccessPoint{office, guest}},
+		{aps: []AccessPoint{office, guest}},
+	}}
+	ws := newWiFiServiceWithBackend(backend)
+	defer ws.Close()
+	var presence []APPresenceEvent
+	SubscribeTo(ws.bus, func(e APPresenceEvent) { presence = append(presence, e) })
+
+	network := func(ssid string) (Network, bool) {
+		for _, n := range ws.GetNetworks() {
+			if n.SSID == ssid {
+				return n, true
+			}
+		}
+		return Network{}, false
+	}
+
+	// A cached entry from before the scan is reported, but stale.
+	ws.performScan(context.Background(), "wlan0")
+	if n, ok := network("Lab"); !ok || !n.Stale || n.AccessPoints[0].AgeMs < 20000 {
+		t.Errorf("cached network = %+v", n)
+	}
+	if n, _ := network("Office"); n.Stale {
+		t.Error("freshly scanned network marked stale")
+	}
+	if len(presence) != 0 {
+		t.Errorf("first scan published %+v, want only a baseline", presence)
+	}
+
+	// Missing from the next scan, it's held over rather than dropped.
+	ws.performScan(context.Background(), "wlan0")
+	if n, ok := network("Lab"); !ok || !n.Stale {
+		t.Errorf("missed network = %+v, %v", n, ok)
+	}
+	if len(presence) != 1 || !presence[0].Appeared || presence[0].AP.BSSID != guest.BSSID {
+		t.Errorf("presence = %+v, want Guest appeared", presence)
+	}
+
+	// Once past the max age it disappears.
+	cfg := ws.GetConfig()
+	cfg.ScanMaxAgeSeconds = 10
+	ws.config.Set(cfg)
+	presence = nil
+	ws.performScan(context.Background(), "wlan0")
+	if _, ok := network("Lab"); ok {
+		t.Error("network older than the max age still listed")
+	}
+	if len(presence) != 1 || presence[0].Appeared || presence[0].AP.BSSID != lab.BSSID {
+		t.Errorf("presence = %+v, want Lab disappeared", presence)
+	}
+}
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Scheduled survey jobs. Each [[job]] table in config.toml runs unattended
+// on a daily or interval schedule: scan for duration_seconds, then write
+// the report in each requested format to output_dir and apply the job's
+// retention limits there. Runs are serialized (they share the radios) and
+// recorded in a history persisted next to the config file.
+//
+//	[[job]]
+//	name = "nightly"
+//	at = "02:00"
+//	interface = "wlan0"
+//	duration_seconds = 600
+//	formats = ["json", "html"]
+//	output_dir = "/var/lib/wifi-app/reports"
+//	keep_runs = 30
+//
+// If scanning is already running when a job fires (the GUI, `monitor`),
+// the job observes that scan instead of switching interfaces under the
+// user, and leaves it running afterwards.
+
+// SurveyJob is one scheduled job from the config file.
+//
+//   - At: daily start time, "HH:MM" local time. Weekdays optionally
+//     limits it to some days ("mon" … "sun").
+//   - Every: interval alternative to At, a Go duration of at least 1m.
+//     The first run is one interval after the scheduler starts.
+//   - Interface: interface(s) to scan, comma-separated. Empty uses
+//     default_interface, then the first available.
+//   - DurationSeconds: how long to scan before writing the report.
+//   - Formats: any of "json", "html", "pdf". Default json + html.
+//   - OutputDir: where reports go; empty means reportsDir().
+//   - KeepRuns / KeepDays: retention for this job's files in OutputDir —
+//     the newest KeepRuns runs, none older than KeepDays. 0 is unlimited.
+type SurveyJob struct {
+	Name            string   `toml:"name" json:"name"`
+	At              string   `toml:"at" json:"at"`
+	Weekdays        []string `toml:"weekdays" json:"weekdays"`
+	Every           string   `toml:"every" json:"every"`
+	Interface       string   `toml:"interface" json:"interface"`
+	DurationSeconds int      `toml:"duration_seconds" json:"durationSeconds"`
+	Formats         []string `toml:"formats" json:"formats"`
+	OutputDir       string   `toml:"output_dir" json:"outputDir"`
+	KeepRuns        int      `toml:"keep_runs" json:"keepRuns"`
+	KeepDays        int      `toml:"keep_days" json:"keepDays"`
+}
+
+// Job run states.
+const (
+	JobRunning = "running"
+	JobOK      = "ok"
+	JobFailed  = "failed"
+)
+
+// JobRun is one execution of a job, as shown in the job history.
+type JobRun struct {
+	Job        string    `json:"job"`
+	Started    time.Time `json:"started"`
+	Finished   time.Time `json:"finished"` // zero while running
+	Status     string    `json:"status"`
+	Interfaces []string  `json:"interfaces"`
+	Files      []string  `json:"files"`  // reports written
+	Pruned     int       `json:"pruned"` // old report files removed by retention
+	Note       string    `json:"note,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// jobHistorySize bounds the persisted history.
+const jobHistorySize = 200
+
+var (
+	jobFormats   = []string{"json", "html", "pdf"}
+	jobWeekdays  = map[string]time.Weekday{"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6}
+	jobNameRegex = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// validateSurveyJobs drops jobs that can't be scheduled and fills
+// defaults, returning a note per adjustment.
+func validateSurveyJobs(jobs []SurveyJob) ([]SurveyJob, []string) {
+	var out []SurveyJob
+	var notes []string
+	seen := make(map[string]bool)
+	for i, j := range jobs {
+		switch {
+		case j.Name == "":
+			notes = append(notes, fmt.Sprintf("job %d has no name, dropped", i))
+			continue
+		case !jobNameRegex.MatchString(j.Name):
+			// The name prefixes report file names.
+			notes = append(notes, fmt.Sprintf("job %q: name may only use letters, digits, '.', '_' and '-', dropped", j.Name))
+			continue
+		case seen[j.Name]:
+			notes = append(notes, fmt.Sprintf("job %q defined twice, later one dropped", j.Name))
+			continue
+		case (j.At == "") == (j.Every == ""):
+			notes = append(notes, fmt.Sprintf("job %q: set exactly one of at or every, dropped", j.Name))
+			continue
+		}
+		if j.At != "" {
+			if _, err := time.Parse("15:04", j.At); err != nil {
+				notes = append(notes, fmt.Sprintf("job %q: at=%q is not HH:MM, dropped", j.Name, j.At))
+				continue
+			}
+		}
+		var every time.Duration
+		if j.Every != "" {
+			var err error
+			if every, err = time.ParseDuration(j.Every); err != nil || every < time.Minute {
+				notes = append(notes, fmt.Sprintf("job %q: every=%q must be a duration of at least 1m, dropped", j.Name, j.Every))
+				continue
+			}
+		}
+		days := j.Weekdays[:0:0]
+		for _, d := range j.Weekdays {
+			d = strings.ToLower(d)
+			if _, ok := jobWeekdays[d]; !ok {
+				notes = append(notes, fmt.Sprintf("job %q: unknown weekday %q ignored", j.Name, d))
+				continue
+			}
+			days = append(days, d)
+		}
+		j.Weekdays = days
+		formats := j.Formats[:0:0]
+		for _, f := range j.Formats {
+			f = strings.ToLower(f)
+			if !slices.Contains(jobFormats, f) {
+				notes = append(notes, fmt.Sprintf("job %q: unknown format %q ignored", j.Name, f))
+				continue
+			}
+			if !slices.Contains(formats, f) {
+				formats = append(formats, f)
+			}
+		}
+		if len(formats) == 0 {
+			formats = []string{"json", "html"}
+		}
+		j.Formats = formats
+		if j.DurationSeconds <= 0 {
+			j.DurationSeconds = 300
+		}
+		if j.DurationSeconds > 86400 {
+			notes = append(notes, fmt.Sprintf("job %q: duration_seconds=%d clamped to 86400", j.Name, j.DurationSeconds))
+			j.DurationSeconds = 86400
+		}
+		// A scan longer than the interval would run into the next start.
+		if every > 0 && time.Duration(j.DurationSeconds)*time.Second > every {
+			notes = append(notes, fmt.Sprintf("job %q: duration_seconds=%d clamped to every=%s", j.Name, j.DurationSeconds, j.Every))
+			j.DurationSeconds = int(every / time.Second)
+		}
+		j.KeepRuns = max(j.KeepRuns, 0)
+		j.KeepDays = max(j.KeepDays, 0)
+		seen[j.Name] = true
+		out = append(out, j)
+	}
+	return out, notes
+}
+
+// next returns the first start time strictly after t.
+func (j SurveyJob) next(t time.Time) time.Time {
+	if j.Every != "" {
+		d, _ := time.ParseDuration(j.Every)
+		return t.Add(d)
+	}
+	hm, _ := time.Parse("15:04", j.At)
+	for i := 0; i <= 7; i++ {
+		day := t.AddDate(0, 0, i)
+		start := time.Date(day.Year(), day.Month(), day.Day(), hm.Hour(), hm.Minute(), 0, 0, t.Location())
+		if start.After(t) && j.onWeekday(start.Weekday()) {
+			return start
+		}
+	}
+	return time.Time{} // unreachable with validated weekdays
+}
+
+func (j SurveyJob) onWeekday(d time.Weekday) bool {
+	if len(j.Weekdays) == 0 {
+		return true
+	}
+	for _, w := range j.Weekdays {
+		if jobWeekdays[w] == d {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduleKey identifies a job's schedule, so editing it in the config
+// recomputes the next start while unrelated edits don't.
+func (j SurveyJob) scheduleKey() string {
+	return j.Name + "|" + j.At + "|" + strings.Join(j.Weekdays, ",") + "|" + j.Every
+}
+
+// reportsDir is the default job output directory, next to the config.
+func reportsDir() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "reports"), nil
+}
+
+func jobHistoryPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "job_history.json"), nil
+}
+
+// jobScheduler fires config.Jobs and keeps their run history.
+type jobScheduler struct {
+	ws   *WiFiService
+	path string // job_history.json; "" disables persistence
+
+	runMu sync.Mutex // one job at a time
+
+	mu      sync.Mutex
+	loaded  bool
+	history []JobRun // oldest first
+	next    map[string]time.Time
+	pending map[string]bool // by job name: running or waiting for runMu
+	now     func() time.Time
+	after   func(time.Duration) <-chan time.Time // survey wait; tests skip it
+	tick    time.Duration
+}
+
+func newJobScheduler(ws *WiFiService, path string) *jobScheduler {
+	return &jobScheduler{ws: ws, path: path, next: make(map[string]time.Time), pending: make(map[string]bool), now: time.Now, after: time.After, tick: 15 * time.Second}
+}
+
+// StartJobScheduler runs the configured jobs until ctx ends. Only the GUI
+// and the long-running CLI commands start it; one-shot commands don't.
+func (ws *WiFiService) StartJobScheduler(ctx context.Context) {
+	ws.schedulerOnce.Do(func() {
+		ctx, cancel := context.WithCancel(ctx)
+		ws.schedulerCancel = cancel
+		go ws.jobs.run(ctx)
+	})
+}
+
+func (s *jobScheduler) run(ctx context.Context) {
+	t := time.NewTicker(s.tick)
+	defer t.Stop()
+	for {
+		for _, job := range s.due() {
+			go s.execute(ctx, job)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// due returns the jobs whose start time has passed and schedules their
+// next run. Jobs seen for the first time are only scheduled, and a job
+// whose previous run is still running or queued is skipped this time.
+func (s *jobScheduler) due() []SurveyJob {
+	now := s.now()
+	jobs := s.ws.config.Get().Jobs
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []SurveyJob
+	active := make(map[string]bool, len(jobs))
+	for _, j := range jobs {
+		key := j.scheduleKey()
+		active[key] = true
+		next, ok := s.next[key]
+		switch {
+		case !ok:
+			s.next[key] = j.next(now)
+		case !now.Before(next):
+			s.next[key] = j.next(now)
+			if s.pending[j.Name] {
+				slog.Warn("job skipped, previous run not finished", "event", "job_skipped", "job", j.Name)
+				continue
+			}
+			s.pending[j.Name] = true
+			out = append(out, j)
+		}
+	}
+	for key := range s.next {
+		if !active[key] {
+			delete(s.next, key)
+		}
+	}
+	return out
+}
+
+// execute performs one run of job and records it, unless ctx has ended
+// by the time the previous run lets it start.
+func (s *jobScheduler) execute(ctx context.Context, job SurveyJob) JobRun {
+	defer func() {
+		s.mu.Lock()
+		delete(s.pending, job.Name)
+		s.mu.Unlock()
+	}()
+	s.runMu.Lock()
+	defer s.runMu.Unlock()
+	// A job queued behind a long one may still be waiting here when the
+	// scheduler stops; don't start it then.
+	if err := ctx.Err(); err != nil {
+		slog.Info("job skipped, scheduler stopped", "event", "job_skipped", "job", job.Name)
+		return JobRun{Job: job.Name, Status: JobFailed, Files: []string{}, Error: err.Error()}
+	}
+
+	run := JobRun{Job: job.Name, Started: s.now(), Status: JobRunning, Files: []string{}}
+	idx := s.record(run, -1)
+	slog.Info("job started", "event", "job_start", "job", job.Name)
+	err := s.survey(ctx, job, &run)
+	run.Finished = s.now()
+	run.Status = JobOK
+	if err != nil {
+		run.Status, run.Error = JobFailed, err.Error()
+		slog.Warn("job failed", "event", "job_error", "job", job.Name, "err", err)
+	} else {
+		slog.Info("job finished", "event", "job_done", "job", job.Name, "files", len(run.Files), "pruned", run.Pruned)
+	}
+	s.record(run, idx)
+	return run
+}
+
+// survey scans for the job's duration, writes its reports and prunes the
+// output directory.
+func (s *jobScheduler) survey(ctx context.Context, job SurveyJob, run *JobRun) error {
+	ws := s.ws
+	if ws.IsScanning() {
+		run.Interfaces = ws.ScanningInterfaces()
+		run.Note = "observed the scan already running"
+	} else {
+		ifaces, err := s.interfaces(ctx, job)
+		if err != nil {
+			return err
+		}
+		if err := ws.StartScanningInterfaces(ifaces); err != nil {
+			return err
+		}
+		defer ws.StopScanning()
+		run.Interfaces = ifaces
+	}
+
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("interrupted after %s", s.now().Sub(run.Started).Round(time.Second))
+	case <-s.after(time.Duration(job.DurationSeconds) * time.Second):
+	}
+
+	dir := job.OutputDir
+	if dir == "" {
+		var err error
+		if dir, err = reportsDir(); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("output dir: %w", err)
+	}
+	data := ws.BuildReportData()
+	base := filepath.Join(dir, job.Name+"-"+run.Started.Format("20060102-150405"))
+	for _, format := range job.Formats {
+		path := base + "." + format
+		if err := writeJobReport(path, format, data, ws.GetConfig().ReportTemplatePath); err != nil {
+			return err
+		}
+		run.Files = append(run.Files, path)
+	}
+	pruned, err := pruneJobReports(dir, job, s.now())
+	run.Pruned = pruned
+	if err != nil {
+		return fmt.Errorf("retention: %w", err)
+	}
+	return nil
+}
+
+// interfaces resolves the job's interface list like the CLI does.
+func (s *jobScheduler) interfaces(ctx context.Context, job SurveyJob) ([]string, error) {
+	iface := job.Interface
+	if iface == "" {
+		iface = s.ws.GetConfig().DefaultInterface
+	}
+	if iface == "" {
+		ifaces, err := s.ws.scanner.GetInterfaces(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("list interfaces: %w", err)
+		}
+		if len(ifaces) == 0 {
+			return nil, fmt.Errorf("no WiFi interface found")
+		}
+		iface = ifaces[0]
+	}
+	return strings.Split(iface, ","), nil
+}
+
+// writeJobReport renders one format to path via a temp file, so a failed
+// render never leaves a partial report that retention would count.
+func writeJobReport(path, format string, data ReportData, templatePath string) error {
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "json":
+		var b []byte
+		if b, err = json.MarshalIndent(data, "", "  "); err == nil {
+			_, err = buf.Write(b)
+		}
+	case "html":
+		err = renderHTMLReport(&buf, data, templatePath)
+	case "pdf":
+		err = renderPDFReport(&buf, data)
+	}
+	if err != nil {
+		return fmt.Errorf("%s report: %w", format, err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return err
+	}
+	_ = chownToSudoUser(path)
+	return nil
+}
+
+// pruneJobReports applies the job's retention to its own files in dir
+// ("<name>-YYYYMMDD-HHMMSS.<format>"); other files are never touched.
+// Runs are grouped by timestamp so KeepRuns counts runs, not files.
+func pruneJobReports(dir string, job SurveyJob, now time.Time) (int, error) {
+	if job.KeepRuns == 0 && job.KeepDays == 0 {
+		return 0, nil
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	runs := make(map[string][]string) // stamp → files
+	for _, e := range entries {
+		name := e.Name()
+		ext := filepath.Ext(name)
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, job.Name+"-"), ext)
+		if e.IsDir() || !strings.HasPrefix(name, job.Name+"-") || !slices.Contains(jobFormats, strings.TrimPrefix(ext, ".")) {
+			continue
+		}
+		if _, err := time.ParseInLocation("20060102-150405", stamp, now.Location()); err != nil {
+			continue
+		}
+		runs[stamp] = append(runs[stamp], name)
+	}
+	stamps := make([]string, 0, len(runs))
+	for stamp := range runs {
+		stamps = append(stamps, stamp)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(stamps))) // newest first
+	cutoff := now.AddDate(0, 0, -job.KeepDays)
+	removed := 0
+	var errs []error
+	for i, stamp := range stamps {
+		started, _ := time.ParseInLocation("20060102-150405", stamp, now.Location())
+		if (job.KeepRuns == 0 || i < job.KeepRuns) && (job.KeepDays == 0 || !started.Before(cutoff)) {
+			continue
+		}
+		for _, name := range runs[stamp] {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			removed++
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// record stores run at idx, or appends it when idx < 0, and persists the
+// history. Returns the run's index.
+func (s *jobScheduler) record(run JobRun, idx int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	if idx < 0 || idx >= len(s.history) {
+		s.history = append(s.history, run)
+		if n := len(s.history) - jobHistorySize; n > 0 {
+			s.history = append(s.history[:0], s.history[n:]...)
+		}
+		idx = len(s.history) - 1
+	} else {
+		s.history[idx] = run
+	}
+	s.saveLocked()
+	return idx
+}
+
+// History returns the job runs, newest first.
+func (s *jobScheduler) History() []JobRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.loadLocked()
+	out := make([]JobRun, len(s.history))
+	for i, r := range s.history {
+		out[len(out)-1-i] = r
+	}
+	return out
+}
+
+func (s *jobScheduler) loadLocked() {
+	if s.loaded || s.path == "" {
+		s.loaded = true
+		return
+	}
+	s.loaded = true
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("job history unreadable, starting fresh", "event", "job_history_error", "path", s.path, "err", err)
+		}
+		return
+	}
+	if err := json.Unmarshal(data, &s.history); err != nil {
+		slog.Warn("job history corrupt, starting fresh", "event", "job_history_error", "path", s.path, "err", err)
+		s.history = nil
+		return
+	}
+	// A run still marked running was cut short by a restart.
+	for i := range s.history {
+		if s.history[i].Status == JobRunning {
+			s.history[i].Status, s.history[i].Error = JobFailed, "interrupted by shutdown"
+		}
+	}
+}
+
+// saveLocked writes the history atomically. Failures are logged: the
+// history stays available in memory.
+func (s *jobScheduler) saveLocked() {
+	if s.path == "" {
+		return
+	}
+	data, err := json.MarshalIndent(s.history, "", "  ")
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(s.path), 0o755)
+	}
+	tmp := s.path + ".tmp"
+	if err == nil {
+		err = os.WriteFile(tmp, data, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, s.path)
+	}
+	if err != nil {
+		slog.Warn("saving job history failed", "event", "job_history_error", "path", s.path, "err", err)
+		return
+	}
+	_ = chownToSudoUser(s.path)
+}
+
+// GetJobHistory returns scheduled job runs, newest first.
+func (ws *WiFiService) GetJobHistory() []JobRun {
+	return ws.jobs.History()
+}
@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSurveyJobNext(t *testing.T) {
+	// Wednesday 2024-05-01 09:30.
+	now := time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)
+	cases := []struct {
+		job  SurveyJob
+		want time.Time
+	}{
+		{SurveyJob{At: "02:00"}, time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC)},
+		{SurveyJob{At: "10:15"}, time.Date(2024, 5, 1, 10, 15, 0, 0, time.UTC)},
+		{SurveyJob{At: "09:30"}, time.Date(2024, 5, 2, 9, 30, 0, 0, time.UTC)}, // strictly after
+		{SurveyJob{At: "02:00", Weekdays: []string{"mon"}}, time.Date(2024, 5, 6, 2, 0, 0, 0, time.UTC)},
+		{SurveyJob{At: "12:00", Weekdays: []string{"wed"}}, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)},
+		{SurveyJob{At: "08:00", Weekdays: []string{"wed"}}, time.Date(2024, 5, 8, 8, 0, 0, 0, time.UTC)},
+		{SurveyJob{Every: "90m"}, now.Add(90 * time.Minute)},
+	}
+	for _, c := range cases {
+		if got := c.job.next(now); !got.Equal(c.want) {
+			t.Errorf("%+v: next = %s, want %s", c.job, got, c.want)
+		}
+	}
+}
+
+func TestValidateSurveyJobs(t *testing.T) {
+	jobs, notes := validateSurveyJobs([]SurveyJob{
+		{Name: "nightly", At: "02:00", Weekdays: []string{"Mon", "funday"}, Formats: []string{"PDF", "docx", "pdf"}},
+		{Name: "nightly", Every: "1h"},
+		{Name: "both", At: "02:00", Every: "1h"},
+		{Name: "bad time", At: "2am"},
+		{Name: "fast", Every: "10s"},
+		{Name: "hourly", Every: "1h", DurationSeconds: 100000, KeepRuns: -1},
+	})
+	if len(jobs) != 2 || jobs[0].Name != "nightly" || jobs[1].Name != "hourly" {
+		t.Fatalf("jobs = %+v", jobs)
+	}
+	if !slices.Equal(jobs[0].Weekdays, []string{"mon"}) || !slices.Equal(jobs[0].Formats, []string{"pdf"}) || jobs[0].DurationSeconds != 300 {
+		t.Errorf("nightly = %+v", jobs[0])
+	}
+	if !slices.Equal(jobs[1].Formats, []string{"json", "html"}) || jobs[1].DurationSeconds != 3600 || jobs[1].KeepRuns != 0 {
+		t.Errorf("hourly = %+v", jobs[1])
+	}
+	// funday, docx, the duplicate, both, bad time, fast, and hourly's
+	// duration clamped to a day, then to its interval.
+	if len(notes) != 8 {
+		t.Errorf("notes = %q", notes)
+	}
+}
+
+func TestJobRunWritesReportsAndPrunes(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 5)
+	ws.AttachHeadless(context.Background(), func(string, interface{}) {})
+	out := t.TempDir()
+	start := time.Date(2024, 5, 1, 2, 0, 0, 0, time.Local)
+	ws.jobs.now = func() time.Time { return start }
+	ws.jobs.after = func(time.Duration) <-chan time.Time {
+		ch := make(chan time.Time, 1)
+		ch <- start
+		return ch
+	}
+
+	// Two earlier runs (one json+html pair, one lone json), one run past
+	// keep_days, and files retention must leave alone.
+	for _, name := range []string{
+		"nightly-20240430-020000.json", "nightly-20240430-020000.html",
+		"nightly-20240429-020000.json",
+		"nightly-20240401-020000.json",
+		"other-20240301-020000.json", "nightly-notes.txt",
+	} {
+		if err := os.WriteFile(filepath.Join(out, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	job := SurveyJob{Name: "nightly", At: "02:00", DurationSeconds: 60, Formats: []string{"json", "html"}, OutputDir: out, KeepRuns: 2, KeepDays: 7}
+	run := ws.jobs.execute(context.Background(), job)
+	if run.Status != JobOK || run.Error != "" {
+		t.Fatalf("run = %+v", run)
+	}
+	if len(run.Files) != 2 || run.Pruned != 2 {
+		t.Errorf("files %v, pruned %d; want 2 written and 2 pruned", run.Files, run.Pruned)
+	}
+	if ws.IsScanning() {
+		t.Error("job left its own scan running")
+	}
+	html, err := os.ReadFile(filepath.Join(out, "nightly-20240501-020000.html"))
+	if err != nil || !strings.Contains(string(html), "02:00:00:00:01:01") {
+		t.Errorf("html report: %v", err)
+	}
+
+	var left []string
+	entries, _ := os.ReadDir(out)
+	for _, e := range entries {
+		left = append(left, e.Name())
+	}
+	want := []string{"nightly-20240430-020000.html", "nightly-20240430-020000.json", "nightly-20240501-020000.html",
+		"nightly-20240501-020000.json", "nightly-notes.txt", "other-20240301-020000.json"}
+	if !slices.Equal(left, want) {
+		t.Errorf("output dir = %v\nwant %v", left, want)
+	}
+
+	// The history survives a restart.
+	path, _ := jobHistoryPath()
+	history := newJobScheduler(ws, path).History()
+	if len(history) != 1 || history[0].Status != JobOK || len(history[0].Files) != 2 {
+		t.Errorf("reloaded history = %+v", history)
+	}
+}
+
+func TestJobSchedulerDue(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 0)
+	cfg := ws.GetConfig()
+	cfg.Jobs = []SurveyJob{{Name: "hourly", Every: "1h"}}
+	ws.config.Set(cfg)
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s := newJobScheduler(ws, "")
+	s.now = func() time.Time { return now }
+
+	if due := s.due(); len(due) != 0 {
+		t.Fatalf("job due on first sight: %+v", due)
+	}
+	now = now.Add(59 * time.Minute)
+	if due := s.due(); len(due) != 0 {
+		t.Fatalf("job due early: %+v", due)
+	}
+	now = now.Add(time.Minute)
+	if due := s.due(); len(due) != 1 {
+		t.Fatalf("job not due after an hour")
+	}
+	if due := s.due(); len(due) != 0 {
+		t.Fatalf("job due twice")
+	}
+}
+
+func TestJobSchedulerSkipsOverlap(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 0)
+	cfg := ws.GetConfig()
+	cfg.Jobs = []SurveyJob{{Name: "hourly", Every: "1h"}}
+	ws.config.Set(cfg)
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	s := newJobScheduler(ws, "")
+	s.now = func() time.Time { return now }
+
+	s.due()
+	now = now.Add(time.Hour)
+	first := s.due()
+	if len(first) != 1 {
+		t.Fatalf("job not due after an hour")
+	}
+	now = now.Add(time.Hour)
+	if due := s.due(); len(due) != 0 {
+		t.Fatalf("job started again while its previous run is pending: %+v", due)
+	}
+
+	// Once the run ends the next start goes ahead.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	s.execute(ctx, first[0])
+	now = now.Add(time.Hour)
+	if due := s.due(); len(due) != 1 {
+		t.Fatalf("job not due after its previous run finished")
+	}
+}
+
+func TestJobSkippedAfterStop(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 0)
+	s := newJobScheduler(ws, "")
+	ctx, cancel := context.WithCancel(context.Background())
+	s.runMu.Lock() // a run in progress
+	done := make(chan JobRun)
+	go func() { done <- s.execute(ctx, SurveyJob{Name: "queued", DurationSeconds: 60}) }()
+	cancel()
+	s.runMu.Unlock()
+	if run := <-done; run.Status != JobFailed || !run.Started.IsZero() {
+		t.Errorf("run = %+v, want skipped", run)
+	}
+	if h := s.History(); len(h) != 0 {
+		t.Errorf("skipped run recorded: %+v", h)
+	}
+}
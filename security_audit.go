@@ -0,0 +1,214 @@
+package main
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Security posture audit. Each Network is checked against securityRules
+// using the per-AP fields the scanners already fill (Security,
+// SecurityCiphers, AuthMethods, PMF, WPS) and graded from the findings.
+// Unlike rogue detection this needs no history: it judges what the
+// network advertises right now.
+
+// Severity penalties for the 0–100 score.
+var securityPenalty = map[string]int{
+	"critical": 60,
+	"high":     30,
+	"medium":   15,
+	"low":      5,
+}
+
+// SecurityFinding is one failed rule for a network.
+type SecurityFinding struct {
+	Rule        string   `json:"rule"`
+	Severity    string   `json:"severity"` // "critical", "high", "medium", "low"
+	Title       string   `json:"title"`
+	Remediation string   `json:"remediation"`
+	BSSIDs      []string `json:"bssids"` // APs the finding applies to
+}
+
+// NetworkSecurityAudit grades one network. Score starts at 100 and loses
+// securityPenalty per finding; Grade buckets the score A–F.
+type NetworkSecurityAudit struct {
+	SSID     string            `json:"ssid"`
+	Security string            `json:"security"`
+	APCount  int               `json:"apCount"`
+	Score    int               `json:"score"`
+	Grade    string            `json:"grade"`
+	Findings []SecurityFinding `json:"findings"`
+}
+
+// securityRule checks a network and returns the BSSIDs that fail, or nil.
+type securityRule struct {
+	id          string
+	severity    string
+	title       string
+	remediation string
+	check       func(n Network) []string
+}
+
+// failingAPs returns the BSSIDs of the APs for which fails is true.
+func failingAPs(n Network, fails func(ap AccessPoint) bool) []string {
+	var out []string
+	for _, ap := range n.AccessPoints {
+		if fails(ap) {
+			out = append(out, ap.BSSID)
+		}
+	}
+	return out
+}
+
+func apHasSAE(ap AccessPoint) bool { return slices.ContainsFunc(ap.AuthMethods, isSAE) }
+func apHasPSK(ap AccessPoint) bool { return slices.ContainsFunc(ap.AuthMethods, isPSK) }
+
+// sha256EnterpriseAKMs are the 802.1X AKMs that use SHA-256 or stronger
+// key derivation (and so can negotiate PMF properly).
+var sha256EnterpriseAKMs = []string{
+	"802.1X-SHA256", "FT-802.1X", "802.1X-SHA384", "FT-802.1X-SHA384", "802.1X-Suite-B", "802.1X-CNSA",
+}
+
+var securityRules = []securityRule{
+	{
+		id: "open", severity: "high",
+		title:       "Open network: traffic is unencrypted",
+		remediation: "Use WPA3-Personal/Enterprise, or OWE (Enhanced Open) for guest networks.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool { return ap.Security == "Open" })
+		},
+	},
+	{
+		id: "wep", severity: "critical",
+		title:       "WEP encryption is broken and can be cracked in minutes",
+		remediation: "Replace WEP with WPA2 (AES/CCMP) at minimum, preferably WPA3.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool {
+				return ap.Security == "WEP" || slices.ContainsFunc(ap.SecurityCiphers, func(c string) bool {
+					return strings.HasPrefix(strings.ToUpper(c), "WEP")
+				})
+			})
+		},
+	},
+	{
+		id: "wpa1", severity: "high",
+		title:       "WPA (version 1) is deprecated",
+		remediation: "Disable WPA1 and use WPA2/WPA3 only.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool { return ap.Security == "WPA" })
+		},
+	},
+	{
+		id: "tkip", severity: "high",
+		title:       "TKIP cipher enabled",
+		remediation: "Disable TKIP (WPA/WPA2 mixed mode) and allow CCMP/GCMP only; TKIP also caps 802.11n/ac rates at 54 Mbps.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool {
+				return slices.ContainsFunc(ap.SecurityCiphers, func(c string) bool { return strings.EqualFold(c, "TKIP") })
+			})
+		},
+	},
+	{
+		id: "wps", severity: "medium",
+		title:       "WPS enabled",
+		remediation: "Disable WPS; the PIN method is brute-forceable regardless of the passphrase.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool { return ap.WPS })
+		},
+	},
+	{
+		id: "wpa3_pmf", severity: "high",
+		title:       "WPA3-only network without PMF required",
+		remediation: "Set PMF (802.11w) to required; WPA3 mandates it outside transition mode.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool {
+				return apHasSAE(ap) && !apHasPSK(ap) && ap.PMF != "Required"
+			})
+		},
+	},
+	{
+		id: "sae_transition_mismatch", severity: "medium",
+		title:       "Inconsistent WPA3 transition mode across APs",
+		remediation: "Configure every AP of the SSID with the same AKMs; clients that joined with SAE can't roam to PSK-only APs without dropping to WPA2.",
+		check: func(n Network) []string {
+			var sae, pskOnly []string
+			for _, ap := range n.AccessPoints {
+				switch {
+				case apHasSAE(ap):
+					sae = append(sae, ap.BSSID)
+				case apHasPSK(ap):
+					pskOnly = append(pskOnly, ap.BSSID)
+				}
+			}
+			if len(sae) == 0 {
+				return nil
+			}
+			return pskOnly
+		},
+	},
+	{
+		id: "sae_transition", severity: "low",
+		title:       "WPA3 transition mode allows WPA2 downgrade",
+		remediation: "Move to WPA3-only (SAE, PMF required) once all clients support it.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool { return apHasSAE(ap) && apHasPSK(ap) })
+		},
+	},
+	{
+		id: "enterprise_sha1", severity: "medium",
+		title:       "WPA2-Enterprise without SHA-256 AKMs",
+		remediation: "Enable 802.1X-SHA256 (or FT-802.1X) alongside or instead of legacy 802.1X so PMF can be negotiated.",
+		check: func(n Network) []string {
+			return failingAPs(n, func(ap AccessPoint) bool {
+				return slices.ContainsFunc(ap.AuthMethods, is8021X) &&
+					!slices.ContainsFunc(ap.AuthMethods, func(akm string) bool { return slices.Contains(sha256EnterpriseAKMs, akm) })
+			})
+		},
+	},
+}
+
+// auditNetwork runs every rule against n.
+func auditNetwork(n Network) NetworkSecurityAudit {
+	audit := NetworkSecurityAudit{
+		SSID: n.SSID, Security: n.Security, APCount: len(n.AccessPoints),
+		Score: 100, Findings: []SecurityFinding{},
+	}
+	for _, r := range securityRules {
+		bssids := r.check(n)
+		if len(bssids) == 0 {
+			continue
+		}
+		audit.Findings = append(audit.Findings, SecurityFinding{
+			Rule: r.id, Severity: r.severity, Title: r.title, Remediation: r.remediation, BSSIDs: bssids,
+		})
+		audit.Score -= securityPenalty[r.severity]
+	}
+	audit.Score = max(audit.Score, 0)
+	audit.Grade = securityGrade(audit.Score)
+	return audit
+}
+
+func securityGrade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 75:
+		return "B"
+	case score >= 60:
+		return "C"
+	case score >= 40:
+		return "D"
+	}
+	return "F"
+}
+
+// GetSecurityAudit grades every network from the latest scan, worst first.
+func (ws *WiFiService) GetSecurityAudit() []NetworkSecurityAudit {
+	networks := ws.GetNetworks()
+	out := make([]NetworkSecurityAudit, 0, len(networks))
+	for _, n := range networks {
+		out = append(out, auditNetwork(n))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score < out[j].Score })
+	return out
+}
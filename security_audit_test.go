@@ -0,0 +1,89 @@
+package main
+
+import "testing"
+
+func TestAuditNetworkRules(t *testing.T) {
+	cases := []struct {
+		name  string
+		aps   []AccessPoint
+		rules []string
+		grade string
+	}{
+		{
+			name:  "wpa3 only, pmf required",
+			aps:   []AccessPoint{{BSSID: "a", Security: "WPA3", AuthMethods: []string{"SAE"}, SecurityCiphers: []string{"CCMP"}, PMF: "Required"}},
+			grade: "A",
+		},
+		{
+			name:  "wpa3 only, pmf optional",
+			aps:   []AccessPoint{{BSSID: "a", Security: "WPA3", AuthMethods: []string{"SAE"}, PMF: "Optional"}},
+			rules: []string{"wpa3_pmf"},
+			grade: "C",
+		},
+		{
+			name: "mixed mode with tkip and wps",
+			aps: []AccessPoint{{BSSID: "a", Security: "WPA2", AuthMethods: []string{"PSK"},
+				SecurityCiphers: []string{"TKIP", "CCMP"}, WPS: true, PMF: "Disabled"}},
+			rules: []string{"tkip", "wps"},
+			grade: "D",
+		},
+		{
+			name: "transition mode on one AP only",
+			aps: []AccessPoint{
+				{BSSID: "a", Security: "WPA3", AuthMethods: []string{"SAE", "PSK"}, PMF: "Optional"},
+				{BSSID: "b", Security: "WPA2", AuthMethods: []string{"PSK"}, PMF: "Disabled"},
+			},
+			rules: []string{"sae_transition_mismatch", "sae_transition"},
+			grade: "B",
+		},
+		{
+			name:  "legacy enterprise",
+			aps:   []AccessPoint{{BSSID: "a", Security: "WPA2", AuthMethods: []string{"802.1X"}, PMF: "Optional"}},
+			rules: []string{"enterprise_sha1"},
+			grade: "B",
+		},
+		{
+			name:  "wep",
+			aps:   []AccessPoint{{BSSID: "a", Security: "WEP"}},
+			rules: []string{"wep"},
+			grade: "D",
+		},
+		{
+			name:  "open",
+			aps:   []AccessPoint{{BSSID: "a", Security: "Open"}},
+			rules: []string{"open"},
+			grade: "C",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			audit := auditNetwork(Network{SSID: "x", AccessPoints: tc.aps})
+			var got []string
+			for _, f := range audit.Findings {
+				got = append(got, f.Rule)
+				if f.Remediation == "" || len(f.BSSIDs) == 0 {
+					t.Errorf("finding %s missing remediation or BSSIDs: %+v", f.Rule, f)
+				}
+			}
+			if len(got) != len(tc.rules) {
+				t.Fatalf("rules = %v, want %v", got, tc.rules)
+			}
+			for i := range got {
+				if got[i] != tc.rules[i] {
+					t.Errorf("rules = %v, want %v", got, tc.rules)
+				}
+			}
+			if audit.Grade != tc.grade {
+				t.Errorf("grade %s (score %d), want %s", audit.Grade, audit.Score, tc.grade)
+			}
+		})
+	}
+}
+
+func TestSecurityAuditFromSimulation(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 1)
+	audits := ws.GetSecurityAudit()
+	if len(audits) != 1 || audits[0].SSID != "Office" || audits[0].APCount == 0 {
+		t.Fatalf("audits = %+v", audits)
+	}
+}
@@ -0,0 +1,461 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Session recording. Each session is one append-only NDJSON file under
+// <user config dir>/wifi-app/sessions/<id>.ndjson. The first line is a
+// header record describing the session; every following line is one
+// scan tick, client sample, roaming event, latency probe, or throughput
+// test, in the order they happened.
+//
+// NDJSON rather than an embedded database keeps the binary cgo-free on
+// Linux and Windows, survives a crash with at most one torn trailing line
+// (which the reader skips), and stays greppable with standard tools.
+const (
+	sessionRecordHeader     = "session"
+	sessionRecordScan       = "scan"
+	sessionRecordClient     = "client"
+	sessionRecordRoam       = "roam"
+	sessionRecordLatency    = "latency"
+	sessionRecordThroughput = "throughput"
+)
+
+// sessionFileExt is the on-disk suffix for session files.
+const sessionFileExt = ".ndjson"
+
+// sessionIDPattern restricts IDs accepted from the frontend to the shape
+// newSessionID produces, so OpenSession/DeleteSession can't be pointed at
+// arbitrary paths.
+var sessionIDPattern = regexp.MustCompile(`^[0-9]{8}-[0-9]{6}(-[0-9]+)?$`)
+
+// SessionInfo describes one recorded session. EndedAt is the last write
+// time of the file, which for an active session is the most recent record.
+type SessionInfo struct {
+	ID        string    `json:"id"`
+	StartedAt time.Time `json:"startedAt"`
+	EndedAt   time.Time `json:"endedAt"`
+	Interface string    `json:"interface"`
+	Hostname  string    `json:"hostname"`
+	SizeBytes int64     `json:"sizeBytes"`
+	Active    bool      `json:"active"` // currently being written to
+	// Capabilities of the backend on each interface when recording began.
+	Capabilities []BackendCapabilities `json:"capabilities,omitempty"`
+	// ScanOptions the session scanned with; nil for a full active scan.
+	ScanOptions *ScanOptions `json:"scanOptions,omitempty"`
+}
+
+// SessionScan is one recorded scan tick: the raw (normalized) access points
+// the backend returned, before SSID aggregation.
+type SessionScan struct {
+	Timestamp    time.Time     `json:"timestamp"`
+	Interface    string        `json:"interface"`
+	AccessPoints []AccessPoint `json:"accessPoints"`
+}
+
+// Session is a fully loaded recording, as returned by OpenSession.
+type Session struct {
+	Info          SessionInfo        `json:"info"`
+	Scans         []SessionScan      `json:"scans"`
+	ClientSamples []ClientStats      `json:"clientSamples"`
+	RoamingEvents []RoamingEvent     `json:"roamingEvents"`
+	LatencyProbes []LatencyProbe     `json:"latencyProbes"`
+	Throughputs   []ThroughputResult `json:"throughputs"`
+}
+
+// sessionRecord is the on-disk envelope for every line in a session file.
+type sessionRecord struct {
+	Type      string          `json:"type"`
+	Timestamp time.Time       `json:"ts"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// SessionRecorder owns the sessions directory and the currently open
+// session file, if any. All Record* methods are no-ops while no session is
+// active, so callers can invoke them unconditionally on every tick.
+type SessionRecorder struct {
+	dir string
+
+	mu     sync.Mutex
+	file   *os.File
+	enc    *json.Encoder
+	info   SessionInfo
+	failed bool // last write failed; logged once until the next Start
+}
+
+// sessionsDir returns the directory session files live in, next to
+// config.toml.
+func sessionsDir() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "sessions"), nil
+}
+
+// newSessionRecorder returns a recorder rooted at dir. The directory is
+// created lazily on the first Start so merely constructing the service
+// doesn't touch the filesystem.
+func newSessionRecorder(dir string) *SessionRecorder {
+	return &SessionRecorder{dir: dir}
+}
+
+// Start opens a new session file and writes its header. A session already
+// in progress is closed first. caps describes the backend on the
+// session's interfaces and scan how it scans them.
+func (r *SessionRecorder) Start(iface string, caps []BackendCapabilities, scan ScanOptions) (SessionInfo, error) {
+	if r == nil {
+		return SessionInfo{}, errors.New("session recorder not configured")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeLocked()
+
+	if err := os.MkdirAll(r.dir, 0o755); err != nil {
+		return SessionInfo{}, fmt.Errorf("mkdir %s: %w", r.dir, err)
+	}
+	_ = chownToSudoUser(r.dir)
+
+	now := time.Now()
+	id, f, err := r.createSessionFile(now)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	_ = chownToSudoUser(f.Name())
+
+	hostname, _ := os.Hostname()
+	r.file = f
+	r.enc = json.NewEncoder(f)
+	r.failed = false
+	r.info = SessionInfo{
+		ID:           id,
+		StartedAt:    now,
+		Interface:    iface,
+		Hostname:     hostname,
+		Capabilities: caps,
+	}
+	if scan.targeted() {
+		r.info.ScanOptions = &scan
+	}
+	r.writeLocked(sessionRecordHeader, now, r.info)
+	slog.Info("session recording started", "event", "session_start", "id", id, "interface", iface)
+
+	info := r.info
+	info.Active = true
+	return info, nil
+}
+
+// createSessionFile picks an unused ID for the given start time. Two
+// sessions started within the same second get -2, -3, ... suffixes.
+func (r *SessionRecorder) createSessionFile(now time.Time) (string, *os.File, error) {
+	base := now.Format("20060102-150405")
+	for n := 1; n < 100; n++ {
+		id := base
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		path := filepath.Join(r.dir, id+sessionFileExt)
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL|os.O_APPEND, 0o644)
+		if err == nil {
+			return id, f, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return "", nil, fmt.Errorf("create %s: %w", path, err)
+		}
+	}
+	return "", nil, fmt.Errorf("too many sessions started at %s", base)
+}
+
+// Stop closes the active session, if any.
+func (r *SessionRecorder) Stop() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closeLocked()
+}
+
+func (r *SessionRecorder) closeLocked() {
+	if r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		slog.Warn("session file close failed", "id", r.info.ID, "err", err)
+	}
+	slog.Info("session recording stopped", "event", "session_stop", "id", r.info.ID)
+	r.file = nil
+	r.enc = nil
+	r.info = SessionInfo{}
+}
+
+// Active returns the in-progress session, if any.
+func (r *SessionRecorder) Active() (SessionInfo, bool) {
+	if r == nil {
+		return SessionInfo{}, false
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return SessionInfo{}, false
+	}
+	info := r.info
+	info.Active = true
+	return info, true
+}
+
+// RecordScan appends one scan tick.
+func (r *SessionRecorder) RecordScan(ts time.Time, iface string, aps []AccessPoint) {
+	r.record(sessionRecordScan, ts, SessionScan{Timestamp: ts, Interface: iface, AccessPoints: aps})
+}
+
+// RecordClient appends one client stats sample. The rolling SignalHistory
+// and RoamingHistory slices are dropped: they're reconstructible from the
+// samples and roam records, and writing them every tick would make the
+// file grow quadratically.
+func (r *SessionRecorder) RecordClient(ts time.Time, stats ClientStats) {
+	stats.SignalHistory = nil
+	stats.RoamingHistory = nil
+	r.record(sessionRecordClient, ts, stats)
+}
+
+// RecordRoam appends one roaming event.
+func (r *SessionRecorder) RecordRoam(ev RoamingEvent) {
+	r.record(sessionRecordRoam, ev.Timestamp, ev)
+}
+
+// RecordLatency appends one latency probe result.
+func (r *SessionRecorder) RecordLatency(p LatencyProbe) {
+	r.record(sessionRecordLatency, p.Timestamp, p)
+}
+
+// RecordThroughput appends one throughput test result.
+func (r *SessionRecorder) RecordThroughput(t ThroughputResult) {
+	r.record(sessionRecordThroughput, t.Timestamp, t)
+}
+
+// handleEvent is the recorder's EventBus subscriber.
+func (r *SessionRecorder) handleEvent(e Event) {
+	switch ev := e.(type) {
+	case ScanResultEvent:
+		r.RecordScan(ev.Timestamp, ev.Interface, ev.APs)
+	case ClientUpdateEvent:
+		if ev.Stats.Connected {
+			r.RecordClient(ev.Timestamp, ev.Stats)
+		}
+	case RoamEvent:
+		r.RecordRoam(ev.Roam)
+	case LatencyProbeEvent:
+		r.RecordLatency(ev.Probe)
+	case ThroughputEvent:
+		r.RecordThroughput(ev.Result)
+	}
+}
+
+func (r *SessionRecorder) record(kind string, ts time.Time, data interface{}) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.file == nil {
+		return
+	}
+	r.writeLocked(kind, ts, data)
+}
+
+// writeLocked encodes one record. json.Encoder issues a single Write per
+// value, so with O_APPEND a crash can tear at most the final line.
+func (r *SessionRecorder) writeLocked(kind string, ts time.Time, data interface{}) {
+	raw, err := json.Marshal(data)
+	if err == nil {
+		err = r.enc.Encode(sessionRecord{Type: kind, Timestamp: ts, Data: raw})
+	}
+	if err != nil {
+		if !r.failed {
+			slog.Warn("session write failed", "event", "session_write_error", "id", r.info.ID, "type", kind, "err", err)
+		}
+		r.failed = true
+		return
+	}
+	r.failed = false
+}
+
+// List returns every session on disk, newest first.
+func (r *SessionRecorder) List() ([]SessionInfo, error) {
+	if r == nil {
+		return []SessionInfo{}, nil
+	}
+	entries, err := os.ReadDir(r.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return []SessionInfo{}, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", r.dir, err)
+	}
+	active, hasActive := r.Active()
+
+	out := make([]SessionInfo, 0, len(entries))
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), sessionFileExt)
+		if !ok || e.IsDir() || !sessionIDPattern.MatchString(id) {
+			continue
+		}
+		info, err := r.readInfo(id)
+		if err != nil {
+			slog.Warn("skipping unreadable session", "id", id, "err", err)
+			continue
+		}
+		info.Active = hasActive && active.ID == id
+		out = append(out, info)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].StartedAt.After(out[j].StartedAt)
+	})
+	return out, nil
+}
+
+// readInfo reads only the header line plus file metadata, so listing stays
+// cheap even for multi-hour recordings.
+func (r *SessionRecorder) readInfo(id string) (SessionInfo, error) {
+	path := filepath.Join(r.dir, id+sessionFileExt)
+	f, err := os.Open(path)
+	if err != nil {
+		return SessionInfo{}, err
+	}
+	defer f.Close()
+	st, err := f.Stat()
+	if err != nil {
+		return SessionInfo{}, err
+	}
+
+	var info SessionInfo
+	br := bufio.NewReader(f)
+	line, err := br.ReadBytes('\n')
+	if err != nil && len(line) == 0 {
+		return SessionInfo{}, fmt.Errorf("read header: %w", err)
+	}
+	var rec sessionRecord
+	if err := json.Unmarshal(line, &rec); err != nil || rec.Type != sessionRecordHeader {
+		return SessionInfo{}, fmt.Errorf("missing session header")
+	}
+	if err := json.Unmarshal(rec.Data, &info); err != nil {
+		return SessionInfo{}, fmt.Errorf("decode header: %w", err)
+	}
+	info.ID = id
+	info.EndedAt = st.ModTime()
+	info.SizeBytes = st.Size()
+	return info, nil
+}
+
+// Open loads a whole session into memory. Unknown record types are skipped
+// so older builds can read files written by newer ones, and a torn final
+// line (crash mid-write) is tolerated.
+func (r *SessionRecorder) Open(id string) (Session, error) {
+	if r == nil {
+		return Session{}, errors.New("session recorder not configured")
+	}
+	if !sessionIDPattern.MatchString(id) {
+		return Session{}, fmt.Errorf("invalid session id %q", id)
+	}
+	info, err := r.readInfo(id)
+	if err != nil {
+		return Session{}, fmt.Errorf("open session %s: %w", id, err)
+	}
+	if active, ok := r.Active(); ok && active.ID == id {
+		info.Active = true
+	}
+
+	f, err := os.Open(filepath.Join(r.dir, id+sessionFileExt))
+	if err != nil {
+		return Session{}, fmt.Errorf("open session %s: %w", id, err)
+	}
+	defer f.Close()
+
+	s := Session{
+		Info:          info,
+		Scans:         []SessionScan{},
+		ClientSamples: []ClientStats{},
+		RoamingEvents: []RoamingEvent{},
+		LatencyProbes: []LatencyProbe{},
+		Throughputs:   []ThroughputResult{},
+	}
+	sc := bufio.NewScanner(f)
+	// A single scan record in a dense environment can run to a few hundred
+	// KB; give the scanner plenty of headroom.
+	sc.Buffer(make([]byte, 0, 256*1024), 16*1024*1024)
+	lineNo := 0
+	for sc.Scan() {
+		lineNo++
+		var rec sessionRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			slog.Debug("skipping malformed session line", "id", id, "line", lineNo, "err", err)
+			continue
+		}
+		var decodeErr error
+		switch rec.Type {
+		case sessionRecordScan:
+			var v SessionScan
+			if decodeErr = json.Unmarshal(rec.Data, &v); decodeErr == nil {
+				s.Scans = append(s.Scans, v)
+			}
+		case sessionRecordClient:
+			var v ClientStats
+			if decodeErr = json.Unmarshal(rec.Data, &v); decodeErr == nil {
+				s.ClientSamples = append(s.ClientSamples, v)
+			}
+		case sessionRecordRoam:
+			var v RoamingEvent
+			if decodeErr = json.Unmarshal(rec.Data, &v); decodeErr == nil {
+				s.RoamingEvents = append(s.RoamingEvents, v)
+			}
+		case sessionRecordLatency:
+			var v LatencyProbe
+			if decodeErr = json.Unmarshal(rec.Data, &v); decodeErr == nil {
+				s.LatencyProbes = append(s.LatencyProbes, v)
+			}
+		case sessionRecordThroughput:
+			var v ThroughputResult
+			if decodeErr = json.Unmarshal(rec.Data, &v); decodeErr == nil {
+				s.Throughputs = append(s.Throughputs, v)
+			}
+		}
+		if decodeErr != nil {
+			slog.Debug("skipping undecodable session record", "id", id, "line", lineNo, "type", rec.Type, "err", decodeErr)
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return Session{}, fmt.Errorf("read session %s: %w", id, err)
+	}
+	return s, nil
+}
+
+// Delete removes a session file. The active session can't be deleted —
+// stop recording first.
+func (r *SessionRecorder) Delete(id string) error {
+	if r == nil {
+		return errors.New("session recorder not configured")
+	}
+	if !sessionIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid session id %q", id)
+	}
+	if active, ok := r.Active(); ok && active.ID == id {
+		return fmt.Errorf("session %s is still recording", id)
+	}
+	if err := os.Remove(filepath.Join(r.dir, id+sessionFileExt)); err != nil {
+		return fmt.Errorf("delete session %s: %w", id, err)
+	}
+	return nil
+}
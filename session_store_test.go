@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSessionRecorderRoundTrip(t *testing.T) {
+	r := newSessionRecorder(t.TempDir())
+
+	// Record* before Start must be a silent no-op.
+	r.RecordScan(time.Now(), "wlan0", []AccessPoint{{BSSID: "aa:bb:cc:00:00:01"}})
+
+	info, err := r.Start("wlan0", []BackendCapabilities{{Interface: "wlan0", Backend: "nl80211", Survey: true}}, ScanOptions{Channels: []int{36}, Passive: true})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if !info.Active || info.Interface != "wlan0" {
+		t.Fatalf("Start info = %+v", info)
+	}
+
+	ts := time.Now()
+	r.RecordScan(ts, "wlan0", []AccessPoint{
+		{BSSID: "aa:bb:cc:00:00:01", SSID: "Office", Signal: -50},
+		{BSSID: "aa:bb:cc:00:00:02", SSID: "Office", Signal: -67},
+	})
+	r.RecordClient(ts, ClientStats{
+		Connected:     true,
+		BSSID:         "aa:bb:cc:00:00:01",
+		Signal:        -50,
+		SignalHistory: []SignalDataPoint{{Signal: -50}},
+	})
+	r.RecordRoam(RoamingEvent{Timestamp: ts, PreviousBSSID: "aa:bb:cc:00:00:01", NewBSSID: "aa:bb:cc:00:00:02"})
+	r.RecordLatency(LatencyProbe{Timestamp: ts, Label: "gateway", RTTMs: 2.5})
+
+	if err := r.Delete(info.ID); err == nil {
+		t.Error("Delete of the active session succeeded, want error")
+	}
+
+	list, err := r.List()
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(list) != 1 || list[0].ID != info.ID || !list[0].Active {
+		t.Fatalf("List = %+v, want the one active session", list)
+	}
+
+	r.Stop()
+	s, err := r.Open(info.ID)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if s.Info.Active {
+		t.Error("Info.Active = true after Stop")
+	}
+	if len(s.Info.Capabilities) != 1 || !s.Info.Capabilities[0].Survey {
+		t.Errorf("Info.Capabilities = %+v", s.Info.Capabilities)
+	}
+	if s.Info.ScanOptions == nil || !s.Info.ScanOptions.Passive {
+		t.Errorf("Info.ScanOptions = %+v", s.Info.ScanOptions)
+	}
+	if len(s.Scans) != 1 || len(s.Scans[0].AccessPoints) != 2 {
+		t.Errorf("Scans = %+v", s.Scans)
+	}
+	if len(s.ClientSamples) != 1 || s.ClientSamples[0].BSSID != "aa:bb:cc:00:00:01" {
+		t.Errorf("ClientSamples = %+v", s.ClientSamples)
+	}
+	if s.ClientSamples[0].SignalHistory != nil {
+		t.Error("client sample persisted SignalHistory, want it stripped")
+	}
+	if len(s.RoamingEvents) != 1 || s.RoamingEvents[0].NewBSSID != "aa:bb:cc:00:00:02" {
+		t.Errorf("RoamingEvents = %+v", s.RoamingEvents)
+	}
+	if len(s.LatencyProbes) != 1 || s.LatencyProbes[0].RTTMs != 2.5 {
+		t.Errorf("LatencyProbes = %+v", s.LatencyProbes)
+	}
+
+	if err := r.Delete(info.ID); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if list, _ := r.List(); len(list) != 0 {
+		t.Errorf("List after Delete = %+v", list)
+	}
+}
+
+func TestSessionRecorderTornTail(t *testing.T) {
+	dir := t.TempDir()
+	r := newSessionRecorder(dir)
+	info, err := r.Start("wlan0", nil, ScanOptions{})
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	r.RecordRoam(RoamingEvent{Timestamp: time.Now(), NewBSSID: "aa:bb:cc:00:00:02"})
+	r.Stop()
+
+	// Simulate a crash mid-write: a partial JSON line with no newline.
+	f, err := os.OpenFile(filepath.Join(dir, info.ID+sessionFileExt), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = f.WriteString(`{"type":"roam","ts":"2024-`)
+	f.Close()
+
+	s, err := r.Open(info.ID)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if len(s.RoamingEvents) != 1 {
+		t.Errorf("RoamingEvents = %d, want 1 (torn tail skipped)", len(s.RoamingEvents))
+	}
+}
+
+func TestSessionRecorderRejectsBadID(t *testing.T) {
+	r := newSessionRecorder(t.TempDir())
+	for _, id := range []string{"../config", "", "20240101-120000/../../x"} {
+		if _, err := r.Open(id); err == nil {
+			t.Errorf("Open(%q) succeeded, want error", id)
+		}
+		if err := r.Delete(id); err == nil {
+			t.Errorf("Delete(%q) succeeded, want error", id)
+		}
+	}
+}
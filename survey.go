@@ -0,0 +1,721 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Site surveys. While scanning, the user starts a survey, walks the site
+// and marks named measurement points ("Room 204", "Lobby east"). Marking a
+// point captures a burst of survey_burst_scans scans — the scan loops skip
+// their interval sleep while a capture is pending — and stores per-BSSID
+// signal statistics for the point together with the primary radio's
+// ClientStats and the latency summaries at that moment.
+//
+// Each survey is one JSON document, surveys/<id>.json next to config.toml,
+// rewritten after every point so a crash loses at most the point being
+// captured.
+
+// Survey is a walk-through survey and its measurement points, in the order
+// they were taken.
+type Survey struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	Started   time.Time     `json:"started"`
+	Ended     time.Time     `json:"ended"` // zero while the survey is active
+	FloorPlan *FloorPlan    `json:"floorPlan,omitempty"`
+	Points    []SurveyPoint `json:"points"`
+	// Capabilities of the backend on each interface points were taken on,
+	// as of the latest point.
+	Capabilities []BackendCapabilities `json:"capabilities,omitempty"`
+}
+
+// SurveyInfo is the listing form of a survey.
+type SurveyInfo struct {
+	ID      string    `json:"id"`
+	Name    string    `json:"name"`
+	Started time.Time `json:"started"`
+	Ended   time.Time `json:"ended"`
+	Points  int       `json:"points"`
+	Active  bool      `json:"active"`
+}
+
+// SurveyPoint is one named measurement location.
+type SurveyPoint struct {
+	Name       string                 `json:"name"`
+	Started    time.Time              `json:"started"`
+	Finished   time.Time              `json:"finished"`
+	Interfaces []string               `json:"interfaces"`
+	Scans      int                    `json:"scans"`        // primary-radio scans in the burst
+	At         *FloorPoint            `json:"at,omitempty"` // position on the floor plan, if placed
+	APs        []SurveyAPStats        `json:"aps"`          // strongest first
+	Client     ClientStats            `json:"client"`
+	Latency    []LatencyTargetSummary `json:"latency"`
+}
+
+// SurveyAPStats summarizes one BSSID's signal over a point's burst.
+// Samples can exceed the burst size when several radios hear the AP.
+type SurveyAPStats struct {
+	BSSID    string  `json:"bssid"`
+	SSID     string  `json:"ssid"`
+	Band     string  `json:"band"`
+	Channel  int     `json:"channel"`
+	Security string  `json:"security"`
+	Samples  int     `json:"samples"`
+	Min      int     `json:"min"`
+	Max      int     `json:"max"`
+	Avg      float64 `json:"avg"`
+	Stddev   float64 `json:"stddev"`
+}
+
+// SurveyComparison matches the points of two surveys by name (e.g. before
+// and after moving an AP) and reports each BSSID's average signal change.
+type SurveyComparison struct {
+	A      SurveyInfo              `json:"a"`
+	B      SurveyInfo              `json:"b"`
+	Points []SurveyPointComparison `json:"points"` // A's order, then points only in B
+}
+
+type SurveyPointComparison struct {
+	Name string          `json:"name"`
+	InA  bool            `json:"inA"`
+	InB  bool            `json:"inB"`
+	APs  []SurveyAPDelta `json:"aps"`
+}
+
+// SurveyAPDelta is one BSSID at one point. A or B is nil when the BSSID
+// wasn't heard there in that survey; Delta is B − A when both were.
+type SurveyAPDelta struct {
+	BSSID string   `json:"bssid"`
+	SSID  string   `json:"ssid"`
+	A     *float64 `json:"a"`
+	B     *float64 `json:"b"`
+	Delta *float64 `json:"delta"`
+}
+
+// surveyFileExt is the on-disk suffix for survey files. IDs have the same
+// shape as session IDs and are checked against sessionIDPattern.
+const surveyFileExt = ".json"
+
+// surveysDir returns the directory surveys live in, next to config.toml.
+func surveysDir() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "surveys"), nil
+}
+
+// surveyStore owns the active survey and the point capture in progress.
+// It is a bus subscriber: captures are fed by scan and client events.
+type surveyStore struct {
+	dir     string // "" keeps surveys in memory only
+	cfg     *liveConfig
+	bus     *EventBus
+	now     func() time.Time
+	latency func() []LatencyTargetSummary
+	caps    func([]string) []BackendCapabilities
+
+	mu      sync.Mutex
+	active  *Survey
+	capture *surveyCapture
+	wakeCh  chan struct{} // closed when a capture starts
+}
+
+// surveyCapture accumulates one point's burst.
+type surveyCapture struct {
+	point   SurveyPoint
+	primary string
+	want    int
+	samples map[string]*surveySamples // by lower-case BSSID
+	done    chan struct{}
+	err     error
+}
+
+type surveySamples struct {
+	ap      AccessPoint // latest sighting, for SSID/band/channel
+	signals []int
+}
+
+func newSurveyStore(dir string, cfg *liveConfig, bus *EventBus, now func() time.Time, latency func() []LatencyTargetSummary, caps func([]string) []BackendCapabilities) *surveyStore {
+	return &surveyStore{dir: dir, cfg: cfg, bus: bus, now: now, latency: latency, caps: caps, wakeCh: make(chan struct{})}
+}
+
+// Start begins a survey. Only one survey is active at a time.
+func (s *surveyStore) Start(name string) (Survey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return Survey{}, errors.New("survey name is required")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active != nil {
+		return Survey{}, fmt.Errorf("survey %q is already in progress", s.active.Name)
+	}
+	started := s.now()
+	id, err := s.newID(started)
+	if err != nil {
+		return Survey{}, err
+	}
+	s.active = &Survey{ID: id, Name: name, Started: started, Points: []SurveyPoint{}}
+	if err := s.saveLocked(s.active); err != nil {
+		s.active = nil
+		return Survey{}, err
+	}
+	return *s.active, nil
+}
+
+// newID picks an unused ID for a survey started at t.
+func (s *surveyStore) newID(t time.Time) (string, error) {
+	base := t.Format("20060102-150405")
+	for n := 1; n < 100; n++ {
+		id := base
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		if s.dir == "" {
+			return id, nil
+		}
+		if _, err := os.Stat(filepath.Join(s.dir, id+surveyFileExt)); errors.Is(err, os.ErrNotExist) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("too many surveys started at %s", base)
+}
+
+// Stop ends the active survey. A point still being captured is abandoned.
+func (s *surveyStore) Stop() (Survey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		return Survey{}, errors.New("no survey in progress")
+	}
+	s.finishCaptureLocked(errors.New("survey stopped"))
+	s.active.Ended = s.now()
+	survey := *s.active
+	s.active = nil
+	return survey, s.saveLocked(&survey)
+}
+
+// Active returns the survey in progress.
+func (s *surveyStore) Active() (Survey, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.active == nil {
+		return Survey{}, false
+	}
+	survey := *s.active
+	survey.Points = append([]SurveyPoint(nil), s.active.Points...)
+	return survey, true
+}
+
+// Mark captures a measurement point named name, optionally placed on the
+// floor plan, on interfaces (primary first) and blocks until its burst is
+// complete, ctx ends, or the burst takes far longer than the scan interval
+// allows.
+func (s *surveyStore) Mark(ctx context.Context, name string, at *FloorPoint, interfaces []string) (SurveyPoint, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return SurveyPoint{}, errors.New("point name is required")
+	}
+	if len(interfaces) == 0 {
+		return SurveyPoint{}, errors.New("start scanning before marking survey points")
+	}
+	cfg := s.cfg.Get()
+	c := &surveyCapture{
+		point:   SurveyPoint{Name: name, Started: s.now(), Interfaces: interfaces, At: at},
+		primary: interfaces[0],
+		want:    cfg.SurveyBurstScans,
+		samples: make(map[string]*surveySamples),
+		done:    make(chan struct{}),
+	}
+	s.mu.Lock()
+	switch {
+	case s.active == nil:
+		s.mu.Unlock()
+		return SurveyPoint{}, errors.New("no survey in progress")
+	case s.capture != nil:
+		s.mu.Unlock()
+		return SurveyPoint{}, fmt.Errorf("point %q is still being captured", s.capture.point.Name)
+	}
+	if err := s.active.FloorPlan.check(at); err != nil {
+		s.mu.Unlock()
+		return SurveyPoint{}, err
+	}
+	s.capture = c
+	close(s.wakeCh) // cut the scan loops' interval sleep short
+	s.wakeCh = make(chan struct{})
+	s.mu.Unlock()
+
+	// Each scan can take a while (backoff retries on a flaky driver);
+	// allow generously for it on top of the interval.
+	timeout := time.Duration(c.want) * (cfg.ScanInterval() + 30*time.Second)
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		s.abandon(c, ctx.Err())
+	case <-timer.C:
+		s.abandon(c, fmt.Errorf("no complete burst of %d scans within %s", c.want, timeout))
+	}
+	<-c.done
+	if c.err != nil {
+		return SurveyPoint{}, fmt.Errorf("point %q: %w", name, c.err)
+	}
+	return c.point, nil
+}
+
+func (s *surveyStore) abandon(c *surveyCapture, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.capture == c {
+		s.finishCaptureLocked(err)
+	}
+}
+
+func (s *surveyStore) finishCaptureLocked(err error) {
+	if s.capture == nil {
+		return
+	}
+	s.capture.err = err
+	close(s.capture.done)
+	s.capture = nil
+}
+
+// capturing reports whether a point burst is pending; the scan loops
+// don't sleep between scans while it is.
+func (s *surveyStore) capturing() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.capture != nil
+}
+
+// wake returns a channel closed when the next capture starts.
+func (s *surveyStore) wake() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.wakeCh
+}
+
+func (s *surveyStore) handleEvent(e Event) {
+	switch ev := e.(type) {
+	case ScanResultEvent:
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		c := s.capture
+		if c == nil {
+			return
+		}
+		for _, ap := range ev.APs {
+			key := strings.ToLower(ap.BSSID)
+			if key == "" || ap.Stale {
+				continue // a stale AP wasn't heard at this point
+			}
+			smp := c.samples[key]
+			if smp == nil {
+				smp = &surveySamples{}
+				c.samples[key] = smp
+			}
+			smp.ap = ap
+			smp.signals = append(smp.signals, ap.Signal)
+		}
+		if ev.Interface == c.primary {
+			c.point.Scans++
+		}
+	case ClientUpdateEvent:
+		// The primary radio's client update follows its scan result, so
+		// it closes the burst with the freshest client snapshot.
+		if point, ok := s.completeCapture(ev); ok {
+			s.bus.Publish(SurveyPointEvent{Point: point})
+		}
+	}
+}
+
+// completeCapture finishes the pending capture if ev ends its burst, and
+// records the point in the active survey.
+func (s *surveyStore) completeCapture(ev ClientUpdateEvent) (SurveyPoint, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c := s.capture
+	if c == nil || ev.Interface != c.primary || c.point.Scans < c.want {
+		return SurveyPoint{}, false
+	}
+	c.point.Finished = ev.Timestamp
+	c.point.Client = ev.Stats
+	c.point.Client.SignalHistory = nil // the survey keeps the burst, not the session
+	c.point.Client.RoamingHistory = nil
+	c.point.APs = summarizeSurveySamples(c.samples)
+	c.point.Latency = []LatencyTargetSummary{}
+	if s.latency != nil {
+		for _, l := range s.latency() {
+			l.History = nil
+			c.point.Latency = append(c.point.Latency, l)
+		}
+	}
+	s.active.Points = append(s.active.Points, c.point)
+	if s.caps != nil {
+		s.active.Capabilities = mergeCapabilities(s.active.Capabilities, s.caps(c.point.Interfaces))
+	}
+	if err := s.saveLocked(s.active); err != nil {
+		// Keep the point in memory; Stop retries the save.
+		slog.Warn("saving survey failed", "event", "survey_error", "id", s.active.ID, "err", err)
+	}
+	point := c.point
+	s.finishCaptureLocked(nil)
+	return point, true
+}
+
+// mergeCapabilities replaces the entries of have for the interfaces in
+// latest and appends the rest.
+func mergeCapabilities(have, latest []BackendCapabilities) []BackendCapabilities {
+	out := slices.DeleteFunc(slices.Clone(have), func(c BackendCapabilities) bool {
+		return slices.ContainsFunc(latest, func(l BackendCapabilities) bool { return l.Interface == c.Interface })
+	})
+	return append(out, latest...)
+}
+
+func summarizeSurveySamples(samples map[string]*surveySamples) []SurveyAPStats {
+	out := make([]SurveyAPStats, 0, len(samples))
+	for _, smp := range samples {
+		st := SurveyAPStats{
+			BSSID: smp.ap.BSSID, SSID: smp.ap.SSID, Band: smp.ap.Band, Channel: smp.ap.Channel, Security: smp.ap.Security,
+			Samples: len(smp.signals), Min: smp.signals[0], Max: smp.signals[0],
+		}
+		var sum float64
+		for _, v := range smp.signals {
+			st.Min, st.Max = min(st.Min, v), max(st.Max, v)
+			sum += float64(v)
+		}
+		st.Avg = sum / float64(st.Samples)
+		var variance float64
+		for _, v := range smp.signals {
+			variance += (float64(v) - st.Avg) * (float64(v) - st.Avg)
+		}
+		st.Stddev = math.Sqrt(variance / float64(st.Samples))
+		out = append(out, st)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Avg != out[j].Avg {
+			return out[i].Avg > out[j].Avg
+		}
+		return out[i].BSSID < out[j].BSSID
+	})
+	return out
+}
+
+// saveLocked rewrites a survey's file atomically.
+func (s *surveyStore) saveLocked(survey *Survey) error {
+	if s.dir == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(survey, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return fmt.Errorf("create %s: %w", s.dir, err)
+	}
+	path := filepath.Join(s.dir, survey.ID+surveyFileExt)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("save survey: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("save survey: %w", err)
+	}
+	_ = chownToSudoUser(path)
+	return nil
+}
+
+// List returns every survey on disk, newest first.
+func (s *surveyStore) List() ([]SurveyInfo, error) {
+	out := []SurveyInfo{}
+	if s.dir == "" {
+		if survey, ok := s.Active(); ok {
+			out = append(out, survey.info(true))
+		}
+		return out, nil
+	}
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return out, nil
+		}
+		return nil, fmt.Errorf("read %s: %w", s.dir, err)
+	}
+	active, hasActive := s.Active()
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), surveyFileExt)
+		if !ok || e.IsDir() || !sessionIDPattern.MatchString(id) {
+			continue
+		}
+		survey, err := s.Open(id)
+		if err != nil {
+			slog.Warn("skipping unreadable survey", "id", id, "err", err)
+			continue
+		}
+		out = append(out, survey.info(hasActive && active.ID == id))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Started.After(out[j].Started)
+	})
+	return out, nil
+}
+
+func (sv Survey) info(active bool) SurveyInfo {
+	return SurveyInfo{ID: sv.ID, Name: sv.Name, Started: sv.Started, Ended: sv.Ended, Points: len(sv.Points), Active: active}
+}
+
+// Open loads a survey by ID; the active survey is served from memory.
+func (s *surveyStore) Open(id string) (Survey, error) {
+	if !sessionIDPattern.MatchString(id) {
+		return Survey{}, fmt.Errorf("invalid survey id %q", id)
+	}
+	if survey, ok := s.Active(); ok && survey.ID == id {
+		return survey, nil
+	}
+	return s.read(id)
+}
+
+// read loads a survey file.
+func (s *surveyStore) read(id string) (Survey, error) {
+	if s.dir == "" {
+		return Survey{}, fmt.Errorf("survey %s not found", id)
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, id+surveyFileExt))
+	if err != nil {
+		return Survey{}, fmt.Errorf("open survey %s: %w", id, err)
+	}
+	var survey Survey
+	if err := json.Unmarshal(data, &survey); err != nil {
+		return Survey{}, fmt.Errorf("decode survey %s: %w", id, err)
+	}
+	survey.ID = id
+	return survey, nil
+}
+
+// Delete removes a survey file. The active survey can't be deleted.
+func (s *surveyStore) Delete(id string) error {
+	if !sessionIDPattern.MatchString(id) {
+		return fmt.Errorf("invalid survey id %q", id)
+	}
+	if survey, ok := s.Active(); ok && survey.ID == id {
+		return fmt.Errorf("survey %s is still in progress", id)
+	}
+	if s.dir == "" {
+		return fmt.Errorf("survey %s not found", id)
+	}
+	survey, err := s.read(id)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		slog.Warn("deleting unreadable survey", "id", id, "err", err)
+	}
+	if err := os.Remove(filepath.Join(s.dir, id+surveyFileExt)); err != nil {
+		return fmt.Errorf("delete survey %s: %w", id, err)
+	}
+	if survey.FloorPlan != nil {
+		if path, err := survey.FloorPlan.path(s.dir, id); err != nil {
+			slog.Warn("floor plan not removed", "id", id, "err", err)
+		} else {
+			_ = os.Remove(path)
+		}
+	}
+	return nil
+}
+
+// update applies fn to a survey, in memory for the active one, and saves
+// it. fn must validate before it modifies anything.
+func (s *surveyStore) update(id string, fn func(*Survey) error) (Survey, error) {
+	if !sessionIDPattern.MatchString(id) {
+		return Survey{}, fmt.Errorf("invalid survey id %q", id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	survey := s.active
+	if survey == nil || survey.ID != id {
+		loaded, err := s.read(id)
+		if err != nil {
+			return Survey{}, err
+		}
+		survey = &loaded
+	}
+	if err := fn(survey); err != nil {
+		return Survey{}, err
+	}
+	return *survey, s.saveLocked(survey)
+}
+
+// Export renders a survey as "json" (the stored document) or "csv" (one
+// row per point per BSSID).
+func (s *surveyStore) Export(id, format string) (string, error) {
+	survey, err := s.Open(id)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(survey, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"point", "timestamp", "ssid", "bssid", "band", "channel", "security", "samples", "min_dbm", "avg_dbm", "max_dbm", "stddev_db", "connected"})
+		f := func(v float64) string { return strconv.FormatFloat(v, 'f', 1, 64) }
+		for _, p := range survey.Points {
+			for _, ap := range p.APs {
+				_ = w.Write([]string{
+					p.Name, p.Started.UTC().Format(time.RFC3339), ap.SSID, ap.BSSID, ap.Band, strconv.Itoa(ap.Channel), ap.Security,
+					strconv.Itoa(ap.Samples), strconv.Itoa(ap.Min), f(ap.Avg), strconv.Itoa(ap.Max), f(ap.Stddev),
+					strconv.FormatBool(p.Client.Connected && strings.EqualFold(p.Client.BSSID, ap.BSSID)),
+				})
+			}
+		}
+		w.Flush()
+		return buf.String(), w.Error()
+	}
+	return "", fmt.Errorf("unsupported format: %s. Use 'json' or 'csv'", format)
+}
+
+// Compare matches two surveys' points by name, case-insensitively.
+func (s *surveyStore) Compare(idA, idB string) (SurveyComparison, error) {
+	a, err := s.Open(idA)
+	if err != nil {
+		return SurveyComparison{}, err
+	}
+	b, err := s.Open(idB)
+	if err != nil {
+		return SurveyComparison{}, err
+	}
+	cmp := SurveyComparison{A: a.info(false), B: b.info(false), Points: []SurveyPointComparison{}}
+	key := func(name string) string { return strings.ToLower(strings.TrimSpace(name)) }
+	bPoints := make(map[string]SurveyPoint, len(b.Points))
+	for _, p := range b.Points {
+		bPoints[key(p.Name)] = p // a re-taken point replaces the earlier one
+	}
+	aPoints := make(map[string]SurveyPoint, len(a.Points))
+	for _, p := range a.Points {
+		aPoints[key(p.Name)] = p
+	}
+	seen := make(map[string]bool)
+	add := func(name string) {
+		k := key(name)
+		if seen[k] {
+			return
+		}
+		seen[k] = true
+		pa, inA := aPoints[k]
+		pb, inB := bPoints[k]
+		cmp.Points = append(cmp.Points, SurveyPointComparison{Name: name, InA: inA, InB: inB, APs: compareSurveyAPs(pa.APs, pb.APs)})
+	}
+	for _, p := range a.Points {
+		add(p.Name)
+	}
+	for _, p := range b.Points {
+		add(p.Name)
+	}
+	return cmp, nil
+}
+
+func compareSurveyAPs(a, b []SurveyAPStats) []SurveyAPDelta {
+	byBSSID := make(map[string]*SurveyAPDelta)
+	var order []string
+	get := func(ap SurveyAPStats) *SurveyAPDelta {
+		k := strings.ToLower(ap.BSSID)
+		d, ok := byBSSID[k]
+		if !ok {
+			d = &SurveyAPDelta{BSSID: ap.BSSID, SSID: ap.SSID}
+			byBSSID[k] = d
+			order = append(order, k)
+		}
+		return d
+	}
+	for _, ap := range a {
+		avg := ap.Avg
+		get(ap).A = &avg
+	}
+	for _, ap := range b {
+		avg := ap.Avg
+		get(ap).B = &avg
+	}
+	out := make([]SurveyAPDelta, 0, len(order))
+	for _, k := range order {
+		d := byBSSID[k]
+		if d.A != nil && d.B != nil {
+			delta := *d.B - *d.A
+			d.Delta = &delta
+		}
+		out = append(out, *d)
+	}
+	return out
+}
+
+// StartSurvey begins a named walk-through survey.
+func (ws *WiFiService) StartSurvey(name string) (Survey, error) {
+	return ws.survey.Start(name)
+}
+
+// StopSurvey ends the active survey and returns it.
+func (ws *WiFiService) StopSurvey() (Survey, error) {
+	return ws.survey.Stop()
+}
+
+// MarkSurveyPoint captures a measurement point in the active survey on the
+// interfaces being scanned. It returns once the burst is complete.
+func (ws *WiFiService) MarkSurveyPoint(name string) (SurveyPoint, error) {
+	return ws.markSurveyPoint(name, nil)
+}
+
+func (ws *WiFiService) markSurveyPoint(name string, at *FloorPoint) (SurveyPoint, error) {
+	if !ws.IsScanning() {
+		return SurveyPoint{}, errors.New("start scanning before marking survey points")
+	}
+	return ws.survey.Mark(ws.appContext(), name, at, ws.ScanningInterfaces())
+}
+
+// GetActiveSurvey returns the survey in progress, if any.
+func (ws *WiFiService) GetActiveSurvey() (Survey, bool) {
+	return ws.survey.Active()
+}
+
+// ListSurveys returns the surveys on disk, newest first.
+func (ws *WiFiService) ListSurveys() ([]SurveyInfo, error) {
+	return ws.survey.List()
+}
+
+// OpenSurvey loads a survey by ID.
+func (ws *WiFiService) OpenSurvey(id string) (Survey, error) {
+	return ws.survey.Open(id)
+}
+
+// DeleteSurvey removes a finished survey.
+func (ws *WiFiService) DeleteSurvey(id string) error {
+	return ws.survey.Delete(id)
+}
+
+// ExportSurvey renders a survey as "json" or "csv".
+func (ws *WiFiService) ExportSurvey(id, format string) (string, error) {
+	return ws.survey.Export(id, format)
+}
+
+// CompareSurveys matches the points of two surveys by name.
+func (ws *WiFiService) CompareSurveys(a, b string) (SurveyComparison, error) {
+	return ws.survey.Compare(a, b)
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// markPoint captures a survey point, driving the burst's scans itself.
+func markPoint(t *testing.T, ws *WiFiService, name string, at *FloorPoint) SurveyPoint {
+	t.Helper()
+	sim := ws.scanner.(*simBackend)
+	type result struct {
+		p   SurveyPoint
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		p, err := ws.survey.Mark(context.Background(), name, at, []string{sim.sc.Interface})
+		done <- result{p, err}
+	}()
+	for !ws.survey.capturing() {
+		time.Sleep(time.Millisecond)
+	}
+	for {
+		select {
+		case r := <-done:
+			if r.err != nil {
+				t.Fatal(r.err)
+			}
+			return r.p
+		default:
+			ws.performScan(context.Background(), sim.sc.Interface)
+		}
+	}
+}
+
+func TestSurveyPointCapture(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 0)
+	if _, err := ws.survey.Mark(context.Background(), "Lobby", nil, []string{"sim0"}); err == nil {
+		t.Error("marked a point without a survey")
+	}
+	survey, err := ws.StartSurvey("Floor 2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ws.StartSurvey("Floor 3"); err == nil {
+		t.Error("started a second survey while one is active")
+	}
+
+	lobby := markPoint(t, ws, "Lobby", nil)
+	if lobby.Scans != 3 || len(lobby.APs) != 2 || !lobby.Client.Connected || lobby.Client.SignalHistory != nil {
+		t.Fatalf("point = %+v", lobby)
+	}
+	ap := lobby.APs[0]
+	if ap.BSSID != "02:00:00:00:01:01" || ap.Samples != 3 || ap.Min > int(ap.Avg) || float64(ap.Max) < ap.Avg || ap.Avg < lobby.APs[1].Avg {
+		t.Errorf("strongest AP = %+v", ap)
+	}
+	markPoint(t, ws, "Room 204", nil)
+
+	stopped, err := ws.StopSurvey()
+	if err != nil || len(stopped.Points) != 2 || stopped.Ended.IsZero() {
+		t.Fatalf("stopped = %+v, %v", stopped, err)
+	}
+	// Reload from disk through a fresh store.
+	reopened, err := newSurveyStore(ws.survey.dir, ws.config, ws.bus, time.Now, nil, nil).Open(survey.ID)
+	if err != nil || reopened.Name != "Floor 2" || len(reopened.Points) != 2 || reopened.Points[1].Name != "Room 204" {
+		t.Fatalf("reopened = %+v, %v", reopened, err)
+	}
+	if caps := reopened.Capabilities; len(caps) != 1 || caps[0].Interface != "sim0" || caps[0].Backend != "sim" {
+		t.Errorf("capabilities = %+v", caps)
+	}
+	list, _ := ws.ListSurveys()
+	if len(list) != 1 || list[0].Points != 2 || list[0].Active {
+		t.Errorf("list = %+v", list)
+	}
+
+	out, err := ws.ExportSurvey(survey.ID, "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if len(rows) != 5 || rows[1][0] != "Lobby" || rows[1][3] != "02:00:00:00:01:01" || rows[1][12] != "true" {
+		t.Errorf("csv = %q", rows)
+	}
+}
+
+func TestCompareSurveys(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 0)
+	ws.survey.now = func() time.Time { return time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC) }
+	before, _ := ws.StartSurvey("Before")
+	markPoint(t, ws, "Lobby", nil)
+	markPoint(t, ws, "Kitchen", nil)
+	ws.StopSurvey()
+	ws.survey.now = func() time.Time { return time.Date(2024, 5, 2, 9, 0, 0, 0, time.UTC) }
+	after, _ := ws.StartSurvey("After")
+	markPoint(t, ws, "lobby ", nil)
+	markPoint(t, ws, "Patio", nil)
+	ws.StopSurvey()
+
+	cmp, err := ws.CompareSurveys(before.ID, after.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range cmp.Points {
+		got = append(got, p.Name)
+	}
+	if strings.Join(got, ",") != "Lobby,Kitchen,Patio" {
+		t.Fatalf("points = %v", got)
+	}
+	lobby := cmp.Points[0]
+	if !lobby.InA || !lobby.InB || len(lobby.APs) != 2 {
+		t.Fatalf("lobby = %+v", lobby)
+	}
+	for _, d := range lobby.APs {
+		if d.A == nil || d.B == nil || d.Delta == nil || *d.Delta != *d.B-*d.A {
+			t.Errorf("delta %+v", d)
+		}
+	}
+	if kitchen := cmp.Points[1]; kitchen.InB || kitchen.APs[0].B != nil || kitchen.APs[0].Delta != nil {
+		t.Errorf("kitchen = %+v", kitchen)
+	}
+	if _, err := ws.CompareSurveys(before.ID, "../../etc/passwd"); err == nil {
+		t.Error("compared against an invalid id")
+	}
+}
@@ -0,0 +1,348 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Active throughput tests. ClientStats only has the link's PHY rates; this
+// measures what actually gets through, with the iperf3 client in iperf.go,
+// against iperf3 -s or `wifi-app throughput-server` on a wired machine.
+// Tests run on demand or every interval_minutes, one mode after another,
+// and each result carries the link it ran over so a slow run can be tied
+// to a BSSID, channel and signal.
+
+// ThroughputConfig is the [throughput] table.
+//
+//   - Host: the iperf3 server, "host" or "host:port" (port 5201 when
+//     omitted). Empty disables scheduled tests.
+//   - IntervalMinutes: run the tests this often; 0 runs them only on
+//     demand. At least 5 — each test saturates the link.
+//   - DurationSeconds: length of each test.
+//   - Modes: tests per run, in order: "tcp-down", "tcp-up", "udp-down",
+//     "udp-up". Down is the server sending (iperf3 -R).
+//   - Parallel: streams per test (iperf3 -P).
+//   - UDPMbps: total UDP send rate. UDP isn't congestion controlled, so
+//     loss and jitter are measured at this rate rather than at capacity.
+type ThroughputConfig struct {
+	Host            string   `toml:"host" json:"host"`
+	IntervalMinutes int      `toml:"interval_minutes" json:"intervalMinutes"`
+	DurationSeconds int      `toml:"duration_seconds" json:"durationSeconds"`
+	Modes           []string `toml:"modes" json:"modes"`
+	Parallel        int      `toml:"parallel" json:"parallel"`
+	UDPMbps         int      `toml:"udp_mbps" json:"udpMbps"`
+}
+
+// throughputModes are the accepted test modes.
+var throughputModes = []string{"tcp-down", "tcp-up", "udp-down", "udp-up"}
+
+func defaultThroughputConfig() ThroughputConfig {
+	return ThroughputConfig{
+		DurationSeconds: 10,
+		Modes:           []string{"tcp-down", "tcp-up"},
+		Parallel:        1,
+		UDPMbps:         50,
+	}
+}
+
+// validate fills defaults and clamps ranges, returning a note per
+// adjustment a user would want to know about.
+func (c ThroughputConfig) validate() (ThroughputConfig, []string) {
+	defaults := defaultThroughputConfig()
+	var notes []string
+	c.Host = strings.TrimSpace(c.Host)
+	if c.IntervalMinutes < 0 {
+		c.IntervalMinutes = 0
+	}
+	if c.IntervalMinutes > 0 && c.IntervalMinutes < 5 {
+		notes = append(notes, fmt.Sprintf("throughput interval_minutes=%d raised to 5", c.IntervalMinutes))
+		c.IntervalMinutes = 5
+	}
+	if c.IntervalMinutes > 1440 {
+		notes = append(notes, fmt.Sprintf("throughput interval_minutes=%d clamped to 1440", c.IntervalMinutes))
+		c.IntervalMinutes = 1440
+	}
+	if c.DurationSeconds < 1 {
+		c.DurationSeconds = defaults.DurationSeconds
+	}
+	if c.DurationSeconds > 60 {
+		notes = append(notes, fmt.Sprintf("throughput duration_seconds=%d clamped to 60", c.DurationSeconds))
+		c.DurationSeconds = 60
+	}
+	if c.Parallel < 1 {
+		c.Parallel = defaults.Parallel
+	}
+	if c.Parallel > 16 {
+		notes = append(notes, fmt.Sprintf("throughput parallel=%d clamped to 16", c.Parallel))
+		c.Parallel = 16
+	}
+	if c.UDPMbps < 1 {
+		c.UDPMbps = defaults.UDPMbps
+	}
+	if c.UDPMbps > 10000 {
+		notes = append(notes, fmt.Sprintf("throughput udp_mbps=%d clamped to 10000", c.UDPMbps))
+		c.UDPMbps = 10000
+	}
+	var modes []string
+	for _, m := range c.Modes {
+		m = strings.ToLower(strings.TrimSpace(m))
+		switch {
+		case !slices.Contains(throughputModes, m):
+			notes = append(notes, fmt.Sprintf("throughput mode %q unknown, dropped", m))
+		case !slices.Contains(modes, m):
+			modes = append(modes, m)
+		}
+	}
+	if len(modes) == 0 {
+		modes = defaults.Modes
+	}
+	c.Modes = modes
+	return c, notes
+}
+
+// ThroughputResult is one test. Link fields are the primary interface's
+// from the last scan tick before the test, so they're only filled while
+// scanning.
+type ThroughputResult struct {
+	Timestamp   time.Time `json:"timestamp"` // test start
+	Host        string    `json:"host"`      // host:port tested against
+	Mode        string    `json:"mode"`
+	Streams     int       `json:"streams"`
+	DurationSec float64   `json:"durationSec"`
+	Bytes       int64     `json:"bytes"`
+	Mbps        float64   `json:"mbps"`
+	// UDP only, as measured by the receiver.
+	JitterMs    float64 `json:"jitterMs"`
+	Packets     int64   `json:"packets"`
+	LostPackets int64   `json:"lostPackets"`
+	LossPercent float64 `json:"lossPercent"`
+	Error       string  `json:"error,omitempty"`
+
+	Interface string  `json:"interface"`
+	SSID      string  `json:"ssid"`
+	BSSID     string  `json:"bssid"`
+	Channel   int     `json:"channel"`
+	Band      string  `json:"band"`
+	Signal    int     `json:"signal"`
+	TxBitrate float64 `json:"txBitrate"` // PHY rates, for comparison
+	RxBitrate float64 `json:"rxBitrate"`
+	// Roamed is set when the BSSID had changed by the end of the test.
+	Roamed bool `json:"roamed"`
+}
+
+// throughputHistorySize bounds the results kept in memory; the session
+// recorder and history store keep the long-term record.
+const throughputHistorySize = 200
+
+// throughputBusyRetries is how often a test refused by a busy server is
+// retried, throughputBusyRetryDelay apart. Shared iperf3 servers run one
+// test at a time.
+const (
+	throughputBusyRetries    = 3
+	throughputBusyRetryDelay = 2 * time.Second
+)
+
+// ThroughputTester runs throughput tests on demand and on the
+// config.Throughput schedule, publishing a ThroughputEvent per test.
+type ThroughputTester struct {
+	cfg  *liveConfig
+	bus  *EventBus
+	link func() ClientStats
+	now  func() time.Time
+	tick time.Duration
+
+	runMu sync.Mutex // one run at a time
+
+	mu      sync.Mutex
+	results []ThroughputResult // oldest first
+	last    time.Time          // start of the last scheduled run
+	cancel  context.CancelFunc
+}
+
+// NewThroughputTester builds a tester that reads the link from link and
+// publishes on bus.
+func NewThroughputTester(cfg *liveConfig, bus *EventBus, link func() ClientStats) *ThroughputTester {
+	return &ThroughputTester{cfg: cfg, bus: bus, link: link, now: time.Now, tick: 30 * time.Second}
+}
+
+// Start runs the schedule until Stop or parent is cancelled. The first
+// scheduled run is one interval after Start, not at launch.
+func (t *ThroughputTester) Start(parent context.Context) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cancel != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(parent)
+	t.cancel = cancel
+	t.last = t.now()
+	go t.loop(ctx)
+}
+
+// Stop ends the schedule and any test in progress.
+func (t *ThroughputTester) Stop() {
+	t.mu.Lock()
+	cancel := t.cancel
+	t.cancel = nil
+	t.mu.Unlock()
+	if cancel != nil {
+		cancel()
+	}
+}
+
+func (t *ThroughputTester) loop(ctx context.Context) {
+	ticker := time.NewTicker(t.tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if t.due() {
+				if _, err := t.Run(ctx, "", nil); err != nil && !errors.Is(err, errThroughputRunning) {
+					slog.Warn("scheduled throughput test failed", "event", "throughput_error", "err", err)
+				}
+			}
+		}
+	}
+}
+
+// due reports whether a scheduled run should start, and claims it.
+func (t *ThroughputTester) due() bool {
+	cfg := t.cfg.Get().Throughput
+	if cfg.Host == "" || cfg.IntervalMinutes <= 0 {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := t.now()
+	if now.Sub(t.last) < time.Duration(cfg.IntervalMinutes)*time.Minute {
+		return false
+	}
+	t.last = now
+	return true
+}
+
+var errThroughputRunning = errors.New("a throughput test is already running")
+
+// Run tests each mode against host in turn. Empty host or modes use the
+// config. A failed test is recorded with its error and the run continues;
+// the returned error is for a run that couldn't start.
+func (t *ThroughputTester) Run(ctx context.Context, host string, modes []string) ([]ThroughputResult, error) {
+	cfg := t.cfg.Get().Throughput
+	if host == "" {
+		host = cfg.Host
+	}
+	if host == "" {
+		return nil, errors.New("no throughput server configured (set host in [throughput])")
+	}
+	if len(modes) == 0 {
+		modes = cfg.Modes
+	}
+	for _, m := range modes {
+		if !slices.Contains(throughputModes, m) {
+			return nil, fmt.Errorf("unknown throughput mode %q (use %s)", m, strings.Join(throughputModes, ", "))
+		}
+	}
+	if !t.runMu.TryLock() {
+		return nil, errThroughputRunning
+	}
+	defer t.runMu.Unlock()
+
+	addr := iperfAddr(host)
+	var out []ThroughputResult
+	for _, mode := range modes {
+		if ctx.Err() != nil {
+			break
+		}
+		r := t.test(ctx, addr, mode, cfg)
+		t.mu.Lock()
+		t.results = appendCapped(t.results, r, throughputHistorySize)
+		t.mu.Unlock()
+		t.bus.Publish(ThroughputEvent{Result: r})
+		out = append(out, r)
+	}
+	return out, ctx.Err()
+}
+
+// test runs one mode, retrying while the server is busy.
+func (t *ThroughputTester) test(ctx context.Context, addr, mode string, cfg ThroughputConfig) ThroughputResult {
+	spec := iperfSpec{
+		UDP:      strings.HasPrefix(mode, "udp"),
+		Reverse:  strings.HasSuffix(mode, "-down"),
+		Duration: time.Duration(cfg.DurationSeconds) * time.Second,
+		Parallel: cfg.Parallel,
+	}
+	if spec.UDP {
+		spec.Bandwidth = int64(cfg.UDPMbps) * 1e6 / int64(cfg.Parallel)
+	}
+	link := t.link()
+	r := ThroughputResult{
+		Timestamp: t.now(), Host: addr, Mode: mode, Streams: spec.Parallel,
+		Interface: link.Interface, SSID: link.SSID, BSSID: link.BSSID, Channel: link.Channel,
+		Signal: link.Signal, TxBitrate: link.TxBitrate, RxBitrate: link.RxBitrate,
+	}
+	if link.Frequency > 0 {
+		r.Band = frequencyBand(link.Frequency)
+	}
+
+	var o iperfOutcome
+	var err error
+	for attempt := 0; ; attempt++ {
+		o, err = runIperfClient(ctx, addr, spec)
+		if !errors.Is(err, errIperfBusy) || attempt == throughputBusyRetries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(throughputBusyRetryDelay):
+		}
+	}
+	if err != nil {
+		r.Error = err.Error()
+		return r
+	}
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	r.DurationSec = round(o.Seconds)
+	r.Bytes = o.Bytes
+	r.Mbps = round(o.Mbps())
+	if spec.UDP {
+		r.JitterMs = round(o.JitterMs)
+		r.Packets = o.Packets
+		r.LostPackets = o.Lost
+		r.LossPercent = round(o.LossPercent())
+	}
+	if end := t.link(); r.BSSID != "" && !strings.EqualFold(end.BSSID, r.BSSID) {
+		r.Roamed = true
+	}
+	return r
+}
+
+// Results returns the recent results, newest first.
+func (t *ThroughputTester) Results() []ThroughputResult {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make([]ThroughputResult, len(t.results))
+	for i, r := range t.results {
+		out[len(out)-1-i] = r
+	}
+	return out
+}
+
+// RunThroughputTest runs throughput tests now. Empty host or modes use
+// the [throughput] config.
+func (ws *WiFiService) RunThroughputTest(host string, modes []string) ([]ThroughputResult, error) {
+	return ws.throughput.Run(ws.appContext(), host, modes)
+}
+
+// GetThroughputResults returns recent throughput results, newest first.
+func (ws *WiFiService) GetThroughputResults() []ThroughputResult {
+	return ws.throughput.Results()
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThroughputTester(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 3)
+	addr := startIperfServer(t)
+	cfg := ws.GetConfig()
+	cfg.Throughput = ThroughputConfig{Host: addr, DurationSeconds: 1, Parallel: 2, UDPMbps: 20}
+	ws.config.Set(cfg)
+	var events []ThroughputResult
+	SubscribeTo(ws.bus, func(e ThroughputEvent) { events = append(events, e.Result) })
+
+	results, err := ws.RunThroughputTest("", []string{"tcp-down", "udp-up"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 || len(events) != 2 {
+		t.Fatalf("%d results, %d events", len(results), len(events))
+	}
+	tcp, udp := results[0], results[1]
+	if tcp.Error != "" || tcp.Mode != "tcp-down" || tcp.Host != addr || tcp.Streams != 2 || tcp.Mbps <= 0 || tcp.JitterMs != 0 {
+		t.Errorf("tcp = %+v", tcp)
+	}
+	if tcp.BSSID != "02:00:00:00:01:01" || tcp.SSID != "Office" || tcp.Channel == 0 || tcp.Band == "" || tcp.Signal == 0 {
+		t.Errorf("tcp link = %s %s ch %d %s %d dBm", tcp.SSID, tcp.BSSID, tcp.Channel, tcp.Band, tcp.Signal)
+	}
+	if udp.Error != "" || udp.Mbps < 14 || udp.Mbps > 26 || udp.Packets == 0 || udp.LossPercent > 5 {
+		t.Errorf("udp = %+v", udp)
+	}
+	if got := ws.GetThroughputResults(); len(got) != 2 || got[0].Mode != "udp-up" {
+		t.Errorf("results = %+v", got)
+	}
+
+	series, _ := ws.QueryHistory(HistoryQuery{Metric: HistoryThroughputMbps, Resolution: HistoryResolutionRaw})
+	if len(series) != 2 || series[0].Label != addr {
+		t.Errorf("history = %+v", series)
+	}
+	jitter, _ := ws.QueryHistory(HistoryQuery{Metric: HistoryThroughputJitter, Resolution: HistoryResolutionRaw})
+	if len(jitter) != 1 || jitter[0].Subject != "udp-up" {
+		t.Errorf("jitter history = %+v", jitter)
+	}
+	var b strings.Builder
+	if err := ws.WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	if want := `wifi_throughput_mbps{host="` + addr + `",mode="tcp-down"}`; !strings.Contains(b.String(), want) {
+		t.Errorf("metrics output missing %q", want)
+	}
+
+	// A server that isn't there is a failed result, kept out of history.
+	failed, err := ws.RunThroughputTest("127.0.0.1:1", []string{"tcp-up"})
+	if err != nil || len(failed) != 1 || failed[0].Error == "" {
+		t.Fatalf("unreachable server: %+v, %v", failed, err)
+	}
+	if series, _ := ws.QueryHistory(HistoryQuery{Metric: HistoryThroughputMbps, Subject: "tcp-up", Resolution: HistoryResolutionRaw}); len(series) != 0 {
+		t.Errorf("failed test in history: %+v", series)
+	}
+	if _, err := ws.RunThroughputTest("", []string{"tcp-sideways"}); err == nil {
+		t.Error("ran an unknown mode")
+	}
+}
+
+func TestThroughputSchedule(t *testing.T) {
+	cfg := DefaultConfig()
+	live := newLiveConfig(cfg)
+	tester := NewThroughputTester(live, nil, func() ClientStats { return ClientStats{} })
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	tester.now = func() time.Time { return now }
+	tester.last = now
+
+	now = now.Add(time.Hour)
+	if tester.due() {
+		t.Error("due without a host")
+	}
+	cfg.Throughput.Host = "iperf.lan"
+	cfg.Throughput.IntervalMinutes = 30
+	live.Set(cfg)
+	if !tester.due() {
+		t.Error("not due an hour after the last run")
+	}
+	now = now.Add(29 * time.Minute)
+	if tester.due() {
+		t.Error("due again before the interval")
+	}
+	now = now.Add(time.Minute)
+	if !tester.due() {
+		t.Error("not due after the interval")
+	}
+}
+
+func TestThroughputConfigValidate(t *testing.T) {
+	got, notes := ThroughputConfig{
+		Host: " iperf.lan ", IntervalMinutes: 2, DurationSeconds: 120, Parallel: 0,
+		Modes: []string{"TCP-Up", "tcp-up", "bogus"},
+	}.validate()
+	if got.Host != "iperf.lan" || got.IntervalMinutes != 5 || got.DurationSeconds != 60 || got.Parallel != 1 || got.UDPMbps != 50 ||
+		len(got.Modes) != 1 || got.Modes[0] != "tcp-up" || len(notes) != 3 {
+		t.Errorf("validate = %+v, notes %q", got, notes)
+	}
+	if got, _ := (ThroughputConfig{}).validate(); len(got.Modes) != 2 || got.IntervalMinutes != 0 {
+		t.Errorf("defaults = %+v", got)
+	}
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Long-term metric history. The chart histories in wifi_service.go are
+// sized for the live view (SignalHistoryMinutes, at most 4 h); this store
+// keeps multi-day trends by rolling samples into tiers: raw samples for
+// the last HistoryRawMinutes, then min/avg/max buckets of 1 minute (kept
+// a day), 15 minutes (kept a week) and 1 hour (kept HistoryRetentionDays).
+// Every sample updates the open bucket of each tier directly, so there is
+// no compaction pass and a query always sees the current bucket. The
+// bucket tiers are saved to history.json next to the config every few
+// minutes and on shutdown, and loaded at startup; raw samples only live in
+// memory.
+
+// History metrics. Client and latency names match the alert metrics.
+const (
+	HistoryClientSignal    = "client.signal"        // dBm, per interface
+	HistoryClientRetryRate = "client.retry_rate"    // percent, per interface
+	HistoryAPSignal        = "ap.signal"            // dBm, per BSSID
+	HistoryLatencyRTT      = "latency.rtt_ms"       // ms, per target label; lost probes excluded
+	HistoryLatencyLoss     = "latency.loss_percent" // per target label; each probe is 0 or 100
+
+	HistoryThroughputMbps   = "throughput.mbps"         // Mbit/s, per test mode; failed tests excluded
+	HistoryThroughputJitter = "throughput.jitter_ms"    // ms, per UDP mode
+	HistoryThroughputLoss   = "throughput.loss_percent" // per UDP mode
+)
+
+// HistoryResolutionRaw selects unaggregated samples.
+const HistoryResolutionRaw = "raw"
+
+// historyTier is one aggregation level. keep is the maximum retention;
+// the hourly tier is further bounded by config.
+type historyTier struct {
+	name string
+	step time.Duration
+	keep time.Duration
+}
+
+var historyTiers = []historyTier{
+	{"1m", time.Minute, 24 * time.Hour},
+	{"15m", 15 * time.Minute, 7 * 24 * time.Hour},
+	{"1h", time.Hour, 365 * 24 * time.Hour},
+}
+
+// HistoryQuery selects series and a time range. An empty Metric or
+// Subject matches all; a zero To means now. Resolution is "raw", a tier
+// name, or "" for the finest level that still covers From.
+type HistoryQuery struct {
+	Metric     string    `json:"metric"`
+	Subject    string    `json:"subject"`
+	From       time.Time `json:"from"`
+	To         time.Time `json:"to"`
+	Resolution string    `json:"resolution"`
+}
+
+// HistoryPoint is one raw sample (Min = Avg = Max, Count 1) or one bucket,
+// timestamped at the bucket start.
+type HistoryPoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Min       float64   `json:"min"`
+	Avg       float64   `json:"avg"`
+	Max       float64   `json:"max"`
+	Count     int       `json:"count"`
+}
+
+// HistorySeries is the result for one metric + subject.
+type HistorySeries struct {
+	Metric     string         `json:"metric"`
+	Subject    string         `json:"subject"`         // interface, BSSID, latency target label, or throughput mode
+	Label      string         `json:"label,omitempty"` // SSID for ap.signal, server for throughput
+	Resolution string         `json:"resolution"`
+	Points     []HistoryPoint `json:"points"`
+}
+
+type historySample struct {
+	t time.Time
+	v float64
+}
+
+type historyBucket struct {
+	start         time.Time
+	min, max, sum float64
+	n             int
+}
+
+type historyKey struct{ metric, subject string }
+
+type historySeriesData struct {
+	label string
+	last  time.Time
+	raw   []historySample
+	tiers [][]historyBucket // parallel to historyTiers
+}
+
+// historySaveInterval is how often the tiers are written out while scans
+// are coming in.
+const historySaveInterval = 5 * time.Minute
+
+func historyPath() (string, error) {
+	path, err := configPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(path), "history.json"), nil
+}
+
+// historyStore is the bus subscriber that records the series.
+type historyStore struct {
+	cfg  *liveConfig
+	path string // history.json; "" disables persistence
+
+	mu      sync.Mutex
+	series  map[historyKey]*historySeriesData
+	savedAt time.Time // last snapshot taken for saving
+	now     func() time.Time
+
+	writeMu sync.Mutex // serializes file writes, taken without mu
+	written time.Time  // Saved of the snapshot on disk
+}
+
+func newHistoryStore(cfg *liveConfig, path string) *historyStore {
+	h := &historyStore{cfg: cfg, path: path, series: make(map[historyKey]*historySeriesData), now: time.Now}
+	h.load()
+	return h
+}
+
+func (h *historyStore) handleEvent(e Event) {
+	var keep historyRetention
+	switch e.(type) {
+	case ClientUpdateEvent, ScanResultEvent, LatencyProbeEvent, ThroughputEvent:
+		keep = h.retention()
+	default:
+		return
+	}
+	switch ev := e.(type) {
+	case ClientUpdateEvent:
+		if !ev.Stats.Connected {
+			return
+		}
+		h.mu.Lock()
+		h.addLocked(keep, HistoryClientSignal, ev.Interface, "", ev.Timestamp, float64(ev.Stats.Signal))
+		h.addLocked(keep, HistoryClientRetryRate, ev.Interface, "", ev.Timestamp, ev.Stats.RetryRate)
+		h.mu.Unlock()
+	case ScanResultEvent:
+		h.mu.Lock()
+		for _, ap := range ev.APs {
+			if ap.BSSID != "" && !ap.Stale {
+				h.addLocked(keep, HistoryAPSignal, strings.ToLower(ap.BSSID), ap.SSID, ev.Timestamp, float64(ap.Signal))
+			}
+		}
+		h.pruneLocked(keep, ev.Timestamp)
+		var snap *historyFile
+		if h.path != "" && h.now().Sub(h.savedAt) >= historySaveInterval {
+			snap = h.snapshotLocked()
+		}
+		h.mu.Unlock()
+		// Publishers mustn't block on disk; a slow write just delays the
+		// next one.
+		if snap != nil {
+			go h.write(snap)
+		}
+	case LatencyProbeEvent:
+		p := ev.Probe
+		loss := 0.0
+		h.mu.Lock()
+		if p.Lost {
+			loss = 100
+		} else {
+			h.addLocked(keep, HistoryLatencyRTT, p.Label, "", p.Timestamp, p.RTTMs)
+		}
+		h.addLocked(keep, HistoryLatencyLoss, p.Label, "", p.Timestamp, loss)
+		h.mu.Unlock()
+	case ThroughputEvent:
+		r := ev.Result
+		if r.Error != "" {
+			return
+		}
+		h.mu.Lock()
+		h.addLocked(keep, HistoryThroughputMbps, r.Mode, r.Host, r.Timestamp, r.Mbps)
+		if strings.HasPrefix(r.Mode, "udp") {
+			h.addLocked(keep, HistoryThroughputJitter, r.Mode, r.Host, r.Timestamp, r.JitterMs)
+			h.addLocked(keep, HistoryThroughputLoss, r.Mode, r.Host, r.Timestamp, r.LossPercent)
+		}
+		h.mu.Unlock()
+	}
+}
+
+// historyRetention is how long raw samples and each tier are kept, read
+// from config once per event.
+type historyRetention struct {
+	raw   time.Duration
+	tiers []time.Duration // parallel to historyTiers
+}
+
+func (h *historyStore) retention() historyRetention {
+	cfg := h.cfg.Get()
+	days := time.Duration(cfg.HistoryRetentionDays) * 24 * time.Hour
+	r := historyRetention{raw: time.Duration(cfg.HistoryRawMinutes) * time.Minute}
+	for _, t := range historyTiers {
+		keep := t.keep
+		if days < keep {
+			keep = days
+		}
+		r.tiers = append(r.tiers, keep)
+	}
+	return r
+}
+
+func (h *historyStore) addLocked(keep historyRetention, metric, subject, label string, t time.Time, v float64) {
+	if subject == "" || t.IsZero() {
+		return
+	}
+	key := historyKey{metric, subject}
+	s := h.series[key]
+	if s == nil {
+		s = &historySeriesData{tiers: make([][]historyBucket, len(historyTiers))}
+		h.series[key] = s
+	}
+	if label != "" {
+		s.label = label
+	}
+	if t.After(s.last) {
+		s.last = t
+	}
+	s.raw = append(s.raw, historySample{t, v})
+	s.raw = trimHistory(s.raw, t.Add(-keep.raw), func(x historySample) time.Time { return x.t })
+
+	for i, tier := range historyTiers {
+		start := t.Truncate(tier.step)
+		buckets := s.tiers[i]
+		// Samples arrive in time order per source; one that lands in an
+		// earlier bucket (clock step, late probe) is folded into the open
+		// bucket rather than rewriting history.
+		if n := len(buckets); n > 0 && !start.After(buckets[n-1].start) {
+			b := &buckets[n-1]
+			if v < b.min {
+				b.min = v
+			}
+			if v > b.max {
+				b.max = v
+			}
+			b.sum += v
+			b.n++
+		} else {
+			buckets = append(buckets, historyBucket{start: start, min: v, max: v, sum: v, n: 1})
+		}
+		s.tiers[i] = trimHistory(buckets, t.Add(-keep.tiers[i]), func(b historyBucket) time.Time { return b.start })
+	}
+}
+
+// trimHistory drops the leading elements older than cutoff.
+func trimHistory[T any](xs []T, cutoff time.Time, ts func(T) time.Time) []T {
+	i := 0
+	for i < len(xs) && ts(xs[i]).Before(cutoff) {
+		i++
+	}
+	return xs[i:]
+}
+
+// pruneLocked drops series with no sample within the longest retention,
+// e.g. BSSIDs that have gone out of range.
+func (h *historyStore) pruneLocked(keep historyRetention, now time.Time) {
+	longest := keep.raw
+	for _, d := range keep.tiers {
+		longest = max(longest, d)
+	}
+	for k, s := range h.series {
+		if now.Sub(s.last) > longest {
+			delete(h.series, k)
+		}
+	}
+}
+
+// historyFile is the on-disk form of the bucket tiers.
+type historyFile struct {
+	Saved  time.Time           `json:"saved"`
+	Series []historyFileSeries `json:"series"`
+}
+
+type historyFileSeries struct {
+	Metric  string    `json:"metric"`
+	Subject string    `json:"subject"`
+	Label   string    `json:"label,omitempty"`
+	Last    time.Time `json:"last"`
+	// Tiers maps a tier name to its buckets, each [start (Unix seconds),
+	// min, max, sum, count] to keep the file compact.
+	Tiers map[string][][5]float64 `json:"tiers"`
+}
+
+// snapshotLocked copies the tiers for saving. Caller must hold h.mu.
+func (h *historyStore) snapshotLocked() *historyFile {
+	h.savedAt = h.now()
+	f := &historyFile{Saved: h.savedAt, Series: make([]historyFileSeries, 0, len(h.series))}
+	for k, s := range h.series {
+		fs := historyFileSeries{Metric: k.metric, Subject: k.subject, Label: s.label, Last: s.last, Tiers: make(map[string][][5]float64, len(historyTiers))}
+		for i, tier := range historyTiers {
+			buckets := make([][5]float64, len(s.tiers[i]))
+			for j, b := range s.tiers[i] {
+				buckets[j] = [5]float64{float64(b.start.Unix()), b.min, b.max, b.sum, float64(b.n)}
+			}
+			fs.Tiers[tier.name] = buckets
+		}
+		f.Series = append(f.Series, fs)
+	}
+	return f
+}
+
+// Save writes the tiers out now. Called on shutdown.
+func (h *historyStore) Save() {
+	if h.path == "" {
+		return
+	}
+	h.mu.Lock()
+	snap := h.snapshotLocked()
+	h.mu.Unlock()
+	h.write(snap)
+}
+
+// write saves snap unless a newer snapshot is already on disk.
+func (h *historyStore) write(snap *historyFile) {
+	h.writeMu.Lock()
+	defer h.writeMu.Unlock()
+	if !snap.Saved.After(h.written) {
+		return
+	}
+	data, err := json.Marshal(snap)
+	if err == nil {
+		err = os.MkdirAll(filepath.Dir(h.path), 0o755)
+	}
+	tmp := h.path + ".tmp"
+	if err == nil {
+		err = os.WriteFile(tmp, data, 0o644)
+	}
+	if err == nil {
+		err = os.Rename(tmp, h.path)
+	}
+	if err != nil {
+		slog.Warn("saving metric history failed", "event", "history_error", "path", h.path, "err", err)
+		return
+	}
+	h.written = snap.Saved
+	_ = chownToSudoUser(h.path)
+}
+
+// load restores the tiers saved by a previous run. Series past every
+// retention are dropped.
+func (h *historyStore) load() {
+	if h.path == "" {
+		return
+	}
+	data, err := os.ReadFile(h.path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Warn("metric history unreadable, starting fresh", "event", "history_error", "path", h.path, "err", err)
+		}
+		return
+	}
+	var f historyFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		slog.Warn("metric history corrupt, starting fresh", "event", "history_error", "path", h.path, "err", err)
+		return
+	}
+	h.written = f.Saved
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, fs := range f.Series {
+		if fs.Metric == "" || fs.Subject == "" {
+			continue
+		}
+		s := &historySeriesData{label: fs.Label, last: fs.Last, tiers: make([][]historyBucket, len(historyTiers))}
+		for i, tier := range historyTiers {
+			for _, b := range fs.Tiers[tier.name] {
+				if b[4] < 1 {
+					continue
+				}
+				s.tiers[i] = append(s.tiers[i], historyBucket{start: time.Unix(int64(b[0]), 0), min: b[1], max: b[2], sum: b[3], n: int(b[4])})
+			}
+		}
+		h.series[historyKey{fs.Metric, fs.Subject}] = s
+	}
+	h.pruneLocked(h.retention(), h.now())
+}
+
+// Query returns the matching series over the requested range, sorted by
+// metric then subject. Series with no points in range are omitted.
+func (h *historyStore) Query(q HistoryQuery) ([]HistorySeries, error) {
+	now := h.now()
+	if q.To.IsZero() {
+		q.To = now
+	}
+	if q.From.IsZero() {
+		q.From = q.To.Add(-time.Hour)
+	}
+	if q.From.After(q.To) {
+		return nil, fmt.Errorf("history range starts after it ends (%s > %s)", q.From.Format(time.RFC3339), q.To.Format(time.RFC3339))
+	}
+	keep := h.retention()
+	tier := -1 // raw
+	switch q.Resolution {
+	case HistoryResolutionRaw:
+	case "":
+		if q.From.Before(now.Add(-keep.raw)) {
+			tier = len(historyTiers) - 1
+			for i := range historyTiers {
+				if !q.From.Before(now.Add(-keep.tiers[i])) {
+					tier = i
+					break
+				}
+			}
+		}
+	default:
+		for i, t := range historyTiers {
+			if t.name == q.Resolution {
+				tier = i
+			}
+		}
+		if tier < 0 {
+			return nil, fmt.Errorf("unknown history resolution %q (want raw, 1m, 15m or 1h)", q.Resolution)
+		}
+	}
+	resolution := HistoryResolutionRaw
+	if tier >= 0 {
+		resolution = historyTiers[tier].name
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	out := []HistorySeries{}
+	for k, s := range h.series {
+		if (q.Metric != "" && q.Metric != k.metric) || (q.Subject != "" && !strings.EqualFold(q.Subject, k.subject)) {
+			continue
+		}
+		hs := HistorySeries{Metric: k.metric, Subject: k.subject, Label: s.label, Resolution: resolution}
+		if tier < 0 {
+			for _, x := range s.raw {
+				if !x.t.Before(q.From) && !x.t.After(q.To) {
+					hs.Points = append(hs.Points, HistoryPoint{Timestamp: x.t, Min: x.v, Avg: x.v, Max: x.v, Count: 1})
+				}
+			}
+		} else {
+			// A bucket is in range if any part of it overlaps.
+			step := historyTiers[tier].step
+			for _, b := range s.tiers[tier] {
+				if b.start.Add(step).After(q.From) && !b.start.After(q.To) {
+					hs.Points = append(hs.Points, HistoryPoint{Timestamp: b.start, Min: b.min, Avg: b.sum / float64(b.n), Max: b.max, Count: b.n})
+				}
+			}
+		}
+		if len(hs.Points) > 0 {
+			out = append(out, hs)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Metric != out[j].Metric {
+			return out[i].Metric < out[j].Metric
+		}
+		return out[i].Subject < out[j].Subject
+	})
+	return out, nil
+}
+
+// QueryHistory returns recorded metric history for a time range.
+func (ws *WiFiService) QueryHistory(q HistoryQuery) ([]HistorySeries, error) {
+	return ws.history.Query(q)
+}
+
+// ExportHistory renders a history query as "json" or "csv". CSV has one
+// row per point, with min/avg/max equal for raw samples.
+func (ws *WiFiService) ExportHistory(q HistoryQuery, format string) (string, error) {
+	series, err := ws.history.Query(q)
+	if err != nil {
+		return "", err
+	}
+	switch format {
+	case "json":
+		b, err := json.MarshalIndent(series, "", "  ")
+		if err != nil {
+			return "", err
+		}
+		return string(b), nil
+	case "csv":
+		var buf bytes.Buffer
+		w := csv.NewWriter(&buf)
+		_ = w.Write([]string{"timestamp", "metric", "subject", "label", "resolution", "min", "avg", "max", "count"})
+		f := func(v float64) string { return strconv.FormatFloat(v, 'f', -1, 64) }
+		for _, s := range series {
+			for _, p := range s.Points {
+				_ = w.Write([]string{
+					p.Timestamp.UTC().Format(time.RFC3339), s.Metric, s.Subject, s.Label, s.Resolution,
+					f(p.Min), f(p.Avg), f(p.Max), strconv.Itoa(p.Count),
+				})
+			}
+		}
+		w.Flush()
+		return buf.String(), w.Error()
+	}
+	return "", fmt.Errorf("unsupported format: %s. Use 'json' or 'csv'", format)
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestHistoryStore(t *testing.T, now *time.Time) *historyStore {
+	t.Helper()
+	cfg := DefaultConfig()
+	cfg.HistoryRawMinutes = 10
+	cfg.HistoryRetentionDays = 2
+	live := newLiveConfig(DefaultConfig())
+	live.Set(cfg)
+	h := newHistoryStore(live, "")
+	h.now = func() time.Time { return *now }
+	return h
+}
+
+func TestHistoryStoreTiers(t *testing.T) {
+	t0 := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+	now := t0
+	h := newTestHistoryStore(t, &now)
+
+	// Three days of client samples every 30 s, alternating -50 / -70 dBm.
+	for i := 0; i < 3*24*120; i++ {
+		now = t0.Add(time.Duration(i) * 30 * time.Second)
+		sig := -50
+		if i%2 == 1 {
+			sig = -70
+		}
+		h.handleEvent(ClientUpdateEvent{Interface: "wlan0", Primary: true, Timestamp: now,
+			Stats: ClientStats{Connected: true, Signal: sig, RetryRate: 2}})
+	}
+
+	raw, err := h.Query(HistoryQuery{Metric: HistoryClientSignal, From: now.Add(-5 * time.Minute)})
+	if err != nil || len(raw) != 1 || raw[0].Resolution != HistoryResolutionRaw || len(raw[0].Points) != 11 {
+		t.Fatalf("recent query = %+v (%v), want 11 raw points", raw, err)
+	}
+
+	day, _ := h.Query(HistoryQuery{Metric: HistoryClientSignal, From: now.Add(-12 * time.Hour)})
+	if len(day) != 1 || day[0].Resolution != "1m" {
+		t.Fatalf("12h query = %+v, want 1m buckets", day)
+	}
+	if p := day[0].Points[0]; p.Min != -70 || p.Max != -50 || p.Avg != -60 || p.Count != 2 {
+		t.Errorf("1m bucket = %+v", p)
+	}
+
+	// Older than a day falls to 15m buckets; the hourly tier is bounded
+	// by the 2-day retention.
+	old, _ := h.Query(HistoryQuery{Metric: HistoryClientSignal, From: now.Add(-36 * time.Hour)})
+	if len(old) != 1 || old[0].Resolution != "15m" || old[0].Points[0].Count != 30 {
+		t.Fatalf("36h query = %+v", old)
+	}
+	hourly, _ := h.Query(HistoryQuery{Metric: HistoryClientSignal, From: t0, Resolution: "1h"})
+	if first := hourly[0].Points[0].Timestamp; first.Before(now.Add(-49 * time.Hour)) {
+		t.Errorf("hourly tier kept %s, past retention", first)
+	}
+
+	if _, err := h.Query(HistoryQuery{Resolution: "5m"}); err == nil {
+		t.Error("unknown resolution accepted")
+	}
+	if _, err := h.Query(HistoryQuery{From: now, To: now.Add(-time.Hour)}); err == nil {
+		t.Error("inverted range accepted")
+	}
+}
+
+func TestHistoryStoreSourcesAndPrune(t *testing.T) {
+	now := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	h := newTestHistoryStore(t, &now)
+
+	h.handleEvent(ScanResultEvent{Interface: "wlan0", Timestamp: now, APs: []AccessPoint{
+		{SSID: "Corp", BSSID: "AA:BB:CC:00:00:01", Signal: -61},
+	}})
+	h.handleEvent(LatencyProbeEvent{Probe: LatencyProbe{Timestamp: now, Label: "gateway", RTTMs: 4}})
+	h.handleEvent(LatencyProbeEvent{Probe: LatencyProbe{Timestamp: now, Label: "gateway", Lost: true}})
+	h.handleEvent(ClientUpdateEvent{Interface: "wlan0", Timestamp: now}) // disconnected: nothing recorded
+
+	got, _ := h.Query(HistoryQuery{Resolution: "1m"})
+	want := map[string]HistoryPoint{
+		HistoryAPSignal:    {Min: -61, Avg: -61, Max: -61, Count: 1},
+		HistoryLatencyLoss: {Min: 0, Avg: 50, Max: 100, Count: 2},
+		HistoryLatencyRTT:  {Min: 4, Avg: 4, Max: 4, Count: 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("series = %+v", got)
+	}
+	for _, s := range got {
+		p := s.Points[0]
+		p.Timestamp = time.Time{}
+		if p != want[s.Metric] {
+			t.Errorf("%s = %+v, want %+v", s.Metric, p, want[s.Metric])
+		}
+	}
+	if got[0].Subject != "aa:bb:cc:00:00:01" || got[0].Label != "Corp" {
+		t.Errorf("ap series = %+v", got[0])
+	}
+
+	csv, err := (&WiFiService{history: h}).ExportHistory(HistoryQuery{Metric: HistoryLatencyLoss, Resolution: "1m"}, "csv")
+	if err != nil || !strings.Contains(csv, ",latency.loss_percent,gateway,,1m,0,50,100,2\n") {
+		t.Errorf("csv = %q (%v)", csv, err)
+	}
+
+	// An AP gone for longer than every retention is dropped on the next scan.
+	now = now.Add(3 * 24 * time.Hour)
+	h.handleEvent(LatencyProbeEvent{Probe: LatencyProbe{Timestamp: now, Label: "gateway", RTTMs: 5}})
+	h.handleEvent(ScanResultEvent{Interface: "wlan0", Timestamp: now})
+	if len(h.series) != 2 {
+		t.Errorf("%d series after prune, want the 2 latency series", len(h.series))
+	}
+}
+
+func TestHistoryStorePersistsTiers(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.json")
+	// Loading prunes against the wall clock.
+	now := time.Now().UTC().Truncate(15 * time.Minute)
+	h := newTestHistoryStore(t, &now)
+	h.path = path
+	for i, v := range []float64{4, 6} {
+		h.handleEvent(LatencyProbeEvent{Probe: LatencyProbe{Timestamp: now.Add(time.Duration(i) * time.Second), Label: "gateway", RTTMs: v}})
+	}
+	h.Save()
+
+	restored := newHistoryStore(h.cfg, path)
+	restored.now = h.now
+	got, err := restored.Query(HistoryQuery{Metric: HistoryLatencyRTT, Resolution: "15m"})
+	if err != nil || len(got) != 1 || len(got[0].Points) != 1 {
+		t.Fatalf("restored = %+v (%v)", got, err)
+	}
+	if p := got[0].Points[0]; !p.Timestamp.Equal(now) || p.Min != 4 || p.Avg != 5 || p.Max != 6 || p.Count != 2 {
+		t.Errorf("restored bucket = %+v", p)
+	}
+	if raw, _ := restored.Query(HistoryQuery{Resolution: HistoryResolutionRaw}); len(raw) != 0 {
+		t.Errorf("raw samples restored: %+v", raw)
+	}
+
+	// A later sample lands in the restored open bucket.
+	restored.handleEvent(LatencyProbeEvent{Probe: LatencyProbe{Timestamp: now.Add(time.Minute), Label: "gateway", RTTMs: 8}})
+	if got, _ := restored.Query(HistoryQuery{Metric: HistoryLatencyRTT, Resolution: "15m"}); got[0].Points[0].Count != 3 {
+		t.Errorf("after restore = %+v", got[0].Points)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"os"
@@ -33,6 +34,8 @@ type darwinScanner struct {
 	// helper ships alongside the main executable, in which case scans fall
 	// back to plain CoreWLAN data without the IE-derived advanced fields.
 	macHelperPath string
+	// helperAugmented is set once the helper has added IEs to a scan.
+	helperAugmented bool
 }
 
 type trafficStats struct {
@@ -75,13 +78,24 @@ func NewWiFiScanner(cacheFile string) WiFiBackend {
 	return scanner
 }
 
-func (s *darwinScanner) ScanNetworks(iface string) ([]AccessPoint, error) {
+// CoreWLAN calls can't be interrupted; ctx abandons them (see
+// callWithContext) while the command-line tools are killed outright. None
+// of the scan paths can be kept from probing, so a passive scan is refused.
+func (s *darwinScanner) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
+	if opts.Passive {
+		return nil, errPassiveScanUnsupported
+	}
 	if s.hasCoreWLAN {
-		aps, err := coreWLANScanNetworks(iface)
+		aps, err := callWithContext(ctx, func() ([]AccessPoint, error) {
+			return coreWLANScanNetworks(iface)
+		})
 		if err == nil && len(aps) > 0 {
-			s.augmentWithHelper(iface, aps)
+			s.augmentWithHelper(ctx, iface, aps)
 			return aps, nil
 		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		// Surface a Location Services denial directly: the airport CLI and
 		// system_profiler are subject to the same gate on macOS 14+, so the
 		// fallback would just return an empty list without explanation.
@@ -90,7 +104,7 @@ func (s *darwinScanner) ScanNetworks(iface string) ([]AccessPoint, error) {
 		}
 	}
 	if s.hasAirport {
-		cmd := exec.Command(s.airportPath, "-s", "-x")
+		cmd := exec.CommandContext(ctx, s.airportPath, "-s", "-x")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan networks with airport: %w (output: %s)", err, string(output))
@@ -99,7 +113,7 @@ func (s *darwinScanner) ScanNetworks(iface string) ([]AccessPoint, error) {
 	}
 
 	if s.hasSystemProfiler {
-		cmd := exec.Command("system_profiler", "-json", "SPAirPortDataType")
+		cmd := exec.CommandContext(ctx, "system_profiler", "-json", "SPAirPortDataType")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan networks with system_profiler: %w (output: %s)", err, string(output))
@@ -110,7 +124,7 @@ func (s *darwinScanner) ScanNetworks(iface string) ([]AccessPoint, error) {
 	return nil, fmt.Errorf("no macOS WiFi scan command available (airport/system_profiler missing)")
 }
 
-func (s *darwinScanner) GetInterfaces() ([]string, error) {
+func (s *darwinScanner) GetInterfaces(ctx context.Context) ([]string, error) {
 	// Prefer CoreWLAN when available — it returns just the WiFi interfaces
 	// and does not require shelling out. Fall back to networksetup if the
 	// cgo build is not in use or CoreWLAN returned nothing.
@@ -120,7 +134,7 @@ func (s *darwinScanner) GetInterfaces() ([]string, error) {
 		}
 	}
 
-	cmd := exec.Command("/usr/sbin/networksetup", "-listallhardwareports")
+	cmd := exec.CommandContext(ctx, "/usr/sbin/networksetup", "-listallhardwareports")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get interfaces: %w", err)
@@ -152,7 +166,7 @@ func (s *darwinScanner) GetInterfaces() ([]string, error) {
 	return []string{}, fmt.Errorf("no WiFi interfaces found")
 }
 
-func (s *darwinScanner) GetConnectionInfo(iface string) (ConnectionInfo, error) {
+func (s *darwinScanner) GetConnectionInfo(ctx context.Context, iface string) (ConnectionInfo, error) {
 	if s.hasCoreWLAN {
 		info, err := coreWLANConnectionInfo(iface)
 		if err == nil {
@@ -160,7 +174,7 @@ func (s *darwinScanner) GetConnectionInfo(iface string) (ConnectionInfo, error)
 		}
 	}
 	if s.hasAirport {
-		cmd := exec.Command(s.airportPath, "-I")
+		cmd := exec.CommandContext(ctx, s.airportPath, "-I")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return ConnectionInfo{}, fmt.Errorf("failed to get connection info with airport: %w", err)
@@ -169,7 +183,7 @@ func (s *darwinScanner) GetConnectionInfo(iface string) (ConnectionInfo, error)
 	}
 
 	if s.hasWdutil {
-		cmd := exec.Command("wdutil", "info")
+		cmd := exec.CommandContext(ctx, "wdutil", "info")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return ConnectionInfo{}, fmt.Errorf("failed to get connection info with wdutil: %w (output: %s)", err, string(output))
@@ -178,7 +192,7 @@ func (s *darwinScanner) GetConnectionInfo(iface string) (ConnectionInfo, error)
 	}
 
 	if s.hasSystemProfiler {
-		cmd := exec.Command("system_profiler", "-json", "SPAirPortDataType")
+		cmd := exec.CommandContext(ctx, "system_profiler", "-json", "SPAirPortDataType")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
 			return ConnectionInfo{}, fmt.Errorf("failed to get connection info with system_profiler: %w (output: %s)", err, string(output))
@@ -187,63 +201,81 @@ func (s *darwinScanner) GetConnectionInfo(iface string) (ConnectionInfo, error)
 	}
 
 	if s.hasNetworksetup {
-		return parseNetworksetupConnectionInfo(iface), nil
+		return parseNetworksetupConnectionInfo(ctx, iface), nil
 	}
 
 	return ConnectionInfo{}, fmt.Errorf("no macOS WiFi info command available (airport/wdutil/system_profiler/networksetup missing)")
 }
 
-func (s *darwinScanner) GetStationStats(iface string) (map[string]string, error) {
+func (s *darwinScanner) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
 	if s.hasCoreWLAN {
 		stats, err := coreWLANStationInfo(iface)
 		if err == nil {
 			return stats, nil
 		}
 	}
+	stats, err := s.stationInfo(ctx, iface)
+	if err == nil {
+		s.addTrafficStats(ctx, iface, &stats)
+	}
+	return stats, err
+}
+
+// stationInfo reads the station from the text tools.
+func (s *darwinScanner) stationInfo(ctx context.Context, iface string) (StationInfo, error) {
 	if s.hasAirport {
-		cmd := exec.Command(s.airportPath, "-I")
+		cmd := exec.CommandContext(ctx, s.airportPath, "-I")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return map[string]string{"connected": "false"}, fmt.Errorf("failed to get station stats with airport: %w", err)
+			return StationInfo{}, fmt.Errorf("failed to get station stats with airport: %w", err)
 		}
-		return s.airportParser.ParseStation(output)
+		stats, err := s.airportParser.ParseStation(output)
+		return stationInfoFromMap(stats), err
 	}
 
 	if s.hasWdutil {
-		cmd := exec.Command("wdutil", "info")
+		cmd := exec.CommandContext(ctx, "wdutil", "info")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return map[string]string{"connected": "false"}, fmt.Errorf("failed to get station stats with wdutil: %w (output: %s)", err, string(output))
+			return StationInfo{}, fmt.Errorf("failed to get station stats with wdutil: %w (output: %s)", err, string(output))
 		}
-		return parseWdutilStationInfo(output), nil
+		return stationInfoFromMap(parseWdutilStationInfo(output)), nil
 	}
 
 	if s.hasSystemProfiler {
-		cmd := exec.Command("system_profiler", "-json", "SPAirPortDataType")
+		cmd := exec.CommandContext(ctx, "system_profiler", "-json", "SPAirPortDataType")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return map[string]string{"connected": "false"}, fmt.Errorf("failed to get station stats with system_profiler: %w (output: %s)", err, string(output))
+			return StationInfo{}, fmt.Errorf("failed to get station stats with system_profiler: %w (output: %s)", err, string(output))
 		}
-		return parseSystemProfilerStationInfo(output), nil
+		return stationInfoFromMap(parseSystemProfilerStationInfo(output)), nil
 	}
 
 	if s.hasNetworksetup {
-		info := parseNetworksetupConnectionInfo(iface)
-		stats := map[string]string{"connected": strconv.FormatBool(info.Connected)}
-		if info.BSSID != "" {
-			stats["bssid"] = info.BSSID
-		}
-		if info.Signal != 0 {
-			stats["signal"] = strconv.Itoa(info.Signal)
-			stats["signal_avg"] = strconv.Itoa(info.Signal)
+		info := parseNetworksetupConnectionInfo(ctx, iface)
+		var stats StationInfo
+		if info.Connected && info.Signal != 0 {
+			stats.SignalAvg = intPtr(info.Signal)
 		}
 		return stats, nil
 	}
 
-	return map[string]string{"connected": "false"}, fmt.Errorf("no macOS WiFi info command available (airport/wdutil/system_profiler/networksetup missing)")
+	return StationInfo{}, fmt.Errorf("no macOS WiFi info command available (airport/wdutil/system_profiler/networksetup missing)")
 }
 
-func (s *darwinScanner) GetLinkInfo(iface string) (map[string]string, error) {
+func (s *darwinScanner) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	link, err := s.linkInfo(ctx, iface)
+	if err == nil && !link.Connected {
+		// The traffic counters in GetStationStats are per association.
+		s.mu.Lock()
+		delete(s.baselineStats, iface)
+		delete(s.connectionStart, iface)
+		s.mu.Unlock()
+	}
+	return link, err
+}
+
+func (s *darwinScanner) linkInfo(ctx context.Context, iface string) (LinkInfo, error) {
 	if s.hasCoreWLAN {
 		link, err := coreWLANLinkInfo(iface)
 		if err == nil {
@@ -251,76 +283,60 @@ func (s *darwinScanner) GetLinkInfo(iface string) (map[string]string, error) {
 		}
 	}
 	if s.hasAirport {
-		cmd := exec.Command(s.airportPath, "-I")
+		cmd := exec.CommandContext(ctx, s.airportPath, "-I")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return map[string]string{"connected": "false"}, fmt.Errorf("failed to get link info with airport: %w", err)
+			return LinkInfo{}, fmt.Errorf("failed to get link info with airport: %w", err)
 		}
 		result, _ := s.airportParser.ParseLink(output)
-		return s.enrichWithTrafficStats(iface, result), nil
+		return linkInfoFromMap(result), nil
 	}
 
 	if s.hasWdutil {
-		cmd := exec.Command("wdutil", "info")
+		cmd := exec.CommandContext(ctx, "wdutil", "info")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return map[string]string{"connected": "false"}, fmt.Errorf("failed to get link info with wdutil: %w (output: %s)", err, string(output))
+			return LinkInfo{}, fmt.Errorf("failed to get link info with wdutil: %w (output: %s)", err, string(output))
 		}
-		result := parseWdutilLinkInfo(output)
-		return s.enrichWithTrafficStats(iface, result), nil
+		return linkInfoFromMap(parseWdutilLinkInfo(output)), nil
 	}
 
 	if s.hasSystemProfiler {
-		cmd := exec.Command("system_profiler", "-json", "SPAirPortDataType")
+		cmd := exec.CommandContext(ctx, "system_profiler", "-json", "SPAirPortDataType")
 		output, err := cmd.CombinedOutput()
 		if err != nil {
-			return map[string]string{"connected": "false"}, fmt.Errorf("failed to get link info with system_profiler: %w", err)
+			return LinkInfo{}, fmt.Errorf("failed to get link info with system_profiler: %w", err)
 		}
-		result := parseSystemProfilerLinkInfo(output)
-		return s.enrichWithTrafficStats(iface, result), nil
+		return linkInfoFromMap(parseSystemProfilerLinkInfo(output)), nil
 	}
 
 	if s.hasNetworksetup {
-		info := parseNetworksetupConnectionInfo(iface)
-		link := map[string]string{"connected": strconv.FormatBool(info.Connected)}
-		if info.SSID != "" {
-			link["ssid"] = info.SSID
+		info := parseNetworksetupConnectionInfo(ctx, iface)
+		if !info.Connected {
+			return LinkInfo{}, nil
 		}
-		if info.BSSID != "" {
-			link["bssid"] = info.BSSID
-		}
-		return s.enrichWithTrafficStats(iface, link), nil
+		return LinkInfo{Connected: true, SSID: info.SSID, BSSID: info.BSSID}, nil
 	}
 
-	return map[string]string{"connected": "false"}, fmt.Errorf("no macOS WiFi info command available (airport/wdutil/system_profiler/networksetup missing)")
+	return LinkInfo{}, fmt.Errorf("no macOS WiFi info command available (airport/wdutil/system_profiler/networksetup missing)")
 }
 
-func (s *darwinScanner) enrichWithTrafficStats(iface string, result map[string]string) map[string]string {
-	if result["connected"] != "true" {
-		s.mu.Lock()
-		delete(s.baselineStats, iface)
-		delete(s.connectionStart, iface)
-		s.mu.Unlock()
-		return result
-	}
-
-	traffic, err := s.getTrafficStats(iface)
+// addTrafficStats fills the counters the text tools don't report from
+// netstat. The interface counters run from boot, so they're reported
+// relative to the first reading of this association; GetLinkInfo drops
+// the baseline on disconnect. Counters the station already has are kept.
+func (s *darwinScanner) addTrafficStats(ctx context.Context, iface string, st *StationInfo) {
+	traffic, err := s.getTrafficStats(ctx, iface)
 	if err != nil || traffic["rx_bytes"] == "0" {
-		result["rx_bytes"] = "0"
-		result["tx_bytes"] = "0"
-		result["rx_packets"] = "0"
-		result["tx_packets"] = "0"
-		result["tx_retries"] = "0"
-		result["tx_failed"] = "0"
-		result["connected_time"] = "0"
-		result["retry_rate"] = "0.00"
-		return result
+		return
 	}
 
 	rxBytesNow, _ := strconv.ParseUint(traffic["rx_bytes"], 10, 64)
 	txBytesNow, _ := strconv.ParseUint(traffic["tx_bytes"], 10, 64)
 	rxPktsNow, _ := strconv.ParseUint(traffic["rx_packets"], 10, 64)
 	txPktsNow, _ := strconv.ParseUint(traffic["tx_packets"], 10, 64)
+	retries, _ := strconv.ParseUint(traffic["tx_retries"], 10, 64)
+	failed, _ := strconv.ParseUint(traffic["tx_failed"], 10, 64)
 
 	s.mu.Lock()
 	baseline, hasBaseline := s.baselineStats[iface]
@@ -342,44 +358,57 @@ func (s *darwinScanner) enrichWithTrafficStats(iface string, result map[string]s
 	}
 	s.mu.Unlock()
 
-	rxDelta := saturatingSubUint64(rxBytesNow, baseline.inOctets)
-	txDelta := saturatingSubUint64(txBytesNow, baseline.outOctets)
-	rxPktsDelta := saturatingSubUint64(rxPktsNow, baseline.inPackets)
-	txPktsDelta := saturatingSubUint64(txPktsNow, baseline.outPackets)
-	connectedTime := int(time.Since(connStart).Seconds())
-
-	result["rx_bytes"] = strconv.FormatUint(rxDelta, 10)
-	result["tx_bytes"] = strconv.FormatUint(txDelta, 10)
-	result["rx_packets"] = strconv.FormatUint(rxPktsDelta, 10)
-	result["tx_packets"] = strconv.FormatUint(txPktsDelta, 10)
-	result["connected_time"] = strconv.Itoa(connectedTime)
-
-	retries, _ := strconv.ParseUint(traffic["tx_retries"], 10, 64)
-	result["tx_retries"] = strconv.FormatUint(retries, 10)
-
-	failed, _ := strconv.ParseUint(traffic["tx_failed"], 10, 64)
-	result["tx_failed"] = strconv.FormatUint(failed, 10)
-
-	retryRate := 0.0
-	if txPktsDelta > 0 {
-		retryRate = float64(retries) / float64(txPktsDelta) * 100.0
-		if retryRate > 100.0 {
-			retryRate = 100.0
+	fill := func(dst **uint64, v uint64) {
+		if *dst == nil {
+			*dst = uint64Ptr(v)
 		}
 	}
-	result["retry_rate"] = fmt.Sprintf("%.2f", retryRate)
+	fill(&st.RxBytes, saturatingSubUint64(rxBytesNow, baseline.inOctets))
+	fill(&st.TxBytes, saturatingSubUint64(txBytesNow, baseline.outOctets))
+	fill(&st.RxPackets, saturatingSubUint64(rxPktsNow, baseline.inPackets))
+	fill(&st.TxPackets, saturatingSubUint64(txPktsNow, baseline.outPackets))
+	fill(&st.TxRetries, retries)
+	fill(&st.TxFailed, failed)
+	if st.ConnectedTime == nil {
+		st.ConnectedTime = intPtr(int(time.Since(connStart).Seconds()))
+	}
+}
 
-	return result
+// Capabilities follows ScanNetworks' order of preference. CoreWLAN only
+// has IEs through the helper, and only counts them once the helper has
+// delivered; the text tools have none. Nothing on macOS exposes channel
+// surveys or per-station retries.
+func (s *darwinScanner) Capabilities(iface string) BackendCapabilities {
+	caps := BackendCapabilities{Interface: iface, Bands: []string{}}
+	switch {
+	case s.hasCoreWLAN:
+		s.mu.Lock()
+		caps.InformationElements = s.helperAugmented
+		s.mu.Unlock()
+		caps.Backend = "corewlan"
+		caps.Noise = true
+		caps.ActiveScan = true
+	case s.hasAirport:
+		caps.Backend = "airport"
+		caps.Noise = true
+		caps.ActiveScan = true
+	case s.hasSystemProfiler:
+		caps.Backend = "system_profiler"
+		caps.Noise = true
+	default:
+		caps.Backend = "networksetup"
+	}
+	return caps
 }
 
 func (s *darwinScanner) Close() error {
 	return nil
 }
 
-func (s *darwinScanner) getTrafficStats(iface string) (map[string]string, error) {
+func (s *darwinScanner) getTrafficStats(ctx context.Context, iface string) (map[string]string, error) {
 	result := make(map[string]string)
 
-	cmd := exec.Command("netstat", "-i", "-b")
+	cmd := exec.CommandContext(ctx, "netstat", "-i", "-b")
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return result, err
@@ -741,12 +770,12 @@ func parseSystemProfilerStationInfo(output []byte) map[string]string {
 	return info
 }
 
-func parseNetworksetupConnectionInfo(iface string) ConnectionInfo {
+func parseNetworksetupConnectionInfo(ctx context.Context, iface string) ConnectionInfo {
 	connInfo := ConnectionInfo{}
 	if iface == "" {
 		return connInfo
 	}
-	cmd := exec.Command("/usr/sbin/networksetup", "-getairportnetwork", iface)
+	cmd := exec.CommandContext(ctx, "/usr/sbin/networksetup", "-getairportnetwork", iface)
 	output, err := cmd.CombinedOutput()
 	if err != nil {
 		return connInfo
@@ -295,7 +295,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 	"unsafe"
@@ -533,120 +532,66 @@ func coreWLANConnectionInfo(iface string) (ConnectionInfo, error) {
 	return conn, nil
 }
 
-func coreWLANLinkInfo(iface string) (map[string]string, error) {
+func coreWLANLinkInfo(iface string) (LinkInfo, error) {
 	current, err := coreWLANCurrentInfo(iface)
 	if err != nil {
-		return map[string]string{"connected": "false"}, err
+		return LinkInfo{}, err
 	}
-	info := map[string]string{
-		"connected": strconv.FormatBool(current.SSID != "" || current.BSSID != ""),
+	if current.SSID == "" && current.BSSID == "" {
+		return LinkInfo{}, nil
 	}
-	if info["connected"] == "false" {
-		return info, nil
-	}
-	if current.BSSID != "" {
-		info["bssid"] = strings.ToLower(current.BSSID)
+	info := LinkInfo{
+		Connected: true,
+		SSID:      current.SSID,
+		BSSID:     strings.ToLower(current.BSSID),
 	}
 	if current.RSSI != 0 {
-		info["signal"] = strconv.Itoa(current.RSSI)
-		info["signal_avg"] = strconv.Itoa(current.RSSI)
+		info.Signal = intPtr(current.RSSI)
 	}
 	if current.Noise < 0 {
-		info["noise"] = strconv.Itoa(current.Noise)
-		if current.RSSI != 0 {
-			info["snr"] = strconv.Itoa(current.RSSI - current.Noise)
-		}
+		info.Noise = intPtr(current.Noise)
 	}
 	if current.Channel != 0 {
-		info["channel"] = strconv.Itoa(current.Channel)
+		info.Channel = intPtr(current.Channel)
 	}
-	band, freq := cwBandAndFrequency(current.Channel, current.ChannelBand)
-	if freq != 0 {
-		info["frequency"] = strconv.Itoa(freq)
+	if _, freq := cwBandAndFrequency(current.Channel, current.ChannelBand); freq != 0 {
+		info.Frequency = floatPtr(float64(freq))
 	}
-	if band != "" {
-		info["band"] = band
+	if width := mapCWChannelWidth(current.ChannelWidth); width != 0 {
+		info.ChannelWidth = intPtr(width)
 	}
-	width := mapCWChannelWidth(current.ChannelWidth)
-	if width != 0 {
-		info["channel_width"] = strconv.Itoa(width)
-	}
-	if current.TxRate != 0 {
-		info["tx_bitrate"] = strconv.FormatFloat(current.TxRate, 'f', -1, 64)
-	}
-	if rateInfo := cwBitrateInfoString(current.PhyMode, width, current.TxRate); rateInfo != "" {
-		info["tx_bitrate_info"] = rateInfo
-		info["rx_bitrate_info"] = rateInfo
-	}
-	if std := cwPhyModeStandard(current.PhyMode); std != "" {
-		info["wifi_standard"] = std
-	}
-	info["rx_bytes"] = "0"
-	info["tx_bytes"] = "0"
-	info["rx_packets"] = "0"
-	info["tx_packets"] = "0"
-	info["tx_retries"] = "0"
-	info["tx_failed"] = "0"
-	info["connected_time"] = "0"
 	return info, nil
 }
 
-func coreWLANStationInfo(iface string) (map[string]string, error) {
+// coreWLANStationInfo has no counters to offer: CoreWLAN doesn't expose
+// per-station traffic, so those fields stay nil.
+func coreWLANStationInfo(iface string) (StationInfo, error) {
 	current, err := coreWLANCurrentInfo(iface)
 	if err != nil {
-		return map[string]string{"connected": "false"}, err
+		return StationInfo{}, err
 	}
-	stats := map[string]string{
-		"connected": strconv.FormatBool(current.SSID != "" || current.BSSID != ""),
-	}
-	if stats["connected"] == "false" {
-		return stats, nil
-	}
-	if current.BSSID != "" {
-		stats["bssid"] = strings.ToLower(current.BSSID)
+	if current.SSID == "" && current.BSSID == "" {
+		return StationInfo{}, nil
 	}
+	var stats StationInfo
 	if current.RSSI != 0 {
-		stats["signal"] = strconv.Itoa(current.RSSI)
-		stats["signal_avg"] = strconv.Itoa(current.RSSI)
+		stats.SignalAvg = intPtr(current.RSSI)
 	}
 	if current.Noise < 0 {
-		stats["noise"] = strconv.Itoa(current.Noise)
+		stats.Noise = intPtr(current.Noise)
 		if current.RSSI != 0 {
-			stats["snr"] = strconv.Itoa(current.RSSI - current.Noise)
+			stats.SNR = intPtr(current.RSSI - current.Noise)
 		}
 	}
 	if current.TxRate != 0 {
-		stats["tx_bitrate"] = strconv.FormatFloat(current.TxRate, 'f', -1, 64)
-	}
-	if current.Channel != 0 {
-		stats["channel"] = strconv.Itoa(current.Channel)
-	}
-	band, freq := cwBandAndFrequency(current.Channel, current.ChannelBand)
-	if freq != 0 {
-		stats["frequency"] = strconv.Itoa(freq)
-	}
-	if band != "" {
-		stats["band"] = band
+		stats.TxBitrate = floatPtr(current.TxRate)
 	}
 	width := mapCWChannelWidth(current.ChannelWidth)
 	if width != 0 {
-		stats["channel_width"] = strconv.Itoa(width)
-	}
-	if rateInfo := cwBitrateInfoString(current.PhyMode, width, current.TxRate); rateInfo != "" {
-		stats["tx_bitrate_info"] = rateInfo
-		stats["rx_bitrate_info"] = rateInfo
-	}
-	if std := cwPhyModeStandard(current.PhyMode); std != "" {
-		stats["wifi_standard"] = std
-	}
-	stats["rx_bytes"] = "0"
-	stats["tx_bytes"] = "0"
-	stats["rx_packets"] = "0"
-	stats["tx_packets"] = "0"
-	stats["tx_retries"] = "0"
-	stats["tx_failed"] = "0"
-	stats["connected_time"] = "0"
-	stats["last_ack_signal"] = "0"
+		stats.ChannelWidth = intPtr(width)
+	}
+	stats.TxBitrateInfo = cwBitrateInfoString(current.PhyMode, width, current.TxRate)
+	stats.WiFiStandard = cwPhyModeStandard(current.PhyMode)
 	return stats, nil
 }
 
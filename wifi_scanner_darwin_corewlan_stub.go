@@ -29,12 +29,12 @@ func coreWLANConnectionInfo(_ string) (ConnectionInfo, error) {
 	return ConnectionInfo{}, fmt.Errorf("corewlan unavailable (cgo disabled)")
 }
 
-func coreWLANLinkInfo(_ string) (map[string]string, error) {
-	return map[string]string{"connected": "false"}, fmt.Errorf("corewlan unavailable (cgo disabled)")
+func coreWLANLinkInfo(_ string) (LinkInfo, error) {
+	return LinkInfo{}, fmt.Errorf("corewlan unavailable (cgo disabled)")
 }
 
-func coreWLANStationInfo(_ string) (map[string]string, error) {
-	return map[string]string{"connected": "false"}, fmt.Errorf("corewlan unavailable (cgo disabled)")
+func coreWLANStationInfo(_ string) (StationInfo, error) {
+	return StationInfo{}, fmt.Errorf("corewlan unavailable (cgo disabled)")
 }
 
 func coreWLANInterfaces() ([]string, error) {
@@ -56,8 +56,9 @@ type helperPayload struct {
 // The helper is best-effort by design — every field it can populate stays at
 // its CoreWLAN-derived value when augmentation fails. We log the failure at
 // Info level so a misconfigured helper path is visible without spamming the
-// scan log on every tick once the user disables it.
-func (s *darwinScanner) augmentWithHelper(iface string, aps []AccessPoint) {
+// scan log on every tick once the user disables it. The helper is killed
+// when ctx ends, without logging.
+func (s *darwinScanner) augmentWithHelper(ctx context.Context, iface string, aps []AccessPoint) {
 	s.mu.Lock()
 	helperPath := s.macHelperPath
 	s.mu.Unlock()
@@ -65,12 +66,15 @@ func (s *darwinScanner) augmentWithHelper(iface string, aps []AccessPoint) {
 		return
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	helperCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, helperPath, "-iface", iface)
+	cmd := exec.CommandContext(helperCtx, helperPath, "-iface", iface)
 	out, err := cmd.Output()
 	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
 		// Capture stderr from ExitError so the log line is actionable.
 		var ee *exec.ExitError
 		if errors.As(err, &ee) {
@@ -111,6 +115,9 @@ func (s *darwinScanner) augmentWithHelper(iface string, aps []AccessPoint) {
 		matched++
 	}
 	if matched > 0 {
+		s.mu.Lock()
+		s.helperAugmented = true
+		s.mu.Unlock()
 		slog.Debug("mac helper augmented APs", "matched", matched, "total_records", len(payload.Records))
 	}
 }
@@ -1,19 +1,403 @@
 package main
 
-// WiFiBackend defines the interface that all WiFi scanner backends must implement
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WiFiBackend defines the interface that all WiFi scanner backends must implement.
+// Every call that reaches the radio takes a context: when it ends (scanning
+// stopped, interfaces switched, app shutting down) the call returns
+// promptly with the context's error, even where the platform call itself
+// can't be interrupted.
 type WiFiBackend interface {
 	// GetInterfaces returns a list of available WiFi interfaces
-	GetInterfaces() ([]string, error)
+	GetInterfaces(ctx context.Context) ([]string, error)
 
-	// ScanNetworks scans for available WiFi networks on the specified interface
-	ScanNetworks(iface string) ([]AccessPoint, error)
+	// ScanNetworks scans for available WiFi networks on the specified
+	// interface, narrowed by opts where the backend can
+	ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error)
 
 	// GetLinkInfo gets information about the current WiFi connection
-	GetLinkInfo(iface string) (map[string]string, error)
+	GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error)
 
 	// GetStationStats gets detailed statistics about the connected station
-	GetStationStats(iface string) (map[string]string, error)
+	GetStationStats(ctx context.Context, iface string) (StationInfo, error)
+
+	// Capabilities describes which fields the backend fills in on iface
+	Capabilities(iface string) BackendCapabilities
 
 	// Close performs any necessary cleanup
 	Close() error
 }
+
+// callWithContext runs fn and returns its result, or ctx's error as soon
+// as ctx ends. fn then finishes in the background and its result is
+// dropped, so use it only for calls that can't be interrupted (cgo, the
+// Windows WLAN API) and are harmless to abandon.
+func callWithContext[T any](ctx context.Context, fn func() (T, error)) (T, error) {
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		return r.v, r.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// ScanOptions narrows a scan. The zero value is the usual active scan of
+// every channel. Backends apply what they can at the radio; the service
+// filters the results by SSID and frequency either way, so a backend that
+// can't target a scan, or falls back to the kernel's cached results, still
+// reports only what was asked for.
+type ScanOptions struct {
+	// SSIDs are probed for by name, which also finds hidden networks that
+	// answer, and only they are reported. A passive scan doesn't probe.
+	SSIDs []string `toml:"ssids" json:"ssids"`
+	// Frequencies (MHz) and Channels to scan; both empty scans every
+	// channel. Channels 1-14 are 2.4 GHz and 36-165 are 5 GHz; give 6 GHz
+	// channels as frequencies.
+	Frequencies []int `toml:"frequencies" json:"frequencies"`
+	Channels    []int `toml:"channels" json:"channels"`
+	// Passive listens for beacons without sending probe requests. A
+	// backend that can't promise that (BackendCapabilities.PassiveScan)
+	// refuses to scan rather than probe.
+	Passive bool `toml:"passive" json:"passive"`
+	// DwellMs is the time spent on each channel; 0 leaves it to the driver.
+	DwellMs int `toml:"dwell_ms" json:"dwellMs"`
+}
+
+// errPassiveScanUnsupported is returned by backends that can't scan
+// without probing.
+var errPassiveScanUnsupported = errors.New("passive scanning is not supported by this backend")
+
+// maxScanDwell bounds DwellMs: a full scan at 1 s a channel already takes
+// most of a minute.
+const maxScanDwell = time.Second
+
+// validate trims and de-duplicates the lists and clamps the dwell time,
+// returning a note per adjustment.
+func (o ScanOptions) validate() (ScanOptions, []string) {
+	var notes []string
+	var ssids []string
+	for _, s := range o.SSIDs {
+		s = strings.TrimSpace(s)
+		switch {
+		case s == "" || slices.Contains(ssids, s):
+		case len(s) > 32:
+			notes = append(notes, fmt.Sprintf("scan ssid %q dropped: longer than 32 bytes", s))
+		default:
+			ssids = append(ssids, s)
+		}
+	}
+	o.SSIDs = ssids
+	var freqs []int
+	for _, f := range o.Frequencies {
+		if f < 2400 || f > 7125 {
+			notes = append(notes, fmt.Sprintf("scan frequency %d dropped: not a WiFi frequency in MHz", f))
+			continue
+		}
+		if !slices.Contains(freqs, f) {
+			freqs = append(freqs, f)
+		}
+	}
+	o.Frequencies = freqs
+	var channels []int
+	for _, c := range o.Channels {
+		if c < 1 || c > 165 || (c > 14 && c < 36) {
+			notes = append(notes, fmt.Sprintf("scan channel %d dropped: not a 2.4 or 5 GHz channel", c))
+			continue
+		}
+		if !slices.Contains(channels, c) {
+			channels = append(channels, c)
+		}
+	}
+	o.Channels = channels
+	if o.DwellMs < 0 {
+		o.DwellMs = 0
+	}
+	if o.DwellMs > int(maxScanDwell/time.Millisecond) {
+		notes = append(notes, fmt.Sprintf("scan dwell_ms=%d clamped to %d", o.DwellMs, maxScanDwell/time.Millisecond))
+		o.DwellMs = int(maxScanDwell / time.Millisecond)
+	}
+	return o, notes
+}
+
+// frequencies is Frequencies plus Channels converted, lowest first.
+func (o ScanOptions) frequencies() []int {
+	freqs := slices.Clone(o.Frequencies)
+	for _, c := range o.Channels {
+		if f := channelToFrequency(c); f != 0 && !slices.Contains(freqs, f) {
+			freqs = append(freqs, f)
+		}
+	}
+	slices.Sort(freqs)
+	return freqs
+}
+
+// targeted reports whether o differs from a plain full active scan.
+func (o ScanOptions) targeted() bool {
+	return len(o.SSIDs) > 0 || len(o.Frequencies) > 0 || len(o.Channels) > 0 || o.Passive || o.DwellMs > 0
+}
+
+// filter returns the access points within o's SSIDs and frequencies. aps
+// is left alone: it may be a slice already published in an event.
+func (o ScanOptions) filter(aps []AccessPoint) []AccessPoint {
+	freqs := o.frequencies()
+	if len(o.SSIDs) == 0 && len(freqs) == 0 {
+		return aps
+	}
+	out := make([]AccessPoint, 0, len(aps))
+	for _, ap := range aps {
+		if (len(o.SSIDs) > 0 && !slices.Contains(o.SSIDs, ap.SSID)) ||
+			(len(freqs) > 0 && !slices.Contains(freqs, ap.Frequency)) {
+			continue
+		}
+		out = append(out, ap)
+	}
+	return out
+}
+
+// BackendCapabilities says which AccessPoint and ClientStats fields a
+// backend really reports on an interface, so a zero Noise or retry count
+// can be told apart from one nobody measured. Some of it is discovered as
+// the backend runs (nl80211 only knows whether the driver answers survey
+// dumps after the first scan), so ask again rather than keeping the first
+// answer.
+type BackendCapabilities struct {
+	Interface string `json:"interface"`
+	Backend   string `json:"backend"` // "nl80211", "iw", "corewlan", "windows", "sim", ...
+	// InformationElements: beacon IEs are parsed, so BSS load, PMF, HT/VHT/
+	// HE capabilities, MIMO streams and the 802.11k/v/r flags are real.
+	InformationElements bool `json:"informationElements"`
+	// Survey: per-channel survey data (SurveyUtilization, SurveyBusyMs,
+	// SurveyExtBusyMs, MaxTxPowerDbm).
+	Survey bool `json:"survey"`
+	// Noise: the noise floor, and with it SNR, on access points and the link.
+	Noise bool `json:"noise"`
+	// StationRetries: ClientStats TxRetries, TxFailed and RetryRate are the
+	// driver's per-station counters rather than absent or an interface-level
+	// approximation.
+	StationRetries bool `json:"stationRetries"`
+	ActiveScan     bool `json:"activeScan"`  // ScanNetworks triggers a fresh scan
+	PassiveScan    bool `json:"passiveScan"` // the backend can scan without sending probe requests
+	// Bands the radio can scan ("2.4GHz", "5GHz", "6GHz", as AccessPoint.Band);
+	// empty when the backend can't tell.
+	Bands []string `json:"bands"`
+}
+
+// bandsFromFrequencies lists the bands the frequencies fall in, lowest
+// first.
+func bandsFromFrequencies(freqs []int) []string {
+	seen := make(map[string]bool)
+	for _, f := range freqs {
+		seen[frequencyToBand(f)] = true
+	}
+	bands := []string{}
+	for _, b := range []string{"2.4GHz", "5GHz", "6GHz"} {
+		if seen[b] {
+			bands = append(bands, b)
+		}
+	}
+	return bands
+}
+
+// backendInfoVersion is the version of the LinkInfo/StationInfo API.
+// Version 1 returned map[string]string keyed by iw's field names; those
+// maps survive only where text is parsed (iw, airport, wdutil,
+// system_profiler) and in old captures, and reach the service through
+// linkInfoFromMap / stationInfoFromMap. Captures record the version next
+// to each link and station result. Adding a field doesn't change it;
+// changing a field's meaning or unit does.
+const backendInfoVersion = 2
+
+// LinkInfo is the current association as the backend sees it. Pointer
+// fields are nil when the backend doesn't report the value, the same way
+// AccessPoint's BSSLoad fields are: 0 is never used to mean "unknown".
+// The JSON names are the version 1 map keys.
+type LinkInfo struct {
+	Connected    bool     `json:"connected"`
+	SSID         string   `json:"ssid,omitempty"`
+	BSSID        string   `json:"bssid,omitempty"`
+	Frequency    *float64 `json:"frequency,omitempty"`     // MHz
+	Channel      *int     `json:"channel,omitempty"`       // for backends without a frequency
+	ChannelWidth *int     `json:"channel_width,omitempty"` // MHz
+	Signal       *int     `json:"signal,omitempty"`        // dBm
+	Noise        *int     `json:"noise,omitempty"`         // dBm
+}
+
+// StationInfo is the driver's view of the connected AP as a peer: rates
+// and counters. Counters are cumulative for the association where the
+// driver keeps them, otherwise since the backend first saw it. Nil means
+// not reported, as in LinkInfo.
+type StationInfo struct {
+	SignalAvg *int     `json:"signal_avg,omitempty"` // dBm
+	TxBitrate *float64 `json:"tx_bitrate,omitempty"` // Mbps
+	RxBitrate *float64 `json:"rx_bitrate,omitempty"` // Mbps
+	// TxBitrateInfo is the TX rate in iw's notation ("866.7 MBit/s 80MHz
+	// VHT-MCS 9 VHT-NSS 2"); parseBitrateInfo takes the standard, width
+	// and spatial streams from it.
+	TxBitrateInfo string  `json:"tx_bitrate_info,omitempty"`
+	WiFiStandard  string  `json:"wifi_standard,omitempty"` // when known without TxBitrateInfo
+	ChannelWidth  *int    `json:"channel_width,omitempty"` // MHz
+	Noise         *int    `json:"noise,omitempty"`         // dBm
+	SNR           *int    `json:"snr,omitempty"`           // dB
+	TxBytes       *uint64 `json:"tx_bytes,omitempty"`
+	RxBytes       *uint64 `json:"rx_bytes,omitempty"`
+	TxPackets     *uint64 `json:"tx_packets,omitempty"`
+	RxPackets     *uint64 `json:"rx_packets,omitempty"`
+	TxRetries     *uint64 `json:"tx_retries,omitempty"`
+	TxFailed      *uint64 `json:"tx_failed,omitempty"`
+	ConnectedTime *int    `json:"connected_time,omitempty"`  // seconds
+	LastAckSignal *int    `json:"last_ack_signal,omitempty"` // dBm
+}
+
+// legacyInfoKeys are the version 1 keys the adapters understand or
+// deliberately skip. Text parsers put link and station keys in either
+// map, so both adapters accept the whole set; anything else is logged.
+var legacyInfoKeys = map[string]bool{
+	"connected": true, "ssid": true, "bssid": true, "frequency": true, "channel": true,
+	"channel_width": true, "signal": true, "noise": true, "signal_avg": true,
+	"tx_bitrate": true, "rx_bitrate": true, "tx_bitrate_info": true, "wifi_standard": true,
+	"snr": true, "tx_bytes": true, "rx_bytes": true, "tx_packets": true, "rx_packets": true,
+	"tx_retries": true, "tx_failed": true, "connected_time": true, "last_ack_signal": true,
+	// Skipped: derived by the service (retry_rate, band) or not shown.
+	"retry_rate": true, "band": true, "rx_bitrate_info": true,
+	"beacon_loss": true, "dtim_period": true, "beacon_int": true,
+}
+
+// legacyInfoLogged holds the keys already logged by legacyInfoMap, so a
+// misspelled key shows up once rather than every tick.
+var legacyInfoLogged sync.Map
+
+// legacyInfoMap reads typed values out of a version 1 map. Unknown keys
+// and unparsable values are logged once per key instead of being dropped
+// silently.
+type legacyInfoMap map[string]string
+
+func (m legacyInfoMap) check() {
+	for k := range m {
+		if !legacyInfoKeys[k] {
+			m.logOnce(k, "unknown link/station key", "value", m[k])
+		}
+	}
+}
+
+func (m legacyInfoMap) logOnce(key, msg string, args ...any) {
+	if _, seen := legacyInfoLogged.LoadOrStore(key, true); !seen {
+		slog.Debug(msg, append([]any{"key", key}, args...)...)
+	}
+}
+
+func (m legacyInfoMap) int(key string) *int {
+	s, ok := m[key]
+	if !ok {
+		return nil
+	}
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		m.logOnce(key, "unparsable link/station value", "value", s)
+		return nil
+	}
+	return &v
+}
+
+// nonZeroInt is int for the keys where version 1 maps wrote "0" for
+// "unknown" (frequency, channel, width, noise, SNR).
+func (m legacyInfoMap) nonZeroInt(key string) *int {
+	if v := m.int(key); v != nil && *v != 0 {
+		return v
+	}
+	return nil
+}
+
+func (m legacyInfoMap) float(key string) *float64 {
+	s, ok := m[key]
+	if !ok {
+		return nil
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		m.logOnce(key, "unparsable link/station value", "value", s)
+		return nil
+	}
+	return &v
+}
+
+func (m legacyInfoMap) uint(key string) *uint64 {
+	s, ok := m[key]
+	if !ok {
+		return nil
+	}
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		m.logOnce(key, "unparsable link/station value", "value", s)
+		return nil
+	}
+	return &v
+}
+
+// linkInfoFromMap adapts a version 1 link map.
+func linkInfoFromMap(raw map[string]string) LinkInfo {
+	m := legacyInfoMap(raw)
+	m.check()
+	if m["connected"] != "true" {
+		return LinkInfo{}
+	}
+	l := LinkInfo{
+		Connected:    true,
+		SSID:         m["ssid"],
+		BSSID:        m["bssid"],
+		Channel:      m.nonZeroInt("channel"),
+		ChannelWidth: m.nonZeroInt("channel_width"),
+		Signal:       m.int("signal"),
+		Noise:        m.nonZeroInt("noise"),
+	}
+	if f := m.float("frequency"); f != nil && *f != 0 {
+		l.Frequency = f
+	}
+	return l
+}
+
+// stationInfoFromMap adapts a version 1 station map.
+func stationInfoFromMap(raw map[string]string) StationInfo {
+	m := legacyInfoMap(raw)
+	m.check()
+	if m["connected"] == "false" {
+		return StationInfo{}
+	}
+	return StationInfo{
+		SignalAvg:     m.int("signal_avg"),
+		TxBitrate:     m.float("tx_bitrate"),
+		RxBitrate:     m.float("rx_bitrate"),
+		TxBitrateInfo: m["tx_bitrate_info"],
+		WiFiStandard:  m["wifi_standard"],
+		ChannelWidth:  m.nonZeroInt("channel_width"),
+		Noise:         m.nonZeroInt("noise"),
+		SNR:           m.nonZeroInt("snr"),
+		TxBytes:       m.uint("tx_bytes"),
+		RxBytes:       m.uint("rx_bytes"),
+		TxPackets:     m.uint("tx_packets"),
+		RxPackets:     m.uint("rx_packets"),
+		TxRetries:     m.uint("tx_retries"),
+		TxFailed:      m.uint("tx_failed"),
+		ConnectedTime: m.int("connected_time"),
+		LastAckSignal: m.int("last_ack_signal"),
+	}
+}
@@ -0,0 +1,149 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iwScanner is the text-output fallback for hosts where the nl80211 netlink
+// client can't be opened but the iw binary is available and permitted
+// (containers with a restricted netlink family list, some seccomp profiles).
+// It is strictly a fallback: iw's output format isn't stable, and every call
+// forks a process.
+type iwScanner struct {
+	iwPath    string
+	ouiLookup *OUILookup
+	parser    *iwParser
+}
+
+// iwCommandTimeout bounds the cheap link/station/dev queries. Scans use
+// activeScanTimeout like the nl80211 backend.
+const iwCommandTimeout = 5 * time.Second
+
+func newIwScanner(iwPath string, ouiLookup *OUILookup) *iwScanner {
+	return &iwScanner{
+		iwPath:    iwPath,
+		ouiLookup: ouiLookup,
+		parser:    &iwParser{ouiLookup: ouiLookup},
+	}
+}
+
+// run executes iw, killing it when ctx ends or timeout passes. A
+// cancelled ctx is reported as ctx's error rather than iw's exit status.
+func (s *iwScanner) run(ctx context.Context, timeout time.Duration, args ...string) ([]byte, error) {
+	cmdCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	output, err := exec.CommandContext(cmdCtx, s.iwPath, args...).CombinedOutput()
+	if err != nil {
+		if ctx.Err() != nil {
+			return output, ctx.Err()
+		}
+		return output, fmt.Errorf("iw %s: %w (output: %s)", strings.Join(args, " "), err, strings.TrimSpace(string(output)))
+	}
+	return output, nil
+}
+
+func (s *iwScanner) GetInterfaces(ctx context.Context) ([]string, error) {
+	output, err := s.run(ctx, iwCommandTimeout, "dev")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get interfaces: %w", err)
+	}
+	ifaces := parseIwDev(output)
+	if len(ifaces) == 0 {
+		return nil, fmt.Errorf("no WiFi interfaces found")
+	}
+	return ifaces, nil
+}
+
+func (s *iwScanner) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
+	output, err := s.run(ctx, scanTimeout(opts), iwScanArgs(iface, opts)...)
+	if err != nil {
+		// Same EBUSY story as the nl80211 backend: another scan is in flight
+		// on this radio, so read the kernel's cached results instead.
+		if !isTransientScanError(err) {
+			return nil, fmt.Errorf("failed to initiate scan: %w", err)
+		}
+		slog.Info("scan trigger transient, using cached BSS dump",
+			"event", "scan_ebusy", "interface", iface, "backend", "iw", "err", err)
+		output, err = s.run(ctx, iwCommandTimeout, "dev", iface, "scan", "dump")
+		if err != nil {
+			return nil, fmt.Errorf("failed to dump cached scan: %w", err)
+		}
+	}
+
+	return s.parser.ParseScan(output)
+}
+
+// iwScanArgs is the iw command line for a scan with opts, in the order
+// iw's usage gives: freq, duration (in 1.024 ms units), then ssid or
+// passive.
+func iwScanArgs(iface string, opts ScanOptions) []string {
+	args := []string{"dev", iface, "scan"}
+	if freqs := opts.frequencies(); len(freqs) > 0 {
+		args = append(args, "freq")
+		for _, f := range freqs {
+			args = append(args, strconv.Itoa(f))
+		}
+	}
+	if opts.DwellMs > 0 {
+		args = append(args, "duration", strconv.Itoa(opts.DwellMs*1000/1024))
+	}
+	switch {
+	case opts.Passive:
+		args = append(args, "passive")
+	case len(opts.SSIDs) > 0:
+		args = append(args, "ssid")
+		args = append(args, opts.SSIDs...)
+	}
+	return args
+}
+
+func (s *iwScanner) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	output, err := s.run(ctx, iwCommandTimeout, "dev", iface, "link")
+	if err != nil {
+		return LinkInfo{}, fmt.Errorf("failed to get link info: %w", err)
+	}
+	info, err := s.parser.ParseLink(output)
+	if err != nil {
+		return LinkInfo{}, err
+	}
+	return linkInfoFromMap(info), nil
+}
+
+func (s *iwScanner) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
+	output, err := s.run(ctx, iwCommandTimeout, "dev", iface, "station", "dump")
+	if err != nil {
+		return StationInfo{}, fmt.Errorf("failed to get station stats: %w", err)
+	}
+	stats, err := s.parser.ParseStation(output)
+	if err != nil {
+		return StationInfo{}, err
+	}
+	return stationInfoFromMap(stats), nil
+}
+
+// Capabilities: iw's scan text carries the parsed IEs and station dump the
+// retry counters, but this backend doesn't run survey dumps, so there is no
+// noise floor either.
+func (s *iwScanner) Capabilities(iface string) BackendCapabilities {
+	return BackendCapabilities{
+		Interface:           iface,
+		Backend:             "iw",
+		InformationElements: true,
+		StationRetries:      true,
+		ActiveScan:          true,
+		PassiveScan:         true,
+		Bands:               []string{},
+	}
+}
+
+func (s *iwScanner) Close() error {
+	return nil
+}
@@ -0,0 +1,605 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// iwParser turns the text output of `iw dev <if> scan`, `iw dev <if> link`
+// and `iw dev <if> station dump` into the same normalized shapes the nl80211
+// backend produces. It exists for hosts where the netlink client can't be
+// opened (restricted containers, seccomp'd sandboxes) but the iw binary can
+// still talk to the kernel.
+//
+// iw's output is meant for humans and has drifted between releases, so the
+// parser is deliberately tolerant: unknown lines are ignored, section headers
+// may carry their first item on the same line ("RSN:\t * Version: 1"), and
+// a handful of capability markers are matched anywhere in a BSS block.
+type iwParser struct {
+	ouiLookup *OUILookup
+}
+
+var (
+	iwBSSRegex       = regexpMust(`^BSS ([0-9a-fA-F:]{17})`)
+	iwSignalRegex    = regexpMust(`^(-?\d+(?:\.\d+)?)`)
+	iwLastSeenRegex  = regexpMust(`^(\d+) ms ago`)
+	iwDSChannelRegex = regexpMust(`channel (\d+)`)
+	iwDTIMRegex      = regexpMust(`DTIM Period (\d+)`)
+	iwUtilRegex      = regexpMust(`^(\d+)/255`)
+	iwStreamsRegex   = regexpMust(`(\d+) streams: MCS`)
+	iwHTMCSRegex     = regexpMust(`HT RX MCS rate indexes supported: (.+)`)
+	iwBSSColorRegex  = regexpMust(`BSS Color: (\d+)`)
+	iwTxPowerRegex   = regexpMust(`TX power: (-?\d+)`)
+	iwVHTWidthRegex  = regexpMust(`channel width: (\d)`)
+	iwConnectedRegex = regexpMust(`^Connected to ([0-9a-fA-F:]{17})`)
+	iwStationRegex   = regexpMust(`^Station ([0-9a-fA-F:]{17})`)
+	iwTrafficRegex   = regexpMust(`^(\d+) bytes \((\d+) packets\)`)
+	iwBitrateRegex   = regexpMust(`^(\d+(?:\.\d+)?) MBit/s`)
+)
+
+// iwAKMNames maps iw's authentication-suite spelling onto the names the
+// nl80211 backend reports (RSNAKM.String after NormalizeAccessPoint), so the
+// UI and any downstream auditing see one vocabulary regardless of backend.
+var iwAKMNames = map[string]string{
+	"IEEE 802.1X":               "802.1X",
+	"PSK":                       "PSK",
+	"FT/IEEE 802.1X":            "FT-802.1X",
+	"FT/PSK":                    "FT-PSK",
+	"IEEE 802.1X/SHA-256":       "802.1X-SHA256",
+	"PSK/SHA-256":               "PSK-SHA256",
+	"TDLS/TPK":                  "TDLS",
+	"SAE":                       "SAE",
+	"FT/SAE":                    "FT-SAE",
+	"IEEE 802.1X/SUITE-B":       "802.1X-Suite-B",
+	"IEEE 802.1X/SUITE-B-192":   "802.1X-CNSA",
+	"FT/IEEE 802.1X/SHA-384":    "FT-802.1X-SHA384",
+	"FILS/SHA-256":              "FILS-SHA256",
+	"FILS/SHA-384":              "FILS-SHA384",
+	"FT/FILS/SHA-256":           "FT-FILS-SHA256",
+	"FT/FILS/SHA-384":           "FT-FILS-SHA384",
+	"OWE":                       "OWE",
+	"FT/PSK/SHA-384":            "FT-PSK-SHA384",
+	"PSK/SHA-384":               "PSK-SHA384",
+	"SAE-EXT-KEY":               "SAE-EXT-KEY",
+	"FT/SAE-EXT-KEY":            "FT-SAE-EXT-KEY",
+	"IEEE 802.1X/SHA-384":       "802.1X-SHA384",
+	"FT/IEEE 802.1X/SHA-384/EX": "FT-802.1X-SHA384",
+}
+
+// iwBSSState accumulates everything one BSS block says before it's folded
+// into an AccessPoint. Security needs the whole block (RSN vs WPA vs bare
+// Privacy bit) so it's resolved at the end rather than line by line.
+type iwBSSState struct {
+	ap       AccessPoint
+	section  string
+	privacy  bool
+	hasRSN   bool
+	hasWPA   bool
+	htOffset bool
+	vhtWidth int
+	vhtHint  int
+	streams  int
+}
+
+func (p *iwParser) ParseScan(output []byte) ([]AccessPoint, error) {
+	var aps []AccessPoint
+	var cur *iwBSSState
+	now := time.Now()
+
+	flush := func() {
+		if cur == nil {
+			return
+		}
+		aps = append(aps, cur.finish())
+		cur = nil
+	}
+
+	sc := bufio.NewScanner(bytes.NewReader(output))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		line := sc.Text()
+		if m := iwBSSRegex.FindStringSubmatch(line); m != nil {
+			flush()
+			bssid := strings.ToLower(m[1])
+			cur = &iwBSSState{ap: AccessPoint{
+				BSSID:        bssid,
+				LastSeen:     now,
+				Capabilities: []string{},
+			}}
+			if p.ouiLookup != nil {
+				cur.ap.Vendor = p.ouiLookup.LookupVendor(bssid)
+			}
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		cur.parseLine(line, now)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read iw scan output: %w", err)
+	}
+	flush()
+	return aps, nil
+}
+
+// parseLine handles one indented line of a BSS block. Depth-1 lines are
+// attributes or section headers; deeper lines belong to the current section.
+func (st *iwBSSState) parseLine(line string, now time.Time) {
+	depth := len(line) - len(strings.TrimLeft(line, "\t"))
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" {
+		return
+	}
+	st.matchAnywhere(trimmed)
+
+	if depth <= 1 {
+		key, value, _ := strings.Cut(trimmed, ":")
+		key = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		st.section = key
+		st.parseAttribute(key, value, now)
+		// "RSN:\t * Version: 1" — the first section item shares the line.
+		if item, ok := strings.CutPrefix(value, "* "); ok {
+			st.parseSectionItem(item)
+		}
+		return
+	}
+	st.parseSectionItem(strings.TrimPrefix(trimmed, "* "))
+}
+
+func (st *iwBSSState) parseAttribute(key, value string, now time.Time) {
+	ap := &st.ap
+	switch key {
+	case "freq":
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			ap.Frequency = int(f)
+		}
+	case "signal":
+		if m := iwSignalRegex.FindStringSubmatch(value); m != nil {
+			if f, err := strconv.ParseFloat(m[1], 64); err == nil {
+				ap.Signal = int(f)
+			}
+		}
+	case "last seen":
+		// iw prints both "120000.000s [boottime]" and "3 ms ago"; only the
+		// relative form is useful without knowing the boot time.
+		if m := iwLastSeenRegex.FindStringSubmatch(value); m != nil {
+			ms, _ := strconv.Atoi(m[1])
+			ap.LastSeen = now.Add(-time.Duration(ms) * time.Millisecond)
+		}
+	case "beacon interval":
+		ap.BeaconInt = readInt(value)
+	case "capability":
+		st.privacy = strings.Contains(value, "Privacy")
+	case "SSID":
+		ap.SSID = value
+	case "DS Parameter set":
+		if m := iwDSChannelRegex.FindStringSubmatch(value); m != nil {
+			ap.Channel, _ = strconv.Atoi(m[1])
+		}
+	case "TIM":
+		if m := iwDTIMRegex.FindStringSubmatch(value); m != nil {
+			ap.DTIM, _ = strconv.Atoi(m[1])
+		}
+	case "Country":
+		if fields := strings.Fields(value); len(fields) > 0 {
+			ap.CountryCode = fields[0]
+		}
+	case "TPC report":
+		if m := iwTxPowerRegex.FindStringSubmatch(value); m != nil {
+			ap.TxPower, _ = strconv.Atoi(m[1])
+		}
+	case "RSN":
+		st.hasRSN = true
+	case "WPA":
+		st.hasWPA = true
+	case "WPS":
+		ap.WPS = true
+	case "WMM":
+		ap.QoSSupport = true
+	case "HT capabilities":
+		ap.Capabilities = appendUnique(ap.Capabilities, "HT")
+		ap.Capabilities = appendUnique(ap.Capabilities, "WiFi4")
+	case "VHT capabilities":
+		ap.Capabilities = appendUnique(ap.Capabilities, "VHT")
+		ap.Capabilities = appendUnique(ap.Capabilities, "WiFi5")
+	case "HE capabilities":
+		ap.Capabilities = appendUnique(ap.Capabilities, "HE")
+		ap.Capabilities = appendUnique(ap.Capabilities, "WiFi6")
+		// HE APs support DL OFDMA by spec; mirrors parseHECapabilitiesElement.
+		ap.OFDMADownlink = true
+	case "EHT capabilities":
+		ap.Capabilities = appendUnique(ap.Capabilities, "WiFi7")
+	case "Multi-Link":
+		ap.MLO = true
+	case "Mobility Domain":
+		ap.FastRoaming = true
+	}
+}
+
+func (st *iwBSSState) parseSectionItem(item string) {
+	ap := &st.ap
+	key, value, _ := strings.Cut(item, ":")
+	key = strings.TrimSpace(key)
+	value = strings.TrimSpace(value)
+
+	switch st.section {
+	case "RSN", "WPA":
+		switch key {
+		case "Pairwise ciphers":
+			for _, c := range strings.Fields(value) {
+				ap.SecurityCiphers = appendUnique(ap.SecurityCiphers, c)
+			}
+		case "Authentication suites":
+			for _, akm := range splitIwAKMs(value) {
+				ap.AuthMethods = appendUnique(ap.AuthMethods, akm)
+				if strings.HasPrefix(akm, "FT-") {
+					ap.FastRoaming = true
+				}
+			}
+		case "Capabilities":
+			switch {
+			case strings.Contains(value, "MFP-required"):
+				ap.PMF = "Required"
+			case strings.Contains(value, "MFP-capable"):
+				ap.PMF = "Optional"
+			}
+		}
+	case "BSS Load":
+		switch key {
+		case "station count":
+			ap.BSSLoadStations = intPtr(readInt(value))
+		case "channel utilisation", "channel utilization":
+			if m := iwUtilRegex.FindStringSubmatch(value); m != nil {
+				raw, _ := strconv.Atoi(m[1])
+				ap.BSSLoadUtilization = intPtr(raw * 100 / 255)
+			}
+		}
+	case "HT operation":
+		if key == "secondary channel offset" && (value == "above" || value == "below") {
+			st.htOffset = true
+		}
+	case "VHT operation":
+		if m := iwVHTWidthRegex.FindStringSubmatch(item); m != nil {
+			switch m[1] {
+			case "1":
+				st.vhtWidth = 80
+			case "2", "3":
+				st.vhtWidth = 160
+			}
+		}
+	case "VHT capabilities":
+		switch item {
+		case "VHT80":
+			st.vhtHint = 80
+		case "VHT160", "VHT80+80":
+			st.vhtHint = 160
+		}
+	case "WMM":
+		if strings.EqualFold(item, "u-APSD") {
+			ap.UAPSD = true
+		}
+	}
+}
+
+// matchAnywhere picks up capability markers whose position in the output
+// varies between iw versions (top-level in some, nested in others).
+func (st *iwBSSState) matchAnywhere(trimmed string) {
+	ap := &st.ap
+	item := strings.TrimPrefix(trimmed, "* ")
+	switch {
+	case strings.HasPrefix(item, "BSS Transition"):
+		ap.BSSTransition = true
+	case item == "Neighbor Report":
+		ap.NeighborReport = true
+	case strings.Contains(item, "4096-QAM"):
+		ap.QAMSupport = 4096
+	case strings.Contains(item, "1024-QAM"):
+		if ap.QAMSupport < 1024 {
+			ap.QAMSupport = 1024
+		}
+	case strings.Contains(item, "OBSS PD"):
+		ap.OBSSPD = true
+	case item == "MU Beamformer":
+		ap.MUMIMO = true
+	case strings.HasPrefix(item, "TWT Responder"):
+		ap.TWTSupport = true
+	case strings.Contains(item, "UL MU-MIMO"), strings.Contains(item, "OFDMA RA"):
+		ap.OFDMAUplink = true
+	}
+	if m := iwBSSColorRegex.FindStringSubmatch(item); m != nil {
+		ap.BSSColor, _ = strconv.Atoi(m[1])
+	}
+	if m := iwStreamsRegex.FindStringSubmatch(item); m != nil && !strings.Contains(item, "not supported") {
+		if n, _ := strconv.Atoi(m[1]); n > st.streams {
+			st.streams = n
+		}
+	}
+	if m := iwHTMCSRegex.FindStringSubmatch(item); m != nil {
+		// HT MCS indexes encode streams: 0-7 = 1ss, 8-15 = 2ss, ... MCS 32
+		// is the HT-duplicate rate and says nothing about stream count.
+		highest := -1
+		for _, part := range strings.Split(m[1], ",") {
+			part = strings.TrimSpace(part)
+			_, hi, found := strings.Cut(part, "-")
+			if !found {
+				hi = part
+			}
+			if v, err := strconv.Atoi(hi); err == nil && v < 32 && v > highest {
+				highest = v
+			}
+		}
+		if n := highest/8 + 1; highest >= 0 && n > st.streams {
+			st.streams = n
+		}
+	}
+}
+
+// finish resolves the whole-block fields (security, width, streams) and
+// returns the completed AccessPoint.
+func (st *iwBSSState) finish() AccessPoint {
+	ap := st.ap
+	if ap.Channel == 0 && ap.Frequency > 0 {
+		ap.Channel = frequencyToChannel(ap.Frequency)
+	}
+	ap.Band = frequencyToBand(ap.Frequency)
+	if ap.Signal != 0 {
+		ap.SignalQuality = signalToQuality(ap.Signal)
+	}
+
+	ap.ChannelWidth = 20
+	if st.htOffset {
+		ap.ChannelWidth = 40
+	}
+	switch {
+	case st.vhtWidth > 0:
+		ap.ChannelWidth = st.vhtWidth
+	case st.vhtHint > ap.ChannelWidth:
+		ap.ChannelWidth = st.vhtHint
+	}
+
+	if st.streams > 0 {
+		ap.MIMOStreams = st.streams
+	}
+	if ap.QAMSupport == 0 && slices.Contains(ap.Capabilities, "VHT") {
+		ap.QAMSupport = 256
+	}
+
+	ap.Security = iwSecurityLabel(ap.AuthMethods, st.hasRSN, st.hasWPA, st.privacy)
+	if ap.PMF == "" {
+		ap.PMF = "Disabled"
+	}
+	ap.DFS = isDFSChannel(ap.Channel)
+	return ap
+}
+
+// iwSecurityLabel classifies a BSS the same way convertBSSToAccessPoint does
+// for nl80211, plus the WPA1 and WEP cases that only show up as a bare WPA
+// IE or the Privacy capability bit.
+func iwSecurityLabel(akms []string, hasRSN, hasWPA, privacy bool) string {
+	hasSAE, hasWPA3Ent, hasWPA2 := false, false, false
+	for _, akm := range akms {
+		switch akm {
+		case "SAE", "FT-SAE", "SAE-EXT-KEY", "FT-SAE-EXT-KEY":
+			hasSAE = true
+		case "802.1X-Suite-B", "802.1X-CNSA":
+			hasWPA3Ent = true
+		case "PSK", "FT-PSK", "802.1X", "FT-802.1X", "802.1X-SHA256", "PSK-SHA256":
+			hasWPA2 = true
+		}
+	}
+	switch {
+	case hasSAE:
+		return "WPA3"
+	case hasWPA3Ent:
+		return "WPA3-Enterprise"
+	case hasRSN:
+		if !hasWPA2 && slices.Contains(akms, "OWE") {
+			return "OWE"
+		}
+		return "WPA2"
+	case hasWPA:
+		return "WPA"
+	case privacy:
+		return "WEP"
+	}
+	return "Open"
+}
+
+// splitIwAKMs tokenises iw's "Authentication suites:" value. Suite names can
+// contain spaces ("IEEE 802.1X", "FT/IEEE 802.1X"), so tokens are greedily
+// re-joined against the known-name table before falling back to the raw
+// word.
+func splitIwAKMs(value string) []string {
+	words := strings.Fields(value)
+	var out []string
+	for i := 0; i < len(words); i++ {
+		matched := false
+		// Longest known names are three words ("FT/IEEE 802.1X/SHA-384").
+		for n := 3; n >= 1; n-- {
+			if i+n > len(words) {
+				continue
+			}
+			candidate := strings.Join(words[i:i+n], " ")
+			if name, ok := iwAKMNames[candidate]; ok {
+				out = append(out, name)
+				i += n - 1
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			out = append(out, words[i])
+		}
+	}
+	return out
+}
+
+// ParseLink parses `iw dev <if> link` into a version 1 link map (see
+// backendInfoVersion); GetLinkInfo adapts it with linkInfoFromMap.
+func (p *iwParser) ParseLink(output []byte) (map[string]string, error) {
+	info := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(output))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := iwConnectedRegex.FindStringSubmatch(line); m != nil {
+			info["connected"] = "true"
+			info["bssid"] = strings.ToLower(m[1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "SSID":
+			info["ssid"] = value
+		case "freq":
+			if f, err := strconv.ParseFloat(value, 64); err == nil {
+				info["frequency"] = strconv.Itoa(int(f))
+			}
+		case "signal":
+			if m := iwSignalRegex.FindStringSubmatch(value); m != nil {
+				info["signal"] = m[1]
+			}
+		case "RX":
+			if m := iwTrafficRegex.FindStringSubmatch(value); m != nil {
+				info["rx_bytes"], info["rx_packets"] = m[1], m[2]
+			}
+		case "TX":
+			if m := iwTrafficRegex.FindStringSubmatch(value); m != nil {
+				info["tx_bytes"], info["tx_packets"] = m[1], m[2]
+			}
+		case "rx bitrate":
+			setIwBitrate(info, "rx", value)
+		case "tx bitrate":
+			setIwBitrate(info, "tx", value)
+		case "dtim period":
+			info["dtim_period"] = value
+		case "beacon int":
+			info["beacon_int"] = value
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read iw link output: %w", err)
+	}
+	if info["connected"] != "true" {
+		return map[string]string{"connected": "false"}, nil
+	}
+	return info, nil
+}
+
+// ParseStation parses `iw dev <if> station dump`. In station (managed) mode
+// there's exactly one peer — the AP — so only the first block is read.
+func (p *iwParser) ParseStation(output []byte) (map[string]string, error) {
+	stats := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(output))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		if m := iwStationRegex.FindStringSubmatch(line); m != nil {
+			if stats["connected"] == "true" {
+				break
+			}
+			stats["connected"] = "true"
+			stats["bssid"] = strings.ToLower(m[1])
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch strings.TrimSpace(key) {
+		case "rx bytes", "tx bytes", "rx packets", "tx packets", "tx retries", "tx failed", "beacon loss":
+			stats[strings.ReplaceAll(strings.TrimSpace(key), " ", "_")] = strconv.Itoa(readInt(value))
+		case "signal":
+			if m := iwSignalRegex.FindStringSubmatch(value); m != nil {
+				stats["signal"] = m[1]
+			}
+		case "signal avg":
+			if m := iwSignalRegex.FindStringSubmatch(value); m != nil {
+				stats["signal_avg"] = m[1]
+			}
+		case "last ack signal":
+			if m := iwSignalRegex.FindStringSubmatch(value); m != nil {
+				stats["last_ack_signal"] = m[1]
+			}
+		case "tx bitrate":
+			setIwBitrate(stats, "tx", value)
+		case "rx bitrate":
+			setIwBitrate(stats, "rx", value)
+		case "connected time":
+			stats["connected_time"] = strconv.Itoa(readInt(value))
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read iw station output: %w", err)
+	}
+	if stats["connected"] != "true" {
+		return map[string]string{"connected": "false"}, nil
+	}
+
+	retryRate := 0.0
+	txPackets, _ := strconv.ParseFloat(stats["tx_packets"], 64)
+	txRetries, _ := strconv.ParseFloat(stats["tx_retries"], 64)
+	if txPackets > 0 {
+		retryRate = txRetries / txPackets * 100.0
+		if retryRate > 100.0 {
+			retryRate = 100.0
+		}
+	}
+	stats["retry_rate"] = fmt.Sprintf("%.2f", retryRate)
+	return stats, nil
+}
+
+// setIwBitrate splits "458.8 MBit/s 40MHz HE-MCS 9 HE-NSS 2 ..." into the
+// numeric rate and the full descriptor parseBitrateInfo consumes.
+func setIwBitrate(m map[string]string, dir, value string) {
+	if match := iwBitrateRegex.FindStringSubmatch(value); match != nil {
+		m[dir+"_bitrate"] = match[1]
+	}
+	m[dir+"_bitrate_info"] = value
+}
+
+// parseIwDev parses `iw dev` into interface names, station-mode interfaces
+// first, mirroring the ordering WiFiScannerNL80211.GetInterfaces applies.
+func parseIwDev(output []byte) []string {
+	var stations, others []string
+	var name string
+	flush := func(kind string) {
+		if name == "" {
+			return
+		}
+		if kind == "managed" {
+			stations = append(stations, name)
+		} else {
+			others = append(others, name)
+		}
+		name = ""
+	}
+	kind := ""
+	sc := bufio.NewScanner(bytes.NewReader(output))
+	for sc.Scan() {
+		line := strings.TrimSpace(sc.Text())
+		switch {
+		case strings.HasPrefix(line, "Interface "):
+			flush(kind)
+			name = strings.TrimSpace(strings.TrimPrefix(line, "Interface "))
+			kind = ""
+		case strings.HasPrefix(line, "phy#"):
+			flush(kind)
+			kind = ""
+		case strings.HasPrefix(line, "type "):
+			kind = strings.TrimSpace(strings.TrimPrefix(line, "type "))
+		}
+	}
+	flush(kind)
+	return append(stations, others...)
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestIwParser() *iwParser {
+	return &iwParser{
+		ouiLookup: &OUILookup{
+			ouiMap: map[string]string{
+				"AA:BB:CC": "Acme Networks",
+			},
+			loaded: true,
+		},
+	}
+}
+
+func TestIwParseScanWPA2(t *testing.T) {
+	p := newTestIwParser()
+	aps, err := p.ParseScan(mustReadFixtureAirport(t, "iw-scan/wpa2-2g.txt"))
+	if err != nil {
+		t.Fatalf("ParseScan: %v", err)
+	}
+	if len(aps) != 1 {
+		t.Fatalf("want 1 AP, got %d", len(aps))
+	}
+	ap := aps[0]
+	if ap.BSSID != "aa:bb:cc:11:22:33" || ap.SSID != "MyNetwork" {
+		t.Errorf("BSSID/SSID = %q / %q", ap.BSSID, ap.SSID)
+	}
+	if ap.Vendor != "Acme Networks" {
+		t.Errorf("Vendor = %q (OUI lookup)", ap.Vendor)
+	}
+	if ap.Frequency != 2462 || ap.Channel != 11 || ap.Band != "2.4GHz" {
+		t.Errorf("freq/channel/band = %d / %d / %q", ap.Frequency, ap.Channel, ap.Band)
+	}
+	if ap.Signal != -45 || ap.SignalQuality == 0 {
+		t.Errorf("signal/quality = %d / %d", ap.Signal, ap.SignalQuality)
+	}
+	if ap.BeaconInt != 100 || ap.DTIM != 2 || ap.CountryCode != "AU" {
+		t.Errorf("beacon/dtim/country = %d / %d / %q", ap.BeaconInt, ap.DTIM, ap.CountryCode)
+	}
+	if ap.BSSLoadStations == nil || *ap.BSSLoadStations != 5 {
+		t.Errorf("BSSLoadStations = %v, want 5", ap.BSSLoadStations)
+	}
+	if ap.BSSLoadUtilization == nil || *ap.BSSLoadUtilization != 60 {
+		t.Errorf("BSSLoadUtilization = %v, want 60 (153/255)", ap.BSSLoadUtilization)
+	}
+	if ap.ChannelWidth != 20 {
+		t.Errorf("ChannelWidth = %d, want 20 (no secondary)", ap.ChannelWidth)
+	}
+	if ap.MIMOStreams != 2 {
+		t.Errorf("MIMOStreams = %d, want 2 (MCS 32 must not count)", ap.MIMOStreams)
+	}
+	if ap.Security != "WPA2" || ap.PMF != "Optional" {
+		t.Errorf("security/PMF = %q / %q", ap.Security, ap.PMF)
+	}
+	if !contains(ap.AuthMethods, "PSK") || !contains(ap.SecurityCiphers, "CCMP") {
+		t.Errorf("auth/ciphers = %v / %v", ap.AuthMethods, ap.SecurityCiphers)
+	}
+	if !ap.WPS || !ap.QoSSupport {
+		t.Errorf("WPS/QoS = %v / %v", ap.WPS, ap.QoSSupport)
+	}
+	if !contains(ap.Capabilities, "HT") {
+		t.Errorf("Capabilities = %v, want HT", ap.Capabilities)
+	}
+	if age := time.Since(ap.LastSeen); age < 100*time.Millisecond || age > time.Minute {
+		t.Errorf("LastSeen age = %v, want ~100ms", age)
+	}
+}
+
+func TestIwParseScanWPA3HE(t *testing.T) {
+	p := newTestIwParser()
+	aps, err := p.ParseScan(mustReadFixtureAirport(t, "iw-scan/wpa3-5g.txt"))
+	if err != nil {
+		t.Fatalf("ParseScan: %v", err)
+	}
+	if len(aps) != 1 {
+		t.Fatalf("want 1 AP, got %d", len(aps))
+	}
+	ap := aps[0]
+	if ap.Channel != 36 || ap.Band != "5GHz" || ap.ChannelWidth != 80 {
+		t.Errorf("channel/band/width = %d / %q / %d", ap.Channel, ap.Band, ap.ChannelWidth)
+	}
+	if ap.Security != "WPA3" || ap.PMF != "Required" {
+		t.Errorf("security/PMF = %q / %q", ap.Security, ap.PMF)
+	}
+	if !contains(ap.AuthMethods, "SAE") || !contains(ap.AuthMethods, "FT-SAE") {
+		t.Errorf("AuthMethods = %v, want SAE and FT-SAE", ap.AuthMethods)
+	}
+	if !ap.FastRoaming {
+		t.Error("FastRoaming = false, want true (FT AKM)")
+	}
+	for _, c := range []string{"HT", "VHT", "HE", "WiFi6"} {
+		if !contains(ap.Capabilities, c) {
+			t.Errorf("Capabilities = %v, missing %s", ap.Capabilities, c)
+		}
+	}
+	if ap.BSSColor != 7 || !ap.OBSSPD || ap.QAMSupport != 1024 {
+		t.Errorf("color/obsspd/qam = %d / %v / %d", ap.BSSColor, ap.OBSSPD, ap.QAMSupport)
+	}
+	if !ap.MUMIMO || !ap.BSSTransition || !ap.NeighborReport || !ap.OFDMADownlink {
+		t.Errorf("mumimo/btm/nr/ofdma = %v / %v / %v / %v",
+			ap.MUMIMO, ap.BSSTransition, ap.NeighborReport, ap.OFDMADownlink)
+	}
+	if ap.MIMOStreams != 4 {
+		t.Errorf("MIMOStreams = %d, want 4 (HE NSS beats HT MCS range)", ap.MIMOStreams)
+	}
+	if ap.DTIM != 3 {
+		t.Errorf("DTIM = %d", ap.DTIM)
+	}
+}
+
+func TestIwParseScanMultiAP(t *testing.T) {
+	p := newTestIwParser()
+	aps, err := p.ParseScan(mustReadFixtureAirport(t, "iw-scan/multi-ap.txt"))
+	if err != nil {
+		t.Fatalf("ParseScan: %v", err)
+	}
+	if len(aps) != 2 {
+		t.Fatalf("want 2 APs, got %d", len(aps))
+	}
+	if aps[0].SSID != "GuestWiFi" || aps[0].Security != "Open" || aps[0].Channel != 1 {
+		t.Errorf("ap0 = %q / %q / ch %d", aps[0].SSID, aps[0].Security, aps[0].Channel)
+	}
+	if aps[1].SSID != "" {
+		t.Errorf("ap1 SSID = %q, want hidden (empty)", aps[1].SSID)
+	}
+	if aps[1].Security != "WPA2" || aps[1].Channel != 48 || aps[1].DFS {
+		t.Errorf("ap1 = %q / ch %d / dfs %v", aps[1].Security, aps[1].Channel, aps[1].DFS)
+	}
+}
+
+func TestIwParseScanLiveOutput(t *testing.T) {
+	p := newTestIwParser()
+	aps, err := p.ParseScan(mustReadFixtureAirport(t, "iw-scan/live-multi-bss.txt"))
+	if err != nil {
+		t.Fatalf("ParseScan: %v", err)
+	}
+	if len(aps) != 3 {
+		t.Fatalf("want 3 APs, got %d", len(aps))
+	}
+	tests := []struct {
+		bssid    string
+		freq     int
+		width    int
+		security string
+	}{
+		{"02:11:22:33:44:55", 5220, 40, "WPA3"},
+		{"02:11:22:33:44:66", 2437, 20, "WPA2"},
+		{"02:11:22:33:44:77", 5220, 40, "WPA2"},
+	}
+	for i, tt := range tests {
+		ap := aps[i]
+		if ap.BSSID != tt.bssid || ap.Frequency != tt.freq {
+			t.Errorf("ap%d BSSID/freq = %q / %d, want %q / %d", i, ap.BSSID, ap.Frequency, tt.bssid, tt.freq)
+		}
+		if ap.ChannelWidth != tt.width {
+			t.Errorf("ap%d ChannelWidth = %d, want %d", i, ap.ChannelWidth, tt.width)
+		}
+		if ap.Security != tt.security {
+			t.Errorf("ap%d Security = %q, want %q", i, ap.Security, tt.security)
+		}
+	}
+	if aps[1].TxPower != 20 || !aps[1].NeighborReport {
+		t.Errorf("ap1 TxPower/NeighborReport = %d / %v", aps[1].TxPower, aps[1].NeighborReport)
+	}
+	// "last seen" appears twice; the relative "ms ago" form must win over
+	// the boottime stamp.
+	if age := time.Since(aps[1].LastSeen); age < 6*time.Second || age > time.Minute {
+		t.Errorf("ap1 LastSeen age = %v, want ~6.4s", age)
+	}
+}
+
+func TestIwParseLink(t *testing.T) {
+	p := newTestIwParser()
+	tests := []struct {
+		fixture   string
+		connected string
+		bssid     string
+		freq      string
+		signal    string
+		txBitrate string
+	}{
+		{"iw-link/connected.txt", "true", "aa:bb:cc:11:22:33", "2462", "-45", "130.0"},
+		{"iw-link/connected-he.txt", "true", "02:11:22:33:44:55", "5220", "-53", "458.8"},
+		{"iw-link/not-connected.txt", "false", "", "", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			info, err := p.ParseLink(mustReadFixtureAirport(t, tt.fixture))
+			if err != nil {
+				t.Fatalf("ParseLink: %v", err)
+			}
+			if info["connected"] != tt.connected || info["bssid"] != tt.bssid {
+				t.Errorf("connected/bssid = %q / %q", info["connected"], info["bssid"])
+			}
+			if info["frequency"] != tt.freq || info["signal"] != tt.signal {
+				t.Errorf("frequency/signal = %q / %q", info["frequency"], info["signal"])
+			}
+			if info["tx_bitrate"] != tt.txBitrate {
+				t.Errorf("tx_bitrate = %q, want %q", info["tx_bitrate"], tt.txBitrate)
+			}
+		})
+	}
+}
+
+func TestIwParseStation(t *testing.T) {
+	p := newTestIwParser()
+	stats, err := p.ParseStation(mustReadFixtureAirport(t, "iw-station/connected-he.txt"))
+	if err != nil {
+		t.Fatalf("ParseStation: %v", err)
+	}
+	want := map[string]string{
+		"connected":       "true",
+		"bssid":           "02:11:22:33:44:55",
+		"signal":          "-51",
+		"signal_avg":      "-52",
+		"last_ack_signal": "-50",
+		"tx_bitrate":      "458.8",
+		"rx_bitrate":      "137.6",
+		"tx_packets":      "400000",
+		"tx_retries":      "50000",
+		"tx_failed":       "12",
+		"connected_time":  "7200",
+		"retry_rate":      "12.50",
+	}
+	for k, v := range want {
+		if stats[k] != v {
+			t.Errorf("%s = %q, want %q", k, stats[k], v)
+		}
+	}
+	std, width, mimo := parseBitrateInfo(stats["tx_bitrate_info"])
+	if std != "WiFi 6 (802.11ax)" || width != "40" || mimo != "2x2" {
+		t.Errorf("parseBitrateInfo(%q) = %q / %q / %q", stats["tx_bitrate_info"], std, width, mimo)
+	}
+}
+
+func TestLegacyInfoAdapters(t *testing.T) {
+	p := newTestIwParser()
+	raw, err := p.ParseLink(mustReadFixtureAirport(t, "iw-link/connected-he.txt"))
+	if err != nil {
+		t.Fatalf("ParseLink: %v", err)
+	}
+	link := linkInfoFromMap(raw)
+	if !link.Connected || link.BSSID != "02:11:22:33:44:55" || link.Frequency == nil || *link.Frequency != 5220 ||
+		link.Signal == nil || *link.Signal != -53 {
+		t.Errorf("link = %+v", link)
+	}
+	if link.Noise != nil {
+		t.Errorf("noise = %d, want unreported", *link.Noise)
+	}
+	raw, _ = p.ParseLink(mustReadFixtureAirport(t, "iw-link/not-connected.txt"))
+	if link := linkInfoFromMap(raw); link.Connected || link.Signal != nil {
+		t.Errorf("not connected = %+v", link)
+	}
+
+	raw, _ = p.ParseStation(mustReadFixtureAirport(t, "iw-station/connected-he.txt"))
+	station := stationInfoFromMap(raw)
+	if station.SignalAvg == nil || *station.SignalAvg != -52 || station.TxBitrate == nil || *station.TxBitrate != 458.8 ||
+		station.TxRetries == nil || *station.TxRetries != 50000 || station.ConnectedTime == nil || *station.ConnectedTime != 7200 {
+		t.Errorf("station = %+v", station)
+	}
+
+	// Version 1 maps wrote "0" for an unknown noise or width, and a bad
+	// value is dropped rather than read as zero.
+	station = stationInfoFromMap(map[string]string{"noise": "0", "channel_width": "0", "snr": "31", "tx_bytes": "lots"})
+	if station.Noise != nil || station.ChannelWidth != nil || station.SNR == nil || *station.SNR != 31 || station.TxBytes != nil {
+		t.Errorf("zero placeholders = %+v", station)
+	}
+}
+
+func TestParseIwDev(t *testing.T) {
+	output := []byte(`phy#1
+	Interface p2p-dev-wlan0
+		wdev 0x100000002
+		type P2P-device
+phy#0
+	Interface wlan0
+		ifindex 3
+		type managed
+	Interface mon0
+		type monitor
+`)
+	got := parseIwDev(output)
+	want := []string{"wlan0", "p2p-dev-wlan0", "mon0"}
+	if len(got) != len(want) {
+		t.Fatalf("parseIwDev = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("parseIwDev[%d] = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
@@ -4,12 +4,16 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
+	"os/exec"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/mdlayher/wifi"
+	"golang.org/x/sys/unix"
 )
 
 type WiFiScannerNL80211 struct {
@@ -17,16 +21,47 @@ type WiFiScannerNL80211 struct {
 	ouiLookup *OUILookup
 	parser    *mdlayherParser
 	initErr   error
+
+	mu       sync.Mutex
+	observed map[string]nl80211Observed // by interface, see Capabilities
+	noDwell  map[string]bool            // interfaces whose driver rejects a scan dwell time
+}
+
+// nl80211Observed is what scans have shown about an interface's driver:
+// whether it answers survey dumps, whether those carry a noise floor, and
+// the survey's frequencies, one per channel the radio supports.
+type nl80211Observed struct {
+	survey, noise bool
+	freqs         []int
 }
 
 const activeScanTimeout = 20 * time.Second
 
+// scanTimeout is how long a scan with opts may take: activeScanTimeout
+// plus the dwell time on every channel scanned (about 60 when the scan
+// isn't restricted).
+func scanTimeout(opts ScanOptions) time.Duration {
+	channels := len(opts.frequencies())
+	if channels == 0 {
+		channels = 60
+	}
+	return activeScanTimeout + time.Duration(channels*opts.DwellMs)*time.Millisecond
+}
+
 func NewWiFiScanner(cacheFile string) WiFiBackend {
 	ouiLookup := NewOUILookup(cacheFile)
 	ouiLookup.LoadOUIDatabase()
 
 	client, err := wifi.New()
 	if err != nil {
+		// No usable nl80211 socket. If the iw binary is around it can often
+		// still reach the kernel (it may carry capabilities we don't), so
+		// prefer a degraded text backend over no data at all.
+		if iwPath, lookErr := exec.LookPath("iw"); lookErr == nil {
+			slog.Warn("nl80211 client unavailable, falling back to iw",
+				"event", "backend_fallback", "backend", "iw", "path", iwPath, "err", err)
+			return newIwScanner(iwPath, ouiLookup)
+		}
 		return &WiFiScannerNL80211{
 			client:    nil,
 			ouiLookup: ouiLookup,
@@ -43,10 +78,13 @@ func NewWiFiScanner(cacheFile string) WiFiBackend {
 	}
 }
 
-func (s *WiFiScannerNL80211) GetInterfaces() ([]string, error) {
+func (s *WiFiScannerNL80211) GetInterfaces(ctx context.Context) ([]string, error) {
 	if s.initErr != nil {
 		return nil, s.initErr
 	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	interfaces, err := s.client.Interfaces()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get interfaces: %w", err)
@@ -76,7 +114,11 @@ func (s *WiFiScannerNL80211) GetInterfaces() ([]string, error) {
 	return ifaces, nil
 }
 
-func (s *WiFiScannerNL80211) ScanNetworks(iface string) ([]AccessPoint, error) {
+// ScanNetworks triggers a scan bounded by scanTimeout within ctx: the
+// library's full active scan, or triggerScan's targeted one when opts
+// narrow it. Netlink dumps are quick and aren't interrupted; the trigger
+// wait and the EBUSY retry are.
+func (s *WiFiScannerNL80211) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
 	if s.initErr != nil {
 		return nil, s.initErr
 	}
@@ -95,9 +137,18 @@ func (s *WiFiScannerNL80211) ScanNetworks(iface string) ([]AccessPoint, error) {
 		return nil, fmt.Errorf("interface %s not found", iface)
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), activeScanTimeout)
+	scanCtx, cancel := context.WithTimeout(ctx, scanTimeout(opts))
 	defer cancel()
-	if err := s.client.Scan(ctx, targetInterface); err != nil {
+	trigger := s.client.Scan
+	if opts.targeted() {
+		trigger = func(ctx context.Context, ifi *wifi.Interface) error {
+			return s.triggerScan(ctx, ifi, opts)
+		}
+	}
+	if err := trigger(scanCtx, targetInterface); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
 		// EBUSY on the trigger means someone else (NetworkManager,
 		// wpa_supplicant, our own previous scan) has an active scan in flight
 		// on this radio. The kernel still has the last scan results cached
@@ -112,7 +163,11 @@ func (s *WiFiScannerNL80211) ScanNetworks(iface string) ([]AccessPoint, error) {
 
 	bssList, err := s.client.AccessPoints(targetInterface)
 	if err != nil && isTransientScanError(err) {
-		time.Sleep(2 * time.Second)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
 		bssList, err = s.client.AccessPoints(targetInterface)
 	}
 	if err != nil {
@@ -137,8 +192,14 @@ func (s *WiFiScannerNL80211) ScanNetworks(iface string) ([]AccessPoint, error) {
 	utilByFreq := map[int]int{}
 	maxTxPowerByFreq := map[int]int{}
 	surveys, err := s.client.SurveyInfo(targetInterface)
+	var seen nl80211Observed
 	if err == nil && len(surveys) > 0 {
+		seen.survey = true
 		for _, survey := range surveys {
+			if survey.Frequency != 0 {
+				seen.freqs = append(seen.freqs, survey.Frequency)
+			}
+			seen.noise = seen.noise || survey.Noise != 0
 			if survey.Noise != 0 && survey.Frequency != 0 {
 				noiseByFreq[survey.Frequency] = survey.Noise
 			}
@@ -193,9 +254,71 @@ func (s *WiFiScannerNL80211) ScanNetworks(iface string) ([]AccessPoint, error) {
 		}
 		accessPoints[i].DFS = isDFSChannel(accessPoints[i].Channel)
 	}
+	s.observe(iface, seen)
 	return accessPoints, nil
 }
 
+// observe keeps what a scan showed about the driver. A failed survey dump
+// doesn't erase an earlier successful one: some drivers refuse the dump
+// while a scan is still settling.
+func (s *WiFiScannerNL80211) observe(iface string, seen nl80211Observed) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.observed == nil {
+		s.observed = make(map[string]nl80211Observed)
+	}
+	if !seen.survey && s.observed[iface].survey {
+		return
+	}
+	s.observed[iface] = seen
+}
+
+// Capabilities reports survey, noise and band support as discovered by the
+// interface's scans so far; before the first scan they read false.
+func (s *WiFiScannerNL80211) Capabilities(iface string) BackendCapabilities {
+	caps := BackendCapabilities{Interface: iface, Backend: "nl80211", Bands: []string{}}
+	if s.initErr != nil {
+		return caps
+	}
+	s.mu.Lock()
+	seen := s.observed[iface]
+	s.mu.Unlock()
+	caps.InformationElements = true
+	caps.Survey = seen.survey
+	caps.Noise = seen.noise
+	caps.StationRetries = true
+	caps.ActiveScan = true
+	caps.PassiveScan = true
+	caps.Bands = bandsFromFrequencies(seen.freqs)
+	return caps
+}
+
+// triggerScan runs a targeted scan. Drivers without
+// NL80211_EXT_FEATURE_SET_SCAN_DWELL reject a dwell time with EOPNOTSUPP;
+// the scan is retried without one, and the interface remembered so it
+// isn't logged every tick.
+func (s *WiFiScannerNL80211) triggerScan(ctx context.Context, ifi *wifi.Interface, opts ScanOptions) error {
+	s.mu.Lock()
+	if s.noDwell[ifi.Name] {
+		opts.DwellMs = 0
+	}
+	s.mu.Unlock()
+	err := triggerScan(ctx, ifi.Index, opts)
+	if opts.DwellMs > 0 && errors.Is(err, unix.EOPNOTSUPP) {
+		slog.Info("driver can't set the scan dwell time, using its default",
+			"event", "scan_dwell_unsupported", "interface", ifi.Name)
+		s.mu.Lock()
+		if s.noDwell == nil {
+			s.noDwell = make(map[string]bool)
+		}
+		s.noDwell[ifi.Name] = true
+		s.mu.Unlock()
+		opts.DwellMs = 0
+		err = triggerScan(ctx, ifi.Index, opts)
+	}
+	return err
+}
+
 func isTransientScanError(err error) bool {
 	if err == nil {
 		return false
@@ -317,15 +440,19 @@ func (p *mdlayherParser) parseSecurityFromRSN(rsn wifi.RSNInfo, ap *AccessPoint)
 	}
 }
 
-func (s *WiFiScannerNL80211) GetLinkInfo(iface string) (map[string]string, error) {
-	info := make(map[string]string)
+// associatedStation returns the interface's station entry for the AP it's
+// associated with, or nil when it isn't associated.
+func (s *WiFiScannerNL80211) associatedStation(ctx context.Context, iface string) (*wifi.Interface, *wifi.StationInfo, error) {
 	if s.initErr != nil {
-		return info, s.initErr
+		return nil, nil, s.initErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
 	}
 
 	interfaces, err := s.client.Interfaces()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get interfaces: %w", err)
+		return nil, nil, fmt.Errorf("failed to get interfaces: %w", err)
 	}
 
 	var targetIface *wifi.Interface
@@ -337,72 +464,65 @@ func (s *WiFiScannerNL80211) GetLinkInfo(iface string) (map[string]string, error
 	}
 
 	if targetIface == nil || targetIface.Type != wifi.InterfaceTypeStation {
-		info["connected"] = "false"
-		return info, nil
+		return nil, nil, nil
 	}
 
 	stations, err := s.client.StationInfo(targetIface)
-	if err != nil {
-		info["connected"] = "false"
-		return info, nil
+	if err != nil || len(stations) == 0 {
+		return nil, nil, nil
 	}
+	return targetIface, stations[0], nil
+}
 
-	if len(stations) == 0 {
-		info["connected"] = "false"
-		return info, nil
+func (s *WiFiScannerNL80211) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	targetIface, station, err := s.associatedStation(ctx, iface)
+	if err != nil || station == nil {
+		return LinkInfo{}, err
 	}
 
-	station := stations[0]
-
+	info := LinkInfo{
+		Connected: true,
+		BSSID:     station.HardwareAddr.String(),
+		Signal:    intPtr(station.Signal),
+	}
 	// Lookup SSID and frequency from BSS scan results
 	bssList, err := s.client.AccessPoints(targetIface)
-	if err == nil && len(bssList) > 0 {
+	if err == nil {
 		for _, bss := range bssList {
 			if bss.BSSID.String() == station.HardwareAddr.String() {
-				info["ssid"] = bss.SSID
-				info["frequency"] = fmt.Sprintf("%d", bss.Frequency)
+				info.SSID = bss.SSID
+				if bss.Frequency != 0 {
+					info.Frequency = floatPtr(float64(bss.Frequency))
+				}
 				break
 			}
 		}
 	}
-
-	info["connected"] = "true"
-	info["bssid"] = station.HardwareAddr.String()
-	info["signal"] = fmt.Sprintf("%d", station.Signal)
-	info["signal_avg"] = fmt.Sprintf("%d", station.SignalAverage)
-	info["rx_bytes"] = fmt.Sprintf("%d", station.ReceivedBytes)
-	info["tx_bytes"] = fmt.Sprintf("%d", station.TransmittedBytes)
-	info["rx_packets"] = fmt.Sprintf("%d", station.ReceivedPackets)
-	info["tx_packets"] = fmt.Sprintf("%d", station.TransmittedPackets)
-	info["rx_bitrate"] = fmt.Sprintf("%.1f", float64(station.ReceiveBitrate)/1e6)
-	info["tx_bitrate"] = fmt.Sprintf("%.1f", float64(station.TransmitBitrate)/1e6)
-	// rx/tx_bitrate_info feeds parseBitrateInfo (wifi_utils.go) so the WiFi
-	// standard, channel width, and spatial-stream count surface in
-	// ClientStats. We synthesise the iw-style string from the typed RateInfo
-	// because the parser is shaped around that text format.
-	info["rx_bitrate_info"] = formatRateInfo(station.ReceiveRateInfo)
-	info["tx_bitrate_info"] = formatRateInfo(station.TransmitRateInfo)
-	info["tx_retries"] = fmt.Sprintf("%d", station.TransmitRetries)
-	info["tx_failed"] = fmt.Sprintf("%d", station.TransmitFailed)
-	info["connected_time"] = fmt.Sprintf("%d", int(station.Connected.Seconds()))
-
-	retryRate := 0.0
-	if station.TransmittedPackets > 0 {
-		retryRate = float64(station.TransmitRetries) / float64(station.TransmittedPackets) * 100.0
-		if retryRate > 100.0 {
-			retryRate = 100.0
-		}
-	}
-	info["retry_rate"] = fmt.Sprintf("%.2f", retryRate)
-
 	return info, nil
 }
 
-func (s *WiFiScannerNL80211) GetStationStats(iface string) (map[string]string, error) {
-	if s.initErr != nil {
-		return map[string]string{}, s.initErr
-	}
-	return s.GetLinkInfo(iface)
+func (s *WiFiScannerNL80211) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
+	_, station, err := s.associatedStation(ctx, iface)
+	if err != nil || station == nil {
+		return StationInfo{}, err
+	}
+	return StationInfo{
+		SignalAvg: intPtr(station.SignalAverage),
+		TxBitrate: floatPtr(float64(station.TransmitBitrate) / 1e6),
+		RxBitrate: floatPtr(float64(station.ReceiveBitrate) / 1e6),
+		// TxBitrateInfo feeds parseBitrateInfo (wifi_utils.go) so the WiFi
+		// standard, channel width, and spatial-stream count surface in
+		// ClientStats. We synthesise the iw-style string from the typed
+		// RateInfo because the parser is shaped around that text format.
+		TxBitrateInfo: formatRateInfo(station.TransmitRateInfo),
+		TxBytes:       uint64Ptr(uint64(station.TransmittedBytes)),
+		RxBytes:       uint64Ptr(uint64(station.ReceivedBytes)),
+		TxPackets:     uint64Ptr(uint64(station.TransmittedPackets)),
+		RxPackets:     uint64Ptr(uint64(station.ReceivedPackets)),
+		TxRetries:     uint64Ptr(uint64(station.TransmitRetries)),
+		TxFailed:      uint64Ptr(uint64(station.TransmitFailed)),
+		ConnectedTime: intPtr(int(station.Connected.Seconds())),
+	}, nil
 }
 
 func (s *WiFiScannerNL80211) Close() error {
@@ -0,0 +1,127 @@
+//go:build linux
+
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/mdlayher/genetlink"
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// triggerScan is wifi.Client.Scan for a targeted scan, which the library
+// can't express: it sends NL80211_CMD_TRIGGER_SCAN with opts' SSIDs,
+// frequencies and dwell time and waits for the kernel to announce the
+// results. Errors come back the way the library's do (the kernel's EBUSY,
+// an i/o timeout at ctx's deadline), so the caller's cached-dump fallback
+// works the same for both.
+func triggerScan(ctx context.Context, ifindex int, opts ScanOptions) error {
+	conn, err := genetlink.Dial(nil)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	// Receive blocks; a past deadline is the only way to interrupt it.
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Now()) })
+	defer stop()
+
+	family, err := conn.GetFamily(unix.NL80211_GENL_NAME)
+	if err != nil {
+		return err
+	}
+	var group uint32
+	for _, g := range family.Groups {
+		if g.Name == unix.NL80211_MULTICAST_GROUP_SCAN {
+			group = g.ID
+		}
+	}
+	if group == 0 {
+		return errors.New("nl80211 scan multicast group unavailable")
+	}
+	// Join before triggering so a quick scan can't finish unheard.
+	if err := conn.JoinGroup(group); err != nil {
+		return err
+	}
+
+	data, err := encodeTriggerScan(ifindex, opts)
+	if err != nil {
+		return err
+	}
+	req := genetlink.Message{
+		Header: genetlink.Header{Command: unix.NL80211_CMD_TRIGGER_SCAN, Version: family.Version},
+		Data:   data,
+	}
+	if _, err := conn.Send(req, family.ID, netlink.Request|netlink.Acknowledge); err != nil {
+		return err
+	}
+
+	// The trigger's error, if any, arrives here ahead of any scan event.
+	for {
+		msgs, _, err := conn.Receive()
+		if err != nil {
+			return err
+		}
+		for _, m := range msgs {
+			if m.Header.Command != unix.NL80211_CMD_NEW_SCAN_RESULTS && m.Header.Command != unix.NL80211_CMD_SCAN_ABORTED {
+				continue
+			}
+			if scanEventIfindex(m.Data) != ifindex {
+				continue
+			}
+			if m.Header.Command == unix.NL80211_CMD_SCAN_ABORTED {
+				return errors.New("scan aborted by the kernel")
+			}
+			return nil
+		}
+	}
+}
+
+// encodeTriggerScan builds the NL80211_CMD_TRIGGER_SCAN attributes.
+func encodeTriggerScan(ifindex int, opts ScanOptions) ([]byte, error) {
+	ae := netlink.NewAttributeEncoder()
+	ae.Uint32(unix.NL80211_ATTR_IFINDEX, uint32(ifindex))
+	// Without an SSID list the scan is passive. The empty SSID is the
+	// wildcard probe of an ordinary active scan.
+	if !opts.Passive {
+		ssids := opts.SSIDs
+		if len(ssids) == 0 {
+			ssids = []string{""}
+		}
+		ae.Nested(unix.NL80211_ATTR_SCAN_SSIDS, func(nae *netlink.AttributeEncoder) error {
+			for i, ssid := range ssids {
+				nae.Bytes(uint16(i+1), []byte(ssid))
+			}
+			return nil
+		})
+	}
+	if freqs := opts.frequencies(); len(freqs) > 0 {
+		ae.Nested(unix.NL80211_ATTR_SCAN_FREQUENCIES, func(nae *netlink.AttributeEncoder) error {
+			for i, f := range freqs {
+				nae.Uint32(uint16(i+1), uint32(f))
+			}
+			return nil
+		})
+	}
+	if opts.DwellMs > 0 {
+		// In time units of 1.024 ms.
+		ae.Uint16(unix.NL80211_ATTR_MEASUREMENT_DURATION, uint16(opts.DwellMs*1000/1024))
+	}
+	return ae.Encode()
+}
+
+// scanEventIfindex returns the interface a scan event is for, or 0.
+func scanEventIfindex(data []byte) int {
+	ad, err := netlink.NewAttributeDecoder(data)
+	if err != nil {
+		return 0
+	}
+	for ad.Next() {
+		if ad.Type() == unix.NL80211_ATTR_IFINDEX {
+			return int(ad.Uint32())
+		}
+	}
+	return 0
+}
@@ -0,0 +1,86 @@
+//go:build linux
+
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mdlayher/netlink"
+	"github.com/mdlayher/netlink/nlenc"
+	"golang.org/x/sys/unix"
+)
+
+func TestEncodeTriggerScan(t *testing.T) {
+	decode := func(opts ScanOptions) map[uint16][]byte {
+		t.Helper()
+		data, err := encodeTriggerScan(3, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// The completion event carries the same interface attribute.
+		if scanEventIfindex(data) != 3 {
+			t.Error("scanEventIfindex didn't find the interface")
+		}
+		attrs, err := netlink.UnmarshalAttributes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		out := make(map[uint16][]byte)
+		for _, a := range attrs {
+			out[a.Type&^netlink.Nested] = a.Data
+		}
+		return out
+	}
+	nested := func(data []byte) []netlink.Attribute {
+		attrs, err := netlink.UnmarshalAttributes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return attrs
+	}
+
+	// A plain active scan probes with the wildcard SSID.
+	plain := decode(ScanOptions{})
+	if ssids := nested(plain[unix.NL80211_ATTR_SCAN_SSIDS]); len(ssids) != 1 || len(ssids[0].Data) != 0 {
+		t.Errorf("plain scan SSIDs = %+v", ssids)
+	}
+
+	targeted := decode(ScanOptions{SSIDs: []string{"Office", "Lab"}, Channels: []int{36}, Frequencies: []int{2412}, DwellMs: 100})
+	ssids := nested(targeted[unix.NL80211_ATTR_SCAN_SSIDS])
+	if len(ssids) != 2 || string(ssids[0].Data) != "Office" || string(ssids[1].Data) != "Lab" {
+		t.Errorf("SSIDs = %+v", ssids)
+	}
+	var freqs []int
+	for _, a := range nested(targeted[unix.NL80211_ATTR_SCAN_FREQUENCIES]) {
+		freqs = append(freqs, int(nlenc.Uint32(a.Data)))
+	}
+	if !slices.Equal(freqs, []int{2412, 5180}) {
+		t.Errorf("frequencies = %v", freqs)
+	}
+	if d := targeted[unix.NL80211_ATTR_MEASUREMENT_DURATION]; len(d) != 2 || nlenc.Uint16(d) != 97 {
+		t.Errorf("duration = % x, want 97 TU", d)
+	}
+
+	// Passive: no SSID list at all.
+	if _, ok := decode(ScanOptions{Passive: true, SSIDs: []string{"Office"}})[unix.NL80211_ATTR_SCAN_SSIDS]; ok {
+		t.Error("passive scan sent SSIDs")
+	}
+}
+
+func TestIwScanArgs(t *testing.T) {
+	cases := []struct {
+		opts ScanOptions
+		want []string
+	}{
+		{ScanOptions{}, []string{"dev", "wlan0", "scan"}},
+		{ScanOptions{Channels: []int{1}, DwellMs: 50, SSIDs: []string{"Office"}},
+			[]string{"dev", "wlan0", "scan", "freq", "2412", "duration", "48", "ssid", "Office"}},
+		{ScanOptions{Passive: true, SSIDs: []string{"Office"}}, []string{"dev", "wlan0", "scan", "passive"}},
+	}
+	for _, c := range cases {
+		if got := iwScanArgs("wlan0", c.opts); !slices.Equal(got, c.want) {
+			t.Errorf("iwScanArgs(%+v) = %q, want %q", c.opts, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,492 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Capture and replay. A capture is an NDJSON file holding every result the
+// platform backend returned — ScanNetworks, GetLinkInfo, GetStationStats and
+// GetInterfaces, plus its capabilities whenever they change — stamped with
+// the wall-clock time of the call. Replaying it feeds those exact results
+// back through WiFiService, so aggregation, issue detection, roaming
+// detection and the UI behave as they did on-site.
+//
+// Captures are written by wrapping the real backend with captureBackend
+// (WIFI_APP_CAPTURE=<path>) and read by replayBackend (WIFI_APP_REPLAY=<path>,
+// optionally WIFI_APP_REPLAY_SPEED=<factor>, or the CLI's -replay/-speed).
+//
+// Session recordings (session_store.go) are not captures: they store the
+// service's aggregated output, not the raw backend results, and can't be
+// fed back through the pipeline.
+const (
+	captureCallInterfaces = "interfaces"
+	captureCallScan       = "scan"
+	captureCallLink       = "link"
+	captureCallStation    = "station"
+	captureCallCaps       = "caps"
+)
+
+// captureRecord is one line of a capture file. Link and station results
+// are Link / Station, stamped with backendInfoVersion in Version; captures
+// written before the typed API have a version 1 map in Info instead.
+type captureRecord struct {
+	Timestamp time.Time            `json:"ts"`
+	Call      string               `json:"call"`
+	Interface string               `json:"iface,omitempty"`
+	Ifaces    []string             `json:"ifaces,omitempty"`
+	APs       []AccessPoint        `json:"aps,omitempty"`
+	Version   int                  `json:"v,omitempty"`
+	Link      *LinkInfo            `json:"link,omitempty"`
+	Station   *StationInfo         `json:"station,omitempty"`
+	Caps      *BackendCapabilities `json:"caps,omitempty"`
+	Info      map[string]string    `json:"info,omitempty"`
+	Err       string               `json:"err,omitempty"`
+}
+
+// backendClock is implemented by backends that run on their own timeline
+// rather than the wall clock (replay). WiFiService stamps scan results and
+// roaming events with Now() so durations come out as they were on-site
+// even when replaying at 10x.
+type backendClock interface {
+	Now() time.Time
+	// Paced reports whether ScanNetworks currently blocks until the next
+	// result is due. While it does, the scan loop skips its own interval
+	// sleep so the capture's cadence, not scan_interval, sets the pace.
+	Paced() bool
+}
+
+// errReplayFinished is returned by replayBackend.ScanNetworks once every
+// recorded scan for the interface has been played. The service treats it
+// as the end of the stream, not a scan error (see replayFinished).
+var errReplayFinished = errors.New("replay finished: no more recorded scans")
+
+// newBackendFromEnv picks the backend NewWiFiService uses: a replay when
+// WIFI_APP_REPLAY is set, a simulated environment when WIFI_APP_SIM names a
+// scenario file, otherwise the platform scanner. WIFI_APP_CAPTURE wraps
+// whichever was chosen with a capture writer.
+func newBackendFromEnv(cacheFile string) WiFiBackend {
+	var backend WiFiBackend
+	if path := os.Getenv("WIFI_APP_SIM"); path != "" {
+		sim, err := openSimBackend(path)
+		if err != nil {
+			slog.Error("simulator unavailable, using platform scanner", "event", "sim_error", "path", path, "err", err)
+		} else {
+			backend = sim
+		}
+	}
+	if path := os.Getenv("WIFI_APP_REPLAY"); path != "" && backend == nil {
+		speed := 1.0
+		if s := os.Getenv("WIFI_APP_REPLAY_SPEED"); s != "" {
+			if v, err := strconv.ParseFloat(s, 64); err == nil {
+				speed = v
+			} else {
+				slog.Warn("ignoring invalid WIFI_APP_REPLAY_SPEED", "value", s, "err", err)
+			}
+		}
+		replay, err := openReplayBackend(path, speed)
+		if err != nil {
+			slog.Error("replay unavailable, using platform scanner", "event", "replay_error", "path", path, "err", err)
+		} else {
+			backend = replay
+		}
+	}
+	if backend == nil {
+		backend = NewWiFiScanner(cacheFile)
+	}
+	if path := os.Getenv("WIFI_APP_CAPTURE"); path != "" {
+		capture, err := newCaptureBackend(backend, path)
+		if err != nil {
+			slog.Error("capture unavailable", "event", "capture_error", "path", path, "err", err)
+		} else {
+			backend = capture
+		}
+	}
+	return backend
+}
+
+// captureBackend decorates a WiFiBackend and appends every call's result to
+// a capture file. Write failures are logged once and never affect the
+// result handed back to the service.
+type captureBackend struct {
+	inner WiFiBackend
+
+	mu     sync.Mutex
+	file   *os.File
+	enc    *json.Encoder
+	failed bool
+	caps   map[string]BackendCapabilities // last written, by interface
+}
+
+func newCaptureBackend(inner WiFiBackend, path string) (*captureBackend, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open capture %s: %w", path, err)
+	}
+	_ = chownToSudoUser(path)
+	slog.Info("capturing backend results", "event", "capture_start", "path", path)
+	return &captureBackend{inner: inner, file: f, enc: json.NewEncoder(f)}, nil
+}
+
+func (c *captureBackend) write(rec captureRecord, err error) {
+	if err != nil {
+		rec.Err = err.Error()
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.file == nil {
+		return
+	}
+	if werr := c.enc.Encode(rec); werr != nil {
+		if !c.failed {
+			slog.Warn("capture write failed", "event", "capture_write_error", "err", werr)
+		}
+		c.failed = true
+		return
+	}
+	c.failed = false
+}
+
+func (c *captureBackend) GetInterfaces(ctx context.Context) ([]string, error) {
+	ifaces, err := c.inner.GetInterfaces(ctx)
+	c.write(captureRecord{Timestamp: time.Now(), Call: captureCallInterfaces, Ifaces: ifaces}, err)
+	return ifaces, err
+}
+
+func (c *captureBackend) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
+	aps, err := c.inner.ScanNetworks(ctx, iface, opts)
+	if ctx.Err() != nil {
+		// An aborted call says nothing about the radio; replaying it
+		// would inject an error the site never saw.
+		return aps, err
+	}
+	c.write(captureRecord{Timestamp: time.Now(), Call: captureCallScan, Interface: iface, APs: aps}, err)
+	// Scans are where backends discover what the driver supports.
+	c.Capabilities(iface)
+	return aps, err
+}
+
+func (c *captureBackend) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	info, err := c.inner.GetLinkInfo(ctx, iface)
+	if ctx.Err() != nil {
+		return info, err
+	}
+	c.write(captureRecord{Timestamp: time.Now(), Call: captureCallLink, Interface: iface,
+		Version: backendInfoVersion, Link: &info}, err)
+	return info, err
+}
+
+func (c *captureBackend) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
+	info, err := c.inner.GetStationStats(ctx, iface)
+	if ctx.Err() != nil {
+		return info, err
+	}
+	c.write(captureRecord{Timestamp: time.Now(), Call: captureCallStation, Interface: iface,
+		Version: backendInfoVersion, Station: &info}, err)
+	return info, err
+}
+
+// Capabilities records the inner backend's capabilities when they differ
+// from the last ones written for iface.
+func (c *captureBackend) Capabilities(iface string) BackendCapabilities {
+	caps := c.inner.Capabilities(iface)
+	c.mu.Lock()
+	last, ok := c.caps[iface]
+	changed := !ok || !reflect.DeepEqual(last, caps)
+	if changed {
+		if c.caps == nil {
+			c.caps = make(map[string]BackendCapabilities)
+		}
+		c.caps[iface] = caps
+	}
+	c.mu.Unlock()
+	if changed {
+		c.write(captureRecord{Timestamp: time.Now(), Call: captureCallCaps, Interface: iface, Caps: &caps}, nil)
+	}
+	return caps
+}
+
+func (c *captureBackend) Close() error {
+	c.mu.Lock()
+	if c.file != nil {
+		if err := c.file.Close(); err != nil {
+			slog.Warn("capture close failed", "err", err)
+		}
+		c.file = nil
+	}
+	c.mu.Unlock()
+	return c.inner.Close()
+}
+
+// replayBackend serves a capture file back through the WiFiBackend
+// interface. Each ScanNetworks call returns the next recorded scan for the
+// interface, first waiting until the replay clock reaches its timestamp;
+// link and station lookups return the record captured alongside that scan.
+//
+// The replay clock sits at the first record's timestamp until the first
+// ScanNetworks call, then runs at speed x real time. speed <= 0 disables waiting
+// entirely: scans are returned back-to-back and the clock jumps to each
+// record's timestamp, which is what tests want.
+type replayBackend struct {
+	speed      float64
+	ifaces     []string
+	byIface    map[string][]captureRecord
+	startVirt  time.Time
+	done       chan struct{}
+	closeOnce  sync.Once
+	finishOnce sync.Once
+
+	mu        sync.Mutex
+	startReal time.Time      // wall time of the first ScanNetworks call
+	pos       map[string]int // index of the last returned scan record, per interface
+	cursor    time.Time      // timestamp of the most recently returned scan
+	ended     bool
+}
+
+// openReplayBackend loads a capture file into memory. Lines that fail to
+// parse (a torn tail from a crashed capture) are skipped.
+func openReplayBackend(path string, speed float64) (*replayBackend, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open replay %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var records []captureRecord
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 0, 256*1024), 16*1024*1024)
+	for sc.Scan() {
+		var rec captureRecord
+		if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+			continue
+		}
+		records = append(records, rec)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("read replay %s: %w", path, err)
+	}
+	r := newReplayBackend(records, speed)
+	if len(r.ifaces) == 0 {
+		return nil, fmt.Errorf("replay %s contains no scan records", path)
+	}
+	slog.Info("replaying capture", "event", "replay_start", "path", path,
+		"records", len(records), "interfaces", r.ifaces, "speed", speed)
+	return r, nil
+}
+
+func newReplayBackend(records []captureRecord, speed float64) *replayBackend {
+	r := &replayBackend{
+		speed:   speed,
+		byIface: make(map[string][]captureRecord),
+		pos:     make(map[string]int),
+		done:    make(chan struct{}),
+	}
+	scanned := make(map[string]bool)
+	for _, rec := range records {
+		if rec.Call == captureCallInterfaces {
+			continue
+		}
+		if r.startVirt.IsZero() || rec.Timestamp.Before(r.startVirt) {
+			r.startVirt = rec.Timestamp
+		}
+		if rec.Call == captureCallScan && !scanned[rec.Interface] {
+			scanned[rec.Interface] = true
+			r.ifaces = append(r.ifaces, rec.Interface)
+		}
+		r.byIface[rec.Interface] = append(r.byIface[rec.Interface], rec)
+	}
+	for iface := range r.byIface {
+		r.pos[iface] = -1
+	}
+	r.cursor = r.startVirt
+	return r
+}
+
+// resolve maps a requested interface onto a captured one. A capture taken
+// on wlan0 replays fine on a machine whose config says wlp2s0.
+func (r *replayBackend) resolve(iface string) string {
+	if _, ok := r.byIface[iface]; ok {
+		return iface
+	}
+	if len(r.ifaces) > 0 {
+		return r.ifaces[0]
+	}
+	return iface
+}
+
+func (r *replayBackend) Now() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.nowLocked()
+}
+
+func (r *replayBackend) nowLocked() time.Time {
+	if r.speed <= 0 || r.startReal.IsZero() {
+		return r.cursor
+	}
+	elapsed := time.Since(r.startReal)
+	return r.startVirt.Add(time.Duration(float64(elapsed) * r.speed))
+}
+
+func (r *replayBackend) Paced() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.ended
+}
+
+func (r *replayBackend) GetInterfaces(ctx context.Context) ([]string, error) {
+	return append([]string(nil), r.ifaces...), nil
+}
+
+// ScanNetworks returns what was recorded whatever opts asks for; the
+// service's filter still narrows it.
+func (r *replayBackend) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
+	iface = r.resolve(iface)
+
+	r.mu.Lock()
+	if r.startReal.IsZero() {
+		r.startReal = time.Now()
+	}
+	records := r.byIface[iface]
+	next := -1
+	for i := r.pos[iface] + 1; i < len(records); i++ {
+		if records[i].Call == captureCallScan {
+			next = i
+			break
+		}
+	}
+	if next < 0 {
+		r.ended = true
+		r.mu.Unlock()
+		r.finishOnce.Do(func() {
+			slog.Info("replay finished", "event", "replay_end", "interface", iface)
+		})
+		return nil, errReplayFinished
+	}
+	rec := records[next]
+	wait := rec.Timestamp.Sub(r.nowLocked())
+	r.mu.Unlock()
+
+	if r.speed > 0 && wait > 0 {
+		timer := time.NewTimer(time.Duration(float64(wait) / r.speed))
+		select {
+		case <-timer.C:
+		case <-r.done:
+			timer.Stop()
+			return nil, errors.New("replay closed")
+		case <-ctx.Done():
+			// The record stays unplayed for the next scan.
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+
+	r.mu.Lock()
+	r.pos[iface] = next
+	if rec.Timestamp.After(r.cursor) {
+		r.cursor = rec.Timestamp
+	}
+	r.mu.Unlock()
+
+	if rec.Err != "" {
+		return nil, errors.New(rec.Err)
+	}
+	return append([]AccessPoint(nil), rec.APs...), nil
+}
+
+// lookup returns the call record captured in the same tick as the most
+// recent scan — the first one after it and before the following scan —
+// falling back to the latest earlier one if that tick didn't make the call.
+func (r *replayBackend) lookup(iface, call string) (captureRecord, bool) {
+	iface = r.resolve(iface)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records := r.byIface[iface]
+	pos := r.pos[iface]
+
+	for i := pos + 1; i < len(records) && records[i].Call != captureCallScan; i++ {
+		if records[i].Call == call {
+			return records[i], true
+		}
+	}
+	for i := pos; i >= 0; i-- {
+		if records[i].Call == call {
+			return records[i], true
+		}
+	}
+	return captureRecord{}, false
+}
+
+func (r *replayBackend) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	rec, ok := r.lookup(iface, captureCallLink)
+	if !ok {
+		return LinkInfo{}, nil
+	}
+	var info LinkInfo
+	if rec.Link != nil {
+		info = *rec.Link
+	} else {
+		info = linkInfoFromMap(rec.Info) // version 1 capture
+	}
+	if rec.Err != "" {
+		return info, errors.New(rec.Err)
+	}
+	return info, nil
+}
+
+func (r *replayBackend) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
+	rec, ok := r.lookup(iface, captureCallStation)
+	if !ok {
+		return StationInfo{}, nil
+	}
+	var info StationInfo
+	if rec.Station != nil {
+		info = *rec.Station
+	} else {
+		info = stationInfoFromMap(rec.Info) // version 1 capture
+	}
+	if rec.Err != "" {
+		return info, errors.New(rec.Err)
+	}
+	return info, nil
+}
+
+// Capabilities are the captured backend's as of the current tick, so
+// replayed fields are exactly as trustworthy as they were on-site.
+// Captures without them report nothing as supported.
+func (r *replayBackend) Capabilities(iface string) BackendCapabilities {
+	rec, ok := r.lookup(iface, captureCallCaps)
+	if !ok {
+		rec, ok = r.first(iface, captureCallCaps)
+	}
+	if !ok || rec.Caps == nil {
+		return BackendCapabilities{Interface: iface, Backend: "replay", Bands: []string{}}
+	}
+	caps := *rec.Caps
+	caps.Interface = iface
+	return caps
+}
+
+// first returns the interface's earliest record of call.
+func (r *replayBackend) first(iface, call string) (captureRecord, bool) {
+	for _, rec := range r.byIface[r.resolve(iface)] {
+		if rec.Call == call {
+			return rec, true
+		}
+	}
+	return captureRecord{}, false
+}
+
+func (r *replayBackend) Close() error {
+	r.closeOnce.Do(func() { close(r.done) })
+	return nil
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// scriptedBackend returns one canned tick per ScanNetworks call. Used to
+// produce a capture without touching real hardware.
+type scriptedBackend struct {
+	ticks []scriptedTick
+	i     int
+}
+
+type scriptedTick struct {
+	aps   []AccessPoint
+	bssid string
+}
+
+func (b *scriptedBackend) GetInterfaces(ctx context.Context) ([]string, error) {
+	return []string{"wlan0"}, nil
+}
+
+func (b *scriptedBackend) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
+	if b.i >= len(b.ticks) {
+		return nil, fmt.Errorf("script exhausted")
+	}
+	b.i++
+	return b.ticks[b.i-1].aps, nil
+}
+
+func (b *scriptedBackend) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	t := b.ticks[b.i-1]
+	return LinkInfo{Connected: true, SSID: "Office", BSSID: t.bssid, Frequency: floatPtr(5180), Signal: intPtr(-55)}, nil
+}
+
+func (b *scriptedBackend) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
+	return StationInfo{SignalAvg: intPtr(-56)}, nil
+}
+
+func (b *scriptedBackend) Capabilities(iface string) BackendCapabilities {
+	return BackendCapabilities{Interface: iface, Backend: "scripted", Noise: true, Bands: []string{"5GHz"}}
+}
+
+func (b *scriptedBackend) Close() error { return nil }
+
+func TestCaptureReplayRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "capture.ndjson")
+	inner := &scriptedBackend{ticks: []scriptedTick{
+		{aps: []AccessPoint{{BSSID: "aa:bb:cc:00:00:01", SSID: "Office", Signal: -55}}, bssid: "aa:bb:cc:00:00:01"},
+		{aps: nil, bssid: "aa:bb:cc:00:00:02"},
+	}}
+	ctx := context.Background()
+	capture, err := newCaptureBackend(inner, path)
+	if err != nil {
+		t.Fatalf("newCaptureBackend: %v", err)
+	}
+	for range inner.ticks {
+		if _, err := capture.ScanNetworks(ctx, "wlan0", ScanOptions{}); err != nil {
+			t.Fatalf("ScanNetworks: %v", err)
+		}
+		_, _ = capture.GetLinkInfo(ctx, "wlan0")
+		_, _ = capture.GetStationStats(ctx, "wlan0")
+	}
+	if err := capture.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), `"v":2,`) {
+		t.Errorf("link/station records not versioned:\n%s", data)
+	}
+	// Unchanged capabilities are written once, not every scan.
+	if n := strings.Count(string(data), `"call":"caps"`); n != 1 {
+		t.Errorf("%d capability records, want 1", n)
+	}
+
+	replay, err := openReplayBackend(path, 0)
+	if err != nil {
+		t.Fatalf("openReplayBackend: %v", err)
+	}
+	// A capture from wlan0 must replay on whatever interface is asked for.
+	aps, err := replay.ScanNetworks(ctx, "wlp2s0", ScanOptions{})
+	if err != nil || len(aps) != 1 || aps[0].BSSID != "aa:bb:cc:00:00:01" {
+		t.Fatalf("first replayed scan = %+v, %v", aps, err)
+	}
+	link, _ := replay.GetLinkInfo(ctx, "wlp2s0")
+	if link.BSSID != "aa:bb:cc:00:00:01" || link.Frequency == nil || *link.Frequency != 5180 || link.Noise != nil {
+		t.Errorf("first link = %+v", link)
+	}
+	if station, _ := replay.GetStationStats(ctx, "wlp2s0"); station.SignalAvg == nil || *station.SignalAvg != -56 || station.TxBytes != nil {
+		t.Errorf("first station = %+v", station)
+	}
+	if _, err := replay.ScanNetworks(ctx, "wlp2s0", ScanOptions{}); err != nil {
+		t.Fatalf("second replayed scan: %v", err)
+	}
+	if link, _ := replay.GetLinkInfo(ctx, "wlp2s0"); link.BSSID != "aa:bb:cc:00:00:02" {
+		t.Errorf("second link bssid = %q", link.BSSID)
+	}
+	if _, err := replay.ScanNetworks(ctx, "wlp2s0", ScanOptions{}); err != errReplayFinished {
+		t.Errorf("third scan err = %v, want errReplayFinished", err)
+	}
+	if caps := replay.Capabilities("wlp2s0"); caps.Interface != "wlp2s0" || caps.Backend != "scripted" || !caps.Noise {
+		t.Errorf("replayed capabilities = %+v", caps)
+	}
+	if replay.Paced() {
+		t.Error("Paced() = true after the capture ran out")
+	}
+}
+
+func TestReplayScanCancelled(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t0 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	records := []captureRecord{
+		{Timestamp: t0, Call: captureCallScan, Interface: "wlan0"},
+		{Timestamp: t0.Add(time.Hour), Call: captureCallScan, Interface: "wlan0"},
+	}
+	ws := newWiFiServiceWithBackend(newReplayBackend(records, 1))
+	defer ws.Close()
+	var errs []ScanErrorEvent
+	SubscribeTo(ws.bus, func(e ScanErrorEvent) { errs = append(errs, e) })
+
+	ws.performScan(context.Background(), "wlan0")
+	// The second record is an hour away in real time; stopping scanning
+	// must not wait for it, and isn't a scan error.
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	ws.performScan(ctx, "wlan0")
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("cancelled scan took %s", elapsed)
+	}
+	if len(errs) != 0 {
+		t.Errorf("scan errors = %+v", errs)
+	}
+}
+
+func TestReplayFinishedStopsScanning(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+	t0 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	records := []captureRecord{{Timestamp: t0, Call: captureCallScan, Interface: "wlan0"}}
+	ws := newWiFiServiceWithBackend(newReplayBackend(records, 0))
+	defer ws.Close()
+	var errs []ScanErrorEvent
+	var finished []ReplayFinishedEvent
+	SubscribeTo(ws.bus, func(e ScanErrorEvent) { errs = append(errs, e) })
+	done := make(chan struct{})
+	SubscribeTo(ws.bus, func(e ReplayFinishedEvent) {
+		finished = append(finished, e)
+		close(done)
+	})
+
+	if err := ws.StartScanning("wlan0"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("no replay:finished event")
+	}
+	// The end of a replay is neither an error nor repeated every tick.
+	ws.performScan(context.Background(), "wlan0")
+	if len(errs) != 0 || len(finished) != 1 || finished[0].Interface != "wlan0" {
+		t.Errorf("errors %+v, finished %+v", errs, finished)
+	}
+	if ws.IsScanning() {
+		t.Error("still scanning after the replay finished")
+	}
+}
+
+func TestReplayDrivesRoamingWithRecordedTimestamps(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	t0 := time.Date(2024, 5, 1, 9, 0, 0, 0, time.UTC)
+	// Version 1 link records, as captures made before the typed API hold.
+	link := func(ts time.Time, bssid string) captureRecord {
+		return captureRecord{Timestamp: ts, Call: captureCallLink, Interface: "wlan0",
+			Info: map[string]string{"connected": "true", "ssid": "Office", "bssid": bssid, "signal": "-60"}}
+	}
+	scan := func(ts time.Time) captureRecord {
+		return captureRecord{Timestamp: ts, Call: captureCallScan, Interface: "wlan0",
+			APs: []AccessPoint{{BSSID: "aa:bb:cc:00:00:01", SSID: "Office", Signal: -60, Frequency: 5180, Channel: 36}}}
+	}
+	records := []captureRecord{
+		scan(t0), link(t0.Add(100*time.Millisecond), "aa:bb:cc:00:00:01"),
+		scan(t0.Add(4 * time.Second)), link(t0.Add(4100*time.Millisecond), "aa:bb:cc:00:00:01"),
+		scan(t0.Add(9 * time.Second)), link(t0.Add(9100*time.Millisecond), "aa:bb:cc:00:00:02"),
+	}
+
+	ws := newWiFiServiceWithBackend(newReplayBackend(records, 0))
+	defer ws.Close()
+	for range 3 {
+		ws.performScan(context.Background(), "wlan0")
+	}
+
+	report := ws.GetClientStats()
+	if len(report.RoamingHistory) != 1 {
+		t.Fatalf("RoamingHistory = %+v, want one roam", report.RoamingHistory)
+	}
+	roam := report.RoamingHistory[0]
+	if !roam.Timestamp.Equal(t0.Add(9 * time.Second)) {
+		t.Errorf("roam Timestamp = %v, want the recorded scan time", roam.Timestamp)
+	}
+	if roam.DurationMs != 5000 {
+		t.Errorf("roam DurationMs = %d, want 5000 (recorded gap, not replay wall time)", roam.DurationMs)
+	}
+}
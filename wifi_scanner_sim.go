@@ -0,0 +1,455 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// simScenario is a synthetic RF environment: access points at fixed
+// positions and a client walking a path between waypoints. Loaded from TOML
+// (see testdata/sim/) and served through simBackend so the whole pipeline —
+// aggregation, issue detection, roaming detection, roaming analysis — can be
+// exercised on a machine with no radio.
+//
+// Distances are metres, powers dBm. Every scan of the first radio advances
+// the scenario by one tick of TickSeconds of virtual time.
+type simScenario struct {
+	Name             string     `toml:"name"`
+	Seed             uint64     `toml:"seed"`
+	Start            time.Time  `toml:"start"`
+	TickSeconds      float64    `toml:"tick_seconds"`
+	Interface        string     `toml:"interface"`
+	NoiseFloorDbm    int        `toml:"noise_floor_dbm"`
+	NoiseStddevDb    float64    `toml:"noise_stddev_db"`    // log-normal shadowing, applied per AP per tick
+	PathLossExponent float64    `toml:"path_loss_exponent"` // 2 = free space, ~3 = typical office
+	SensitivityDbm   int        `toml:"sensitivity_dbm"`    // APs weaker than this are not reported
+	Radios           []simRadio `toml:"radio"`              // optional; defaults to one radio named Interface
+	APs              []simAP    `toml:"ap"`
+	Client           simClient  `toml:"client"`
+}
+
+// simRadio is one adapter on the simulated rig. The first radio is the one
+// the client associates through and the only one whose scans advance the
+// scenario; the others report the same tick's signals, restricted to Band
+// (e.g. "5GHz"; empty hears everything) and offset by GainDb.
+type simRadio struct {
+	Name   string `toml:"name"`
+	Band   string `toml:"band"`
+	GainDb int    `toml:"gain_db"`
+}
+
+type simAP struct {
+	BSSID        string   `toml:"bssid"`
+	SSID         string   `toml:"ssid"`
+	X            float64  `toml:"x"`
+	Y            float64  `toml:"y"`
+	Channel      int      `toml:"channel"`
+	Frequency    int      `toml:"frequency"` // optional; derived from channel when 0
+	ChannelWidth int      `toml:"channel_width"`
+	TxPowerDbm   int      `toml:"tx_power_dbm"`
+	Security     string   `toml:"security"`
+	Capabilities []string `toml:"capabilities"`
+	Stations     *int     `toml:"stations"`
+}
+
+// simClient describes the station being simulated. An empty SSID leaves it
+// disconnected. The roaming policy is the simple one most drivers
+// approximate: stay put until the current AP drops below RoamThresholdDbm,
+// then move to the strongest same-SSID AP if it beats the current one by
+// RoamHysteresisDb. Setting the threshold very low models a sticky client.
+type simClient struct {
+	SSID             string        `toml:"ssid"`
+	RoamThresholdDbm int           `toml:"roam_threshold_dbm"`
+	RoamHysteresisDb int           `toml:"roam_hysteresis_db"`
+	Waypoints        []simWaypoint `toml:"waypoint"`
+}
+
+type simWaypoint struct {
+	Tick int     `toml:"tick"`
+	X    float64 `toml:"x"`
+	Y    float64 `toml:"y"`
+}
+
+// loadSimScenario reads and defaults a scenario file.
+func loadSimScenario(path string) (*simScenario, error) {
+	var sc simScenario
+	if _, err := toml.DecodeFile(path, &sc); err != nil {
+		return nil, fmt.Errorf("parse scenario %s: %w", path, err)
+	}
+	if err := sc.normalize(); err != nil {
+		return nil, fmt.Errorf("scenario %s: %w", path, err)
+	}
+	return &sc, nil
+}
+
+func (sc *simScenario) normalize() error {
+	if len(sc.APs) == 0 {
+		return fmt.Errorf("no [[ap]] entries")
+	}
+	if sc.Start.IsZero() {
+		sc.Start = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	if sc.TickSeconds <= 0 {
+		sc.TickSeconds = 4
+	}
+	if sc.Interface == "" {
+		sc.Interface = "sim0"
+	}
+	if len(sc.Radios) == 0 {
+		sc.Radios = []simRadio{{Name: sc.Interface}}
+	}
+	for i, r := range sc.Radios {
+		if r.Name == "" {
+			return fmt.Errorf("radio %d has no name", i)
+		}
+	}
+	sc.Interface = sc.Radios[0].Name
+	if sc.NoiseFloorDbm == 0 {
+		sc.NoiseFloorDbm = -95
+	}
+	if sc.PathLossExponent <= 0 {
+		sc.PathLossExponent = 3
+	}
+	if sc.SensitivityDbm == 0 {
+		sc.SensitivityDbm = -92
+	}
+	if sc.Client.RoamThresholdDbm == 0 {
+		sc.Client.RoamThresholdDbm = -70
+	}
+	if sc.Client.RoamHysteresisDb == 0 {
+		sc.Client.RoamHysteresisDb = 6
+	}
+	for i := range sc.APs {
+		ap := &sc.APs[i]
+		if ap.BSSID == "" {
+			return fmt.Errorf("ap %d has no bssid", i)
+		}
+		if ap.Frequency == 0 {
+			ap.Frequency = channelToFrequency(ap.Channel)
+		}
+		if ap.Frequency == 0 {
+			return fmt.Errorf("ap %s: channel %d has no known frequency", ap.BSSID, ap.Channel)
+		}
+		if ap.Channel == 0 {
+			ap.Channel = frequencyToChannel(ap.Frequency)
+		}
+		if ap.ChannelWidth == 0 {
+			ap.ChannelWidth = 20
+		}
+		if ap.TxPowerDbm == 0 {
+			ap.TxPowerDbm = 20
+		}
+		if ap.Security == "" {
+			ap.Security = "WPA2"
+		}
+	}
+	return nil
+}
+
+// position returns the client's location at tick, linearly interpolated
+// between waypoints and clamped to the first/last one.
+func (c simClient) position(tick int) (float64, float64) {
+	wps := c.Waypoints
+	if len(wps) == 0 {
+		return 0, 0
+	}
+	if tick <= wps[0].Tick {
+		return wps[0].X, wps[0].Y
+	}
+	for i := 1; i < len(wps); i++ {
+		a, b := wps[i-1], wps[i]
+		if tick <= b.Tick {
+			f := float64(tick-a.Tick) / float64(max1(b.Tick-a.Tick))
+			return a.X + (b.X-a.X)*f, a.Y + (b.Y-a.Y)*f
+		}
+	}
+	last := wps[len(wps)-1]
+	return last.X, last.Y
+}
+
+// rssi is a log-distance path-loss model anchored on free-space loss at
+// 1 m for the AP's frequency, plus Gaussian shadowing.
+func (sc *simScenario) rssi(ap simAP, x, y float64, rng *rand.Rand) int {
+	d := math.Max(math.Hypot(ap.X-x, ap.Y-y), 1)
+	fspl1m := 20*math.Log10(float64(ap.Frequency)) - 27.55
+	loss := fspl1m + 10*sc.PathLossExponent*math.Log10(d)
+	signal := float64(ap.TxPowerDbm) - loss
+	if sc.NoiseStddevDb > 0 {
+		signal += rng.NormFloat64() * sc.NoiseStddevDb
+	}
+	return int(math.Round(math.Max(signal, -100)))
+}
+
+// simBackend serves a simScenario through WiFiBackend. It implements
+// backendClock so the service timestamps everything on the scenario's
+// virtual timeline; runs are fully deterministic for a given seed.
+type simBackend struct {
+	sc *simScenario
+
+	mu        sync.Mutex
+	rng       *rand.Rand
+	tick      int // ticks completed; the next scan computes tick
+	signals   map[string]int
+	assoc     string // associated BSSID, "" when disconnected
+	assocTick int
+	txPackets uint64
+	rxPackets uint64
+}
+
+func newSimBackend(sc *simScenario) *simBackend {
+	return &simBackend{
+		sc:      sc,
+		rng:     rand.New(rand.NewPCG(sc.Seed, sc.Seed^0x9e3779b97f4a7c15)),
+		signals: make(map[string]int),
+	}
+}
+
+// openSimBackend loads a scenario file and wraps it in a backend.
+func openSimBackend(path string) (*simBackend, error) {
+	sc, err := loadSimScenario(path)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("simulating WiFi environment", "event", "sim_start", "scenario", sc.Name,
+		"path", path, "aps", len(sc.APs), "seed", sc.Seed)
+	return newSimBackend(sc), nil
+}
+
+func (s *simBackend) Now() time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.nowLocked()
+}
+
+func (s *simBackend) nowLocked() time.Time {
+	// The current tick is the last one computed, tick-1.
+	t := max(s.tick-1, 0)
+	return s.sc.Start.Add(time.Duration(float64(t) * s.sc.TickSeconds * float64(time.Second)))
+}
+
+// Paced is false: the simulator has no cadence of its own, so the scan loop
+// keeps its normal interval when it drives one interactively.
+func (s *simBackend) Paced() bool { return false }
+
+func (s *simBackend) GetInterfaces(ctx context.Context) ([]string, error) {
+	names := make([]string, len(s.sc.Radios))
+	for i, r := range s.sc.Radios {
+		names[i] = r.Name
+	}
+	return names, nil
+}
+
+func (s *simBackend) radio(iface string) (simRadio, error) {
+	for _, r := range s.sc.Radios {
+		if r.Name == iface {
+			return r, nil
+		}
+	}
+	return simRadio{}, fmt.Errorf("sim: no radio %q", iface)
+}
+
+// ScanNetworks doesn't advance the scenario once ctx has ended. Simulated
+// radios send no probes, so every scan is as good as passive; opts' SSIDs
+// and frequencies are left to the service's filter.
+func (s *simBackend) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
+	radio, err := s.radio(iface)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if iface == s.sc.Interface {
+		tick := s.tick
+		s.tick++
+		x, y := s.sc.Client.position(tick)
+		for _, sap := range s.sc.APs {
+			s.signals[sap.BSSID] = s.sc.rssi(sap, x, y, s.rng)
+		}
+		s.updateAssociationLocked(tick)
+	}
+	return s.reportLocked(radio), nil
+}
+
+// reportLocked renders the current tick's signals as radio would hear them.
+func (s *simBackend) reportLocked(radio simRadio) []AccessPoint {
+	now := s.nowLocked()
+	aps := make([]AccessPoint, 0, len(s.sc.APs))
+	for _, sap := range s.sc.APs {
+		raw, ok := s.signals[sap.BSSID]
+		if !ok {
+			continue // secondary radio scanned before the primary's first tick
+		}
+		band := frequencyToBand(sap.Frequency)
+		if radio.Band != "" && band != radio.Band {
+			continue
+		}
+		signal := raw + radio.GainDb
+		if signal < s.sc.SensitivityDbm {
+			continue
+		}
+		ap := AccessPoint{
+			BSSID:           sap.BSSID,
+			SSID:            sap.SSID,
+			Frequency:       sap.Frequency,
+			Channel:         sap.Channel,
+			ChannelWidth:    sap.ChannelWidth,
+			Band:            band,
+			Signal:          signal,
+			Noise:           s.sc.NoiseFloorDbm,
+			SNR:             signal - s.sc.NoiseFloorDbm,
+			TxPower:         sap.TxPowerDbm,
+			Security:        sap.Security,
+			Capabilities:    append([]string(nil), sap.Capabilities...),
+			BSSLoadStations: sap.Stations,
+			LastSeen:        now,
+			BeaconInt:       100,
+		}
+		aps = append(aps, ap)
+	}
+	return aps
+}
+
+// updateAssociationLocked applies the client's roaming policy to this
+// tick's signals.
+func (s *simBackend) updateAssociationLocked(tick int) {
+	ssid := s.sc.Client.SSID
+	if ssid == "" {
+		s.assoc = ""
+		return
+	}
+	best, bestSignal := "", math.MinInt
+	for _, ap := range s.sc.APs {
+		if ap.SSID != ssid {
+			continue
+		}
+		if sig := s.signals[ap.BSSID]; sig >= s.sc.SensitivityDbm && sig > bestSignal {
+			best, bestSignal = ap.BSSID, sig
+		}
+	}
+
+	cur, ok := s.signals[s.assoc]
+	switch {
+	case best == "":
+		s.assoc = ""
+	case s.assoc == "" || !ok || cur < s.sc.SensitivityDbm:
+		s.associateLocked(best, tick)
+	case cur < s.sc.Client.RoamThresholdDbm && bestSignal-cur >= s.sc.Client.RoamHysteresisDb:
+		s.associateLocked(best, tick)
+	}
+	if s.assoc != "" {
+		// Rough traffic model so rate/counter fields aren't all zero.
+		s.txPackets += 1000
+		s.rxPackets += 1500
+	}
+}
+
+func (s *simBackend) associateLocked(bssid string, tick int) {
+	if s.assoc != bssid {
+		s.assoc = bssid
+		s.assocTick = tick
+	}
+}
+
+func (s *simBackend) associatedAP() (simAP, bool) {
+	for _, ap := range s.sc.APs {
+		if ap.BSSID == s.assoc {
+			return ap, true
+		}
+	}
+	return simAP{}, false
+}
+
+// Only the first radio is ever associated; the others are scan-only.
+func (s *simBackend) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	if _, err := s.radio(iface); err != nil {
+		return LinkInfo{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ap, ok := s.associatedAP()
+	if !ok || iface != s.sc.Interface {
+		return LinkInfo{}, nil
+	}
+	return LinkInfo{
+		Connected:    true,
+		SSID:         ap.SSID,
+		BSSID:        ap.BSSID,
+		Frequency:    floatPtr(float64(ap.Frequency)),
+		Channel:      intPtr(ap.Channel),
+		ChannelWidth: intPtr(ap.ChannelWidth),
+		Noise:        intPtr(s.sc.NoiseFloorDbm),
+		Signal:       intPtr(s.signals[ap.BSSID]),
+	}, nil
+}
+
+func (s *simBackend) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
+	if _, err := s.radio(iface); err != nil {
+		return StationInfo{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ap, ok := s.associatedAP()
+	if !ok || iface != s.sc.Interface {
+		return StationInfo{}, nil
+	}
+	signal := s.signals[ap.BSSID]
+	snr := signal - s.sc.NoiseFloorDbm
+	// Map SNR onto a plausible single-stream MCS so bitrate tracks signal.
+	mcs := max(0, min(9, (snr-5)/4))
+	rate := math.Round(float64(ap.ChannelWidth)/20*(6.5+float64(mcs)*8.7)*10) / 10
+	connected := float64(s.tick-1-s.assocTick) * s.sc.TickSeconds
+	return StationInfo{
+		SignalAvg:     intPtr(signal),
+		SNR:           intPtr(snr),
+		TxBitrate:     floatPtr(rate),
+		RxBitrate:     floatPtr(rate),
+		TxBitrateInfo: fmt.Sprintf("%.1f MBit/s %dMHz VHT-MCS %d VHT-NSS 1", rate, ap.ChannelWidth, mcs),
+		TxPackets:     uint64Ptr(s.txPackets),
+		RxPackets:     uint64Ptr(s.rxPackets),
+		TxBytes:       uint64Ptr(s.txPackets * 1200),
+		RxBytes:       uint64Ptr(s.rxPackets * 1200),
+		TxRetries:     uint64Ptr(0),
+		TxFailed:      uint64Ptr(0),
+		ConnectedTime: intPtr(int(connected)),
+	}, nil
+}
+
+// Capabilities: the scenario models IE-derived fields and a noise floor
+// but not channel surveys. Bands are those the radio hears APs on.
+func (s *simBackend) Capabilities(iface string) BackendCapabilities {
+	caps := BackendCapabilities{
+		Interface:           iface,
+		Backend:             "sim",
+		InformationElements: true,
+		Noise:               true,
+		StationRetries:      true,
+		ActiveScan:          true,
+		PassiveScan:         true,
+		Bands:               []string{},
+	}
+	radio, err := s.radio(iface)
+	if err != nil {
+		return caps
+	}
+	var freqs []int
+	for _, sap := range s.sc.APs {
+		if radio.Band == "" || frequencyToBand(sap.Frequency) == radio.Band {
+			freqs = append(freqs, sap.Frequency)
+		}
+	}
+	caps.Bands = bandsFromFrequencies(freqs)
+	return caps
+}
+
+func (s *simBackend) Close() error { return nil }
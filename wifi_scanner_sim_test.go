@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runSimScenario drives ticks scan iterations of a testdata/sim scenario
+// through a real WiFiService and returns it for inspection. Config is
+// isolated to a temp dir so a developer's own config.toml can't leak in.
+func runSimScenario(t *testing.T, name string, ticks int) *WiFiService {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	sim, err := openSimBackend(filepath.Join("testdata", "sim", name))
+	if err != nil {
+		t.Fatalf("openSimBackend(%s): %v", name, err)
+	}
+	ws := newWiFiServiceWithBackend(sim)
+	t.Cleanup(func() { _ = ws.Close() })
+	for range ticks {
+		ws.performScan(context.Background(), sim.sc.Interface)
+	}
+	return ws
+}
+
+func TestSimRoamingDetection(t *testing.T) {
+	ws := runSimScenario(t, "roaming.toml", 21)
+
+	stats := ws.GetClientStats()
+	if len(stats.RoamingHistory) != 1 {
+		t.Fatalf("RoamingHistory = %+v, want exactly one roam", stats.RoamingHistory)
+	}
+	roam := stats.RoamingHistory[0]
+	if roam.PreviousBSSID != "02:00:00:00:01:01" || roam.NewBSSID != "02:00:00:00:01:02" {
+		t.Errorf("roam %s -> %s, want AP1 -> AP2", roam.PreviousBSSID, roam.NewBSSID)
+	}
+	if roam.NewSignal <= roam.PreviousSignal {
+		t.Errorf("roam signal %d -> %d, want an improvement", roam.PreviousSignal, roam.NewSignal)
+	}
+	if roam.DurationMs != 4000 {
+		t.Errorf("roam DurationMs = %d, want 4000 (one virtual tick)", roam.DurationMs)
+	}
+
+	report := ws.AnalyzeRoamingQuality()
+	if report.TotalRoams != 1 || report.GoodRoams != 1 || report.StickyClient {
+		t.Errorf("report = %+v", report)
+	}
+	if stats.BSSID != "02:00:00:00:01:02" {
+		t.Errorf("final BSSID = %s, want AP2", stats.BSSID)
+	}
+}
+
+func TestSimStickyClient(t *testing.T) {
+	ws := runSimScenario(t, "sticky.toml", 16)
+
+	stats := ws.GetClientStats()
+	if len(stats.RoamingHistory) != 0 {
+		t.Fatalf("RoamingHistory = %+v, want none (client never roams)", stats.RoamingHistory)
+	}
+	if stats.BSSID != "02:00:00:00:01:01" {
+		t.Errorf("BSSID = %s, want still on AP1", stats.BSSID)
+	}
+	report := ws.AnalyzeRoamingQuality()
+	if !report.StickyClient {
+		t.Errorf("StickyClient = false at %d dBm with AP2 close by; report = %+v", stats.Signal, report)
+	}
+	if !strings.Contains(report.RoamingAdvice, "sticky") {
+		t.Errorf("RoamingAdvice = %q", report.RoamingAdvice)
+	}
+}
+
+func TestSimChannelCongestion(t *testing.T) {
+	ws := runSimScenario(t, "congested-2g.toml", 1)
+
+	var ch6, ch3 *ChannelInfo
+	channels := ws.GetChannelAnalysis()
+	for i := range channels {
+		switch channels[i].Channel {
+		case 6:
+			ch6 = &channels[i]
+		case 3:
+			ch3 = &channels[i]
+		}
+	}
+	if ch6 == nil || ch3 == nil {
+		t.Fatalf("channels = %+v, want 3 and 6", channels)
+	}
+	if ch6.NetworkCount != 7 || ch6.CongestionLevel != "high" {
+		t.Errorf("channel 6 = %d networks, %q; want 7, high", ch6.NetworkCount, ch6.CongestionLevel)
+	}
+	if ch3.OverlappingCount != 1 {
+		t.Errorf("channel 3 OverlappingCount = %d, want 1", ch3.OverlappingCount)
+	}
+
+	found := false
+	for _, n := range ws.GetNetworks() {
+		if n.SSID == "Overlapper" {
+			found = n.HasIssues && len(n.IssueMessages) > 0 &&
+				strings.Contains(n.IssueMessages[0], "Channel 3 may overlap")
+		}
+	}
+	if !found {
+		t.Error("Overlapper network not flagged for channel overlap")
+	}
+	if ws.GetClientStats().Connected {
+		t.Error("client connected, want no association in this scenario")
+	}
+}
+
+func TestSimDeterministic(t *testing.T) {
+	scan := func() []AccessPoint {
+		sim, err := openSimBackend(filepath.Join("testdata", "sim", "roaming.toml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var last []AccessPoint
+		for range 5 {
+			last, _ = sim.ScanNetworks(context.Background(), "sim0", ScanOptions{})
+		}
+		return last
+	}
+	a, b := scan(), scan()
+	if len(a) != len(b) {
+		t.Fatalf("runs differ in AP count: %d vs %d", len(a), len(b))
+	}
+	for i := range a {
+		if a[i].Signal != b[i].Signal {
+			t.Errorf("AP %s signal %d vs %d across identical runs", a[i].BSSID, a[i].Signal, b[i].Signal)
+		}
+	}
+}
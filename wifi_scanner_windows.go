@@ -3,6 +3,7 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -300,7 +301,12 @@ func (s *windowsScanner) ensureHandle() error {
 	return nil
 }
 
-func (s *windowsScanner) GetInterfaces() ([]string, error) {
+// The WLAN API calls return quickly and can't be interrupted, so the
+// methods below check ctx between calls rather than abandoning them.
+func (s *windowsScanner) GetInterfaces(ctx context.Context) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	if err := s.ensureHandle(); err != nil {
 		return nil, err
 	}
@@ -350,7 +356,15 @@ func (s *windowsScanner) GetInterfaces() ([]string, error) {
 	return interfaces, nil
 }
 
-func (s *windowsScanner) ScanNetworks(iface string) ([]AccessPoint, error) {
+// ScanNetworks always scans actively: WlanScan has no passive mode, and
+// Windows probes on its own schedule anyway.
+func (s *windowsScanner) ScanNetworks(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if opts.Passive {
+		return nil, errPassiveScanUnsupported
+	}
 	if err := s.ensureHandle(); err != nil {
 		return nil, err
 	}
@@ -372,7 +386,11 @@ func (s *windowsScanner) ScanNetworks(iface string) ([]AccessPoint, error) {
 		slog.Warn("WlanScan failed", "ret", ret)
 	}
 
-	time.Sleep(100 * time.Millisecond)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-time.After(100 * time.Millisecond):
+	}
 
 	var bssList *WLAN_BSS_LIST
 
@@ -1012,10 +1030,13 @@ func (s *windowsScanner) getInterfaceStats(guid windows.GUID) (*MIB_IF_ROW2, err
 	return &row, nil
 }
 
-func (s *windowsScanner) GetLinkInfo(iface string) (map[string]string, error) {
+func (s *windowsScanner) GetLinkInfo(ctx context.Context, iface string) (LinkInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return LinkInfo{}, err
+	}
 	info, err := s.GetConnectionInfo(iface)
 	if err != nil {
-		return map[string]string{"connected": "false"}, err
+		return LinkInfo{}, err
 	}
 
 	if !info.Connected {
@@ -1023,152 +1044,91 @@ func (s *windowsScanner) GetLinkInfo(iface string) (map[string]string, error) {
 		delete(s.baselineStats, iface)
 		delete(s.connectionStart, iface)
 		s.mu.Unlock()
-		return map[string]string{"connected": "false"}, nil
+		return LinkInfo{}, nil
 	}
 
-	guid, _ := s.resolveInterfaceGUID(iface)
-	stats, err := s.getInterfaceStats(guid)
-
-	result := map[string]string{
-		"connected":       "true",
-		"ssid":            info.SSID,
-		"bssid":           info.BSSID,
-		"channel":         fmt.Sprintf("%d", info.Channel),
-		"signal":          fmt.Sprintf("%d", info.Signal),
-		"signal_avg":      fmt.Sprintf("%d", info.SignalAvg),
-		"rx_bitrate":      fmt.Sprintf("%.1f", info.RxBitrate),
-		"tx_bitrate":      fmt.Sprintf("%.1f", info.TxBitrate),
-		"wifi_standard":   info.WiFiStandard,
-		"tx_bitrate_info": formatRateInfoFromAssoc(info),
-		"rx_bitrate_info": formatRateInfoFromAssoc(info),
-	}
-
-	if err == nil && stats != nil {
-		s.mu.Lock()
-		baseline, hasBaseline := s.baselineStats[iface]
-		connStart, hasConnStart := s.connectionStart[iface]
-
-		if !hasBaseline {
-			s.baselineStats[iface] = trafficStats{
-				inOctets:   stats.InOctets,
-				outOctets:  stats.OutOctets,
-				inPackets:  stats.InUcastPkts + stats.InNUcastPkts,
-				outPackets: stats.OutUcastPkts + stats.OutNUcastPkts,
-				timestamp:  time.Now(),
-			}
-			baseline = s.baselineStats[iface]
-		}
-
-		if !hasConnStart {
-			s.connectionStart[iface] = time.Now()
-			connStart = s.connectionStart[iface]
-		}
-		s.mu.Unlock()
-
-		rxBytes := stats.InOctets - baseline.inOctets
-		txBytes := stats.OutOctets - baseline.outOctets
-		rxPackets := (stats.InUcastPkts + stats.InNUcastPkts) - baseline.inPackets
-		txPackets := (stats.OutUcastPkts + stats.OutNUcastPkts) - baseline.outPackets
-		connectedTime := int(time.Since(connStart).Seconds())
-
-		result["rx_bytes"] = fmt.Sprintf("%d", rxBytes)
-		result["tx_bytes"] = fmt.Sprintf("%d", txBytes)
-		result["rx_packets"] = fmt.Sprintf("%d", rxPackets)
-		result["tx_packets"] = fmt.Sprintf("%d", txPackets)
-		result["tx_retries"] = fmt.Sprintf("%d", stats.OutDiscards)
-		result["tx_failed"] = fmt.Sprintf("%d", stats.OutErrors)
-		result["connected_time"] = fmt.Sprintf("%d", connectedTime)
-		result["retry_rate"] = fmt.Sprintf("%.2f", calculateRetryRate(stats.OutDiscards, txPackets))
-		result["frequency"] = fmt.Sprintf("%d", info.Frequency)
-	} else {
-		result["rx_bytes"] = "0"
-		result["tx_bytes"] = "0"
-		result["rx_packets"] = "0"
-		result["tx_packets"] = "0"
-		result["tx_retries"] = "0"
-		result["tx_failed"] = "0"
-		result["connected_time"] = "0"
-		result["retry_rate"] = "0.00"
-		result["frequency"] = "0"
-	}
-
-	return result, nil
+	link := LinkInfo{
+		Connected: true,
+		SSID:      info.SSID,
+		BSSID:     info.BSSID,
+		Signal:    intPtr(info.Signal),
+	}
+	if info.Channel != 0 {
+		link.Channel = intPtr(info.Channel)
+	}
+	if info.Frequency != 0 {
+		link.Frequency = floatPtr(float64(info.Frequency))
+	}
+	return link, nil
 }
 
-func (s *windowsScanner) GetStationStats(iface string) (map[string]string, error) {
+func (s *windowsScanner) GetStationStats(ctx context.Context, iface string) (StationInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return StationInfo{}, err
+	}
 	info, err := s.GetConnectionInfo(iface)
-	if err != nil {
-		return map[string]string{"connected": "false"}, err
+	if err != nil || !info.Connected {
+		return StationInfo{}, err
 	}
 
-	if !info.Connected {
-		return map[string]string{"connected": "false"}, nil
+	station := StationInfo{
+		SignalAvg:     intPtr(info.SignalAvg),
+		RxBitrate:     floatPtr(info.RxBitrate),
+		TxBitrate:     floatPtr(info.TxBitrate),
+		TxBitrateInfo: formatRateInfoFromAssoc(info),
 	}
 
 	guid, _ := s.resolveInterfaceGUID(iface)
 	stats, err := s.getInterfaceStats(guid)
-
-	result := map[string]string{
-		"connected":       "true",
-		"bssid":           info.BSSID,
-		"signal":          fmt.Sprintf("%d", info.Signal),
-		"signal_avg":      fmt.Sprintf("%d", info.SignalAvg),
-		"rx_bitrate":      fmt.Sprintf("%.1f", info.RxBitrate),
-		"tx_bitrate":      fmt.Sprintf("%.1f", info.TxBitrate),
-		"tx_bitrate_info": formatRateInfoFromAssoc(info),
-		"rx_bitrate_info": formatRateInfoFromAssoc(info),
+	if err != nil || stats == nil {
+		return station, nil
 	}
 
-	if err == nil && stats != nil {
-		s.mu.Lock()
-		baseline, hasBaseline := s.baselineStats[iface]
-		connStart, hasConnStart := s.connectionStart[iface]
-
-		if !hasBaseline {
-			s.baselineStats[iface] = trafficStats{
-				inOctets:   stats.InOctets,
-				outOctets:  stats.OutOctets,
-				inPackets:  stats.InUcastPkts + stats.InNUcastPkts,
-				outPackets: stats.OutUcastPkts + stats.OutNUcastPkts,
-				timestamp:  time.Now(),
-			}
-			baseline = s.baselineStats[iface]
+	// The interface counters run from boot, so they're reported relative
+	// to the first reading of this association.
+	s.mu.Lock()
+	baseline, hasBaseline := s.baselineStats[iface]
+	connStart, hasConnStart := s.connectionStart[iface]
+
+	if !hasBaseline {
+		s.baselineStats[iface] = trafficStats{
+			inOctets:   stats.InOctets,
+			outOctets:  stats.OutOctets,
+			inPackets:  stats.InUcastPkts + stats.InNUcastPkts,
+			outPackets: stats.OutUcastPkts + stats.OutNUcastPkts,
+			timestamp:  time.Now(),
 		}
+		baseline = s.baselineStats[iface]
+	}
 
-		if !hasConnStart {
-			s.connectionStart[iface] = time.Now()
-			connStart = s.connectionStart[iface]
-		}
-		s.mu.Unlock()
+	if !hasConnStart {
+		s.connectionStart[iface] = time.Now()
+		connStart = s.connectionStart[iface]
+	}
+	s.mu.Unlock()
 
-		rxBytes := stats.InOctets - baseline.inOctets
-		txBytes := stats.OutOctets - baseline.outOctets
-		rxPackets := (stats.InUcastPkts + stats.InNUcastPkts) - baseline.inPackets
-		txPackets := (stats.OutUcastPkts + stats.OutNUcastPkts) - baseline.outPackets
-		connectedTime := int(time.Since(connStart).Seconds())
-
-		result["rx_bytes"] = fmt.Sprintf("%d", rxBytes)
-		result["tx_bytes"] = fmt.Sprintf("%d", txBytes)
-		result["rx_packets"] = fmt.Sprintf("%d", rxPackets)
-		result["tx_packets"] = fmt.Sprintf("%d", txPackets)
-		result["tx_retries"] = fmt.Sprintf("%d", stats.OutDiscards)
-		result["tx_failed"] = fmt.Sprintf("%d", stats.OutErrors)
-		result["connected_time"] = fmt.Sprintf("%d", connectedTime)
-		result["last_ack_signal"] = fmt.Sprintf("%d", info.Signal)
-		result["retry_rate"] = fmt.Sprintf("%.2f", calculateRetryRate(stats.OutDiscards, txPackets))
-	} else {
-		result["rx_bytes"] = "0"
-		result["tx_bytes"] = "0"
-		result["rx_packets"] = "0"
-		result["tx_packets"] = "0"
-		result["tx_retries"] = "0"
-		result["tx_failed"] = "0"
-		result["connected_time"] = "0"
-		result["last_ack_signal"] = "0"
-		result["retry_rate"] = "0.00"
-	}
-
-	return result, nil
+	station.RxBytes = uint64Ptr(stats.InOctets - baseline.inOctets)
+	station.TxBytes = uint64Ptr(stats.OutOctets - baseline.outOctets)
+	station.RxPackets = uint64Ptr((stats.InUcastPkts + stats.InNUcastPkts) - baseline.inPackets)
+	station.TxPackets = uint64Ptr((stats.OutUcastPkts + stats.OutNUcastPkts) - baseline.outPackets)
+	station.TxRetries = uint64Ptr(stats.OutDiscards)
+	station.TxFailed = uint64Ptr(stats.OutErrors)
+	station.ConnectedTime = intPtr(int(time.Since(connStart).Seconds()))
+	station.LastAckSignal = intPtr(info.Signal)
+	return station, nil
+}
+
+// Capabilities: the BSS list carries raw IEs, but the WLAN API has no
+// noise floor or channel survey, and the retry counters are interface
+// discards standing in for per-station retries.
+func (s *windowsScanner) Capabilities(iface string) BackendCapabilities {
+	return BackendCapabilities{
+		Interface:           iface,
+		Backend:             "windows",
+		InformationElements: true,
+		ActiveScan:          true,
+		Bands:               []string{},
+	}
 }
 
 func (s *windowsScanner) Close() error {
@@ -1363,17 +1323,6 @@ func getVHTQAM(mcsMap uint16) int {
 	return 64
 }
 
-func calculateRetryRate(retries, totalPackets uint64) float64 {
-	if totalPackets == 0 {
-		return 0.0
-	}
-	rate := float64(retries) / float64(totalPackets) * 100.0
-	if rate > 100.0 {
-		rate = 100.0
-	}
-	return rate
-}
-
 // formatRateInfoFromAssoc synthesizes a rate info string compatible with
 // parseBitrateInfo (wifi_utils.go). The Windows WLAN API exposes the phy type
 // and negotiated rate but not MCS index or spatial stream count directly, so
@@ -2,15 +2,17 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"net"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
+	"strings"
 	"sync"
-	"sync/atomic"
 	"time"
 
 	"github.com/wailsapp/wails/v2/pkg/runtime"
@@ -39,15 +41,35 @@ type apSignalEntry struct {
 	lastSeen time.Time
 }
 
+// eventEmitter delivers a named event to whichever front end is attached:
+// the Wails runtime in the GUI, stdout in the headless CLI. It is fed from
+// the EventBus by emitterSubscriber; before attach nothing is subscribed,
+// which is what tests and pre-startup scans want.
+type eventEmitter func(name string, data interface{})
+
+// wailsEmitter adapts runtime.EventsEmit to eventEmitter. ctx must be the
+// context handed to App.startup — the Wails runtime log.Fatal()s on anything
+// else, which is why the service never calls EventsEmit directly.
+func wailsEmitter(ctx context.Context) eventEmitter {
+	return func(name string, data interface{}) {
+		runtime.EventsEmit(ctx, name, data)
+	}
+}
+
 // WiFiService manages WiFi scanning and data aggregation
 type WiFiService struct {
-	scanner          WiFiBackend
-	ctx              context.Context
+	scanner WiFiBackend
+	ctx     context.Context
+	// bus carries every service event. The attached front end, API
+	// streams, metrics, session recorder and logger are all subscribers.
+	bus              *EventBus
+	detachEmitter    func()
 	mu               sync.RWMutex
 	scanning         bool
 	cancelFunc       context.CancelFunc
-	currentInterface string
-	scanInFlight     atomic.Bool
+	currentInterface string // primary interface; see radio_state.go
+	interfaces       []string
+	scanOptions      ScanOptions // for the current scanning session
 
 	// Live config — read at the top of each scan iteration so SaveConfig()
 	// changes (e.g. scan_interval) take effect on the next tick without
@@ -60,28 +82,64 @@ type WiFiService struct {
 	latencySampler *LatencySampler
 	samplerOnce    sync.Once
 	samplerCancel  context.CancelFunc
-
-	// Aggregated data
+	// throughput runs iperf3 tests on demand and on the [throughput]
+	// schedule, alongside the latency sampler.
+	throughput *ThroughputTester
+
+	// recorder persists scan ticks, client samples, roams and latency
+	// probes to disk while config.RecordSessions is on. Nil when the
+	// sessions directory can't be resolved.
+	recorder *SessionRecorder
+
+	// metrics accumulates the counters and histograms behind /metrics.
+	// The listener itself only runs while config.MetricsListen is set.
+	metrics       *metricsCollector
+	metricsMu     sync.Mutex
+	metricsServer *metricsServer
+
+	// stream fans bus events out to API stream clients; apiServer is
+	// the listener, running only while config.APIListen is set.
+	stream    *eventStream
+	apiMu     sync.Mutex
+	apiServer *apiServer
+
+	// alerts evaluates config.Alerts against scan and latency ticks.
+	alerts *alertEngine
+	// rogue learns each SSID's legitimate APs and flags impostors.
+	rogue *rogueDetector
+	// notifier delivers roams, scan errors, latency loss and alerts to
+	// the sinks in config.Notify.
+	notifier *notifier
+	// history keeps long-term client, AP and latency series in
+	// min/avg/max rollups.
+	history *historyStore
+	// jobs runs the scheduled survey jobs in config.Jobs once
+	// StartJobScheduler is called.
+	jobs            *jobScheduler
+	schedulerOnce   sync.Once
+	schedulerCancel context.CancelFunc
+	// survey holds the active site survey and captures its points.
+	survey *surveyStore
+
+	// Per-interface scan results, client stats and roaming state.
+	radios map[string]*radioState
+	// mergeMu serializes merge → aggregate → commit across the per-radio
+	// scan loops so an older merge can't overwrite a newer one.
+	mergeMu sync.Mutex
+	// presentAPs is the merged view's BSSIDs as of the last tick, for
+	// APPresenceEvents. Guarded by mergeMu.
+	presentAPs map[string]AccessPoint
+
+	// Aggregated data, merged across radios
 	networks       []Network
 	channelInfo    []ChannelInfo
-	clientStats    ClientStats
 	lastScanResult *ScanResult
 
-	// Signal history tracking
-	signalHistory  []SignalDataPoint
-	roamingHistory []RoamingEvent
 	// Per-BSSID signal history for nearby APs. Filled on every scan tick
 	// regardless of UI state so the Signal tab's "Other APs" chart survives
 	// tab switches and short-term remounts. Entries unseen for
 	// apHistoryRetention are garbage-collected to bound memory.
 	apSignalHistory map[string]*apSignalEntry
-	lastBSSID       string
-	// lastBSSIDSeenAt is the wall-clock time of the last scan tick that
-	// observed lastBSSID as the active (connected) BSSID. Used to estimate
-	// RoamingEvent.DurationMs on the next BSSID transition. Stays frozen
-	// across disconnects so a reconnect to a different BSSID naturally
-	// reports duration-including-the-gap rather than duration-since-reconnect.
-	lastBSSIDSeenAt time.Time
 }
 
 // NewWiFiService creates a new WiFi service
@@ -96,6 +154,13 @@ func NewWiFiService() *WiFiService {
 		cacheFile = filepath.Join(os.TempDir(), "oui.txt")
 	}
 
+	return newWiFiServiceWithBackend(newBackendFromEnv(cacheFile))
+}
+
+// newWiFiServiceWithBackend wires a service around an already-constructed
+// backend. NewWiFiService uses it for the platform scanner; the CLI's
+// -replay flag uses it to run a capture through the pipeline.
+func newWiFiServiceWithBackend(backend WiFiBackend) *WiFiService {
 	// Load config eagerly so the first scan respects user settings. A failed
 	// load (corrupted file, etc.) falls back to defaults inside LoadConfig.
 	cfg, err := LoadConfig()
@@ -104,18 +169,66 @@ func NewWiFiService() *WiFiService {
 	}
 
 	ws := &WiFiService{
-		scanner:         NewWiFiScanner(cacheFile),
+		scanner:         backend,
 		config:          newLiveConfig(cfg),
 		networks:        []Network{},
 		channelInfo:     []ChannelInfo{},
-		signalHistory:   []SignalDataPoint{},
-		roamingHistory:  []RoamingEvent{},
+		radios:          make(map[string]*radioState),
 		apSignalHistory: make(map[string]*apSignalEntry),
+		metrics:         newMetricsCollector(),
+		stream:          newEventStream(),
+		bus:             NewEventBus(),
+	}
+	ws.latencySampler = NewLatencySampler(ws.config, ws.bus)
+	ws.throughput = NewThroughputTester(ws.config, ws.bus, ws.GetClientStats)
+	ws.alerts = newAlertEngine(ws.config, ws.bus)
+	ws.notifier = newNotifier(ws.config)
+	// A replayed or simulated timeline isn't this machine's history, and
+	// its networks shouldn't be learned as trusted ones.
+	var historyFile, trustedPath string
+	if _, ok := backend.(backendClock); !ok {
+		if historyFile, err = historyPath(); err != nil {
+			slog.Warn("metric history won't persist", "err", err)
+		}
+		if trustedPath, err = trustedNetworksPath(); err != nil {
+			slog.Warn("trusted networks won't persist", "err", err)
+		}
 	}
-	ws.latencySampler = NewLatencySampler(ws.config)
+	ws.history = newHistoryStore(ws.config, historyFile)
+	ws.rogue = newRogueDetector(ws.config, ws.bus, trustedPath)
+	jobHistory, err := jobHistoryPath()
+	if err != nil {
+		slog.Warn("job history won't persist", "err", err)
+	}
+	ws.jobs = newJobScheduler(ws, jobHistory)
+	surveys, err := surveysDir()
+	if err != nil {
+		slog.Warn("surveys won't persist", "err", err)
+	}
+	ws.survey = newSurveyStore(surveys, ws.config, ws.bus, ws.now, ws.GetLatencySummaries, ws.backendCapabilities)
+	if dir, err := sessionsDir(); err != nil {
+		slog.Warn("session recording unavailable", "err", err)
+	} else {
+		ws.recorder = newSessionRecorder(dir)
+	}
+	ws.bus.Subscribe(logEvent)
+	ws.bus.Subscribe(ws.recorder.handleEvent)
+	ws.bus.Subscribe(ws.metrics.handleEvent)
+	ws.bus.Subscribe(ws.stream.handleEvent)
+	ws.bus.Subscribe(ws.alerts.handleEvent)
+	ws.bus.Subscribe(ws.rogue.handleEvent)
+	ws.bus.Subscribe(ws.notifier.handleEvent)
+	ws.bus.Subscribe(ws.history.handleEvent)
+	ws.bus.Subscribe(ws.survey.handleEvent)
 	return ws
 }
 
+// Events returns the bus the service publishes on, for additional
+// subscribers (exporters, tests).
+func (ws *WiFiService) Events() *EventBus {
+	return ws.bus
+}
+
 // GetConfig returns the current live config snapshot.
 func (ws *WiFiService) GetConfig() Config {
 	return ws.config.Get()
@@ -128,6 +241,9 @@ func (ws *WiFiService) UpdateConfig(cfg Config) error {
 		return err
 	}
 	ws.config.Set(cfg)
+	if ws.ctx != nil {
+		ws.syncListeners()
+	}
 	return nil
 }
 
@@ -135,41 +251,64 @@ func (ws *WiFiService) UpdateConfig(cfg Config) error {
 // sampler — its lifecycle is tied to the Wails app context, not to the scan
 // loop, so probes keep flowing even when the user pauses scanning.
 func (ws *WiFiService) SetContext(ctx context.Context) {
+	ws.attach(ctx, wailsEmitter(ctx))
+}
+
+// AttachHeadless is the SetContext equivalent for runs without a webview
+// (the `scan` / `monitor` CLI). Events go to emit instead of the Wails
+// runtime; ctx bounds the latency sampler and any scan loop started later.
+func (ws *WiFiService) AttachHeadless(ctx context.Context, emit eventEmitter) {
+	ws.attach(ctx, emit)
+}
+
+func (ws *WiFiService) attach(ctx context.Context, emit eventEmitter) {
 	ws.ctx = ctx
+	if ws.detachEmitter != nil {
+		ws.detachEmitter()
+	}
+	ws.detachEmitter = ws.bus.Subscribe(emitterSubscriber(emit))
 	if ws.latencySampler != nil {
-		ws.latencySampler.SetWailsContext(ctx)
 		ws.samplerOnce.Do(func() {
 			samplerCtx, cancel := context.WithCancel(ctx)
 			ws.samplerCancel = cancel
 			ws.latencySampler.Start(samplerCtx)
+			ws.throughput.Start(samplerCtx)
 		})
 	}
+	ws.syncListeners()
 }
 
-// StartScanning begins periodic WiFi scanning
-func (ws *WiFiService) StartScanning(iface string) error {
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
-
-	if ws.scanning {
-		return fmt.Errorf("scanning already in progress")
+// appContext is the context passed to SetContext or AttachHeadless, or
+// Background before either is called (tests). Work started from a binding
+// runs under it so app shutdown cancels it.
+func (ws *WiFiService) appContext() context.Context {
+	if ws.ctx == nil {
+		return context.Background()
 	}
+	return ws.ctx
+}
 
-	// Inherit from the Wails app context so app shutdown cancels scanning.
-	// Fall back to Background when SetContext hasn't been called (tests).
-	parent := ws.ctx
-	if parent == nil {
-		parent = context.Background()
-	}
-	scanCtx, cancel := context.WithCancel(parent)
-
-	ws.currentInterface = iface
-	ws.scanning = true
-	ws.cancelFunc = cancel
+// syncListeners brings the optional HTTP listeners (/metrics, /api) in
+// line with the live config.
+func (ws *WiFiService) syncListeners() {
+	ws.syncMetricsServer()
+	ws.syncAPIServer()
+}
 
-	go ws.scanLoop(scanCtx, iface)
+// now returns the backend's clock when it has one (replay), otherwise the
+// wall clock. Everything that timestamps scan-derived data goes through
+// here so a replay reproduces on-site timings.
+func (ws *WiFiService) now() time.Time {
+	if c, ok := ws.scanner.(backendClock); ok {
+		return c.Now()
+	}
+	return time.Now()
+}
 
-	return nil
+// StartScanning begins periodic WiFi scanning on a single interface. See
+// StartScanningInterfaces for scanning several at once.
+func (ws *WiFiService) StartScanning(iface string) error {
+	return ws.StartScanningInterfaces([]string{iface})
 }
 
 // StopScanning stops the periodic scanning
@@ -183,10 +322,14 @@ func (ws *WiFiService) StopScanning() {
 	}
 
 	ws.scanning = false
+	ws.recorder.Stop()
 }
 
 // Close stops scanning and releases scanner resources.
 func (ws *WiFiService) Close() error {
+	if ws.schedulerCancel != nil {
+		ws.schedulerCancel()
+	}
 	ws.StopScanning()
 	if ws.samplerCancel != nil {
 		ws.samplerCancel()
@@ -194,6 +337,17 @@ func (ws *WiFiService) Close() error {
 	if ws.latencySampler != nil {
 		ws.latencySampler.Stop()
 	}
+	ws.throughput.Stop()
+	ws.metricsMu.Lock()
+	ws.metricsServer.Close()
+	ws.metricsServer = nil
+	ws.metricsMu.Unlock()
+	ws.apiMu.Lock()
+	ws.apiServer.Close()
+	ws.apiServer = nil
+	ws.apiMu.Unlock()
+	ws.notifier.Close()
+	ws.history.Save()
 	if ws.scanner != nil {
 		return ws.scanner.Close()
 	}
@@ -210,7 +364,7 @@ func (ws *WiFiService) GetLatencySummaries() []LatencyTargetSummary {
 	return ws.latencySampler.SnapshotSummaries()
 }
 
-// scanLoop runs the periodic scanning loop
+// scanLoop runs the periodic scanning loop for one interface
 func (ws *WiFiService) scanLoop(ctx context.Context, iface string) {
 	for {
 		select {
@@ -221,6 +375,9 @@ func (ws *WiFiService) scanLoop(ctx context.Context, iface string) {
 
 		start := time.Now()
 		ws.performScan(ctx, iface)
+		if ws.radio(iface).replayDone.Load() {
+			return
+		}
 		elapsed := time.Since(start)
 		// Read the interval fresh each loop so a SaveConfig-driven change
 		// (e.g. user drops scan_interval from 4 s to 1 s in the Settings
@@ -230,66 +387,187 @@ func (ws *WiFiService) scanLoop(ctx context.Context, iface string) {
 		if sleepFor < 0 {
 			sleepFor = 0
 		}
+		// A paced replay waits inside ScanNetworks until its next record
+		// is due; sleeping here as well would halve the replay speed.
+		if c, ok := ws.scanner.(backendClock); ok && c.Paced() {
+			sleepFor = 0
+		}
+		// A survey point wants a quick burst of scans, not one per interval.
+		if ws.survey.capturing() {
+			sleepFor = 0
+		}
 
 		select {
 		case <-ctx.Done():
 			return
 		case <-time.After(sleepFor):
+		case <-ws.survey.wake():
 		}
 	}
 }
 
 // performScan executes a single scan operation. ctx is propagated into
-// scanWithBackoff so a pending retry-backoff doesn't block app shutdown.
+// the backend and scanWithBackoff, so stopping scanning or shutting the app
+// down abandons an in-flight scan instead of waiting for it; a scan cut
+// short that way isn't reported as a scan error.
 func (ws *WiFiService) performScan(ctx context.Context, iface string) {
-	if !ws.scanInFlight.CompareAndSwap(false, true) {
+	r := ws.radio(iface)
+	if !r.inFlight.CompareAndSwap(false, true) {
 		return
 	}
-	defer ws.scanInFlight.Store(false)
+	defer r.inFlight.Store(false)
 
-	aps, err := ws.scanWithBackoff(ctx, iface)
+	ws.mu.RLock()
+	opts := ws.scanOptions
+	ws.mu.RUnlock()
+	start := ws.now()
+	aps, err := ws.scanWithBackoff(ctx, iface, opts)
 	if err != nil {
-		slog.Error("scan failed", "event", "scan_error", "interface", iface, "err", err)
-		runtime.EventsEmit(ws.ctx, "scan:error", err.Error())
+		if ctx.Err() != nil {
+			return
+		}
+		if errors.Is(err, errReplayFinished) {
+			ws.replayFinished(r, iface)
+			return
+		}
+		ws.bus.Publish(ScanErrorEvent{Interface: iface, Err: err})
 		return
 	}
+	aps = opts.filter(aps)
 	for i := range aps {
 		NormalizeAccessPoint(&aps[i])
+		deriveAccessPointFields(&aps[i])
+	}
+	// Backend I/O happens here, before the locks: a slow link query must
+	// not block every reader of the service.
+	client, clientOK := ws.readClient(ctx, iface)
+
+	ws.mergeMu.Lock()
+	ws.mu.Lock()
+	now := ws.now()
+	// Held-over APs are filtered again in case the options changed.
+	aps, held := ageScan(aps, opts.filter(r.aps), start, now, ws.config.Get().ScanMaxAge())
+	r.aps = append(slices.Clip(aps), held...)
+	r.scannedAt = now
+	merged := ws.mergeRadiosLocked()
+	primary := ws.currentInterface
+	scanning := append([]string(nil), ws.interfaces...)
+	ws.mu.Unlock()
+	if len(scanning) == 0 {
+		scanning = []string{primary}
 	}
+	ws.syncRecorder(strings.Join(scanning, ","))
 
 	// Aggregate data (read-only — no shared state touched)
-	result := ws.aggregateData(aps, iface)
+	result := ws.aggregateData(merged, primary)
+	presence := ws.presenceChangesLocked(merged, result.Timestamp)
 
 	// Commit aggregated results + refresh client stats under a single write
-	// lock, then snapshot what we're about to emit. The emit happens *after*
-	// the lock is released so slow listeners can't block further scans.
+	// lock, then snapshot what we're about to publish. Publishing happens
+	// *after* the lock is released so subscribers can call back into the
+	// service and slow ones can't block further scans.
 	ws.mu.Lock()
 	ws.lastScanResult = result
 	ws.networks = result.Networks
 	ws.channelInfo = result.Channels
-	ws.recordAPSignalHistoryLocked(aps, result.Timestamp)
-	ws.updateClientStatsLocked(iface)
+	ws.recordAPSignalHistoryLocked(merged, result.Timestamp)
+	var roam *RoamingEvent
+	if clientOK {
+		roam = ws.updateClientStatsLocked(r, iface, client)
+	}
 	networksSnapshot := ws.networks
 	channelsSnapshot := ws.channelInfo
-	clientSnapshot := ws.cloneClientStatsLocked()
+	clientSnapshot := r.cloneClientStats()
 	ws.mu.Unlock()
+	ws.mergeMu.Unlock()
+
+	if roam != nil {
+		ws.bus.Publish(RoamEvent{Interface: iface, Roam: *roam})
+	}
+	ws.bus.Publish(ScanResultEvent{
+		Interface: iface,
+		Timestamp: result.Timestamp,
+		APs:       aps,
+		Networks:  networksSnapshot,
+		Channels:  channelsSnapshot,
+	})
+	ws.bus.Publish(ClientUpdateEvent{Interface: iface, Primary: iface == primary, Timestamp: result.Timestamp, Stats: clientSnapshot})
+	for _, ev := range presence {
+		ws.bus.Publish(ev)
+	}
+}
+
+// replayFinished handles a replay running out of scans for iface: it is
+// the end of the stream rather than a scan error, so it is reported once,
+// and scanning stops once every scanning interface's replay has ended.
+func (ws *WiFiService) replayFinished(r *radioState, iface string) {
+	if !r.replayDone.CompareAndSwap(false, true) {
+		return
+	}
+	ws.mu.RLock()
+	done := true
+	for _, name := range ws.interfaces {
+		if rs := ws.radios[name]; rs == nil || !rs.replayDone.Load() {
+			done = false
+		}
+	}
+	ws.mu.RUnlock()
+	if done {
+		ws.StopScanning()
+	}
+	ws.bus.Publish(ReplayFinishedEvent{Interface: iface})
+}
+
+// syncRecorder starts or stops session recording to match the live
+// config, so toggling record_sessions in Settings takes effect on the next
+// scan tick. A session is bound to one set of interfaces (comma-joined in
+// iface); changing the set starts a fresh one.
+func (ws *WiFiService) syncRecorder(iface string) {
+	if ws.recorder == nil {
+		return
+	}
+	want := ws.config.Get().RecordSessions
+	active, recording := ws.recorder.Active()
+	switch {
+	case want && (!recording || active.Interface != iface):
+		if _, err := ws.recorder.Start(iface, ws.backendCapabilities(strings.Split(iface, ",")), ws.ScanOptions()); err != nil {
+			slog.Warn("session recording failed to start", "event", "session_error", "interface", iface, "err", err)
+		}
+	case !want && recording:
+		ws.recorder.Stop()
+	}
+}
 
-	runtime.EventsEmit(ws.ctx, "networks:updated", networksSnapshot)
-	runtime.EventsEmit(ws.ctx, "channels:updated", channelsSnapshot)
-	runtime.EventsEmit(ws.ctx, "client:updated", clientSnapshot)
+// ListSessions returns the recorded sessions on disk, newest first.
+func (ws *WiFiService) ListSessions() ([]SessionInfo, error) {
+	return ws.recorder.List()
+}
+
+// OpenSession loads a recorded session by ID.
+func (ws *WiFiService) OpenSession(id string) (Session, error) {
+	return ws.recorder.Open(id)
+}
+
+// DeleteSession removes a recorded session. The session currently being
+// written can't be deleted.
+func (ws *WiFiService) DeleteSession(id string) error {
+	return ws.recorder.Delete(id)
 }
 
 // scanWithBackoff retries a failing scan with short exponential backoff so a
 // single transient driver hiccup doesn't surface as a scan:error toast.
 // The backoff wait is ctx-aware so app shutdown doesn't have to wait for the
 // full backoff window before the scan goroutine exits.
-func (ws *WiFiService) scanWithBackoff(ctx context.Context, iface string) ([]AccessPoint, error) {
+func (ws *WiFiService) scanWithBackoff(ctx context.Context, iface string, opts ScanOptions) ([]AccessPoint, error) {
 	var lastErr error
 	for attempt := 0; attempt <= len(scanBackoffDelays); attempt++ {
-		aps, err := ws.scanner.ScanNetworks(iface)
+		aps, err := ws.scanner.ScanNetworks(ctx, iface, opts)
 		if err == nil {
 			return aps, nil
 		}
+		if errors.Is(err, errReplayFinished) {
+			return nil, err // nothing to retry
+		}
 		lastErr = err
 		if attempt == len(scanBackoffDelays) {
 			break
@@ -303,6 +581,14 @@ func (ws *WiFiService) scanWithBackoff(ctx context.Context, iface string) ([]Acc
 	return nil, lastErr
 }
 
+// deriveAccessPointFields fills the fields computed from an AP's
+// capabilities rather than read from the scan.
+func deriveAccessPointFields(ap *AccessPoint) {
+	ap.WiFiGeneration = deriveWiFiGeneration(ap.Capabilities)
+	ap.WiFiStandard = getDominantWiFiStandard(ap.Capabilities, ap.Band)
+	ap.Beamforming = hasBeamformingSupport(ap.Capabilities, ap.MUMIMO)
+}
+
 // aggregateData aggregates access point data into networks and channel info
 func (ws *WiFiService) aggregateData(aps []AccessPoint, iface string) *ScanResult {
 	networkMap := make(map[string]*Network)
@@ -311,11 +597,6 @@ func (ws *WiFiService) aggregateData(aps []AccessPoint, iface string) *ScanResul
 	for i := range aps {
 		ap := aps[i]
 
-		ap.WiFiGeneration = deriveWiFiGeneration(ap.Capabilities)
-		ap.WiFiStandard = getDominantWiFiStandard(ap.Capabilities, ap.Band)
-		ap.Beamforming = hasBeamformingSupport(ap.Capabilities, ap.MUMIMO)
-		aps[i] = ap
-
 		// Group by SSID. Hidden APs advertise an empty SSID; we key those
 		// per-BSSID so two unrelated hidden networks don't collapse into one
 		// row. The on-the-wire SSID stays empty so the UI can render "(hidden)".
@@ -331,12 +612,14 @@ func (ws *WiFiService) aggregateData(aps []AccessPoint, iface string) *ScanResul
 				Security:      ap.Security,
 				Channel:       ap.Channel,
 				IssueMessages: []string{},
+				Stale:         true,
 			}
 		}
 
 		network := networkMap[key]
 		network.AccessPoints = append(network.AccessPoints, ap)
 		network.APCount = len(network.AccessPoints)
+		network.Stale = network.Stale && ap.Stale
 
 		if ap.Signal > network.BestSignal {
 			network.BestSignal = ap.Signal
@@ -397,7 +680,7 @@ func (ws *WiFiService) aggregateData(aps []AccessPoint, iface string) *ScanResul
 	})
 
 	return &ScanResult{
-		Timestamp:     time.Now(),
+		Timestamp:     ws.now(),
 		Interface:     iface,
 		Networks:      networks,
 		Channels:      channels,
@@ -461,142 +744,160 @@ func (ws *WiFiService) countOverlappingChannels(channel int, channelMap map[int]
 	return count
 }
 
-// updateClientStatsLocked updates client connection statistics.
-// The caller MUST hold ws.mu.Lock for the duration of the call; the function
-// reads and writes ws.clientStats, ws.signalHistory, ws.roamingHistory, and
-// ws.lastBSSID without acquiring the mutex itself.
-func (ws *WiFiService) updateClientStatsLocked(iface string) {
-	linkInfo, err := ws.scanner.GetLinkInfo(iface)
-	if err != nil {
-		ws.clientStats.Connected = false
-		return
-	}
-
-	if linkInfo["connected"] == "false" {
-		ws.clientStats.Connected = false
-		return
-	}
+// clientReading is one tick's backend link and station data for an
+// interface, fetched by readClient before any lock is taken.
+type clientReading struct {
+	connected  bool
+	link       LinkInfo
+	station    StationInfo
+	stationErr error
+	localIP    string
+	gateway    string
+}
 
-	ws.clientStats.Connected = true
-	ws.clientStats.Interface = iface
-	ws.clientStats.SSID = linkInfo["ssid"]
-	ws.clientStats.BSSID = linkInfo["bssid"]
-	ws.clientStats.LocalIP = ifaceIPv4(iface)
-	ws.clientStats.Gateway = defaultGatewayString()
+// readClient queries the backend for iface's association. It does the
+// tick's client I/O so that none of it runs under ws.mu. ok is false when
+// ctx ended mid-call, in which case the stats should be left as they were
+// rather than marked disconnected.
+func (ws *WiFiService) readClient(ctx context.Context, iface string) (c clientReading, ok bool) {
+	link, err := ws.scanner.GetLinkInfo(ctx, iface)
+	if err != nil && ctx.Err() != nil {
+		return c, false
+	}
+	if err != nil || !link.Connected {
+		return c, true
+	}
+	c.connected = true
+	c.link = link
+	c.station, c.stationErr = ws.scanner.GetStationStats(ctx, iface)
+	c.localIP = ifaceIPv4(iface)
+	c.gateway = defaultGatewayString()
+	return c, true
+}
 
-	if freq, err := strconv.ParseFloat(linkInfo["frequency"], 64); err == nil && freq != 0 {
-		ws.clientStats.Frequency = freq
-		ws.clientStats.Channel = frequencyToChannel(int(freq))
-	}
-	// Some backends (CoreWLAN) emit channel directly without a frequency
-	// string; honour that and synthesise the frequency the other way round.
-	if channel, err := strconv.Atoi(linkInfo["channel"]); err == nil && channel != 0 {
-		if ws.clientStats.Channel == 0 {
-			ws.clientStats.Channel = channel
+// updateClientStatsLocked updates r's client connection statistics from c
+// and returns the roaming event detected on this tick, if any, for the
+// caller to publish once the lock is released.
+// The caller MUST hold ws.mu.Lock for the duration of the call; the function
+// reads and writes r.clientStats, r.signalHistory, r.roamingHistory, and
+// r.lastBSSID without acquiring the mutex itself.
+func (ws *WiFiService) updateClientStatsLocked(r *radioState, iface string, c clientReading) *RoamingEvent {
+	if !c.connected {
+		r.clientStats.Connected = false
+		return nil
+	}
+
+	link := c.link
+	cs := &r.clientStats
+	cs.Connected = true
+	cs.Interface = iface
+	cs.SSID = link.SSID
+	cs.BSSID = link.BSSID
+	cs.LocalIP = c.localIP
+	cs.Gateway = c.gateway
+
+	if link.Frequency != nil {
+		cs.Frequency = *link.Frequency
+		cs.Channel = frequencyToChannel(int(*link.Frequency))
+	}
+	// Some backends (CoreWLAN) report the channel without a frequency;
+	// honour that and synthesise the frequency the other way round.
+	if link.Channel != nil {
+		if cs.Channel == 0 {
+			cs.Channel = *link.Channel
 		}
-		if ws.clientStats.Frequency == 0 {
-			ws.clientStats.Frequency = float64(channelToFrequency(channel))
+		if cs.Frequency == 0 {
+			cs.Frequency = float64(channelToFrequency(*link.Channel))
 		}
 	}
-	if width, err := strconv.Atoi(linkInfo["channel_width"]); err == nil && width != 0 {
-		ws.clientStats.ChannelWidth = width
+	if link.ChannelWidth != nil {
+		cs.ChannelWidth = *link.ChannelWidth
 	}
-	if noise, err := strconv.Atoi(linkInfo["noise"]); err == nil && noise != 0 {
-		ws.clientStats.Noise = noise
+	if link.Noise != nil {
+		cs.Noise = *link.Noise
 	}
-
-	if signal, err := strconv.Atoi(linkInfo["signal"]); err == nil {
-		ws.clientStats.Signal = signal
+	if link.Signal != nil {
+		cs.Signal = *link.Signal
 	}
 
-	stationStats, err := ws.scanner.GetStationStats(iface)
-	if err == nil {
-		if signalAvg, err := strconv.Atoi(stationStats["signal_avg"]); err == nil {
-			ws.clientStats.SignalAvg = signalAvg
-		} else {
-			ws.clientStats.SignalAvg = ws.clientStats.Signal
+	if station := c.station; c.stationErr == nil {
+		cs.SignalAvg = cs.Signal
+		if station.SignalAvg != nil {
+			cs.SignalAvg = *station.SignalAvg
 		}
-
-		if txBitrate, err := strconv.ParseFloat(stationStats["tx_bitrate"], 64); err == nil {
-			ws.clientStats.TxBitrate = txBitrate
+		if station.TxBitrate != nil {
+			cs.TxBitrate = *station.TxBitrate
 		}
-		if rxBitrate, err := strconv.ParseFloat(stationStats["rx_bitrate"], 64); err == nil {
-			ws.clientStats.RxBitrate = rxBitrate
+		if station.RxBitrate != nil {
+			cs.RxBitrate = *station.RxBitrate
 		}
 
-		if wifiStd := stationStats["wifi_standard"]; wifiStd != "" {
-			ws.clientStats.WiFiStandard = wifiStd
+		if station.WiFiStandard != "" {
+			cs.WiFiStandard = station.WiFiStandard
 		}
 		// Always run parseBitrateInfo when we have a bitrate string: it's the
 		// only source of MIMOConfig (and ChannelWidth on backends that don't
-		// emit it directly). Don't let an explicit wifi_standard skip it.
-		if txBitrateInfo := stationStats["tx_bitrate_info"]; txBitrateInfo != "" {
-			parsedStd, parsedWidth, parsedMimo := parseBitrateInfo(txBitrateInfo)
-			if ws.clientStats.WiFiStandard == "" {
-				ws.clientStats.WiFiStandard = parsedStd
+		// report it directly). Don't let an explicit WiFiStandard skip it.
+		if station.TxBitrateInfo != "" {
+			parsedStd, parsedWidth, parsedMimo := parseBitrateInfo(station.TxBitrateInfo)
+			if cs.WiFiStandard == "" {
+				cs.WiFiStandard = parsedStd
 			}
-			if ws.clientStats.ChannelWidth == 0 {
-				ws.clientStats.ChannelWidth, _ = strconv.Atoi(parsedWidth)
+			if cs.ChannelWidth == 0 {
+				cs.ChannelWidth, _ = strconv.Atoi(parsedWidth)
 			}
 			if parsedMimo != "" && parsedMimo != "1x1" {
-				ws.clientStats.MIMOConfig = parsedMimo
-			} else if ws.clientStats.MIMOConfig == "" {
-				ws.clientStats.MIMOConfig = parsedMimo
+				cs.MIMOConfig = parsedMimo
+			} else if cs.MIMOConfig == "" {
+				cs.MIMOConfig = parsedMimo
 			}
 		}
-		if width, err := strconv.Atoi(stationStats["channel_width"]); err == nil && width != 0 && ws.clientStats.ChannelWidth == 0 {
-			ws.clientStats.ChannelWidth = width
+		if station.ChannelWidth != nil && cs.ChannelWidth == 0 {
+			cs.ChannelWidth = *station.ChannelWidth
 		}
-		if noise, err := strconv.Atoi(stationStats["noise"]); err == nil && noise != 0 {
-			ws.clientStats.Noise = noise
+		if station.Noise != nil {
+			cs.Noise = *station.Noise
 		}
-		if snr, err := strconv.Atoi(stationStats["snr"]); err == nil && snr != 0 {
-			ws.clientStats.SNR = snr
+		if station.SNR != nil {
+			cs.SNR = *station.SNR
 		}
 
-		if txBytes, err := strconv.ParseUint(stationStats["tx_bytes"], 10, 64); err == nil {
-			ws.clientStats.TxBytes = txBytes
-		}
-		if rxBytes, err := strconv.ParseUint(stationStats["rx_bytes"], 10, 64); err == nil {
-			ws.clientStats.RxBytes = rxBytes
-		}
-		if txPackets, err := strconv.ParseUint(stationStats["tx_packets"], 10, 64); err == nil {
-			ws.clientStats.TxPackets = txPackets
-		}
-		if rxPackets, err := strconv.ParseUint(stationStats["rx_packets"], 10, 64); err == nil {
-			ws.clientStats.RxPackets = rxPackets
-		}
-
-		if txRetries, err := strconv.ParseUint(stationStats["tx_retries"], 10, 64); err == nil {
-			ws.clientStats.TxRetries = txRetries
+		setCounter := func(dst *uint64, v *uint64) {
+			if v != nil {
+				*dst = *v
+			}
 		}
-		if txFailed, err := strconv.ParseUint(stationStats["tx_failed"], 10, 64); err == nil {
-			ws.clientStats.TxFailed = txFailed
+		setCounter(&cs.TxBytes, station.TxBytes)
+		setCounter(&cs.RxBytes, station.RxBytes)
+		setCounter(&cs.TxPackets, station.TxPackets)
+		setCounter(&cs.RxPackets, station.RxPackets)
+		setCounter(&cs.TxRetries, station.TxRetries)
+		setCounter(&cs.TxFailed, station.TxFailed)
+
+		if cs.TxPackets > 0 {
+			cs.RetryRate = (float64(cs.TxRetries) / float64(cs.TxPackets)) * 100
 		}
 
-		if ws.clientStats.TxPackets > 0 {
-			ws.clientStats.RetryRate = (float64(ws.clientStats.TxRetries) / float64(ws.clientStats.TxPackets)) * 100
+		if station.ConnectedTime != nil {
+			cs.ConnectedTime = *station.ConnectedTime
 		}
-
-		if connectedTime, err := strconv.Atoi(stationStats["connected_time"]); err == nil {
-			ws.clientStats.ConnectedTime = connectedTime
-		}
-
-		if lastAckSignal, err := strconv.Atoi(stationStats["last_ack_signal"]); err == nil {
-			ws.clientStats.LastAckSignal = lastAckSignal
+		if station.LastAckSignal != nil {
+			cs.LastAckSignal = *station.LastAckSignal
 		}
 	}
 
-	ws.updateSignalHistoryLocked()
-	NormalizeClientStats(&ws.clientStats)
+	roam := ws.updateSignalHistoryLocked(r)
+	NormalizeClientStats(&r.clientStats)
+	return roam
 }
 
 // updateSignalHistoryLocked appends the current signal reading and detects
-// roaming events. Caller must hold ws.mu.Lock.
+// roaming events, returning the event when the BSSID changed. Caller must
+// hold ws.mu.Lock.
 //
 // This function only runs on scan ticks where the client is connected —
 // updateClientStatsLocked early-returns on a disconnected interface. That
-// means ws.lastBSSIDSeenAt stays frozen across disconnect gaps, so the
+// means r.lastBSSIDSeenAt stays frozen across disconnect gaps, so the
 // DurationMs computation below naturally covers "old BSSID last observed"
 // through "new BSSID first observed", including any mid-roam outage.
 //
@@ -604,53 +905,54 @@ func (ws *WiFiService) updateClientStatsLocked(iface string) {
 // cadence a 200 ms real roam and a 1500 ms real roam will both report
 // somewhere in the 0–4000 ms range depending on tick phase. Tighten
 // `scan_interval_seconds` in config for finer granularity.
-func (ws *WiFiService) updateSignalHistoryLocked() {
-	now := time.Now()
+func (ws *WiFiService) updateSignalHistoryLocked(r *radioState) *RoamingEvent {
+	now := ws.now()
 	dataPoint := SignalDataPoint{
 		Timestamp: now,
-		Signal:    ws.clientStats.Signal,
-		BSSID:     ws.clientStats.BSSID,
+		Signal:    r.clientStats.Signal,
+		BSSID:     r.clientStats.BSSID,
 	}
 
 	// appendCapped allocates a fresh backing array when truncating, so prior
 	// slice headers handed out via GetClientStats remain valid and immutable.
 	cfg := ws.config.Get()
-	ws.signalHistory = appendCapped(ws.signalHistory, dataPoint, cfg.SignalHistorySize())
+	r.signalHistory = appendCapped(r.signalHistory, dataPoint, cfg.SignalHistorySize())
 
 	// Detect roaming events
-	if ws.lastBSSID != "" && ws.lastBSSID != ws.clientStats.BSSID {
+	var roam *RoamingEvent
+	if r.lastBSSID != "" && r.lastBSSID != r.clientStats.BSSID {
 		// Find previous signal
 		var prevSignal int
-		for i := len(ws.signalHistory) - 2; i >= 0; i-- {
-			if ws.signalHistory[i].BSSID == ws.lastBSSID {
-				prevSignal = ws.signalHistory[i].Signal
+		for i := len(r.signalHistory) - 2; i >= 0; i-- {
+			if r.signalHistory[i].BSSID == r.lastBSSID {
+				prevSignal = r.signalHistory[i].Signal
 				break
 			}
 		}
 
 		var durationMs int64
-		if !ws.lastBSSIDSeenAt.IsZero() {
-			durationMs = now.Sub(ws.lastBSSIDSeenAt).Milliseconds()
+		if !r.lastBSSIDSeenAt.IsZero() {
+			durationMs = now.Sub(r.lastBSSIDSeenAt).Milliseconds()
 		}
 
 		roamingEvent := RoamingEvent{
 			Timestamp:      now,
-			PreviousBSSID:  ws.lastBSSID,
-			NewBSSID:       ws.clientStats.BSSID,
+			PreviousBSSID:  r.lastBSSID,
+			NewBSSID:       r.clientStats.BSSID,
 			PreviousSignal: prevSignal,
-			NewSignal:      ws.clientStats.Signal,
+			NewSignal:      r.clientStats.Signal,
 			DurationMs:     durationMs,
 		}
-		ws.roamingHistory = appendCapped(ws.roamingHistory, roamingEvent, cfg.RoamingHistorySize)
-
-		runtime.EventsEmit(ws.ctx, "roaming:detected", roamingEvent)
+		r.roamingHistory = appendCapped(r.roamingHistory, roamingEvent, cfg.RoamingHistorySize)
+		roam = &roamingEvent
 	}
 
-	ws.lastBSSID = ws.clientStats.BSSID
-	ws.lastBSSIDSeenAt = now
+	r.lastBSSID = r.clientStats.BSSID
+	r.lastBSSIDSeenAt = now
 	// Note: SignalHistory/RoamingHistory on ClientStats are populated lazily
 	// via cloneClientStatsLocked when a caller asks for a snapshot; we never
 	// hand out the live backing slices anymore.
+	return roam
 }
 
 // recordAPSignalHistoryLocked appends one signal sample per observed BSSID
@@ -669,7 +971,8 @@ func (ws *WiFiService) recordAPSignalHistoryLocked(aps []AccessPoint, ts time.Ti
 		cap = 1
 	}
 	for _, ap := range aps {
-		if ap.BSSID == "" {
+		// A stale AP's signal is a repeat of one already recorded.
+		if ap.BSSID == "" || ap.Stale {
 			continue
 		}
 		entry, ok := ws.apSignalHistory[ap.BSSID]
@@ -717,25 +1020,6 @@ func (ws *WiFiService) GetAPSignalHistories() []APSignalHistory {
 	return out
 }
 
-// cloneClientStatsLocked returns a deep copy of ws.clientStats with fresh
-// SignalHistory/RoamingHistory slices. Caller must hold the lock.
-func (ws *WiFiService) cloneClientStatsLocked() ClientStats {
-	out := ws.clientStats
-	if n := len(ws.signalHistory); n > 0 {
-		out.SignalHistory = make([]SignalDataPoint, n)
-		copy(out.SignalHistory, ws.signalHistory)
-	} else {
-		out.SignalHistory = nil
-	}
-	if n := len(ws.roamingHistory); n > 0 {
-		out.RoamingHistory = make([]RoamingEvent, n)
-		copy(out.RoamingHistory, ws.roamingHistory)
-	} else {
-		out.RoamingHistory = nil
-	}
-	return out
-}
-
 // GetNetworks returns the list of discovered WiFi networks
 func (ws *WiFiService) GetNetworks() []Network {
 	ws.mu.RLock()
@@ -749,7 +1033,7 @@ func (ws *WiFiService) GetNetworks() []Network {
 func (ws *WiFiService) GetClientStats() ClientStats {
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
-	return ws.cloneClientStatsLocked()
+	return ws.primaryLocked().cloneClientStats()
 }
 
 // GetChannelAnalysis returns channel utilization information
@@ -766,18 +1050,42 @@ func (ws *WiFiService) IsScanning() bool {
 	return ws.scanning
 }
 
-// AnalyzeRoamingQuality analyzes roaming quality based on signal history.
+// AnalyzeRoamingQuality analyzes roaming quality based on the primary
+// interface's signal and roaming history.
 func (ws *WiFiService) AnalyzeRoamingQuality() RoamingQualityReport {
 	ws.mu.RLock()
 	defer ws.mu.RUnlock()
+	r := ws.primaryLocked()
 
-	if len(ws.roamingHistory) == 0 {
+	// A sticky client is by definition one that hasn't roamed, so it is
+	// only ever reported by the no-roams early return below.
+	stickyClient := false
+	if r.clientStats.Connected && r.clientStats.Signal < -75 && len(r.roamingHistory) == 0 {
+		for _, network := range ws.networks {
+			if network.SSID == r.clientStats.SSID && len(network.AccessPoints) > 1 {
+				for _, ap := range network.AccessPoints {
+					if ap.BSSID != r.clientStats.BSSID && ap.Signal > r.clientStats.Signal+10 {
+						stickyClient = true
+						break
+					}
+				}
+			}
+		}
+	}
+
+	if len(r.roamingHistory) == 0 {
+		if stickyClient {
+			return RoamingQualityReport{
+				StickyClient:  true,
+				RoamingAdvice: "Your device appears to be a 'sticky client' - it's staying connected to a weak AP when better options are available. Consider enabling 802.11k/v/r on your network or adjusting client roaming settings.",
+			}
+		}
 		return RoamingQualityReport{
 			RoamingAdvice: "No roaming data available yet. Connect to a network with multiple APs to see roaming analysis.",
 		}
 	}
 
-	totalRoams := len(ws.roamingHistory)
+	totalRoams := len(r.roamingHistory)
 	goodRoams := 0
 	badRoams := 0
 	totalSignalChange := 0
@@ -792,7 +1100,7 @@ func (ws *WiFiService) AnalyzeRoamingQuality() RoamingQualityReport {
 	durationSamples := 0
 	slowRoamCount := 0
 
-	for _, event := range ws.roamingHistory {
+	for _, event := range r.roamingHistory {
 		signalChange := event.NewSignal - event.PreviousSignal
 		totalSignalChange += signalChange
 		if signalChange >= 0 {
@@ -822,26 +1130,12 @@ func (ws *WiFiService) AnalyzeRoamingQuality() RoamingQualityReport {
 		avgDurationMs = totalDurationMs / int64(durationSamples)
 	}
 
-	excessiveRoaming := totalRoams > 10 && len(ws.signalHistory) > 0 &&
-		float64(totalRoams)/float64(len(ws.signalHistory))*100 > 5
-
-	stickyClient := false
-	if ws.clientStats.Connected && ws.clientStats.Signal < -75 && totalRoams == 0 {
-		for _, network := range ws.networks {
-			if network.SSID == ws.clientStats.SSID && len(network.AccessPoints) > 1 {
-				for _, ap := range network.AccessPoints {
-					if ap.BSSID != ws.clientStats.BSSID && ap.Signal > ws.clientStats.Signal+10 {
-						stickyClient = true
-						break
-					}
-				}
-			}
-		}
-	}
+	excessiveRoaming := totalRoams > 10 && len(r.signalHistory) > 0 &&
+		float64(totalRoams)/float64(len(r.signalHistory))*100 > 5
 
 	var timeSinceLastRoam string
-	lastRoam := ws.roamingHistory[len(ws.roamingHistory)-1]
-	timeSince := time.Since(lastRoam.Timestamp)
+	lastRoam := r.roamingHistory[len(r.roamingHistory)-1]
+	timeSince := ws.now().Sub(lastRoam.Timestamp)
 	switch {
 	case timeSince < time.Minute:
 		timeSinceLastRoam = fmt.Sprintf("%ds ago", int(timeSince.Seconds()))
@@ -861,8 +1155,6 @@ func (ws *WiFiService) AnalyzeRoamingQuality() RoamingQualityReport {
 		advice = "Multiple roams exceeded 2 s. Common causes: 802.1X / RADIUS latency, missing 802.11r/k/v support, or AKM mismatches between APs. Check the authentication path first."
 	case excessiveRoaming:
 		advice = "Your device is roaming excessively. This may indicate overlapping AP coverage or unstable connections. Consider adjusting AP placement or roaming aggressiveness settings."
-	case stickyClient:
-		advice = "Your device appears to be a 'sticky client' - it's staying connected to a weak AP when better options are available. Consider enabling 802.11k/v/r on your network or adjusting client roaming settings."
 	case avgSignalChange > 5:
 		advice = "Roaming is working well! Your device is successfully moving to stronger access points."
 	case avgSignalChange < -5:
@@ -877,7 +1169,6 @@ func (ws *WiFiService) AnalyzeRoamingQuality() RoamingQualityReport {
 		BadRoams:          badRoams,
 		AvgSignalChange:   avgSignalChange,
 		ExcessiveRoaming:  excessiveRoaming,
-		StickyClient:      stickyClient,
 		TimeSinceLastRoam: timeSinceLastRoam,
 		RoamingAdvice:     advice,
 		AvgRoamDurationMs: avgDurationMs,
@@ -127,6 +127,15 @@ func intPtr(v int) *int {
 	return &v
 }
 
+// floatPtr and uint64Ptr are intPtr for LinkInfo / StationInfo fields.
+func floatPtr(v float64) *float64 {
+	return &v
+}
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
 // NormalizeAccessPoint applies consistent defaults and derived values across platforms.
 func NormalizeAccessPoint(ap *AccessPoint) {
 	if ap == nil {